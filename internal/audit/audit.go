@@ -0,0 +1,188 @@
+// Package audit mirrors content changes to a Discord channel. Every replica
+// runs a Mirror, but only the one holding the audit lock posts, and posted
+// revisions are marked in the database so each change is posted once.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// LockName is the storage lock held by the replica mirroring changes
+const LockName = "mutsumi-audit"
+
+// DefaultPollInterval is how often new revisions are checked by default
+const DefaultPollInterval = 15 * time.Second
+
+// maxAge bounds which revisions are mirrored, so that enabling the audit
+// channel doesn't replay the whole history
+const maxAge = 24 * time.Hour
+
+// batchSize is the most revisions posted per tick
+const batchSize = 20
+
+// maxDiffLine is the longest diff line posted, in runes
+const maxDiffLine = 300
+
+// Sender posts messages to channels; *discordgo.Session satisfies it
+type Sender interface {
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// Options configures a Mirror
+type Options struct {
+	// ChannelID is the channel changes are posted to
+	ChannelID string
+	// PollInterval is how often new revisions are checked
+	PollInterval time.Duration
+}
+
+// Mirror posts new revisions to the audit channel
+type Mirror struct {
+	store  storage.Store
+	sender Sender
+	opts   Options
+	now    func() time.Time
+
+	// lock is held while this replica is the leader
+	lock storage.Lock
+}
+
+// New creates a mirror posting the revisions of store through sender
+func New(store storage.Store, sender Sender, opts Options) *Mirror {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	return &Mirror{store: store, sender: sender, opts: opts, now: time.Now}
+}
+
+// Run posts new revisions every poll interval until ctx is done
+func (m *Mirror) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.opts.PollInterval)
+	defer ticker.Stop()
+	defer m.resign()
+
+	for {
+		m.Tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick posts the pending revisions if this replica is, or becomes, the leader
+func (m *Mirror) Tick(ctx context.Context) {
+	if !m.lead(ctx) {
+		return
+	}
+
+	pending, err := m.store.PendingRevisions(ctx, m.now().Add(-maxAge), batchSize)
+	if err != nil {
+		logger.Logger.Error("Failed to load revisions to mirror", zap.Error(err))
+		return
+	}
+	for _, rev := range pending {
+		// Changes quote entries, whose mentions must not ping the channel
+		_, err := m.sender.ChannelMessageSendComplex(m.opts.ChannelID, &discordgo.MessageSend{
+			Content:         Describe(rev),
+			AllowedMentions: &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}},
+		})
+		if err != nil {
+			// Keep the order: this revision and the next ones are retried
+			logger.Logger.Error("Failed to mirror revision",
+				zap.Int64("revision_id", rev.ID),
+				zap.String("channel_id", m.opts.ChannelID),
+				zap.Error(err))
+			return
+		}
+		if err := m.store.MarkRevisionsMirrored(ctx, []int64{rev.ID}); err != nil {
+			logger.Logger.Error("Failed to mark revision mirrored", zap.Int64("revision_id", rev.ID), zap.Error(err))
+			return
+		}
+	}
+}
+
+// lead keeps or acquires the audit lock and reports whether it is held
+func (m *Mirror) lead(ctx context.Context) bool {
+	if m.lock != nil {
+		err := m.lock.Check(ctx)
+		if err == nil {
+			return true
+		}
+		logger.Logger.Warn("Lost the audit lock", zap.Error(err))
+		m.resign()
+	}
+
+	lock, err := m.store.TryLock(ctx, LockName)
+	if errors.Is(err, storage.ErrLocked) {
+		logger.Logger.Debug("Another replica mirrors content changes")
+		return false
+	}
+	if err != nil {
+		logger.Logger.Warn("Failed to take the audit lock", zap.Error(err))
+		return false
+	}
+	logger.Logger.Info("This replica now mirrors content changes")
+	m.lock = lock
+	return true
+}
+
+// resign releases the audit lock if it is held
+func (m *Mirror) resign() {
+	if m.lock == nil {
+		return
+	}
+	if err := m.lock.Release(); err != nil {
+		logger.Logger.Warn("Failed to release the audit lock", zap.Error(err))
+	}
+	m.lock = nil
+}
+
+// snowflake matches Discord IDs, which are mentioned instead of shown raw
+var snowflake = regexp.MustCompile(`^[0-9]{17,20}$`)
+
+// Describe formats a revision as a Discord message: what happened to which
+// entry, who did it and when, then the changed fields
+func Describe(rev storage.Revision) string {
+	actor := rev.Actor
+	switch {
+	case actor == "":
+		actor = "unknown"
+	case snowflake.MatchString(actor):
+		actor = "<@" + actor + ">"
+	}
+	category := rev.After.Category
+	if rev.After.ID == 0 {
+		category = rev.Before.Category
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**#%d** %s of entry #%d in `%s` by %s <t:%d:R>",
+		rev.ID, rev.Action, rev.EntryID, category, actor, rev.At.Unix())
+	for _, line := range rev.Diff() {
+		b.WriteString("\n> " + shorten(line, maxDiffLine))
+	}
+	return b.String()
+}
+
+// shorten cuts s to at most n runes on a single line
+func shorten(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// errSend is returned by fakeSender when it is set to fail
+var errSend = errors.New("send failed")
+
+// fakeSender records the messages sent by the mirror
+type fakeSender struct {
+	mu       sync.Mutex
+	messages []*discordgo.Message
+	fail     bool
+}
+
+func (f *fakeSender) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return nil, errSend
+	}
+	if data.AllowedMentions == nil || len(data.AllowedMentions.Parse) != 0 {
+		return nil, errors.New("audit posts must not ping anyone")
+	}
+	msg := &discordgo.Message{ChannelID: channelID, Content: data.Content}
+	f.messages = append(f.messages, msg)
+	return msg, nil
+}
+
+func (f *fakeSender) sent() []*discordgo.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*discordgo.Message(nil), f.messages...)
+}
+
+// setupTestMirror creates a mirror on a memory store
+func setupTestMirror(t *testing.T, store *storage.MemoryStore) (*Mirror, *fakeSender) {
+	t.Helper()
+	if err := logger.Init(); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(logger.Close)
+
+	sender := &fakeSender{}
+	m := New(store, sender, Options{ChannelID: "audit-channel"})
+	t.Cleanup(m.resign)
+	return m, sender
+}
+
+// TestMirror_Tick tests that each change is posted once, in order, by the
+// replica holding the lock.
+func TestMirror_Tick(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	store := storage.NewMemoryStore()
+	id, _ := store.AddContent(ctx, "wooper", "Wooper!")
+	old, _ := store.AddContent(ctx, "wooper", "Old wooper")
+	store.ApplyChanges(ctx, storage.ChangeSet{Delete: []int64{old}, Actor: "cli", At: now.Add(-48 * time.Hour)})
+	store.ApplyChanges(ctx, storage.ChangeSet{
+		Update: []storage.Entry{{ID: id, Category: "wooper", Content: "Wooper wooper!"}},
+		Actor:  "123456789012345678",
+		At:     now.Add(-time.Minute),
+	})
+	store.ApplyChanges(ctx, storage.ChangeSet{Delete: []int64{id}, Actor: "cli", At: now})
+
+	leader, sender := setupTestMirror(t, store)
+	other, otherSender := setupTestMirror(t, store)
+	leader.now = func() time.Time { return now }
+	other.now = leader.now
+
+	leader.Tick(ctx)
+	other.Tick(ctx)
+	leader.Tick(ctx)
+
+	sent := sender.sent()
+	if len(sent) != 2 || len(otherSender.sent()) != 0 {
+		t.Fatalf("Expected the leader to post the 2 recent changes once, got %d and %d posts", len(sent), len(otherSender.sent()))
+	}
+	if sent[0].ChannelID != "audit-channel" {
+		t.Errorf("Expected posts in the audit channel, got %s", sent[0].ChannelID)
+	}
+	want := "**#2** update of entry #1 in `wooper` by <@123456789012345678> <t:1772441940:R>\n> content: \"Wooper!\" → \"Wooper wooper!\""
+	if sent[0].Content != want {
+		t.Errorf("Expected %q, got %q", want, sent[0].Content)
+	}
+	if !strings.HasPrefix(sent[1].Content, "**#3** delete of entry #1 in `wooper` by cli") {
+		t.Errorf("Unexpected delete post %q", sent[1].Content)
+	}
+}
+
+// TestMirror_SendFailure tests that revisions that couldn't be posted are
+// retried on the next tick.
+func TestMirror_SendFailure(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	id, _ := store.AddContent(ctx, "wooper", "Wooper!")
+	store.ApplyChanges(ctx, storage.ChangeSet{Delete: []int64{id}, Actor: "cli"})
+
+	m, sender := setupTestMirror(t, store)
+	sender.fail = true
+	m.Tick(ctx)
+	sender.fail = false
+	m.Tick(ctx)
+
+	if sent := sender.sent(); len(sent) != 1 {
+		t.Errorf("Expected the revision to be posted after the failure, got %+v", sent)
+	}
+}
+
+// TestDescribe tests shortening long content changes.
+func TestDescribe(t *testing.T) {
+	rev := storage.Revision{
+		ID: 4, EntryID: 2, Action: storage.RevisionUpdate, At: time.Unix(0, 0),
+		Before: storage.Entry{ID: 2, Category: "wooper", Content: "a"},
+		After:  storage.Entry{ID: 2, Category: "wooper", Content: strings.Repeat("b\n", 500)},
+	}
+	got := Describe(rev)
+	if !strings.HasPrefix(got, "**#4** update of entry #2 in `wooper` by unknown") {
+		t.Errorf("Unexpected description %q", got)
+	}
+	lines := strings.Split(got, "\n")
+	if len(lines) != 2 || len([]rune(lines[1])) != maxDiffLine+2 || !strings.HasSuffix(lines[1], "…") {
+		t.Errorf("Expected a single shortened diff line, got %q", lines[1:])
+	}
+}
@@ -2,27 +2,114 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 type Bot struct {
+	// session makes REST calls and runs shard 0 when this process owns it
 	session *discordgo.Session
+	guildID string
+
+	// shardCount is the total number of shards, 0 uses Discord's recommendation
+	shardCount int
+	// shardIDs are the shards run by this process, empty runs all of them
+	shardIDs []int
+
+	mu       sync.Mutex
+	handlers []*handler
+	shards   []*Shard
+}
+
+// handler is an event handler added to every shard session
+type handler struct {
+	fn      interface{}
+	removes []func()
+}
+
+// Option configures optional settings of the bot
+type Option func(*Bot)
+
+// WithHTTPClient sets the HTTP client used for REST calls and gateway
+// discovery, e.g. to point the bot at a fake Discord server in tests
+func WithHTTPClient(client *http.Client) Option {
+	return func(b *Bot) {
+		b.session.Client = client
+	}
+}
+
+// WithIntents sets the gateway intents requested when identifying
+func WithIntents(intents discordgo.Intent) Option {
+	return func(b *Bot) {
+		b.session.Identify.Intents = intents
+	}
+}
+
+// WithGuildID registers slash commands in a single guild, where changes
+// show up instantly, instead of globally
+func WithGuildID(guildID string) Option {
+	return func(b *Bot) {
+		b.guildID = guildID
+	}
+}
+
+// WithShards runs the bot as count gateway shards, count 0 using the number
+// recommended by Discord. ids selects the shards run by this process so a
+// large bot can be split across processes; none runs all of them.
+func WithShards(count int, ids ...int) Option {
+	return func(b *Bot) {
+		b.shardCount = count
+		b.shardIDs = slices.Clone(ids)
+	}
 }
 
-func New(token string) (*Bot, error) {
+func New(token string, opts ...Option) (*Bot, error) {
 	dg, err := discordgo.New("Bot " + token)
 	if err != nil {
 		return nil, fmt.Errorf("create discord session: %w", err)
 	}
 	dg.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentMessageContent
-	return &Bot{session: dg}, nil
+	b := &Bot{session: dg}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b, nil
 }
 
-func (b *Bot) AddHandler(handler interface{}) func() {
-	return b.session.AddHandler(handler)
+// AddHandler adds an event handler to every shard, including the ones
+// opened later
+func (b *Bot) AddHandler(fn interface{}) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := &handler{fn: fn}
+	for _, sh := range b.shards {
+		h.removes = append(h.removes, sh.session.AddHandler(fn))
+	}
+	b.handlers = append(b.handlers, h)
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.handlers = slices.DeleteFunc(b.handlers, func(other *handler) bool { return other == h })
+		for _, remove := range h.removes {
+			remove()
+		}
+		h.removes = nil
+	}
+}
+
+// Session returns the session used for REST calls, e.g. to post messages
+// outside of an event handler
+func (b *Bot) Session() *discordgo.Session {
+	return b.session
 }
 
 func (b *Bot) RegisterSlashCommands(commands []*discordgo.ApplicationCommand) error {
@@ -32,7 +119,7 @@ func (b *Bot) RegisterSlashCommands(commands []*discordgo.ApplicationCommand) er
 	}
 
 	for _, cmd := range commands {
-		_, err := b.session.ApplicationCommandCreate(b.session.State.User.ID, "", cmd)
+		_, err := b.session.ApplicationCommandCreate(b.session.State.User.ID, b.guildID, cmd)
 		if err != nil {
 			return fmt.Errorf("create application command %s: %w", cmd.Name, err)
 		}
@@ -41,24 +128,196 @@ func (b *Bot) RegisterSlashCommands(commands []*discordgo.ApplicationCommand) er
 	return nil
 }
 
+// Start opens the shards and keeps them running until ctx is done
 func (b *Bot) Start(ctx context.Context) error {
-	if err := b.session.Open(); err != nil {
-		return fmt.Errorf("open discord session: %w", err)
-	}
-	<-ctx.Done()
-	return b.session.Close()
+	return b.StartWithCommands(ctx, nil)
 }
 
+// StartWithCommands opens the shards, registers commands once and keeps the
+// shards running until ctx is done. Only the process running shard 0
+// registers commands.
 func (b *Bot) StartWithCommands(ctx context.Context, commands []*discordgo.ApplicationCommand) error {
-	if err := b.session.Open(); err != nil {
-		return fmt.Errorf("open discord session: %w", err)
+	if err := b.Open(ctx); err != nil {
+		return err
 	}
 
-	// Register slash commands after session is open
-	if err := b.RegisterSlashCommands(commands); err != nil {
-		return fmt.Errorf("register slash commands: %w", err)
+	if len(commands) > 0 {
+		if b.ownsShard(0) {
+			if err := b.RegisterSlashCommands(commands); err != nil {
+				b.Close()
+				return fmt.Errorf("register slash commands: %w", err)
+			}
+		} else {
+			log.Printf("Not registering slash commands, the process running shard 0 does")
+		}
 	}
 
 	<-ctx.Done()
-	return b.session.Close()
+	return b.Close()
+}
+
+// Open connects the shards run by this process. Shards identify in waves
+// that respect Discord's session start limits.
+func (b *Bot) Open(ctx context.Context) error {
+	gateway, err := b.session.GatewayBot()
+	if err != nil {
+		return fmt.Errorf("open discord session: get gateway: %w", err)
+	}
+
+	count := b.shardCount
+	if count == 0 {
+		count = max(gateway.Shards, 1)
+	}
+	ids := slices.Clone(b.shardIDs)
+	if len(ids) == 0 {
+		for id := range count {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	limit := gateway.SessionStartLimit
+	if limit.Total > 0 && limit.Remaining < len(ids) {
+		wait := time.Duration(limit.ResetAfter) * time.Millisecond
+		log.Printf("Session start limit reached, waiting %s before identifying", wait)
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+
+	for i, wave := range identifyWaves(ids, limit.MaxConcurrency) {
+		if i > 0 {
+			if err := sleep(ctx, identifyInterval); err != nil {
+				b.Close()
+				return err
+			}
+		}
+		if err := b.openWave(wave, count); err != nil {
+			b.Close()
+			return err
+		}
+	}
+
+	log.Printf("Opened %d of %d shards", len(ids), count)
+	return nil
+}
+
+// openWave opens the given shards concurrently
+func (b *Bot) openWave(ids []int, count int) error {
+	shards := make([]*Shard, len(ids))
+	for i, id := range ids {
+		sh, err := b.newShard(id, count)
+		if err != nil {
+			return err
+		}
+		shards[i] = sh
+	}
+
+	errs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, sh := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sh.session.Open(); err != nil {
+				errs[i] = fmt.Errorf("open discord session: shard %d: %w", sh.ID, err)
+				return
+			}
+			sh.connected.Store(true)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// newShard creates the session of a shard and adds the event handlers to it.
+// Shard 0 uses the bot's own session.
+func (b *Bot) newShard(id, count int) (*Shard, error) {
+	session := b.session
+	if id != 0 {
+		var err error
+		session, err = discordgo.New(b.session.Token)
+		if err != nil {
+			return nil, fmt.Errorf("create discord session: %w", err)
+		}
+		session.Client = b.session.Client
+		session.Identify.Intents = b.session.Identify.Intents
+	}
+	session.ShardID = id
+	session.ShardCount = count
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sh := newShard(id, session)
+	for _, h := range b.handlers {
+		h.removes = append(h.removes, session.AddHandler(h.fn))
+	}
+	b.shards = append(b.shards, sh)
+	return sh, nil
+}
+
+// Close disconnects every shard
+func (b *Bot) Close() error {
+	b.mu.Lock()
+	shards := b.shards
+	b.shards = nil
+	b.mu.Unlock()
+
+	// Each session waits for the gateway to acknowledge the close, so
+	// shards are closed concurrently
+	errs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, sh := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sh.session.Close(); err != nil {
+				errs[i] = fmt.Errorf("close shard %d: %w", sh.ID, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Shards returns the status of the shards run by this process
+func (b *Bot) Shards() []ShardStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	statuses := make([]ShardStatus, len(b.shards))
+	for i, sh := range b.shards {
+		statuses[i] = sh.Status()
+	}
+	return statuses
+}
+
+// Ready returns an error unless every shard run by this process is connected
+func (b *Bot) Ready() error {
+	statuses := b.Shards()
+	if len(statuses) == 0 {
+		return errors.New("no shard is open")
+	}
+	for _, status := range statuses {
+		if !status.Connected {
+			return fmt.Errorf("shard %d is disconnected", status.ID)
+		}
+	}
+	return nil
+}
+
+// ownsShard reports whether this process runs shard id
+func (b *Bot) ownsShard(id int) bool {
+	return len(b.shardIDs) == 0 || slices.Contains(b.shardIDs, id)
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
@@ -2,8 +2,13 @@ package bot
 
 import (
 	"context"
+	"slices"
 	"testing"
 	"time"
+
+	"mutsumi-bot/tests/fakediscord"
+
+	"github.com/bwmarrin/discordgo"
 )
 
 // TestNew tests the New function for creating Discord bot instances.
@@ -94,3 +99,202 @@ func TestBot_Start(t *testing.T) {
 		t.Log("Note: Start() succeeded unexpectedly (this might be due to test environment)")
 	}
 }
+
+// TestBot_StartWithCommands tests that slash commands are registered once the session is ready.
+func TestBot_StartWithCommands(t *testing.T) {
+	srv := fakediscord.New(t)
+
+	bot, err := New("test-token", WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create bot: %v", err)
+	}
+
+	commands := []*discordgo.ApplicationCommand{
+		{Name: "command", Description: "Get content from a registered command"},
+		{Name: "other", Description: "Another command"},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bot.StartWithCommands(ctx, commands)
+	}()
+
+	registered := srv.WaitFor(5*time.Second, func() bool {
+		return len(srv.Commands("")) == len(commands)
+	})
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !registered {
+		t.Fatalf("Expected %d commands to be registered, got %d", len(commands), len(srv.Commands("")))
+	}
+	if !srv.WaitFor(time.Second, func() bool { return srv.ConnectedClients() == 0 }) {
+		t.Errorf("Expected session to be closed, got %d connected clients", srv.ConnectedClients())
+	}
+}
+
+// TestBot_Options tests that intents and the development guild are used.
+func TestBot_Options(t *testing.T) {
+	srv := fakediscord.New(t)
+	const guildID = "300000000000000001"
+	intents := discordgo.IntentsGuilds | discordgo.IntentsGuildMessages
+
+	bot, err := New("test-token", WithHTTPClient(srv.Client()), WithIntents(intents), WithGuildID(guildID))
+	if err != nil {
+		t.Fatalf("Failed to create bot: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bot.StartWithCommands(ctx, []*discordgo.ApplicationCommand{{Name: "command", Description: "Get content"}})
+	}()
+
+	registered := srv.WaitFor(5*time.Second, func() bool { return len(srv.Commands(guildID)) == 1 })
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !registered {
+		t.Fatalf("Expected the command to be registered in guild %s", guildID)
+	}
+	if global := srv.Commands(""); len(global) != 0 {
+		t.Errorf("Expected no global commands, got %d", len(global))
+	}
+	if identifies := srv.Identifies(); len(identifies) == 0 || identifies[0].Intents != intents {
+		t.Errorf("Expected IDENTIFY with intents %d, got %+v", intents, identifies)
+	}
+}
+
+// TestBot_Shards tests that every shard identifies, that events are routed
+// to the shard owning the guild and that commands are registered once.
+func TestBot_Shards(t *testing.T) {
+	interval := identifyInterval
+	identifyInterval = 10 * time.Millisecond
+	t.Cleanup(func() { identifyInterval = interval })
+
+	tests := []struct {
+		name          string
+		shardCount    int
+		shardIDs      []int
+		wantShards    []int
+		wantRegisters int
+	}{
+		{name: "recommended shard count", wantShards: []int{0, 1, 2}, wantRegisters: 1},
+		{name: "configured shard count", shardCount: 4, wantShards: []int{0, 1, 2, 3}, wantRegisters: 1},
+		{name: "subset without shard 0", shardCount: 4, shardIDs: []int{3, 1}, wantShards: []int{1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakediscord.New(t)
+			srv.Shards = 3
+			srv.MaxConcurrency = 2
+
+			bot, err := New("test-token", WithHTTPClient(srv.Client()), WithShards(tt.shardCount, tt.shardIDs...))
+			if err != nil {
+				t.Fatalf("Failed to create bot: %v", err)
+			}
+			messages := make(chan int, 10)
+			bot.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+				messages <- s.ShardID
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() {
+				done <- bot.StartWithCommands(ctx, []*discordgo.ApplicationCommand{{Name: "command", Description: "Get content"}})
+			}()
+			defer func() {
+				cancel()
+				if err := <-done; err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}()
+
+			if !srv.WaitFor(5*time.Second, func() bool {
+				return bot.Ready() == nil && len(bot.Shards()) == len(tt.wantShards) && srv.CommandWrites() == tt.wantRegisters
+			}) {
+				t.Fatalf("Expected %d ready shards, got %+v", len(tt.wantShards), bot.Shards())
+			}
+
+			count := tt.shardCount
+			if count == 0 {
+				count = srv.Shards
+			}
+			var got []int
+			for _, identify := range srv.Identifies() {
+				if identify.Shard == nil || identify.Shard[1] != count {
+					t.Fatalf("Expected IDENTIFY with %d shards, got %v", count, identify.Shard)
+				}
+				got = append(got, identify.Shard[0])
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.wantShards) {
+				t.Errorf("Expected shards %v to identify, got %v", tt.wantShards, got)
+			}
+			if writes := srv.CommandWrites(); writes != tt.wantRegisters {
+				t.Errorf("Expected %d command registrations, got %d", tt.wantRegisters, writes)
+			}
+
+			// Guild 1 << 22 belongs to shard 1 % count
+			err = srv.DispatchMessageCreate(&discordgo.Message{
+				ChannelID: "300000000000000001",
+				GuildID:   "4194304",
+				Content:   "hello",
+				Author:    &discordgo.User{ID: "500000000000000001", Username: "tester"},
+			})
+			if err != nil {
+				t.Fatalf("Failed to dispatch message: %v", err)
+			}
+			select {
+			case shard := <-messages:
+				if shard != 1 {
+					t.Errorf("Expected shard 1 to receive the message, got %d", shard)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("No shard received the message")
+			}
+			select {
+			case shard := <-messages:
+				t.Errorf("Expected a single shard to receive the message, shard %d did too", shard)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+// TestIdentifyWaves tests that shards sharing a rate limit bucket identify
+// in separate waves.
+func TestIdentifyWaves(t *testing.T) {
+	tests := []struct {
+		name           string
+		ids            []int
+		maxConcurrency int
+		want           [][]int
+	}{
+		{"single shard", []int{0}, 1, [][]int{{0}}},
+		{"one bucket", []int{0, 1, 2}, 1, [][]int{{0}, {1}, {2}}},
+		{"two buckets", []int{0, 1, 2, 3, 4}, 2, [][]int{{0, 1}, {2, 3}, {4}}},
+		{"subset", []int{1, 3, 4}, 4, [][]int{{1, 3}, {4}}},
+		{"no limit reported", []int{0, 1}, 0, [][]int{{0}, {1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := identifyWaves(tt.ids, tt.maxConcurrency)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if !slices.Equal(got[i], tt.want[i]) {
+					t.Errorf("Expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// identifyInterval is how long a rate limit bucket waits between two
+// IDENTIFY calls, see Discord's max_concurrency
+var identifyInterval = 5 * time.Second
+
+// Shard is one gateway connection of the bot
+type Shard struct {
+	ID      int
+	session *discordgo.Session
+
+	connected atomic.Bool
+	connects  atomic.Uint64
+	events    atomic.Uint64
+	lastEvent atomic.Int64 // unix nanoseconds
+}
+
+// ShardStatus reports the health and activity of a shard
+type ShardStatus struct {
+	ID        int  `json:"id"`
+	Connected bool `json:"connected"`
+	// LatencyMS is the last heartbeat round trip, 0 until one completes
+	LatencyMS  int64     `json:"latency_ms"`
+	Events     uint64    `json:"events"`
+	Reconnects uint64    `json:"reconnects"`
+	LastEvent  time.Time `json:"last_event,omitzero"`
+}
+
+// newShard wraps session and tracks its connection state
+func newShard(id int, session *discordgo.Session) *Shard {
+	sh := &Shard{ID: id, session: session}
+	session.AddHandler(func(_ *discordgo.Session, _ *discordgo.Connect) {
+		sh.connects.Add(1)
+	})
+	session.AddHandler(func(_ *discordgo.Session, _ *discordgo.Disconnect) {
+		sh.connected.Store(false)
+	})
+	session.AddHandler(func(_ *discordgo.Session, _ *discordgo.Ready) {
+		sh.connected.Store(true)
+	})
+	session.AddHandler(func(_ *discordgo.Session, _ *discordgo.Resumed) {
+		sh.connected.Store(true)
+	})
+	session.AddHandler(func(_ *discordgo.Session, _ *discordgo.Event) {
+		sh.events.Add(1)
+		sh.lastEvent.Store(time.Now().UnixNano())
+	})
+	return sh
+}
+
+// Status returns the current health and activity of the shard
+func (sh *Shard) Status() ShardStatus {
+	status := ShardStatus{
+		ID:        sh.ID,
+		Connected: sh.connected.Load(),
+		Events:    sh.events.Load(),
+	}
+	if n := sh.connects.Load(); n > 1 {
+		status.Reconnects = n - 1
+	}
+	if ns := sh.lastEvent.Load(); ns != 0 {
+		status.LastEvent = time.Unix(0, ns).UTC()
+	}
+
+	sh.session.RLock()
+	sent, ack := sh.session.LastHeartbeatSent, sh.session.LastHeartbeatAck
+	sh.session.RUnlock()
+	if !ack.IsZero() && !ack.Before(sent) {
+		status.LatencyMS = ack.Sub(sent).Milliseconds()
+	}
+	return status
+}
+
+// identifyWaves groups shard IDs into waves that can IDENTIFY together.
+// Shards share a rate limit bucket when their ID modulo maxConcurrency is
+// equal, so each wave holds at most one shard per bucket.
+func identifyWaves(ids []int, maxConcurrency int) [][]int {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	var waves [][]int
+	for i, id := range ids {
+		if i == 0 || id/maxConcurrency != ids[i-1]/maxConcurrency {
+			waves = append(waves, nil)
+		}
+		waves[len(waves)-1] = append(waves[len(waves)-1], id)
+	}
+	return waves
+}
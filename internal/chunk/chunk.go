@@ -0,0 +1,183 @@
+// Package chunk splits text too long for a single Discord message into
+// several, without breaking its markdown. Cuts are made between paragraphs
+// when possible, then between lines, then between words, and code blocks
+// cut in the middle are closed and reopened so that each message renders
+// on its own.
+package chunk
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Limit is the number of characters Discord accepts in a message
+const Limit = 2000
+
+// fence opens and closes code blocks
+const fence = "```"
+
+// Split cuts text into chunks of at most max characters. Text that fits is
+// returned as is; chunks are trimmed of the blank lines around them and of
+// trailing spaces.
+func Split(text string, max int) []string {
+	if utf8.RuneCountInString(text) <= max {
+		return []string{text}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	size := 0
+	flush := func() {
+		if chunk := strings.TrimRight(strings.TrimLeft(current.String(), "\n"), " \t\n"); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+		size = 0
+	}
+	add := func(piece string) {
+		if size+length(piece) > max {
+			flush()
+		}
+		current.WriteString(piece)
+		size += utf8.RuneCountInString(piece)
+	}
+
+	for _, block := range blocks(text) {
+		if length(block) <= max {
+			add(block)
+			continue
+		}
+		for _, piece := range splitBlock(block, max) {
+			add(piece)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// length counts the characters of a piece without its trailing whitespace,
+// which is dropped when the piece ends a chunk
+func length(piece string) int {
+	return utf8.RuneCountInString(strings.TrimRight(piece, " \t\n"))
+}
+
+// blocks cuts text into paragraphs, each with the blank lines that follow
+// it, and code blocks
+func blocks(text string) []string {
+	var blocks []string
+	var current strings.Builder
+	inFence, ended := false, false
+	cut := func() {
+		if current.Len() > 0 {
+			blocks = append(blocks, current.String())
+			current.Reset()
+		}
+		ended = false
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inFence:
+			current.WriteString(line)
+			if strings.HasPrefix(trimmed, fence) {
+				inFence = false
+				cut()
+			}
+		case strings.HasPrefix(trimmed, fence):
+			cut()
+			current.WriteString(line)
+			// A fence closed on its own line, e.g. ```code```, is inline
+			inFence = strings.Count(trimmed, fence) == 1
+			if !inFence {
+				ended = true
+			}
+		case trimmed == "":
+			current.WriteString(line)
+			ended = true
+		default:
+			if ended {
+				cut()
+			}
+			current.WriteString(line)
+		}
+	}
+	cut()
+	return blocks
+}
+
+// splitBlock cuts a block longer than max into pieces of at most max
+// characters. A code block is cut between lines into several code blocks.
+func splitBlock(block string, max int) []string {
+	open, body, ok := strings.Cut(block, "\n")
+	if !ok || !strings.HasPrefix(strings.TrimSpace(open), fence) {
+		return splitLines(block, max)
+	}
+	open += "\n"
+	if i := strings.LastIndex(body, fence); i >= 0 && strings.TrimSpace(body[i:]) == fence {
+		body = body[:i]
+	}
+
+	// Room for the code, with a newline before the closing fence
+	room := max - utf8.RuneCountInString(open) - len(fence) - 1
+	if room < 1 {
+		return splitLines(block, max)
+	}
+
+	var pieces []string
+	var current strings.Builder
+	size := 0
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		code := current.String()
+		if !strings.HasSuffix(code, "\n") {
+			code += "\n"
+		}
+		pieces = append(pieces, open+code+fence+"\n")
+		current.Reset()
+		size = 0
+	}
+	for _, line := range splitLines(body, room) {
+		n := utf8.RuneCountInString(line)
+		if size+n > room {
+			flush()
+		}
+		current.WriteString(line)
+		size += n
+	}
+	flush()
+	return pieces
+}
+
+// splitLines cuts text into its lines, and lines longer than max into
+// pieces of at most max characters, between words when possible
+func splitLines(text string, max int) []string {
+	var pieces []string
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for utf8.RuneCountInString(line) > max {
+			cut := byteOffset(line, max)
+			if space := strings.LastIndexAny(line[:cut], " \t"); space > 0 {
+				cut = space + 1
+			}
+			pieces = append(pieces, line[:cut])
+			line = line[cut:]
+		}
+		if line != "" {
+			pieces = append(pieces, line)
+		}
+	}
+	return pieces
+}
+
+// byteOffset returns the offset of the character at index n of s
+func byteOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
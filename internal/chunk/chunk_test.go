@@ -0,0 +1,116 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestSplit tests where long text is cut.
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want []string
+	}{
+		{
+			name: "fits",
+			text: "Wooper!\n\nWooper wooper!",
+			max:  30,
+			want: []string{"Wooper!\n\nWooper wooper!"},
+		},
+		{
+			name: "between paragraphs",
+			text: "First paragraph.\n\nSecond paragraph.\n\nThird.",
+			max:  40,
+			want: []string{"First paragraph.\n\nSecond paragraph.", "Third."},
+		},
+		{
+			name: "between lines",
+			text: "line one\nline two\nline three",
+			max:  20,
+			want: []string{"line one\nline two", "line three"},
+		},
+		{
+			name: "between words",
+			text: "a very long line without any newline",
+			max:  15,
+			want: []string{"a very long", "line without", "any newline"},
+		},
+		{
+			name: "single word",
+			text: "abcdefghij",
+			max:  4,
+			want: []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name: "characters, not bytes",
+			text: "ウーパールーパー ウーパールーパー",
+			max:  9,
+			want: []string{"ウーパールーパー", "ウーパールーパー"},
+		},
+		{
+			name: "code block kept whole",
+			text: "Intro\n```go\nfmt.Println(1)\n```\nOutro",
+			max:  25,
+			want: []string{"Intro", "```go\nfmt.Println(1)\n```", "Outro"},
+		},
+		{
+			name: "code block reopened",
+			text: "```\nline 1\nline 2\nline 3\nline 4\n```",
+			max:  22,
+			want: []string{"```\nline 1\nline 2\n```", "```\nline 3\nline 4\n```"},
+		},
+		{
+			name: "blank lines in a code block",
+			text: "```\na\n\nb\n```\n\nafter the code block",
+			max:  20,
+			want: []string{"```\na\n\nb\n```", "after the code block"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.text, tt.max)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Split() = %q, want %q", got, tt.want)
+			}
+			for _, chunk := range got {
+				if n := utf8.RuneCountInString(chunk); n > tt.max {
+					t.Errorf("Chunk %q has %d characters, more than %d", chunk, n, tt.max)
+				}
+			}
+		})
+	}
+}
+
+// TestSplit_Fences tests that every chunk of a long code block is a code
+// block of its own.
+func TestSplit_Fences(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("Some code:\n\n```python\n")
+	for range 300 {
+		b.WriteString("print('wooper wooper wooper')\n")
+	}
+	b.WriteString("```\n\nThat's all.")
+
+	chunks := Split(b.String(), Limit)
+	if len(chunks) < 5 {
+		t.Fatalf("Expected several chunks, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if n := utf8.RuneCountInString(chunk); n > Limit {
+			t.Errorf("Chunk %d has %d characters", i, n)
+		}
+		if strings.Count(chunk, "```")%2 != 0 {
+			t.Errorf("Chunk %d leaves a code block open:\n%s", i, chunk)
+		}
+	}
+	if !strings.HasPrefix(chunks[1], "```python\n") {
+		t.Errorf("Expected the code block to be reopened with its language, got %q", chunks[1][:20])
+	}
+	if last := chunks[len(chunks)-1]; !strings.HasSuffix(last, "That's all.") {
+		t.Errorf("Expected the text after the code block last, got %q", last)
+	}
+}
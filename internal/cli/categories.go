@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"mutsumi-bot/internal/services"
+)
+
+// runCategories prints every category with its number of entries
+func (a *app) runCategories(_ context.Context, args []string) error {
+	fs := a.newFlagSet("categories", "categories")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return a.withService(func(svc *services.DatabaseService) error {
+		counts, err := svc.CategoryCounts()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "category\tentries")
+		total := 0
+		for _, c := range counts {
+			fmt.Fprintf(tw, "%s\t%d\n", c.Category, c.Count)
+			total += c.Count
+		}
+		fmt.Fprintf(tw, "total\t%d\n", total)
+		return tw.Flush()
+	})
+}
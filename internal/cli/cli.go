@@ -0,0 +1,175 @@
+// Package cli implements the offline subcommands of mutsumi-bot, which
+// manage the database without starting a Discord session.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"mutsumi-bot/internal/config"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+)
+
+// errUsage is returned when a command is invoked with invalid arguments
+var errUsage = errors.New("invalid usage")
+
+// app holds the I/O and dependencies shared by all subcommands
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	// loadConfig reads the configuration from file, or from CONFIG_FILE when
+	// empty, then applies the bot flags in args; the bot token is optional
+	loadConfig func(file string, args []string) (config.Config, error)
+	// openService connects to the configured database
+	openService func(cfg config.Config) (*services.DatabaseService, error)
+	// inspectService connects to the configured database without applying
+	// pending migrations
+	inspectService func(cfg config.Config) (*services.DatabaseService, error)
+	// httpClient is used for Discord API calls
+	httpClient *http.Client
+}
+
+// command is a CLI subcommand
+type command struct {
+	usage string
+	help  string
+	run   func(a *app, ctx context.Context, args []string) error
+}
+
+// commands lists the top-level subcommands
+var commands = map[string]command{
+	"content": {
+		usage: "content list|show|add|rm|history|restore|export|import",
+		help:  "Manage content entries",
+		run:   (*app).runContent,
+	},
+	"categories": {
+		usage: "categories",
+		help:  "List categories and their entry counts",
+		run:   (*app).runCategories,
+	},
+	"config": {
+		usage: "config print [-config file] [-format yaml|toml] [-- flags]",
+		help:  "Validate and print the configuration with secrets redacted",
+		run:   (*app).runConfig,
+	},
+	"doctor": {
+		usage: "doctor [-config file]",
+		help:  "Check config, database, schema version and bot token",
+		run:   (*app).runDoctor,
+	},
+}
+
+// Run executes the subcommand named by args[0] and returns the process exit code
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	a := &app{
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+		loadConfig: func(file string, args []string) (config.Config, error) {
+			return config.LoadWith(config.Options{File: file, Args: args})
+		},
+		openService: func(cfg config.Config) (*services.DatabaseService, error) {
+			return services.NewDatabaseService(cfg.DatabaseConnection)
+		},
+		inspectService: func(cfg config.Config) (*services.DatabaseService, error) {
+			return services.NewDatabaseServiceWithOptions(cfg.DatabaseConnection, storage.Options{SkipMigrations: true})
+		},
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	return a.run(ctx, args)
+}
+
+func (a *app) run(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		a.printUsage()
+		return 0
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(a.stderr, "unknown command %q\n\n", args[0])
+		a.printUsage()
+		return 2
+	}
+
+	if err := cmd.run(a, ctx, args[1:]); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			if !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintf(a.stderr, "%v\n", err)
+			}
+			return 2
+		}
+		fmt.Fprintf(a.stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func (a *app) printUsage() {
+	fmt.Fprintln(a.stderr, "Usage: mutsumi-bot [command]")
+	fmt.Fprintln(a.stderr, "\nWithout a command the bot is started.\n\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(a.stderr, "  %-40s %s\n", commands[name].usage, commands[name].help)
+	}
+}
+
+// newFlagSet creates a flag set writing its usage to stderr
+func (a *app) newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: mutsumi-bot %s\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// usageError wraps a message so Run exits with status 2
+func usageError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, args...))
+}
+
+// withService connects to the configured database, runs fn and disconnects
+func (a *app) withService(fn func(*services.DatabaseService) error) error {
+	cfg, err := a.loadConfig("", nil)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	svc, err := a.openService(cfg)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer svc.Close()
+	return fn(svc)
+}
+
+// openInput opens a file for reading, or stdin for "-"
+func (a *app) openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(a.stdin), nil
+	}
+	return os.Open(path)
+}
+
+// subcommandUsage formats a list of subcommands for error messages
+func subcommandUsage(names ...string) string {
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,382 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mutsumi-bot/internal/config"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+	"mutsumi-bot/tests/fakediscord"
+)
+
+// testApp is an app wired to an in-memory store and captured output
+type testApp struct {
+	*app
+	store  *storage.MemoryStore
+	cfg    config.Config
+	stdin  *strings.Reader
+	stdout *bytes.Buffer
+	stderr *bytes.Buffer
+}
+
+// newTestApp creates an app backed by a memory store
+func newTestApp(t *testing.T, stdin string) *testApp {
+	t.Helper()
+	if err := logger.Init(); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(logger.Close)
+
+	ta := &testApp{
+		store:  storage.NewMemoryStore(),
+		stdin:  strings.NewReader(stdin),
+		stdout: &bytes.Buffer{},
+		stderr: &bytes.Buffer{},
+		cfg:    config.Config{DatabaseConnection: "memory://"},
+	}
+	ta.app = &app{
+		stdin:      ta.stdin,
+		stdout:     ta.stdout,
+		stderr:     ta.stderr,
+		loadConfig: func(string, []string) (config.Config, error) { return ta.cfg, nil },
+		openService: func(config.Config) (*services.DatabaseService, error) {
+			return services.NewDatabaseServiceWithStore(ta.store), nil
+		},
+		inspectService: func(config.Config) (*services.DatabaseService, error) {
+			return services.NewDatabaseServiceWithStore(ta.store), nil
+		},
+	}
+	return ta
+}
+
+// TestRun_Usage tests exit codes for help and invalid commands.
+func TestRun_Usage(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{name: "help", args: []string{"help"}, wantCode: 0},
+		{name: "unknown command", args: []string{"frobnicate"}, wantCode: 2},
+		{name: "content without subcommand", args: []string{"content"}, wantCode: 2},
+		{name: "unknown content subcommand", args: []string{"content", "frobnicate"}, wantCode: 2},
+		{name: "import without file", args: []string{"content", "import"}, wantCode: 2},
+		{name: "import stdin without format", args: []string{"content", "import", "-"}, wantCode: 2},
+		{name: "export bad format", args: []string{"content", "export", "-format", "xml"}, wantCode: 2},
+		{name: "list without category", args: []string{"content", "list"}, wantCode: 2},
+		{name: "show bad ID", args: []string{"content", "show", "abc"}, wantCode: 2},
+		{name: "add without content", args: []string{"content", "add", "wooper"}, wantCode: 2},
+		{name: "rm without IDs", args: []string{"content", "rm"}, wantCode: 2},
+		{name: "rm category without confirmation", args: []string{"content", "rm", "-category", "wooper"}, wantCode: 2},
+		{name: "show missing entry", args: []string{"content", "show", "42"}, wantCode: 1},
+		{name: "list empty category", args: []string{"content", "list", "wooper"}, wantCode: 1},
+		{name: "history without ID", args: []string{"content", "history"}, wantCode: 2},
+		{name: "history missing entry", args: []string{"content", "history", "42"}, wantCode: 1},
+		{name: "restore missing entry", args: []string{"content", "restore", "42"}, wantCode: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestApp(t, "")
+			if code := ta.run(context.Background(), tt.args); code != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d (stderr: %s)", tt.wantCode, code, ta.stderr)
+			}
+		})
+	}
+}
+
+// TestContentImportExport tests importing from stdin and exporting to a file.
+func TestContentImportExport(t *testing.T) {
+	ctx := context.Background()
+	ta := newTestApp(t, "category,content,weight,tags\nwooper,Wooper!,2,blue\nwooper,Wooper!,2,blue\ncats,Meow,,\n")
+
+	if code := ta.run(ctx, []string{"content", "import", "-format", "csv", "-"}); code != 0 {
+		t.Fatalf("Import failed with code %d: %s", code, ta.stderr)
+	}
+	if !strings.Contains(ta.stdout.String(), "duplicates") {
+		t.Errorf("Expected a summary table, got %q", ta.stdout)
+	}
+
+	entries, _ := ta.store.ListEntries(ctx, "")
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 imported entries, got %+v", entries)
+	}
+
+	out := filepath.Join(t.TempDir(), "backup.yaml")
+	if code := ta.run(ctx, []string{"content", "export", "-category", "wooper", "-o", out}); code != 0 {
+		t.Fatalf("Export failed with code %d: %s", code, ta.stderr)
+	}
+
+	// Importing the export again changes nothing
+	ta.stdout.Reset()
+	if code := ta.run(ctx, []string{"content", "import", "-mode", "replace", out}); code != 0 {
+		t.Fatalf("Re-import failed with code %d: %s", code, ta.stderr)
+	}
+	var wooper []string
+	for _, line := range strings.Split(ta.stdout.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "wooper" {
+			wooper = fields
+		}
+	}
+	if strings.Join(wooper, " ") != "wooper 0 0 0 1 0" {
+		t.Errorf("Expected the wooper entry to be unchanged, got:\n%s", ta.stdout)
+	}
+}
+
+// TestContentManagement tests adding, listing, showing and removing entries.
+func TestContentManagement(t *testing.T) {
+	ctx := context.Background()
+	ta := newTestApp(t, "line one\nline two\n")
+
+	steps := [][]string{
+		{"content", "add", "-weight", "3", "-tags", "Blue,cute", "wooper", "Wooper", "is", "here"},
+		{"content", "add", "wooper", "-"},
+		{"content", "add", "-type", "reaction", "cats", "🐱"},
+	}
+	for _, args := range steps {
+		if code := ta.run(ctx, args); code != 0 {
+			t.Fatalf("%v failed with code %d: %s", args, code, ta.stderr)
+		}
+	}
+
+	entries, _ := ta.store.ListEntries(ctx, "wooper")
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 wooper entries, got %+v", entries)
+	}
+	if entries[0].Content != "Wooper is here" || entries[0].Weight != 3 || strings.Join(entries[0].Tags, ",") != "blue,cute" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Content != "line one\nline two" {
+		t.Errorf("Expected content from stdin, got %q", entries[1].Content)
+	}
+
+	if cats, _ := ta.store.ListEntries(ctx, "cats"); len(cats) != 1 || cats[0].Type != storage.EntryReaction {
+		t.Errorf("Expected a reaction entry, got %+v", cats)
+	}
+	if code := ta.run(ctx, []string{"content", "add", "-type", "reaction", "cats", "Meow"}); code != 1 {
+		t.Errorf("Expected exit code 1 for a reaction entry without emoji, got %d", code)
+	}
+
+	ta.stdout.Reset()
+	if code := ta.run(ctx, []string{"content", "list", "wooper"}); code != 0 {
+		t.Fatalf("List failed with code %d: %s", code, ta.stderr)
+	}
+	if !strings.Contains(ta.stdout.String(), "Wooper is here") || !strings.Contains(ta.stdout.String(), "line one …") {
+		t.Errorf("Unexpected list output:\n%s", ta.stdout)
+	}
+
+	ta.stdout.Reset()
+	if code := ta.run(ctx, []string{"content", "show", "2"}); code != 0 {
+		t.Fatalf("Show failed with code %d: %s", code, ta.stderr)
+	}
+	if !strings.Contains(ta.stdout.String(), "line one\nline two") {
+		t.Errorf("Expected the full content, got:\n%s", ta.stdout)
+	}
+
+	ta.stdout.Reset()
+	if code := ta.run(ctx, []string{"categories"}); code != 0 {
+		t.Fatalf("Categories failed with code %d: %s", code, ta.stderr)
+	}
+	if !strings.Contains(ta.stdout.String(), "wooper") || !strings.HasSuffix(strings.Join(strings.Fields(ta.stdout.String()), " "), "total 3") {
+		t.Errorf("Unexpected categories output:\n%s", ta.stdout)
+	}
+
+	// Removing an unknown ID removes nothing
+	if code := ta.run(ctx, []string{"content", "rm", "1", "99"}); code != 1 {
+		t.Errorf("Expected exit code 1 for an unknown ID, got %d", code)
+	}
+	if count, _ := ta.store.CountContent(ctx, "wooper"); count != 2 {
+		t.Errorf("Expected no entry to be removed, got %d left", count)
+	}
+
+	if code := ta.run(ctx, []string{"content", "rm", "-category", "wooper", "-yes"}); code != 0 {
+		t.Fatalf("Remove failed with code %d: %s", code, ta.stderr)
+	}
+	if categories, _ := ta.store.Categories(ctx); strings.Join(categories, ",") != "cats" {
+		t.Errorf("Expected only cats to remain, got %v", categories)
+	}
+
+	// Removed entries keep their history and can be restored
+	ta.stdout.Reset()
+	if code := ta.run(ctx, []string{"content", "history", "1"}); code != 0 {
+		t.Fatalf("History failed with code %d: %s", code, ta.stderr)
+	}
+	if !strings.Contains(ta.stdout.String(), "delete by cli") {
+		t.Errorf("Expected the delete in the history, got:\n%s", ta.stdout)
+	}
+	if code := ta.run(ctx, []string{"content", "restore", "1"}); code != 0 {
+		t.Fatalf("Restore failed with code %d: %s", code, ta.stderr)
+	}
+	if e, err := ta.store.GetEntry(ctx, 1); err != nil || e.Content != "Wooper is here" {
+		t.Errorf("Expected entry 1 to be restored, got %+v (%v)", e, err)
+	}
+	if code := ta.run(ctx, []string{"content", "restore", "1"}); code != 1 {
+		t.Errorf("Expected exit code 1 when restoring a live entry, got %d", code)
+	}
+}
+
+// TestDoctor tests the doctor checks against a fake Discord server.
+func TestDoctor(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		database   string
+		wantCode   int
+		wantOutput []string
+	}{
+		{
+			name:       "all checks pass",
+			token:      "good-token",
+			wantCode:   0,
+			wantOutput: []string{"[ok]   database", "[ok]   schema", "valid for mutsumi-test"},
+		},
+		{
+			name:       "invalid token",
+			token:      "bad-token",
+			wantCode:   1,
+			wantOutput: []string{"[ok]   database", "[FAIL] token", "401"},
+		},
+		{
+			name:       "missing token",
+			wantCode:   1,
+			wantOutput: []string{"[FAIL] token", "DISCORD_BOT_TOKEN is not set"},
+		},
+		{
+			name:       "pending migrations",
+			token:      "good-token",
+			database:   "sqlite://" + filepath.Join(t.TempDir(), "bot.db"),
+			wantCode:   1,
+			wantOutput: []string{"[ok]   database", "[FAIL] schema", fmt.Sprintf("version 0, %d pending migrations", storage.LatestSchemaVersion())},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakediscord.New(t)
+			server.Token = "good-token"
+
+			ta := newTestApp(t, "")
+			ta.cfg.DiscordBotToken = tt.token
+			if tt.database != "" {
+				ta.cfg.DatabaseConnection = tt.database
+			}
+			ta.httpClient = server.Client()
+			ta.inspectService = func(cfg config.Config) (*services.DatabaseService, error) {
+				return services.NewDatabaseServiceWithOptions(cfg.DatabaseConnection, storage.Options{SkipMigrations: true})
+			}
+
+			if code := ta.run(context.Background(), []string{"doctor"}); code != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantCode, code)
+			}
+			if code := ta.run(context.Background(), []string{"doctor"}); code != tt.wantCode {
+				t.Errorf("Expected doctor to leave the database unchanged, got exit code %d on the second run", code)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(ta.stdout.String(), want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, ta.stdout)
+				}
+			}
+		})
+	}
+}
+
+// TestConfigPrint tests printing a configuration file with secrets redacted.
+func TestConfigPrint(t *testing.T) {
+	for _, env := range []string{"DISCORD_BOT_TOKEN", "DATABASE_CONNECTION", "CONFIG_FILE", "COMMAND_PREFIX", "BUTTON_SECRET"} {
+		t.Setenv(env, "")
+	}
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "bot.yaml")
+	writeFile(t, valid, "discord_bot_token: secret-token\ndatabase_connection: postgres://bot:hunter2@db:5432/bot\nbot:\n  prefix: \"?\"\nbuttons:\n  secret: secret-button-key-0123456789abcdef\n")
+	invalid := filepath.Join(dir, "broken.yaml")
+	writeFile(t, invalid, "database_connection: mysql://db\nbot:\n  prefix: \"\"\nhttp:\n  health_port: 0\n")
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantCode    int
+		wantStdout  []string
+		wantStderr  []string
+		wantMissing []string
+	}{
+		{
+			name:        "yaml",
+			args:        []string{"config", "print", "-config", valid},
+			wantStdout:  []string{"discord_bot_token: REDACTED", "postgres://bot:REDACTED@db:5432/bot", "prefix: '?'", "health_port: 8089", "secret: REDACTED"},
+			wantMissing: []string{"secret-token", "hunter2", "secret-button-key"},
+		},
+		{
+			name:        "toml",
+			args:        []string{"config", "print", "-config", valid, "-format", "toml"},
+			wantStdout:  []string{`discord_bot_token = "REDACTED"`, "[bot]", `prefix = "?"`},
+			wantMissing: []string{"secret-token", "hunter2"},
+		},
+		{
+			name:        "flags override the file",
+			args:        []string{"config", "print", "-config", valid, "--", "-prefix", "%", "-database", "postgres://bot:swordfish@db:5432/bot"},
+			wantStdout:  []string{"prefix: '%'", "postgres://bot:REDACTED@db:5432/bot"},
+			wantMissing: []string{"secret-token", "swordfish"},
+		},
+		{
+			name:       "unknown flag",
+			args:       []string{"config", "print", "--", "-frobnicate"},
+			wantCode:   1,
+			wantStderr: []string{"frobnicate"},
+		},
+		{
+			name:       "every problem is listed",
+			args:       []string{"config", "print", "-config", invalid},
+			wantCode:   1,
+			wantStderr: []string{"3 problems", "database_connection", "bot.prefix", "http.health_port"},
+		},
+		{
+			name:     "bad format",
+			args:     []string{"config", "print", "-format", "ini"},
+			wantCode: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestApp(t, "")
+			ta.loadConfig = func(file string, args []string) (config.Config, error) {
+				return config.LoadWith(config.Options{File: file, Args: args})
+			}
+
+			if code := ta.run(context.Background(), tt.args); code != tt.wantCode {
+				t.Fatalf("Expected exit code %d, got %d (stderr: %s)", tt.wantCode, code, ta.stderr)
+			}
+			for _, want := range tt.wantStdout {
+				if !strings.Contains(ta.stdout.String(), want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, ta.stdout)
+				}
+			}
+			for _, want := range tt.wantStderr {
+				if !strings.Contains(ta.stderr.String(), want) {
+					t.Errorf("Expected error to contain %q, got:\n%s", want, ta.stderr)
+				}
+			}
+			for _, secret := range tt.wantMissing {
+				if strings.Contains(ta.stdout.String(), secret) {
+					t.Errorf("Expected %q to be redacted, got:\n%s", secret, ta.stdout)
+				}
+			}
+		})
+	}
+}
+
+// writeFile writes a test fixture
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+)
+
+// configSubcommands lists the config subcommands for usage messages
+var configSubcommands = []string{"print"}
+
+// runConfig dispatches the config subcommands
+func (a *app) runConfig(_ context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageError("usage: mutsumi-bot config %s", subcommandUsage(configSubcommands...))
+	}
+
+	switch args[0] {
+	case "print":
+		return a.configPrint(args[1:])
+	}
+	return usageError("unknown config subcommand %q (expected %s)", args[0], subcommandUsage(configSubcommands...))
+}
+
+// configPrint prints the effective configuration, after layering the
+// defaults, the file, the environment and the bot flags given after --, with
+// secrets redacted
+func (a *app) configPrint(args []string) error {
+	fs := a.newFlagSet("config print", "config print [-config file] [-format yaml|toml] [-- flags]")
+	file := fs.String("config", "", "configuration file (default $CONFIG_FILE)")
+	format := fs.String("format", "yaml", "output format: yaml or toml")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "yaml" && *format != "toml" {
+		return usageError("unsupported format %q (expected yaml or toml)", *format)
+	}
+
+	cfg, err := a.loadConfig(*file, fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := cfg.Redacted().Write(a.stdout, *format); err != nil {
+		return fmt.Errorf("encode %s: %w", *format, err)
+	}
+	return nil
+}
@@ -0,0 +1,403 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"mutsumi-bot/internal/contentio"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+)
+
+// contentSubcommands lists the content subcommands for usage messages
+var contentSubcommands = []string{"list", "show", "add", "rm", "history", "restore", "export", "import"}
+
+// cliActor is recorded in the content history for changes made from the shell
+const cliActor = "cli"
+
+// runContent dispatches the content subcommands
+func (a *app) runContent(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageError("usage: mutsumi-bot content %s", subcommandUsage(contentSubcommands...))
+	}
+
+	switch args[0] {
+	case "list":
+		return a.contentList(args[1:])
+	case "show":
+		return a.contentShow(args[1:])
+	case "add":
+		return a.contentAdd(args[1:])
+	case "rm":
+		return a.contentRemove(args[1:])
+	case "history":
+		return a.contentHistory(args[1:])
+	case "restore":
+		return a.contentRestore(args[1:])
+	case "export":
+		return a.contentExport(ctx, args[1:])
+	case "import":
+		return a.contentImport(ctx, args[1:])
+	}
+	return usageError("unknown content subcommand %q (expected %s)", args[0], subcommandUsage(contentSubcommands...))
+}
+
+// contentList prints the entries of a category, one per line
+func (a *app) contentList(args []string) error {
+	fs := a.newFlagSet("content list", "content list [-full] <category>")
+	full := fs.Bool("full", false, "print whole entries instead of a one-line preview")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return usageError("expected a category")
+	}
+	category := fs.Arg(0)
+
+	return a.withService(func(svc *services.DatabaseService) error {
+		entries, err := svc.ListEntries(category)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("no entries in category %q", category)
+		}
+
+		tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "id\tweight\ttags\tcontent")
+		for _, e := range entries {
+			content := e.Content
+			if !*full {
+				content = preview(content, 60)
+			}
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%s\n", e.ID, e.Weight, strings.Join(e.Tags, ","), content)
+		}
+		return tw.Flush()
+	})
+}
+
+// contentShow prints a single entry in full
+func (a *app) contentShow(args []string) error {
+	fs := a.newFlagSet("content show", "content show <id>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil || len(ids) != 1 {
+		fs.Usage()
+		return usageError("expected a single entry ID")
+	}
+
+	return a.withService(func(svc *services.DatabaseService) error {
+		e, err := svc.GetEntry(ids[0])
+		if errors.Is(err, storage.ErrEntryNotFound) {
+			return fmt.Errorf("entry %d not found", ids[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(a.stdout, "id:       %d\n", e.ID)
+		fmt.Fprintf(a.stdout, "category: %s\n", e.Category)
+		fmt.Fprintf(a.stdout, "type:     %s\n", e.Type)
+		fmt.Fprintf(a.stdout, "weight:   %d\n", e.Weight)
+		fmt.Fprintf(a.stdout, "tags:     %s\n", strings.Join(e.Tags, ", "))
+		if e.Locale != "" {
+			fmt.Fprintf(a.stdout, "locale:   %s\n", e.Locale)
+		}
+		fmt.Fprintf(a.stdout, "\n%s\n", e.Content)
+		return nil
+	})
+}
+
+// contentAdd stores a new entry from the arguments or stdin
+func (a *app) contentAdd(args []string) error {
+	fs := a.newFlagSet("content add", "content add [-weight n] [-tags a,b] [-type text|reaction] [-locale fr] <category> <content...|->")
+	weight := fs.Int("weight", storage.DefaultWeight, "relative chance of the entry being picked")
+	entryType := fs.String("type", string(storage.EntryText), "text, or reaction for emoji added to the asking message")
+	tags := fs.String("tags", "", "comma-separated tags")
+	locale := fs.String("locale", "", "language of the entry, e.g. fr, preferred for readers of that language")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return usageError("expected a category and content")
+	}
+
+	content := strings.Join(fs.Args()[1:], " ")
+	if content == "-" {
+		data, err := io.ReadAll(a.stdin)
+		if err != nil {
+			return fmt.Errorf("read stdin: %w", err)
+		}
+		content = strings.TrimRight(string(data), "\r\n")
+	}
+
+	entry := storage.Entry{
+		Category: fs.Arg(0),
+		Content:  content,
+		Weight:   *weight,
+		Type:     storage.EntryType(*entryType),
+		Locale:   *locale,
+	}
+	if *tags != "" {
+		entry.Tags = storage.NormalizeTags(strings.Split(*tags, ","))
+	}
+
+	return a.withService(func(svc *services.DatabaseService) error {
+		id, err := svc.AddEntry(entry)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Added entry %d to %s\n", id, entry.Category)
+		return nil
+	})
+}
+
+// contentRemove deletes entries by ID, or every entry of a category
+func (a *app) contentRemove(args []string) error {
+	fs := a.newFlagSet("content rm", "content rm <id>... | content rm -category <name> -yes")
+	category := fs.String("category", "", "remove every entry of this category")
+	yes := fs.Bool("yes", false, "confirm removing a whole category")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *category != "" {
+		if fs.NArg() != 0 {
+			return usageError("entry IDs cannot be combined with -category")
+		}
+		if !*yes {
+			return usageError("removing a whole category requires -yes")
+		}
+	} else if fs.NArg() == 0 {
+		fs.Usage()
+		return usageError("expected entry IDs or -category")
+	}
+
+	ids, err := parseIDs(fs.Args())
+	if err != nil {
+		return usageError("%v", err)
+	}
+
+	return a.withService(func(svc *services.DatabaseService) error {
+		if *category != "" {
+			entries, err := svc.ListEntries(*category)
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				return fmt.Errorf("no entries in category %q", *category)
+			}
+			for _, e := range entries {
+				ids = append(ids, e.ID)
+			}
+		}
+
+		if err := svc.RemoveEntries(cliActor, ids...); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Removed %d entries\n", len(ids))
+		return nil
+	})
+}
+
+// contentHistory prints the revisions of an entry, deleted or not
+func (a *app) contentHistory(args []string) error {
+	fs := a.newFlagSet("content history", "content history <id>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil || len(ids) != 1 {
+		fs.Usage()
+		return usageError("expected a single entry ID")
+	}
+
+	return a.withService(func(svc *services.DatabaseService) error {
+		revisions, err := svc.EntryHistory(ids[0])
+		if errors.Is(err, storage.ErrEntryNotFound) {
+			return fmt.Errorf("entry %d not found", ids[0])
+		}
+		if err != nil {
+			return err
+		}
+		if len(revisions) == 0 {
+			fmt.Fprintf(a.stdout, "Entry %d has no changes\n", ids[0])
+			return nil
+		}
+
+		for _, rev := range revisions {
+			fmt.Fprintf(a.stdout, "#%d  %s  %s by %s\n", rev.ID, rev.At.Format(time.RFC3339), rev.Action, rev.Actor)
+			for _, line := range rev.Diff() {
+				fmt.Fprintf(a.stdout, "    %s\n", line)
+			}
+		}
+		return nil
+	})
+}
+
+// contentRestore undeletes an entry or reverts it to before a revision
+func (a *app) contentRestore(args []string) error {
+	fs := a.newFlagSet("content restore", "content restore [-revision n] <id>")
+	revision := fs.Int64("revision", 0, "revert the entry to how it was before this revision, see content history")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil || len(ids) != 1 {
+		fs.Usage()
+		return usageError("expected a single entry ID")
+	}
+
+	return a.withService(func(svc *services.DatabaseService) error {
+		rev, err := svc.RestoreEntry(ids[0], *revision, cliActor)
+		switch {
+		case errors.Is(err, storage.ErrEntryNotFound):
+			return fmt.Errorf("entry %d not found", ids[0])
+		case errors.Is(err, storage.ErrEntryNotDeleted):
+			return fmt.Errorf("entry %d is not deleted; use -revision to revert a change", ids[0])
+		case errors.Is(err, storage.ErrRevisionNotFound):
+			return fmt.Errorf("entry %d has no revision %d", ids[0], *revision)
+		case err != nil:
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Restored entry %d in %s (revision %d)\n", rev.EntryID, rev.After.Category, rev.ID)
+		return nil
+	})
+}
+
+// contentExport writes content to a file or stdout
+func (a *app) contentExport(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("content export", "content export [-format json|yaml|csv] [-category name] [-o file]")
+	formatName := fs.String("format", "", "output format; defaults to the -o extension, or json")
+	category := fs.String("category", "", "only export this category")
+	output := fs.String("o", "-", "output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	format, err := pickFormat(*formatName, *output)
+	if err != nil {
+		return usageError("%v", err)
+	}
+
+	return a.withService(func(svc *services.DatabaseService) error {
+		entries, err := contentio.Export(ctx, svc.Store(), *category)
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = a.stdout
+		if *output != "-" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if err := contentio.Encode(w, format, entries); err != nil {
+			return fmt.Errorf("encode %s: %w", format, err)
+		}
+		if *output != "-" {
+			fmt.Fprintf(a.stderr, "Exported %d entries to %s\n", len(entries), *output)
+		}
+		return nil
+	})
+}
+
+// contentImport reads content from a file or stdin into the database
+func (a *app) contentImport(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("content import", "content import [-format json|yaml|csv] [-mode upsert|replace] [-dry-run] <file|->")
+	formatName := fs.String("format", "", "input format; defaults to the file extension")
+	modeName := fs.String("mode", string(contentio.ModeUpsert), "upsert keeps other entries, replace makes imported categories match the file")
+	dryRun := fs.Bool("dry-run", false, "only print what would change")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return usageError("expected exactly one file argument")
+	}
+	path := fs.Arg(0)
+
+	mode, err := contentio.ParseMode(*modeName)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	if path == "-" && *formatName == "" {
+		return usageError("-format is required when reading from stdin")
+	}
+	format, err := pickFormat(*formatName, path)
+	if err != nil {
+		return usageError("%v", err)
+	}
+
+	in, err := a.openInput(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	entries, err := contentio.Decode(in, format)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+
+	return a.withService(func(svc *services.DatabaseService) error {
+		summary, err := contentio.Import(ctx, svc.Store(), entries, contentio.Options{Mode: mode, DryRun: *dryRun, Actor: cliActor})
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(a.stdout, summary.String())
+		return nil
+	})
+}
+
+// pickFormat returns the explicit format, or the one matching path's extension
+func pickFormat(name, path string) (contentio.Format, error) {
+	if name != "" {
+		return contentio.ParseFormat(name)
+	}
+	if path == "" || path == "-" {
+		return contentio.FormatJSON, nil
+	}
+	return contentio.FormatFromFilename(path)
+}
+
+// parseIDs parses entry IDs from arguments
+func parseIDs(args []string) ([]int64, error) {
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid entry ID %q", arg)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// preview shortens content to a single line of at most n runes
+func preview(content string, n int) string {
+	line, _, multiline := strings.Cut(content, "\n")
+	runes := []rune(line)
+	if len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	if multiline {
+		return line + " …"
+	}
+	return line
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+
+	"mutsumi-bot/internal/config"
+	"mutsumi-bot/internal/storage"
+)
+
+// errChecksFailed is returned by doctor when at least one check failed
+var errChecksFailed = errors.New("some checks failed")
+
+// runDoctor checks the configuration, database and bot token, printing one
+// line per check
+func (a *app) runDoctor(_ context.Context, args []string) error {
+	fs := a.newFlagSet("doctor", "doctor [-config file]")
+	file := fs.String("config", "", "configuration file (default $CONFIG_FILE)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	failed := false
+	report := func(name string, err error, detail string) {
+		if err != nil {
+			failed = true
+			fmt.Fprintf(a.stdout, "[FAIL] %-8s %v\n", name, err)
+			return
+		}
+		fmt.Fprintf(a.stdout, "[ok]   %-8s %s\n", name, detail)
+	}
+
+	cfg, err := a.loadConfig(*file, nil)
+	report("config", err, "loaded")
+	if err != nil {
+		return errChecksFailed
+	}
+
+	a.checkDatabase(cfg, report)
+
+	user, err := a.checkToken(cfg.DiscordBotToken)
+	if err == nil {
+		report("token", nil, fmt.Sprintf("valid for %s (%s)", user.Username, user.ID))
+	} else {
+		report("token", err, "")
+	}
+
+	if failed {
+		return errChecksFailed
+	}
+	return nil
+}
+
+// checkDatabase reports on connectivity and the schema version, leaving
+// pending migrations for the bot to apply
+func (a *app) checkDatabase(cfg config.Config, report func(string, error, string)) {
+	svc, err := a.inspectService(cfg)
+	if err != nil {
+		report("database", err, "")
+		return
+	}
+	defer svc.Close()
+
+	if err := svc.Ping(); err != nil {
+		report("database", err, "")
+		return
+	}
+	report("database", nil, fmt.Sprintf("connected (%s)", storage.Scheme(cfg.DatabaseConnection)))
+
+	version, err := svc.SchemaVersion()
+	switch latest := storage.LatestSchemaVersion(); {
+	case err != nil:
+		report("schema", err, "")
+	case version < latest:
+		report("schema", fmt.Errorf("version %d, %d pending migrations applied when the bot starts", version, latest-version), "")
+	case version > latest:
+		report("schema", fmt.Errorf("version %d is newer than this binary (%d)", version, latest), "")
+	default:
+		report("schema", nil, fmt.Sprintf("version %d", version))
+	}
+}
+
+// checkToken asks Discord which user the bot token belongs to
+func (a *app) checkToken(token string) (*discordgo.User, error) {
+	if token == "" {
+		return nil, errors.New("DISCORD_BOT_TOKEN is not set")
+	}
+
+	session, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return nil, err
+	}
+	session.Client = a.httpClient
+	session.MaxRestRetries = 0
+
+	user, err := session.User("@me")
+	if err != nil {
+		var restErr *discordgo.RESTError
+		if errors.As(err, &restErr) && restErr.Response.StatusCode == 401 {
+			return nil, errors.New("rejected by Discord (401 Unauthorized)")
+		}
+		return nil, err
+	}
+	return user, nil
+}
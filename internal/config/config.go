@@ -1,35 +1,307 @@
+// Package config loads the bot configuration. Values are layered: built-in
+// defaults, then an optional YAML or TOML file, then environment variables,
+// then command-line flags. The result is validated as a whole so every
+// problem is reported at once.
 package config
 
 import (
 	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
+
+	"mutsumi-bot/internal/i18n"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DiscordBotToken    string
-	DatabaseConnection string
+	DiscordBotToken    string `yaml:"discord_bot_token" toml:"discord_bot_token"`
+	DatabaseConnection string `yaml:"database_connection" toml:"database_connection"`
+	LogLevel           string `yaml:"log_level" toml:"log_level"`
+
+	Bot         BotConfig         `yaml:"bot" toml:"bot"`
+	HTTP        HTTPConfig        `yaml:"http" toml:"http"`
+	Database    DatabaseConfig    `yaml:"database" toml:"database"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit" toml:"rate_limit"`
+	Cache       CacheConfig       `yaml:"cache" toml:"cache"`
+	Scheduler   SchedulerConfig   `yaml:"scheduler" toml:"scheduler"`
+	Suggestions SuggestionsConfig `yaml:"suggestions" toml:"suggestions"`
+	Audit       AuditConfig       `yaml:"audit" toml:"audit"`
+	Buttons     ButtonsConfig     `yaml:"buttons" toml:"buttons"`
+	Selection   SelectionConfig   `yaml:"selection" toml:"selection"`
+	Delivery    DeliveryConfig    `yaml:"delivery" toml:"delivery"`
+
+	// File is the configuration file the values were read from, if any
+	File string `yaml:"-" toml:"-"`
+}
+
+// BotConfig configures the Discord session and command handling
+type BotConfig struct {
+	// Prefix starts text commands, e.g. "!" for !wooper
+	Prefix string `yaml:"prefix" toml:"prefix"`
+	// Intents are the gateway intents to request, see IntentNames
+	Intents []string `yaml:"intents" toml:"intents"`
+	// DevGuildID registers slash commands in a single guild, where they
+	// update instantly, instead of globally
+	DevGuildID string `yaml:"dev_guild_id" toml:"dev_guild_id"`
+	// ShutdownTimeout bounds the graceful shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// ShardCount is the total number of gateway shards across all
+	// processes, 0 uses the count recommended by Discord
+	ShardCount int `yaml:"shard_count" toml:"shard_count"`
+	// ShardIDs are the shards run by this process, empty runs all of them
+	ShardIDs []int `yaml:"shard_ids" toml:"shard_ids"`
+	// Locale is the language of replies in guilds that didn't choose one
+	// with /locale, when the locale of the user is unknown
+	Locale string `yaml:"locale" toml:"locale"`
+}
+
+// HTTPConfig configures the health check server
+type HTTPConfig struct {
+	HealthPort   int           `yaml:"health_port" toml:"health_port"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// AdminToken enables the admin endpoints, authenticated with
+	// "Authorization: Bearer <token>"
+	AdminToken string `yaml:"admin_token" toml:"admin_token"`
+}
+
+// DatabaseConfig configures the database connection pool
+type DatabaseConfig struct {
+	// MaxOpenConns limits open connections, 0 means unlimited
+	MaxOpenConns int `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns int `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	// ConnMaxLifetime and ConnMaxIdleTime recycle connections, 0 keeps them
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+	// StatementTimeout aborts slow queries, 0 disables it
+	StatementTimeout time.Duration `yaml:"statement_timeout" toml:"statement_timeout"`
+	// ConnectTimeout is how long startup retries an unreachable database
+	ConnectTimeout time.Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+}
+
+// RateLimitConfig limits how often a user can run commands
+type RateLimitConfig struct {
+	// Commands is the number of commands a user can run per window, 0 disables the limit
+	Commands int           `yaml:"commands" toml:"commands"`
+	Window   time.Duration `yaml:"window" toml:"window"`
+}
+
+// CacheConfig configures in-process caches
+type CacheConfig struct {
+	// CategoryTTL is how long the category list is cached, 0 disables the cache
+	CategoryTTL time.Duration `yaml:"category_ttl" toml:"category_ttl"`
+}
+
+// SchedulerConfig configures scheduled posts
+type SchedulerConfig struct {
+	// PollInterval is how often due schedules are checked
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval"`
+	// MissedRuns is "once" to post once for runs missed while the bot was
+	// down, or "skip" to drop them
+	MissedRuns string `yaml:"missed_runs" toml:"missed_runs"`
+}
+
+// SuggestionsConfig configures content submitted with /suggest
+type SuggestionsConfig struct {
+	// ReviewChannelID is the channel suggestions are posted to for review;
+	// /suggest is disabled when it is empty
+	ReviewChannelID string `yaml:"review_channel_id" toml:"review_channel_id"`
+	// MaxPending is how many suggestions a user can have awaiting review
+	MaxPending int `yaml:"max_pending" toml:"max_pending"`
+	// DailyLimit is how many suggestions a user can submit per 24 hours
+	DailyLimit int `yaml:"daily_limit" toml:"daily_limit"`
+}
+
+// AuditConfig configures the mirror of content changes
+type AuditConfig struct {
+	// ChannelID is the channel content changes are posted to; nothing is
+	// posted when it is empty
+	ChannelID string `yaml:"channel_id" toml:"channel_id"`
+}
+
+// ButtonsConfig configures the buttons on /command replies
+type ButtonsConfig struct {
+	// Timeout is how long the buttons work before they are disabled, at most
+	// the 15 minutes Discord lets a reply be edited
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+	// Delete adds a Delete button for the user who ran the command
+	Delete bool `yaml:"delete" toml:"delete"`
+	// Secret keys the HMAC signing the button custom IDs; every replica must
+	// share it
+	Secret string `yaml:"secret" toml:"secret"`
+}
+
+// SelectionConfig configures how random entries are picked
+type SelectionConfig struct {
+	// Mode is "weighted" to pick entries by weight, or "popular" to also
+	// favor entries people voted up
+	Mode string `yaml:"mode" toml:"mode"`
+	// Exploration is the bonus of rarely played entries in popular mode,
+	// so that new entries still come up
+	Exploration float64 `yaml:"exploration" toml:"exploration"`
+}
+
+// DeliveryConfig configures where /command replies are sent
+type DeliveryConfig struct {
+	// Mode is "public" to reply in the channel, "ephemeral" to reply to
+	// the user who ran the command only, or "dm" to send them a direct
+	// message
+	Mode string `yaml:"mode" toml:"mode"`
+	// Categories overrides the mode of some categories, as category=mode
+	Categories []string `yaml:"categories" toml:"categories"`
+	// AttachmentThreshold is the length above which content is sent as a
+	// .txt file instead of being split into several messages, 0 to always
+	// split it
+	AttachmentThreshold int `yaml:"attachment_threshold" toml:"attachment_threshold"`
+}
+
+// CategoryModes returns the delivery mode of each category listed in
+// Categories
+func (d DeliveryConfig) CategoryModes() map[string]string {
+	modes := make(map[string]string, len(d.Categories))
+	for _, item := range d.Categories {
+		if category, mode, ok := strings.Cut(item, "="); ok {
+			modes[strings.TrimSpace(category)] = strings.TrimSpace(mode)
+		}
+	}
+	return modes
+}
+
+// Default returns the configuration used when nothing is set
+func Default() Config {
+	return Config{
+		LogLevel: "info",
+		Bot: BotConfig{
+			Prefix:          "!",
+			Intents:         []string{"guild_messages", "message_content"},
+			ShutdownTimeout: 5 * time.Second,
+			Locale:          i18n.DefaultLocale,
+		},
+		HTTP: HTTPConfig{
+			HealthPort:   8089,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			IdleTimeout:  15 * time.Second,
+		},
+		Database: DatabaseConfig{
+			MaxOpenConns:     10,
+			MaxIdleConns:     2,
+			ConnMaxLifetime:  30 * time.Minute,
+			ConnMaxIdleTime:  5 * time.Minute,
+			StatementTimeout: 5 * time.Second,
+			ConnectTimeout:   time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Window: 10 * time.Second,
+		},
+		Cache: CacheConfig{
+			CategoryTTL: 30 * time.Second,
+		},
+		Scheduler: SchedulerConfig{
+			PollInterval: 30 * time.Second,
+			MissedRuns:   "once",
+		},
+		Suggestions: SuggestionsConfig{
+			MaxPending: 5,
+			DailyLimit: 10,
+		},
+		Buttons: ButtonsConfig{
+			Timeout: 10 * time.Minute,
+			Delete:  true,
+		},
+		Selection: SelectionConfig{
+			Mode:        "weighted",
+			Exploration: 0.5,
+		},
+		Delivery: DeliveryConfig{
+			Mode:                "public",
+			AttachmentThreshold: 4000,
+		},
+	}
+}
+
+// Options tells LoadWith where to read the configuration from
+type Options struct {
+	// File is the configuration file; CONFIG_FILE is used when empty
+	File string
+	// Args are command-line flags, see Usage
+	Args []string
+	// RequireToken fails validation when no bot token or button secret is set
+	RequireToken bool
 }
 
 // Load reads configuration from environment variables and validates required fields.
 // If a .env file is present in the working directory, it will be loaded first.
 func Load() (Config, error) {
+	return LoadWith(Options{RequireToken: true})
+}
+
+// LoadOffline reads configuration for offline tools that never connect to
+// Discord, so the bot token is optional.
+func LoadOffline() (Config, error) {
+	return LoadWith(Options{})
+}
+
+// LoadWith layers the defaults, the configuration file, the environment and
+// the flags in opts. Invalid values are returned together as a *ValidationError.
+func LoadWith(opts Options) (Config, error) {
 	// Load .env if present; ignore error when file does not exist
 	_ = godotenv.Load()
 
-	token := os.Getenv("DISCORD_BOT_TOKEN")
-	if token == "" {
-		return Config{}, errors.New("missing DISCORD_BOT_TOKEN env var")
+	flags, file, err := parseFlags(opts.Args)
+	if err != nil {
+		return Config{}, err
+	}
+	if file == "" {
+		file = opts.File
+	}
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
 	}
 
-	dbConn := os.Getenv("DATABASE_CONNECTION")
-	if dbConn == "" {
-		return Config{}, errors.New("missing DATABASE_CONNECTION env var")
+	cfg := Default()
+	var p problems
+	if file != "" {
+		p.merge(readFile(file, &cfg))
 	}
+	p.merge(applyEnv(&cfg))
+	p.merge(flags.apply(&cfg))
+	p.merge(cfg.validate(opts.RequireToken))
 
-	return Config{
-		DiscordBotToken:    token,
-		DatabaseConnection: dbConn,
-	}, nil
+	if err := p.err(); err != nil {
+		return Config{}, err
+	}
+	cfg.File = file
+	return cfg, nil
+}
+
+// Usage prints the command-line flags accepted by LoadWith
+func Usage(w io.Writer) {
+	fs, _, _ := newFlagSet()
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// parseFlags parses the command-line flags. The values are applied later so
+// that they take precedence over the file and the environment.
+func parseFlags(args []string) (flagValues, string, error) {
+	fs, values, file := newFlagSet()
+	fs.SetOutput(io.Discard)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, "", err
+		}
+		return nil, "", fmt.Errorf("parse flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return nil, "", fmt.Errorf("parse flags: unexpected argument %q", fs.Arg(0))
+	}
+	return values, *file, nil
 }
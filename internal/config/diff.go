@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// reloadable lists the settings that can change while the bot is running.
+// Every other setting needs a restart.
+var reloadable = map[string]bool{
+	"log_level":             true,
+	"bot.prefix":            true,
+	"rate_limit.commands":   true,
+	"rate_limit.window":     true,
+	"cache.category_ttl":    true,
+	"selection.mode":        true,
+	"selection.exploration": true,
+}
+
+// Change is a setting that differs between two configurations. Secrets are
+// redacted in Old and New.
+type Change struct {
+	Key        string `json:"key"`
+	Old        string `json:"old"`
+	New        string `json:"new"`
+	Reloadable bool   `json:"reloadable"`
+}
+
+// Diff returns the settings that differ between c and next
+func (c Config) Diff(next Config) []Change {
+	oldRedacted, nextRedacted := c.Redacted(), next.Redacted()
+
+	var changes []Change
+	for _, b := range bindings {
+		if reflect.DeepEqual(fieldValue(b, &c), fieldValue(b, &next)) {
+			continue
+		}
+		changes = append(changes, Change{
+			Key:        b.key,
+			Old:        fmt.Sprint(fieldValue(b, &oldRedacted)),
+			New:        fmt.Sprint(fieldValue(b, &nextRedacted)),
+			Reloadable: reloadable[b.key],
+		})
+	}
+	return changes
+}
+
+// WithReloadable returns c with the reloadable settings taken from next
+func (c Config) WithReloadable(next Config) Config {
+	for _, b := range bindings {
+		if reloadable[b.key] {
+			reflect.ValueOf(b.field(&c)).Elem().Set(reflect.ValueOf(b.field(&next)).Elem())
+		}
+	}
+	return c
+}
+
+// fieldValue returns the value of the field bound by b
+func fieldValue(b binding, c *Config) any {
+	return reflect.ValueOf(b.field(c)).Elem().Interface()
+}
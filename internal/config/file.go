@@ -0,0 +1,71 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// readFile decodes a YAML or TOML file, chosen by its extension, over cfg.
+// Keys missing from the file keep their current value; unknown keys are
+// reported as problems.
+func readFile(path string, cfg *Config) problems {
+	var p problems
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		p.add(path, err.Error())
+		return p
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			var typeErr *yaml.TypeError
+			if errors.As(err, &typeErr) {
+				for _, msg := range typeErr.Errors {
+					p.add(path, msg)
+				}
+			} else {
+				p.add(path, err.Error())
+			}
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			p.add(path, err.Error())
+			break
+		}
+		for _, key := range md.Undecoded() {
+			p.add(path, fmt.Sprintf("unknown key %q", key.String()))
+		}
+	default:
+		p.add(path, fmt.Sprintf("unsupported file extension %q, expected .yaml, .yml or .toml", ext))
+	}
+	return p
+}
+
+// Write encodes the configuration as YAML or TOML
+func (c Config) Write(w io.Writer, format string) error {
+	switch format {
+	case "yaml", "yml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "toml":
+		return toml.NewEncoder(w).Encode(c)
+	}
+	return fmt.Errorf("unsupported format %q, expected yaml or toml", format)
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// binding maps a configuration field to its environment variable and,
+// optionally, its command-line flag
+type binding struct {
+	key   string // dotted file key, used in error messages
+	env   string
+	flag  string // empty when the field has no flag
+	usage string
+	field func(*Config) any
+}
+
+// bindings lists every field settable from the environment. Tokens and
+// secrets have no flag so they never show up in process listings; the
+// database URL has one for password-less databases such as SQLite, and its
+// usage says to keep passwords in the environment.
+var bindings = []binding{
+	{"discord_bot_token", "DISCORD_BOT_TOKEN", "", "Discord bot token", func(c *Config) any { return &c.DiscordBotToken }},
+	{"database_connection", "DATABASE_CONNECTION", "database", "database connection `url`; pass URLs with a password in the environment, flags show in process listings", func(c *Config) any { return &c.DatabaseConnection }},
+	{"log_level", "LOG_LEVEL", "log-level", "log `level`: debug, info, warn or error", func(c *Config) any { return &c.LogLevel }},
+	{"bot.prefix", "COMMAND_PREFIX", "prefix", "text command `prefix`", func(c *Config) any { return &c.Bot.Prefix }},
+	{"bot.intents", "DISCORD_INTENTS", "intents", "comma-separated gateway `intents`", func(c *Config) any { return &c.Bot.Intents }},
+	{"bot.dev_guild_id", "DEV_GUILD_ID", "dev-guild-id", "register slash commands in this guild only", func(c *Config) any { return &c.Bot.DevGuildID }},
+	{"bot.shutdown_timeout", "SHUTDOWN_TIMEOUT", "", "", func(c *Config) any { return &c.Bot.ShutdownTimeout }},
+	{"bot.shard_count", "SHARD_COUNT", "shard-count", "total number of gateway shards, 0 for Discord's recommendation", func(c *Config) any { return &c.Bot.ShardCount }},
+	{"bot.shard_ids", "SHARD_ID", "shard-id", "comma-separated shard `ids` run by this process, empty for all", func(c *Config) any { return &c.Bot.ShardIDs }},
+	{"bot.locale", "BOT_LOCALE", "", "", func(c *Config) any { return &c.Bot.Locale }},
+	{"http.health_port", "HEALTH_PORT", "health-port", "health check server `port`", func(c *Config) any { return &c.HTTP.HealthPort }},
+	{"http.read_timeout", "HTTP_READ_TIMEOUT", "", "", func(c *Config) any { return &c.HTTP.ReadTimeout }},
+	{"http.write_timeout", "HTTP_WRITE_TIMEOUT", "", "", func(c *Config) any { return &c.HTTP.WriteTimeout }},
+	{"http.idle_timeout", "HTTP_IDLE_TIMEOUT", "", "", func(c *Config) any { return &c.HTTP.IdleTimeout }},
+	{"http.admin_token", "ADMIN_TOKEN", "", "", func(c *Config) any { return &c.HTTP.AdminToken }},
+	{"database.max_open_conns", "DB_MAX_OPEN_CONNS", "", "", func(c *Config) any { return &c.Database.MaxOpenConns }},
+	{"database.max_idle_conns", "DB_MAX_IDLE_CONNS", "", "", func(c *Config) any { return &c.Database.MaxIdleConns }},
+	{"database.conn_max_lifetime", "DB_CONN_MAX_LIFETIME", "", "", func(c *Config) any { return &c.Database.ConnMaxLifetime }},
+	{"database.conn_max_idle_time", "DB_CONN_MAX_IDLE_TIME", "", "", func(c *Config) any { return &c.Database.ConnMaxIdleTime }},
+	{"database.statement_timeout", "DB_STATEMENT_TIMEOUT", "", "", func(c *Config) any { return &c.Database.StatementTimeout }},
+	{"database.connect_timeout", "DB_CONNECT_TIMEOUT", "", "", func(c *Config) any { return &c.Database.ConnectTimeout }},
+	{"rate_limit.commands", "RATE_LIMIT_COMMANDS", "", "", func(c *Config) any { return &c.RateLimit.Commands }},
+	{"rate_limit.window", "RATE_LIMIT_WINDOW", "", "", func(c *Config) any { return &c.RateLimit.Window }},
+	{"cache.category_ttl", "CATEGORY_CACHE_TTL", "", "", func(c *Config) any { return &c.Cache.CategoryTTL }},
+	{"scheduler.poll_interval", "SCHEDULER_POLL_INTERVAL", "", "", func(c *Config) any { return &c.Scheduler.PollInterval }},
+	{"scheduler.missed_runs", "SCHEDULER_MISSED_RUNS", "", "", func(c *Config) any { return &c.Scheduler.MissedRuns }},
+	{"suggestions.review_channel_id", "SUGGESTION_CHANNEL_ID", "", "", func(c *Config) any { return &c.Suggestions.ReviewChannelID }},
+	{"suggestions.max_pending", "SUGGESTION_MAX_PENDING", "", "", func(c *Config) any { return &c.Suggestions.MaxPending }},
+	{"suggestions.daily_limit", "SUGGESTION_DAILY_LIMIT", "", "", func(c *Config) any { return &c.Suggestions.DailyLimit }},
+	{"audit.channel_id", "AUDIT_CHANNEL_ID", "", "", func(c *Config) any { return &c.Audit.ChannelID }},
+	{"buttons.timeout", "BUTTON_TIMEOUT", "", "", func(c *Config) any { return &c.Buttons.Timeout }},
+	{"buttons.delete", "BUTTON_DELETE", "", "", func(c *Config) any { return &c.Buttons.Delete }},
+	{"buttons.secret", "BUTTON_SECRET", "", "", func(c *Config) any { return &c.Buttons.Secret }},
+	{"selection.mode", "SELECTION_MODE", "", "", func(c *Config) any { return &c.Selection.Mode }},
+	{"selection.exploration", "SELECTION_EXPLORATION", "", "", func(c *Config) any { return &c.Selection.Exploration }},
+	{"delivery.mode", "DELIVERY_MODE", "", "", func(c *Config) any { return &c.Delivery.Mode }},
+	{"delivery.categories", "DELIVERY_CATEGORIES", "", "", func(c *Config) any { return &c.Delivery.Categories }},
+	{"delivery.attachment_threshold", "ATTACHMENT_THRESHOLD", "", "", func(c *Config) any { return &c.Delivery.AttachmentThreshold }},
+}
+
+// applyEnv sets the fields whose environment variable is set and not empty
+func applyEnv(cfg *Config) problems {
+	var p problems
+	for _, b := range bindings {
+		raw := os.Getenv(b.env)
+		if raw == "" {
+			continue
+		}
+		if err := setValue(b.field(cfg), raw); err != nil {
+			p.add(b.env, err.Error())
+		}
+	}
+	return p
+}
+
+// flagValues holds the raw values of the flags that were set, by binding index
+type flagValues map[int]string
+
+// newFlagSet defines a flag for every binding that has one
+func newFlagSet() (*flag.FlagSet, flagValues, *string) {
+	fs := flag.NewFlagSet("mutsumi-bot", flag.ContinueOnError)
+	file := fs.String("config", "", "YAML or TOML configuration `file` (default $CONFIG_FILE)")
+
+	values := flagValues{}
+	for i, b := range bindings {
+		if b.flag == "" {
+			continue
+		}
+		fs.Func(b.flag, b.usage+" ($"+b.env+")", func(raw string) error {
+			values[i] = raw
+			return nil
+		})
+	}
+	return fs, values, file
+}
+
+// apply sets the fields of the flags that were set
+func (v flagValues) apply(cfg *Config) problems {
+	var p problems
+	for i, raw := range v {
+		b := bindings[i]
+		if err := setValue(b.field(cfg), raw); err != nil {
+			p.add("-"+b.flag, err.Error())
+		}
+	}
+	return p
+}
+
+// setValue parses raw into the field pointed to by ptr
+func setValue(ptr any, raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch field := ptr.(type) {
+	case *string:
+		*field = raw
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		*field = n
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q, expected true or false", raw)
+		}
+		*field = b
+	case *float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		*field = f
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, expected e.g. 30s or 5m", raw)
+		}
+		*field = d
+	case *[]string:
+		var list []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field = list
+	case *[]int:
+		var list []int
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			n, err := strconv.Atoi(item)
+			if err != nil {
+				return fmt.Errorf("invalid integer %q", item)
+			}
+			list = append(list, n)
+		}
+		*field = list
+	default:
+		return fmt.Errorf("unsupported field type %T", ptr)
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/storage"
+)
+
+// Problem is a single invalid configuration value
+type Problem struct {
+	// Field is the file key, environment variable, flag or file the value came from
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every problem found while loading the configuration
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  - %s: %s", p.Field, p.Message)
+	}
+	return b.String()
+}
+
+// problems collects validation problems
+type problems []Problem
+
+func (p *problems) add(field, message string) {
+	*p = append(*p, Problem{Field: field, Message: message})
+}
+
+func (p *problems) merge(other problems) {
+	*p = append(*p, other...)
+}
+
+// err returns a *ValidationError, or nil when there are no problems
+func (p problems) err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p}
+}
+
+// intents maps configuration names to gateway intents
+var intents = map[string]discordgo.Intent{
+	"guilds":                   discordgo.IntentsGuilds,
+	"guild_members":            discordgo.IntentsGuildMembers,
+	"guild_emojis":             discordgo.IntentsGuildEmojis,
+	"guild_messages":           discordgo.IntentsGuildMessages,
+	"guild_message_reactions":  discordgo.IntentsGuildMessageReactions,
+	"direct_messages":          discordgo.IntentsDirectMessages,
+	"direct_message_reactions": discordgo.IntentsDirectMessageReactions,
+	"message_content":          discordgo.IntentMessageContent,
+}
+
+// IntentNames returns the intent names accepted in bot.intents
+func IntentNames() []string {
+	names := make([]string, 0, len(intents))
+	for name := range intents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GatewayIntents returns the gateway intents named in bot.intents
+func (b BotConfig) GatewayIntents() discordgo.Intent {
+	var mask discordgo.Intent
+	for _, name := range b.Intents {
+		mask |= intents[strings.ToLower(name)]
+	}
+	return mask
+}
+
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// deliveryModes are the accepted delivery.mode values
+var deliveryModes = []string{"public", "ephemeral", "dm"}
+
+var snowflake = regexp.MustCompile(`^[0-9]{17,20}$`)
+
+// Validate checks every value and returns a *ValidationError listing all problems
+func (c Config) Validate(requireToken bool) error {
+	return c.validate(requireToken).err()
+}
+
+func (c Config) validate(requireToken bool) problems {
+	var p problems
+
+	if requireToken && c.DiscordBotToken == "" {
+		p.add("discord_bot_token", "required (DISCORD_BOT_TOKEN)")
+	}
+
+	if c.DatabaseConnection == "" {
+		p.add("database_connection", "required (DATABASE_CONNECTION)")
+	} else if scheme := storage.Scheme(c.DatabaseConnection); !storage.SupportedScheme(scheme) {
+		p.add("database_connection", fmt.Sprintf("unsupported scheme %q, expected postgres://, sqlite:// or memory://", scheme))
+	}
+
+	if !slices.Contains(logLevels, c.LogLevel) {
+		p.add("log_level", fmt.Sprintf("unknown level %q, expected one of %s", c.LogLevel, strings.Join(logLevels, ", ")))
+	}
+
+	if c.Bot.Prefix == "" || strings.ContainsAny(c.Bot.Prefix, " \t\n") {
+		p.add("bot.prefix", "must be non-empty and contain no whitespace")
+	}
+	if len(c.Bot.Intents) == 0 {
+		p.add("bot.intents", "at least one intent is required")
+	}
+	for _, name := range c.Bot.Intents {
+		if _, ok := intents[strings.ToLower(name)]; !ok {
+			p.add("bot.intents", fmt.Sprintf("unknown intent %q, expected one of %s", name, strings.Join(IntentNames(), ", ")))
+		}
+	}
+	if c.Bot.DevGuildID != "" && !snowflake.MatchString(c.Bot.DevGuildID) {
+		p.add("bot.dev_guild_id", fmt.Sprintf("%q is not a Discord ID", c.Bot.DevGuildID))
+	}
+	positive(&p, "bot.shutdown_timeout", c.Bot.ShutdownTimeout)
+	if i18n.Default.Match(c.Bot.Locale) == "" {
+		p.add("bot.locale", fmt.Sprintf("unsupported locale %q, expected one of %s", c.Bot.Locale, strings.Join(i18n.Default.Locales(), ", ")))
+	}
+	if c.Bot.ShardCount < 0 {
+		p.add("bot.shard_count", "must not be negative")
+	}
+	if len(c.Bot.ShardIDs) > 0 && c.Bot.ShardCount == 0 {
+		p.add("bot.shard_ids", "requires bot.shard_count so that every process agrees on the total")
+	}
+	seen := make(map[int]bool, len(c.Bot.ShardIDs))
+	for _, id := range c.Bot.ShardIDs {
+		switch {
+		case id < 0 || (c.Bot.ShardCount > 0 && id >= c.Bot.ShardCount):
+			p.add("bot.shard_ids", fmt.Sprintf("shard %d is out of range [0, %d)", id, c.Bot.ShardCount))
+		case seen[id]:
+			p.add("bot.shard_ids", fmt.Sprintf("shard %d is listed twice", id))
+		}
+		seen[id] = true
+	}
+
+	if c.HTTP.HealthPort < 1 || c.HTTP.HealthPort > 65535 {
+		p.add("http.health_port", "must be between 1 and 65535, got "+strconv.Itoa(c.HTTP.HealthPort))
+	}
+	positive(&p, "http.read_timeout", c.HTTP.ReadTimeout)
+	positive(&p, "http.write_timeout", c.HTTP.WriteTimeout)
+	positive(&p, "http.idle_timeout", c.HTTP.IdleTimeout)
+	if c.HTTP.AdminToken != "" && len(c.HTTP.AdminToken) < 16 {
+		p.add("http.admin_token", "must be at least 16 characters")
+	}
+
+	if c.Database.MaxOpenConns < 0 {
+		p.add("database.max_open_conns", "must not be negative")
+	}
+	if c.Database.MaxIdleConns < 0 {
+		p.add("database.max_idle_conns", "must not be negative")
+	} else if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		p.add("database.max_idle_conns", "must not exceed database.max_open_conns")
+	}
+	notNegative(&p, "database.conn_max_lifetime", c.Database.ConnMaxLifetime)
+	notNegative(&p, "database.conn_max_idle_time", c.Database.ConnMaxIdleTime)
+	notNegative(&p, "database.statement_timeout", c.Database.StatementTimeout)
+	notNegative(&p, "database.connect_timeout", c.Database.ConnectTimeout)
+
+	if c.RateLimit.Commands < 0 {
+		p.add("rate_limit.commands", "must not be negative")
+	}
+	if c.RateLimit.Commands > 0 {
+		positive(&p, "rate_limit.window", c.RateLimit.Window)
+	}
+
+	notNegative(&p, "cache.category_ttl", c.Cache.CategoryTTL)
+
+	positive(&p, "scheduler.poll_interval", c.Scheduler.PollInterval)
+	if c.Scheduler.MissedRuns != "once" && c.Scheduler.MissedRuns != "skip" {
+		p.add("scheduler.missed_runs", fmt.Sprintf("unknown policy %q, expected once or skip", c.Scheduler.MissedRuns))
+	}
+
+	if c.Suggestions.ReviewChannelID != "" && !snowflake.MatchString(c.Suggestions.ReviewChannelID) {
+		p.add("suggestions.review_channel_id", fmt.Sprintf("%q is not a Discord ID", c.Suggestions.ReviewChannelID))
+	}
+	if c.Suggestions.MaxPending < 1 {
+		p.add("suggestions.max_pending", "must be at least 1")
+	}
+	if c.Suggestions.DailyLimit < 1 {
+		p.add("suggestions.daily_limit", "must be at least 1")
+	}
+
+	if c.Audit.ChannelID != "" && !snowflake.MatchString(c.Audit.ChannelID) {
+		p.add("audit.channel_id", fmt.Sprintf("%q is not a Discord ID", c.Audit.ChannelID))
+	}
+
+	positive(&p, "buttons.timeout", c.Buttons.Timeout)
+	if c.Buttons.Timeout > 15*time.Minute {
+		p.add("buttons.timeout", "must be at most 15m, after which Discord no longer lets the reply be edited")
+	}
+	if requireToken && c.Buttons.Secret == "" {
+		p.add("buttons.secret", "required (BUTTON_SECRET)")
+	} else if c.Buttons.Secret != "" && len(c.Buttons.Secret) < 32 {
+		p.add("buttons.secret", "must be at least 32 characters")
+	}
+
+	if c.Selection.Mode != "weighted" && c.Selection.Mode != "popular" {
+		p.add("selection.mode", fmt.Sprintf("unknown mode %q, expected weighted or popular", c.Selection.Mode))
+	}
+	if e := c.Selection.Exploration; e < 0 || math.IsNaN(e) || math.IsInf(e, 0) {
+		p.add("selection.exploration", "must be a finite number, not negative")
+	}
+
+	if !slices.Contains(deliveryModes, c.Delivery.Mode) {
+		p.add("delivery.mode", fmt.Sprintf("unknown mode %q, expected one of %s", c.Delivery.Mode, strings.Join(deliveryModes, ", ")))
+	}
+	if c.Delivery.AttachmentThreshold < 0 {
+		p.add("delivery.attachment_threshold", "must not be negative")
+	}
+	for _, item := range c.Delivery.Categories {
+		category, mode, ok := strings.Cut(item, "=")
+		switch {
+		case !ok || strings.TrimSpace(category) == "":
+			p.add("delivery.categories", fmt.Sprintf("%q is not category=mode", item))
+		case !slices.Contains(deliveryModes, strings.TrimSpace(mode)):
+			p.add("delivery.categories", fmt.Sprintf("unknown mode %q for %s, expected one of %s", strings.TrimSpace(mode), strings.TrimSpace(category), strings.Join(deliveryModes, ", ")))
+		}
+	}
+
+	return p
+}
+
+// positive adds a problem when a duration is not positive
+func positive(p *problems, field string, d time.Duration) {
+	if d <= 0 {
+		p.add(field, "must be positive")
+	}
+}
+
+// notNegative adds a problem when a duration is negative
+func notNegative(p *problems, field string, d time.Duration) {
+	if d < 0 {
+		p.add(field, "must not be negative")
+	}
+}
+
+// Redacted returns a copy with the tokens, the button secret and the
+// database password hidden
+func (c Config) Redacted() Config {
+	const mask = "REDACTED"
+
+	if c.DiscordBotToken != "" {
+		c.DiscordBotToken = mask
+	}
+	if c.HTTP.AdminToken != "" {
+		c.HTTP.AdminToken = mask
+	}
+	if c.Buttons.Secret != "" {
+		c.Buttons.Secret = mask
+	}
+	if u, err := url.Parse(c.DatabaseConnection); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), mask)
+			c.DatabaseConnection = u.String()
+		}
+	}
+	c.DatabaseConnection = passwordParam.ReplaceAllString(c.DatabaseConnection, "${1}"+mask)
+	c.Bot.Intents = append([]string(nil), c.Bot.Intents...)
+	c.Bot.ShardIDs = append([]int(nil), c.Bot.ShardIDs...)
+	c.Delivery.Categories = append([]string(nil), c.Delivery.Categories...)
+	return c
+}
+
+// passwordParam matches a password in key=value connection strings
+var passwordParam = regexp.MustCompile(`(?i)(password=)[^&\s]+`)
@@ -0,0 +1,269 @@
+// Package contentio serializes content entries to and from JSON, YAML and
+// CSV, and imports them into a storage backend.
+package contentio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"mutsumi-bot/internal/storage"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DocumentVersion is the version written in JSON and YAML documents
+const DocumentVersion = 1
+
+// ErrUnknownFormat is returned for an unsupported serialization format
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Format is a serialization format
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatCSV  Format = "csv"
+)
+
+// Formats lists the supported formats
+var Formats = []Format{FormatJSON, FormatYAML, FormatCSV}
+
+// ParseFormat parses a format name such as "json", "yaml", "yml" or "csv"
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("%w: %q (expected json, yaml or csv)", ErrUnknownFormat, name)
+}
+
+// FormatFromFilename picks the format matching a file extension
+func FormatFromFilename(filename string) (Format, error) {
+	return ParseFormat(filepath.Ext(filename))
+}
+
+// Document is the JSON and YAML representation of exported content
+type Document struct {
+	Version    int        `json:"version" yaml:"version"`
+	Categories []Category `json:"categories" yaml:"categories"`
+}
+
+// Category groups the entries of one category in a Document
+type Category struct {
+	Name    string `json:"name" yaml:"name"`
+	Entries []Item `json:"entries" yaml:"entries"`
+}
+
+// Item is a single entry in a Document
+type Item struct {
+	Content string   `json:"content" yaml:"content"`
+	Weight  int      `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Tags    []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// Type is omitted for text entries
+	Type storage.EntryType `json:"type,omitempty" yaml:"type,omitempty"`
+	// Locale is omitted for entries of any language
+	Locale string `json:"locale,omitempty" yaml:"locale,omitempty"`
+}
+
+// csvHeader is the header row of CSV exports
+var csvHeader = []string{"category", "content", "weight", "tags", "type", "locale"}
+
+// Encode writes entries in the given format. Entries are expected in
+// category order, as returned by storage.Store.ListEntries.
+func Encode(w io.Writer, format Format, entries []storage.Entry) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(toDocument(entries))
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(toDocument(entries)); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatCSV:
+		return encodeCSV(w, entries)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// Decode reads entries in the given format and validates them
+func Decode(r io.Reader, format Format) ([]storage.Entry, error) {
+	var entries []storage.Entry
+	var err error
+
+	switch format {
+	case FormatJSON:
+		var doc Document
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err = dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("decode json: %w", err)
+		}
+		entries, err = fromDocument(doc)
+	case FormatYAML:
+		var doc Document
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err = dec.Decode(&doc); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("decode yaml: %w", err)
+		}
+		entries, err = fromDocument(doc)
+	case FormatCSV:
+		entries, err = decodeCSV(r)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// toDocument groups entries by category
+func toDocument(entries []storage.Entry) Document {
+	doc := Document{Version: DocumentVersion, Categories: []Category{}}
+	for _, e := range entries {
+		if n := len(doc.Categories); n == 0 || doc.Categories[n-1].Name != e.Category {
+			doc.Categories = append(doc.Categories, Category{Name: e.Category})
+		}
+		c := &doc.Categories[len(doc.Categories)-1]
+		item := Item{Content: e.Content, Weight: e.Weight, Tags: e.Tags, Locale: e.Locale}
+		if e.Type != storage.EntryText {
+			item.Type = e.Type
+		}
+		c.Entries = append(c.Entries, item)
+	}
+	return doc
+}
+
+// fromDocument flattens and validates a Document
+func fromDocument(doc Document) ([]storage.Entry, error) {
+	if doc.Version > DocumentVersion {
+		return nil, fmt.Errorf("unsupported document version %d (max %d)", doc.Version, DocumentVersion)
+	}
+
+	var entries []storage.Entry
+	for i, c := range doc.Categories {
+		for j, item := range c.Entries {
+			e, err := newEntry(c.Name, item.Content, item.Weight, item.Tags, item.Type, item.Locale)
+			if err != nil {
+				return nil, fmt.Errorf("category %d (%q) entry %d: %w", i+1, c.Name, j+1, err)
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func encodeCSV(w io.Writer, entries []storage.Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{e.Category, e.Content, strconv.Itoa(e.Weight), strings.Join(e.Tags, ","), string(e.Type), e.Locale}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func decodeCSV(r io.Reader) ([]storage.Entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode csv: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"category", "content"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("decode csv: missing %q column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var entries []storage.Entry
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode csv: %w", err)
+		}
+
+		weight := 0
+		if raw := strings.TrimSpace(field(record, "weight")); raw != "" {
+			if weight, err = strconv.Atoi(raw); err != nil {
+				return nil, fmt.Errorf("line %d: invalid weight %q", line, raw)
+			}
+		}
+		var tags []string
+		if raw := field(record, "tags"); raw != "" {
+			tags = strings.Split(raw, ",")
+		}
+
+		entryType := storage.EntryType(strings.TrimSpace(field(record, "type")))
+		locale := strings.TrimSpace(field(record, "locale"))
+		e, err := newEntry(field(record, "category"), field(record, "content"), weight, tags, entryType, locale)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// newEntry validates and normalizes a decoded entry
+func newEntry(category, content string, weight int, tags []string, entryType storage.EntryType, locale string) (storage.Entry, error) {
+	e := storage.Entry{
+		Category: strings.TrimSpace(category),
+		Content:  strings.TrimRight(content, " \t\r\n"),
+		Weight:   weight,
+		Tags:     tags,
+		Type:     entryType,
+		Locale:   locale,
+	}
+	if err := e.Validate(); err != nil {
+		return storage.Entry{}, err
+	}
+
+	e.Weight = max(e.Weight, storage.DefaultWeight)
+	e.Tags = storage.NormalizeTags(e.Tags)
+	if e.Type == "" {
+		e.Type = storage.EntryText
+	}
+	return e, nil
+}
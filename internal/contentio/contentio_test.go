@@ -0,0 +1,201 @@
+package contentio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"mutsumi-bot/internal/storage"
+)
+
+// sampleEntries returns entries in category order, as ListEntries does
+func sampleEntries() []storage.Entry {
+	return []storage.Entry{
+		{Category: "cats", Content: "Meow, meow!", Weight: 1, Tags: []string{}, Type: storage.EntryText},
+		{Category: "cats", Content: "Miaou !", Weight: 1, Tags: []string{}, Type: storage.EntryText, Locale: "fr"},
+		{Category: "wooper", Content: "Wooper is the best!", Weight: 3, Tags: []string{"blue", "cute"}, Type: storage.EntryText},
+		{Category: "wooper", Content: "Multi\nline \"quoted\"", Weight: 1, Tags: []string{}, Type: storage.EntryText},
+		{Category: "wooper", Content: "🐸 <:wooper:123>", Weight: 1, Tags: []string{}, Type: storage.EntryReaction},
+	}
+}
+
+// TestEncodeDecode_RoundTrip tests that every format preserves entries.
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, format, sampleEntries()); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			got, err := Decode(&buf, format)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+
+			want := sampleEntries()
+			if len(got) != len(want) {
+				t.Fatalf("Expected %d entries, got %d: %+v", len(want), len(got), got)
+			}
+			for i := range want {
+				if got[i].Category != want[i].Category || got[i].Content != want[i].Content ||
+					got[i].Weight != want[i].Weight || !slices.Equal(got[i].Tags, want[i].Tags) || got[i].Type != want[i].Type ||
+					got[i].Locale != want[i].Locale {
+					t.Errorf("Entry %d: expected %+v, got %+v", i, want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+// TestDecode_Invalid tests validation of decoded entries.
+func TestDecode_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{name: "json missing category", format: FormatJSON, input: `{"categories":[{"entries":[{"content":"x"}]}]}`},
+		{name: "json empty content", format: FormatJSON, input: `{"categories":[{"name":"a","entries":[{"content":"  "}]}]}`},
+		{name: "json unknown field", format: FormatJSON, input: `{"categories":[],"extra":1}`},
+		{name: "json future version", format: FormatJSON, input: `{"version":99,"categories":[]}`},
+		{name: "yaml negative weight", format: FormatYAML, input: "categories:\n  - name: a\n    entries:\n      - content: x\n        weight: -1\n"},
+		{name: "csv missing column", format: FormatCSV, input: "category,weight\na,1\n"},
+		{name: "json invalid reaction", format: FormatJSON, input: `{"categories":[{"name":"a","entries":[{"content":"frog","type":"reaction"}]}]}`},
+		{name: "csv unknown type", format: FormatCSV, input: "category,content,type\na,x,sticker\n"},
+		{name: "csv bad weight", format: FormatCSV, input: "category,content,weight\na,x,heavy\n"},
+		{name: "csv category with spaces", format: FormatCSV, input: "category,content\nmy cat,x\n"},
+		{name: "yaml invalid locale", format: FormatYAML, input: "categories:\n  - name: a\n    entries:\n      - content: x\n        locale: French\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(strings.NewReader(tt.input), tt.format); err == nil {
+				t.Errorf("Expected error but got none")
+			}
+		})
+	}
+}
+
+// TestParseFormat tests format names and file extensions.
+func TestParseFormat(t *testing.T) {
+	for input, want := range map[string]Format{"json": FormatJSON, "YAML": FormatYAML, ".yml": FormatYAML, "csv": FormatCSV} {
+		got, err := ParseFormat(input)
+		if err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	if _, err := FormatFromFilename("backup.xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+// TestImport tests upsert and replace modes, de-duplication and dry runs.
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+
+	seed := func(t *testing.T) *storage.MemoryStore {
+		store := storage.NewMemoryStore()
+		err := store.ApplyChanges(ctx, storage.ChangeSet{Add: []storage.Entry{
+			{Category: "wooper", Content: "keep", Weight: 1},
+			{Category: "wooper", Content: "reweigh", Weight: 1},
+			{Category: "wooper", Content: "stale", Weight: 1},
+			{Category: "cats", Content: "untouched", Weight: 1},
+		}})
+		if err != nil {
+			t.Fatalf("Failed to seed store: %v", err)
+		}
+		return store
+	}
+
+	incoming := []storage.Entry{
+		{Category: "wooper", Content: "keep", Weight: 1, Tags: []string{}},
+		{Category: "wooper", Content: "reweigh", Weight: 5, Tags: []string{}},
+		{Category: "wooper", Content: "new", Weight: 1, Tags: []string{}},
+		{Category: "wooper", Content: "new", Weight: 1, Tags: []string{}},
+		{Category: "dogs", Content: "woof", Weight: 1, Tags: []string{}},
+	}
+
+	tests := []struct {
+		name        string
+		opts        Options
+		wantWooper  CategorySummary
+		wantDogs    CategorySummary
+		wantEntries []string
+	}{
+		{
+			name:        "upsert",
+			opts:        Options{Mode: ModeUpsert},
+			wantWooper:  CategorySummary{Category: "wooper", Added: 1, Updated: 1, Unchanged: 1, Duplicates: 1},
+			wantDogs:    CategorySummary{Category: "dogs", Added: 1},
+			wantEntries: []string{"cats/untouched", "dogs/woof", "wooper/keep", "wooper/new", "wooper/reweigh", "wooper/stale"},
+		},
+		{
+			name:        "replace",
+			opts:        Options{Mode: ModeReplace},
+			wantWooper:  CategorySummary{Category: "wooper", Added: 1, Updated: 1, Deleted: 1, Unchanged: 1, Duplicates: 1},
+			wantDogs:    CategorySummary{Category: "dogs", Added: 1},
+			wantEntries: []string{"cats/untouched", "dogs/woof", "wooper/keep", "wooper/new", "wooper/reweigh"},
+		},
+		{
+			name:        "dry run",
+			opts:        Options{Mode: ModeReplace, DryRun: true},
+			wantWooper:  CategorySummary{Category: "wooper", Added: 1, Updated: 1, Deleted: 1, Unchanged: 1, Duplicates: 1},
+			wantDogs:    CategorySummary{Category: "dogs", Added: 1},
+			wantEntries: []string{"cats/untouched", "wooper/keep", "wooper/reweigh", "wooper/stale"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := seed(t)
+
+			summary, err := Import(ctx, store, incoming, tt.opts)
+			if err != nil {
+				t.Fatalf("Import: %v", err)
+			}
+
+			if len(summary.Categories) != 2 {
+				t.Fatalf("Expected 2 category summaries, got %+v", summary.Categories)
+			}
+			if summary.Categories[0] != tt.wantDogs {
+				t.Errorf("Expected %+v, got %+v", tt.wantDogs, summary.Categories[0])
+			}
+			if summary.Categories[1] != tt.wantWooper {
+				t.Errorf("Expected %+v, got %+v", tt.wantWooper, summary.Categories[1])
+			}
+			if summary.DryRun != tt.opts.DryRun {
+				t.Errorf("Expected DryRun %v, got %v", tt.opts.DryRun, summary.DryRun)
+			}
+
+			entries, _ := store.ListEntries(ctx, "")
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Category+"/"+e.Content)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.wantEntries) {
+				t.Errorf("Expected entries %v, got %v", tt.wantEntries, got)
+			}
+		})
+	}
+}
+
+// TestSummary_String tests the summary table rendering.
+func TestSummary_String(t *testing.T) {
+	summary := Summary{
+		DryRun:     true,
+		Categories: []CategorySummary{{Category: "wooper", Added: 2, Duplicates: 1}},
+	}
+
+	out := summary.String()
+	for _, want := range []string{"Dry run", "category", "wooper", "total"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected summary to contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package contentio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"mutsumi-bot/internal/storage"
+)
+
+// ErrUnknownMode is returned for an unsupported import mode
+var ErrUnknownMode = errors.New("unknown import mode")
+
+// Mode controls how imported entries are merged with existing ones
+type Mode string
+
+const (
+	// ModeUpsert adds new entries and updates the weight and tags of
+	// existing ones, leaving other entries untouched
+	ModeUpsert Mode = "upsert"
+	// ModeReplace makes every category present in the import match it
+	// exactly, deleting entries missing from the import. Categories absent
+	// from the import are left untouched.
+	ModeReplace Mode = "replace"
+)
+
+// ParseMode parses an import mode name
+func ParseMode(name string) (Mode, error) {
+	switch Mode(strings.ToLower(name)) {
+	case ModeUpsert:
+		return ModeUpsert, nil
+	case ModeReplace:
+		return ModeReplace, nil
+	}
+	return "", fmt.Errorf("%w: %q (expected upsert or replace)", ErrUnknownMode, name)
+}
+
+// Options configures an import
+type Options struct {
+	Mode Mode
+	// DryRun computes the summary without writing anything
+	DryRun bool
+	// Actor is recorded in the history of updated and deleted entries
+	Actor string
+}
+
+// CategorySummary counts the changes made to one category
+type CategorySummary struct {
+	Category   string
+	Added      int
+	Updated    int
+	Deleted    int
+	Unchanged  int
+	Duplicates int
+}
+
+// Summary describes the outcome of an import, per category
+type Summary struct {
+	Categories []CategorySummary
+	DryRun     bool
+}
+
+// Total sums the changes across all categories
+func (s Summary) Total() CategorySummary {
+	total := CategorySummary{Category: "total"}
+	for _, c := range s.Categories {
+		total.Added += c.Added
+		total.Updated += c.Updated
+		total.Deleted += c.Deleted
+		total.Unchanged += c.Unchanged
+		total.Duplicates += c.Duplicates
+	}
+	return total
+}
+
+// String renders the summary as an aligned text table
+func (s Summary) String() string {
+	var b strings.Builder
+	if s.DryRun {
+		b.WriteString("Dry run, no changes written\n")
+	}
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "category\tadded\tupdated\tdeleted\tunchanged\tduplicates")
+	for _, c := range append(slices.Clone(s.Categories), s.Total()) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\n",
+			c.Category, c.Added, c.Updated, c.Deleted, c.Unchanged, c.Duplicates)
+	}
+	tw.Flush()
+
+	return b.String()
+}
+
+// entryKey identifies an entry for de-duplication
+func entryKey(e storage.Entry) string {
+	return e.Category + "\x00" + e.Content
+}
+
+// Plan computes the changes needed to merge incoming entries into existing
+// ones. Entries are matched on category and content; repeated incoming
+// entries are counted as duplicates and only the first one is kept.
+func Plan(existing, incoming []storage.Entry, mode Mode) (storage.ChangeSet, Summary) {
+	var changes storage.ChangeSet
+	summaries := make(map[string]*CategorySummary)
+	summary := func(category string) *CategorySummary {
+		if s, ok := summaries[category]; ok {
+			return s
+		}
+		s := &CategorySummary{Category: category}
+		summaries[category] = s
+		return s
+	}
+
+	current := make(map[string]storage.Entry, len(existing))
+	for _, e := range existing {
+		if _, ok := current[entryKey(e)]; !ok {
+			current[entryKey(e)] = e
+		}
+	}
+
+	seen := make(map[string]bool, len(incoming))
+	imported := make(map[string]bool)
+	for _, e := range incoming {
+		key := entryKey(e)
+		s := summary(e.Category)
+		imported[e.Category] = true
+
+		if seen[key] {
+			s.Duplicates++
+			continue
+		}
+		seen[key] = true
+
+		old, ok := current[key]
+		switch {
+		case !ok:
+			changes.Add = append(changes.Add, e)
+			s.Added++
+		case old.Weight != e.Weight || !slices.Equal(old.Tags, e.Tags) || old.Type.OrDefault() != e.Type.OrDefault() || old.Locale != e.Locale:
+			e.ID = old.ID
+			changes.Update = append(changes.Update, e)
+			s.Updated++
+		default:
+			s.Unchanged++
+		}
+	}
+
+	if mode == ModeReplace {
+		kept := make(map[int64]bool)
+		for key := range seen {
+			if old, ok := current[key]; ok {
+				kept[old.ID] = true
+			}
+		}
+		for _, e := range existing {
+			if imported[e.Category] && !kept[e.ID] {
+				changes.Delete = append(changes.Delete, e.ID)
+				summary(e.Category).Deleted++
+			}
+		}
+	}
+
+	result := Summary{}
+	for _, s := range summaries {
+		result.Categories = append(result.Categories, *s)
+	}
+	sort.Slice(result.Categories, func(i, j int) bool {
+		return result.Categories[i].Category < result.Categories[j].Category
+	})
+
+	return changes, result
+}
+
+// Import merges entries into the store according to opts and returns a
+// per-category summary of the changes
+func Import(ctx context.Context, store storage.Store, entries []storage.Entry, opts Options) (Summary, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeUpsert
+	}
+
+	existing, err := store.ListEntries(ctx, "")
+	if err != nil {
+		return Summary{}, fmt.Errorf("list existing entries: %w", err)
+	}
+
+	changes, summary := Plan(existing, entries, opts.Mode)
+	summary.DryRun = opts.DryRun
+	if opts.DryRun {
+		return summary, nil
+	}
+
+	changes.Actor = opts.Actor
+	if err := store.ApplyChanges(ctx, changes); err != nil {
+		return Summary{}, fmt.Errorf("apply changes: %w", err)
+	}
+	return summary, nil
+}
+
+// Export returns the entries of a category, or of all categories when
+// category is empty, ready to be passed to Encode
+func Export(ctx context.Context, store storage.Store, category string) ([]storage.Entry, error) {
+	entries, err := store.ListEntries(ctx, category)
+	if err != nil {
+		return nil, fmt.Errorf("list entries: %w", err)
+	}
+	return entries, nil
+}
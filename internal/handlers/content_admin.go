@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"mutsumi-bot/internal/contentio"
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// maxImportSize is the largest attachment accepted by /content import
+const maxImportSize = 8 << 20
+
+// adminPermissions are the permissions allowed to manage content
+const adminPermissions = discordgo.PermissionAdministrator | discordgo.PermissionManageServer
+
+// ContentAdminCommand returns the definition of the /content admin command
+func ContentAdminCommand() *discordgo.ApplicationCommand {
+	permissions := int64(adminPermissions)
+	dmPermission := false
+
+	formatChoices := make([]*discordgo.ApplicationCommandOptionChoice, len(contentio.Formats))
+	for i, f := range contentio.Formats {
+		formatChoices[i] = &discordgo.ApplicationCommandOptionChoice{Name: string(f), Value: string(f)}
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:                     "content",
+		Description:              "Manage bot content",
+		DefaultMemberPermissions: &permissions,
+		DMPermission:             &dmPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Write a new entry in a form",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "category",
+						Description: "Category of the entry, can be changed in the form",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "edit",
+				Description: "Edit an entry in a form",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "Entry ID",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "export",
+				Description: "Export content as a file",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "format",
+						Description: "File format (default json)",
+						Choices:     formatChoices,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "category",
+						Description: "Only export this category",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "import",
+				Description: "Import content from a JSON, YAML or CSV file",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionAttachment,
+						Name:        "file",
+						Description: "File to import (.json, .yaml or .csv)",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "mode",
+						Description: "upsert keeps other entries, replace makes imported categories match the file",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: string(contentio.ModeUpsert), Value: string(contentio.ModeUpsert)},
+							{Name: string(contentio.ModeReplace), Value: string(contentio.ModeReplace)},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "dry_run",
+						Description: "Only show what would change",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "history",
+				Description: "Show the changes made to an entry",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "Entry ID",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "restore",
+				Description: "Bring back a deleted entry, or revert a change",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "Entry ID",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "revision",
+						Description: "Revert the entry to how it was before this revision, see /content history",
+					},
+				},
+			},
+		},
+	}
+}
+
+// CategoryCache caches the list of categories, which content written
+// directly to the store has to invalidate
+type CategoryCache interface {
+	InvalidateCategories()
+}
+
+// ContentAdminHandler handles the /content admin command
+type ContentAdminHandler struct {
+	Store      storage.Store
+	HTTPClient *http.Client
+	// Locales picks the language of replies and entry forms, nil for the
+	// locale of the user
+	Locales *Localizer
+	// Categories is invalidated after writes so that new categories answer
+	// at once, nil when nothing caches them
+	Categories CategoryCache
+}
+
+func NewContentAdminHandler(store storage.Store) *ContentAdminHandler {
+	return &ContentAdminHandler{
+		Store:      store,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// OnInteractionCreate is the discordgo event handler for interactions
+func (h *ContentAdminHandler) OnInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	h.HandleInteraction(s, i)
+}
+
+// HandleInteraction processes a /content interaction using the given Discord API
+func (h *ContentAdminHandler) HandleInteraction(s DiscordAPI, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand || i.ApplicationCommandData().Name != "content" {
+		return
+	}
+	locale := h.Locales.ForInteraction(i)
+
+	if !isContentAdmin(i) {
+		logger.Logger.Warn("Unauthorized content admin command",
+			zap.String("user_id", interactionUserID(i)),
+			zap.String("guild_id", i.GuildID))
+		respondEphemeral(s, i, i18n.T(locale, "entry.forbidden", nil))
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+	sub := options[0]
+
+	// Forms have to be the first response, they can't follow a deferral
+	switch sub.Name {
+	case "add":
+		h.promptAdd(s, i, sub.Options)
+		return
+	case "edit":
+		h.promptEdit(s, i, sub.Options)
+		return
+	}
+
+	// Downloads and database writes can exceed the 3 second response window
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		logger.Logger.Error("Failed to defer content admin response", zap.Error(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var edit *discordgo.WebhookEdit
+	switch sub.Name {
+	case "export":
+		edit = h.export(ctx, locale, i, sub.Options)
+	case "import":
+		edit = h.importFile(ctx, locale, i, sub.Options)
+	case "history":
+		edit = h.history(ctx, locale, sub.Options)
+	case "restore":
+		edit = h.restore(ctx, locale, i, sub.Options)
+	default:
+		return
+	}
+
+	// Text results, such as the summary of a large import, continue in
+	// followup messages when they outgrow one
+	if edit.Content != nil && len(edit.Files) == 0 {
+		err = editText(s, i, &discordgo.InteractionResponseData{Content: *edit.Content, Flags: discordgo.MessageFlagsEphemeral}, "", 0)
+	} else {
+		_, err = s.InteractionResponseEdit(i.Interaction, edit)
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to send content admin result",
+			zap.String("subcommand", sub.Name),
+			zap.Error(err))
+	}
+}
+
+// export encodes the requested content into a file attachment
+func (h *ContentAdminHandler) export(ctx context.Context, locale string, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.WebhookEdit {
+	format := contentio.FormatJSON
+	var category string
+	for _, opt := range options {
+		switch opt.Name {
+		case "format":
+			f, err := contentio.ParseFormat(opt.StringValue())
+			if err != nil {
+				return textEdit(i18n.T(locale, "content.unknown_format", i18n.Args{"format": opt.StringValue()}))
+			}
+			format = f
+		case "category":
+			category = opt.StringValue()
+		}
+	}
+
+	entries, err := contentio.Export(ctx, h.Store, category)
+	if err != nil {
+		logger.Logger.Error("Failed to export content", zap.Error(err))
+		return textEdit(i18n.T(locale, "content.export_failed", nil))
+	}
+
+	var buf bytes.Buffer
+	if err := contentio.Encode(&buf, format, entries); err != nil {
+		logger.Logger.Error("Failed to encode content", zap.Error(err))
+		return textEdit(i18n.T(locale, "content.export_failed", nil))
+	}
+
+	logger.Logger.Info("Content exported via slash command",
+		zap.String("format", string(format)),
+		zap.String("category", category),
+		zap.Int("entries", len(entries)),
+		zap.String("user_id", interactionUserID(i)))
+
+	name := "content"
+	if category != "" {
+		name += "-" + category
+	}
+	edit := textEdit(i18n.N(locale, "content.exported", len(entries), nil))
+	edit.Files = []*discordgo.File{{
+		Name:        name + "." + string(format),
+		ContentType: "text/plain; charset=utf-8",
+		Reader:      &buf,
+	}}
+	return edit
+}
+
+// importFile downloads an attachment and imports its content
+func (h *ContentAdminHandler) importFile(ctx context.Context, locale string, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.WebhookEdit {
+	opts := contentio.Options{Mode: contentio.ModeUpsert, Actor: interactionUserID(i)}
+	var attachment *discordgo.MessageAttachment
+	for _, opt := range options {
+		switch opt.Name {
+		case "file":
+			if resolved := i.ApplicationCommandData().Resolved; resolved != nil {
+				attachment = resolved.Attachments[opt.Value.(string)]
+			}
+		case "mode":
+			mode, err := contentio.ParseMode(opt.StringValue())
+			if err != nil {
+				return textEdit(i18n.T(locale, "content.unknown_mode", i18n.Args{"mode": opt.StringValue()}))
+			}
+			opts.Mode = mode
+		case "dry_run":
+			opts.DryRun = opt.BoolValue()
+		}
+	}
+
+	if attachment == nil {
+		return textEdit(i18n.T(locale, "content.import_missing", nil))
+	}
+	if attachment.Size > maxImportSize {
+		return textEdit(i18n.T(locale, "content.import_too_large", i18n.Args{"max": maxImportSize >> 20}))
+	}
+	format, err := contentio.FormatFromFilename(attachment.Filename)
+	if err != nil {
+		return textEdit(i18n.T(locale, "content.unsupported_file", i18n.Args{"file": attachment.Filename}))
+	}
+
+	data, err := h.download(ctx, attachment.URL)
+	if err != nil {
+		logger.Logger.Error("Failed to download import file", zap.String("url", attachment.URL), zap.Error(err))
+		return textEdit(i18n.T(locale, "content.import_download_failed", nil))
+	}
+
+	entries, err := contentio.Decode(bytes.NewReader(data), format)
+	if err != nil {
+		return textEdit(i18n.T(locale, "content.import_invalid", i18n.Args{"error": err}))
+	}
+
+	summary, err := contentio.Import(ctx, h.Store, entries, opts)
+	if err != nil {
+		logger.Logger.Error("Failed to import content", zap.Error(err))
+		return textEdit(i18n.T(locale, "content.import_failed", nil))
+	}
+
+	if !opts.DryRun {
+		h.invalidateCategories()
+	}
+
+	total := summary.Total()
+	logger.Logger.Info("Content imported via slash command",
+		zap.String("file", attachment.Filename),
+		zap.String("mode", string(opts.Mode)),
+		zap.Bool("dry_run", opts.DryRun),
+		zap.Int("added", total.Added),
+		zap.Int("updated", total.Updated),
+		zap.Int("deleted", total.Deleted),
+		zap.String("user_id", interactionUserID(i)))
+
+	return textEdit("```\n" + summary.String() + "```")
+}
+
+// download fetches an attachment, refusing bodies larger than maxImportSize
+func (h *ContentAdminHandler) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportSize {
+		return nil, fmt.Errorf("file larger than %d bytes", maxImportSize)
+	}
+	return data, nil
+}
+
+// isContentAdmin reports whether the interaction comes from a guild member
+// allowed to manage content
+func isContentAdmin(i *discordgo.InteractionCreate) bool {
+	return i.Member != nil && i.Member.Permissions&adminPermissions != 0
+}
+
+// interactionUserID returns the ID of the user behind an interaction, in a
+// guild or in DMs
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// invalidateCategories drops the cached categories after a write
+func (h *ContentAdminHandler) invalidateCategories() {
+	if h.Categories != nil {
+		h.Categories.InvalidateCategories()
+	}
+}
+
+// respondEphemeral sends a response only visible to the invoking user. It
+// pings no one, as content may repeat what the user typed.
+func respondEphemeral(s DiscordAPI, i *discordgo.InteractionCreate, content string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         content,
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: noMentions(),
+		},
+	})
+	if err != nil {
+		logger.Logger.Error("Failed to send ephemeral response", zap.Error(err))
+	}
+}
+
+// textEdit builds a response edit replacing the content with text
+func textEdit(content string) *discordgo.WebhookEdit {
+	return &discordgo.WebhookEdit{Content: &content}
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// setupTestContentAdminHandler creates a content admin handler on a seeded memory store
+func setupTestContentAdminHandler(t *testing.T) (*ContentAdminHandler, *storage.MemoryStore) {
+	err := logger.Init()
+	if err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(func() {
+		logger.Close()
+	})
+
+	store := storage.NewMemoryStore()
+	store.AddContent(context.Background(), "wooper", "Wooper!")
+
+	return NewContentAdminHandler(store), store
+}
+
+// newTestContentInteraction builds a /content interaction for a subcommand
+func newTestContentInteraction(permissions int64, sub string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-1",
+			Type:      discordgo.InteractionApplicationCommand,
+			ChannelID: "channel-1",
+			GuildID:   "guild-1",
+			Member: &discordgo.Member{
+				User:        &discordgo.User{ID: "user-1", Username: "admin"},
+				Permissions: permissions,
+			},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: "content",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: sub, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options},
+				},
+				Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+					Attachments: map[string]*discordgo.MessageAttachment{},
+				},
+			},
+		},
+	}
+}
+
+// withAttachment registers an attachment in the interaction's resolved data
+func withAttachment(i *discordgo.InteractionCreate, a *discordgo.MessageAttachment) *discordgo.InteractionCreate {
+	data := i.Data.(discordgo.ApplicationCommandInteractionData)
+	data.Resolved.Attachments[a.ID] = a
+	data.Options[0].Options = append(data.Options[0].Options, &discordgo.ApplicationCommandInteractionDataOption{
+		Name: "file", Type: discordgo.ApplicationCommandOptionAttachment, Value: a.ID,
+	})
+	i.Data = data
+	return i
+}
+
+// TestContentAdminHandler_Permissions tests that non-admins are rejected.
+func TestContentAdminHandler_Permissions(t *testing.T) {
+	handler, _ := setupTestContentAdminHandler(t)
+	discord := newFakeDiscord()
+
+	handler.HandleInteraction(discord, newTestContentInteraction(discordgo.PermissionSendMessages, "export"))
+
+	responses := discord.interactionResponses()
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response, got %d", len(responses))
+	}
+	if responses[0].Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+		t.Errorf("Expected an ephemeral rejection")
+	}
+	if !strings.Contains(responses[0].Data.Content, "Manage Server") {
+		t.Errorf("Unexpected rejection message %q", responses[0].Data.Content)
+	}
+	if len(discord.responseEdits()) != 0 {
+		t.Errorf("Expected no export to be sent")
+	}
+}
+
+// TestContentAdminHandler_Export tests exporting content as an attachment.
+func TestContentAdminHandler_Export(t *testing.T) {
+	handler, _ := setupTestContentAdminHandler(t)
+	discord := newFakeDiscord()
+
+	handler.HandleInteraction(discord, newTestContentInteraction(discordgo.PermissionManageServer, "export",
+		&discordgo.ApplicationCommandInteractionDataOption{Name: "format", Type: discordgo.ApplicationCommandOptionString, Value: "csv"}))
+
+	responses := discord.interactionResponses()
+	if len(responses) != 1 || responses[0].Type != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+		t.Fatalf("Expected a deferred response, got %+v", responses)
+	}
+
+	edits := discord.responseEdits()
+	if len(edits) != 1 || len(edits[0].Files) != 1 {
+		t.Fatalf("Expected one edit with a file, got %+v", edits)
+	}
+	if edits[0].Files[0].Name != "content.csv" {
+		t.Errorf("Expected content.csv, got %s", edits[0].Files[0].Name)
+	}
+	data, _ := io.ReadAll(edits[0].Files[0].Reader)
+	if !strings.Contains(string(data), "wooper,Wooper!,1,") {
+		t.Errorf("Unexpected export:\n%s", data)
+	}
+}
+
+// TestContentAdminHandler_Import tests importing an attachment.
+func TestContentAdminHandler_Import(t *testing.T) {
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "category,content\nwooper,Wooper!\ncats,Meow\ncats,Meow\n")
+	}))
+	defer files.Close()
+
+	tests := []struct {
+		name        string
+		dryRun      bool
+		wantEntries int
+	}{
+		{name: "apply", wantEntries: 2},
+		{name: "dry run", dryRun: true, wantEntries: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, store := setupTestContentAdminHandler(t)
+			discord := newFakeDiscord()
+
+			i := newTestContentInteraction(discordgo.PermissionAdministrator, "import",
+				&discordgo.ApplicationCommandInteractionDataOption{Name: "dry_run", Type: discordgo.ApplicationCommandOptionBoolean, Value: tt.dryRun})
+			i = withAttachment(i, &discordgo.MessageAttachment{ID: "att-1", Filename: "seed.csv", URL: files.URL, Size: 64})
+
+			handler.HandleInteraction(discord, i)
+
+			edits := discord.responseEdits()
+			if len(edits) != 1 || edits[0].Content == nil {
+				t.Fatalf("Expected one summary edit, got %+v", edits)
+			}
+			summary := *edits[0].Content
+			if !strings.Contains(summary, "cats") || !strings.Contains(summary, "total") {
+				t.Errorf("Expected a per-category summary, got:\n%s", summary)
+			}
+			if tt.dryRun != strings.Contains(summary, "Dry run") {
+				t.Errorf("Dry run marker mismatch in:\n%s", summary)
+			}
+
+			entries, _ := store.ListEntries(context.Background(), "")
+			if len(entries) != tt.wantEntries {
+				t.Errorf("Expected %d entries, got %d", tt.wantEntries, len(entries))
+			}
+		})
+	}
+
+	t.Run("new category is listed at once", func(t *testing.T) {
+		handler, store := setupTestContentAdminHandler(t)
+		service := services.NewDatabaseServiceWithStore(store)
+		service.SetCategoryCacheTTL(time.Hour)
+		handler.Categories = service
+		if _, err := service.GetAvailableCategories(); err != nil {
+			t.Fatalf("Failed to cache the categories: %v", err)
+		}
+
+		i := newTestContentInteraction(discordgo.PermissionAdministrator, "import")
+		i = withAttachment(i, &discordgo.MessageAttachment{ID: "att-1", Filename: "seed.csv", URL: files.URL, Size: 64})
+		handler.HandleInteraction(newFakeDiscord(), i)
+
+		categories, err := service.GetAvailableCategories()
+		if err != nil || !slices.Equal(categories, []string{"cats", "wooper"}) {
+			t.Errorf("Expected the imported category listed, got %v, %v", categories, err)
+		}
+	})
+
+	t.Run("long summary continues in followups", func(t *testing.T) {
+		many := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "category,content\n")
+			for n := range 100 {
+				fmt.Fprintf(w, "category-%03d,Content\n", n)
+			}
+		}))
+		defer many.Close()
+		handler, _ := setupTestContentAdminHandler(t)
+		discord := newFakeDiscord()
+
+		i := newTestContentInteraction(discordgo.PermissionAdministrator, "import")
+		i = withAttachment(i, &discordgo.MessageAttachment{ID: "att-1", Filename: "seed.csv", URL: many.URL, Size: 64})
+		handler.HandleInteraction(discord, i)
+
+		edits := discord.responseEdits()
+		followups := discord.followupMessages()
+		if len(edits) != 1 || edits[0].Content == nil || len(followups) == 0 {
+			t.Fatalf("Expected a summary edit and followups, got %d edits and %d followups", len(edits), len(followups))
+		}
+		messages := []string{*edits[0].Content}
+		for _, f := range followups {
+			if f.Flags&discordgo.MessageFlagsEphemeral == 0 {
+				t.Error("Expected ephemeral followups")
+			}
+			messages = append(messages, f.Content)
+		}
+		for _, message := range messages {
+			if utf8.RuneCountInString(message) > 2000 || !strings.HasPrefix(message, "```") || !strings.HasSuffix(message, "```") {
+				t.Errorf("Expected code blocks of at most 2000 characters, got %q", message)
+			}
+		}
+		if last := messages[len(messages)-1]; !strings.Contains(last, "total") {
+			t.Errorf("Expected the total in the last message, got %q", last)
+		}
+	})
+}
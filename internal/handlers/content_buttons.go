@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"mutsumi-bot/internal/chunk"
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// contentButtonPrefix starts the custom ID of the buttons on /command
+// replies: content:<action>:<entry ID>:<invoker ID>:<issued>:<signature>,
+// with the issue time in base 36 Unix seconds
+const contentButtonPrefix = "content:"
+
+// Actions of the content buttons
+const (
+	contentReroll = "reroll"
+	contentUp     = "up"
+	contentDown   = "down"
+	contentDelete = "delete"
+)
+
+// signatureLength is the number of HMAC bytes kept in a signed custom ID
+const signatureLength = 12
+
+// maxRerollPicks bounds the picks made to avoid showing the same entry again
+const maxRerollPicks = 3
+
+// ButtonSigner signs the custom IDs of buttons, so that a button press
+// can't be forged by crafting an interaction
+type ButtonSigner struct {
+	key []byte
+}
+
+func NewButtonSigner(key []byte) *ButtonSigner {
+	return &ButtonSigner{key: key}
+}
+
+// Sign appends the signature of a custom ID to it
+func (s *ButtonSigner) Sign(customID string) string {
+	return customID + ":" + s.signature(customID)
+}
+
+// Verify returns a signed custom ID without its signature, and whether the
+// signature is valid
+func (s *ButtonSigner) Verify(signed string) (string, bool) {
+	i := strings.LastIndexByte(signed, ':')
+	if i < 0 {
+		return "", false
+	}
+	customID, signature := signed[:i], signed[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(s.signature(customID))) {
+		return "", false
+	}
+	return customID, true
+}
+
+// signature returns the truncated HMAC of a custom ID
+func (s *ButtonSigner) signature(customID string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(customID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:signatureLength])
+}
+
+// contentButton is the state carried by the custom ID of a content button
+type contentButton struct {
+	Action    string
+	EntryID   int64
+	InvokerID string
+	Issued    time.Time
+}
+
+// customID encodes the button without its signature
+func (b contentButton) customID() string {
+	return fmt.Sprintf("%s%s:%d:%s:%s", contentButtonPrefix, b.Action, b.EntryID, b.InvokerID, strconv.FormatInt(b.Issued.Unix(), 36))
+}
+
+// with returns a copy of the button with another action
+func (b contentButton) with(action string) contentButton {
+	b.Action = action
+	return b
+}
+
+// parseContentButton decodes a custom ID built by contentButton.customID
+func parseContentButton(customID string) (contentButton, bool) {
+	parts := strings.Split(strings.TrimPrefix(customID, contentButtonPrefix), ":")
+	if len(parts) != 4 {
+		return contentButton{}, false
+	}
+	entryID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return contentButton{}, false
+	}
+	issued, err := strconv.ParseInt(parts[3], 36, 64)
+	if err != nil {
+		return contentButton{}, false
+	}
+	switch parts[0] {
+	case contentReroll, contentUp, contentDown, contentDelete:
+	default:
+		return contentButton{}, false
+	}
+	return contentButton{Action: parts[0], EntryID: entryID, InvokerID: parts[2], Issued: time.Unix(issued, 0)}, true
+}
+
+// ContentButtons adds Reroll, vote and Delete buttons to /command replies
+// and keeps track of the replies whose buttons are live
+type ContentButtons struct {
+	Store  storage.Store
+	Signer *ButtonSigner
+	// Timeout is how long the buttons work before they are disabled, at
+	// most the 15 minutes Discord lets a reply be edited
+	Timeout time.Duration
+	// Delete adds a Delete button for the user who ran the command
+	Delete bool
+
+	now       func() time.Time
+	afterFunc func(time.Duration, func())
+
+	mu sync.Mutex
+	// shown is the entry shown by each reply with live buttons, by the ID
+	// of the /command interaction
+	shown map[string]int64
+}
+
+func NewContentButtons(store storage.Store, signer *ButtonSigner, timeout time.Duration, allowDelete bool) *ContentButtons {
+	return &ContentButtons{
+		Store:     store,
+		Signer:    signer,
+		Timeout:   timeout,
+		Delete:    allowDelete,
+		now:       time.Now,
+		afterFunc: func(d time.Duration, f func()) { time.AfterFunc(d, f) },
+		shown:     map[string]int64{},
+	}
+}
+
+// parse verifies and decodes the custom ID of a pressed button
+func (b *ContentButtons) parse(customID string) (contentButton, bool) {
+	unsigned, ok := b.Signer.Verify(customID)
+	if !ok {
+		return contentButton{}, false
+	}
+	return parseContentButton(unsigned)
+}
+
+// votes returns the tally of an entry, or no votes when it can't be read
+func (b *ContentButtons) votes(ctx context.Context, entryID int64) storage.Votes {
+	votes, err := b.Store.EntryVotes(ctx, entryID)
+	if err != nil {
+		logger.Logger.Warn("Failed to count votes", zap.Int64("entry_id", entryID), zap.Error(err))
+	}
+	return votes
+}
+
+// setShown records the entry now shown by a reply with live buttons
+func (b *ContentButtons) setShown(interactionID string, entryID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.shown[interactionID]; ok {
+		b.shown[interactionID] = entryID
+	}
+}
+
+// forget stops tracking a reply and returns the entry it showed
+func (b *ContentButtons) forget(interactionID string) (int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entryID, ok := b.shown[interactionID]
+	delete(b.shown, interactionID)
+	return entryID, ok
+}
+
+// contentComponents returns the buttons of a /command reply showing the
+// entry of btn. They are labelled in locale, the language of the server, as
+// anyone can see and press them.
+func (h *InteractionHandler) contentComponents(locale string, btn contentButton, votes storage.Votes, disabled bool) []discordgo.MessageComponent {
+	sign := func(action string) string {
+		return h.Buttons.Signer.Sign(btn.with(action).customID())
+	}
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{Label: i18n.T(locale, "buttons.reroll", nil), Emoji: discordgo.ComponentEmoji{Name: "🔁"}, Style: discordgo.PrimaryButton, CustomID: sign(contentReroll), Disabled: disabled},
+		discordgo.Button{Label: voteLabel(votes.Up), Emoji: discordgo.ComponentEmoji{Name: "👍"}, Style: discordgo.SecondaryButton, CustomID: sign(contentUp), Disabled: disabled},
+		discordgo.Button{Label: voteLabel(votes.Down), Emoji: discordgo.ComponentEmoji{Name: "👎"}, Style: discordgo.SecondaryButton, CustomID: sign(contentDown), Disabled: disabled},
+	}
+	if h.SaveButton {
+		save := saveButtonComponent(locale, btn.EntryID)
+		save.Disabled = disabled
+		buttons = append(buttons, save)
+	}
+	if h.Buttons.Delete {
+		buttons = append(buttons, discordgo.Button{Label: i18n.T(locale, "buttons.delete", nil), Style: discordgo.DangerButton, CustomID: sign(contentDelete), Disabled: disabled})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// voteLabel shows a vote count next to its emoji, or nothing without votes
+func voteLabel(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// watchButtons disables the buttons of a /command reply once they time out
+func (h *InteractionHandler) watchButtons(s DiscordAPI, interaction *discordgo.Interaction, btn contentButton) {
+	h.Buttons.mu.Lock()
+	h.Buttons.shown[interaction.ID] = btn.EntryID
+	h.Buttons.mu.Unlock()
+
+	h.Buttons.afterFunc(h.Buttons.Timeout, func() {
+		entryID, ok := h.Buttons.forget(interaction.ID)
+		if !ok {
+			// The reply was deleted
+			return
+		}
+		btn.EntryID = entryID
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		components := h.contentComponents(h.Locales.ForGuild(interaction.GuildID), btn, h.Buttons.votes(ctx, entryID), true)
+		if _, err := s.InteractionResponseEdit(interaction, &discordgo.WebhookEdit{Components: &components}); err != nil {
+			logger.Logger.Debug("Failed to disable content buttons",
+				zap.String("interaction_id", interaction.ID),
+				zap.Error(err))
+		}
+	})
+}
+
+// handleButton processes a press on a content button
+func (h *InteractionHandler) handleButton(s DiscordAPI, i *discordgo.InteractionCreate) {
+	userID := interactionUserID(i)
+	btn, ok := h.Buttons.parse(i.MessageComponentData().CustomID)
+	if !ok {
+		logger.Logger.Warn("Rejected content button with an invalid signature",
+			zap.String("custom_id", i.MessageComponentData().CustomID),
+			zap.String("user_id", userID))
+		respondEphemeral(s, i, i18n.T(h.Locales.ForInteraction(i), "buttons.invalid", nil))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if h.Buttons.now().After(btn.Issued.Add(h.Buttons.Timeout)) {
+		// Timers don't survive a restart, so expired buttons are also
+		// disabled when pressed
+		h.updateReply(s, i, messageContent(i), nil, h.contentComponents(h.Locales.ForGuild(i.GuildID), btn, h.Buttons.votes(ctx, btn.EntryID), true))
+		return
+	}
+
+	switch btn.Action {
+	case contentReroll:
+		h.reroll(ctx, s, i, btn)
+	case contentUp:
+		h.vote(ctx, s, i, btn, 1)
+	case contentDown:
+		h.vote(ctx, s, i, btn, -1)
+	case contentDelete:
+		h.deleteReply(s, i, btn)
+	}
+}
+
+// reroll replaces the entry of a reply with another pick from its category
+func (h *InteractionHandler) reroll(ctx context.Context, s DiscordAPI, i *discordgo.InteractionCreate, btn contentButton) {
+	userID := interactionUserID(i)
+	if userID != btn.InvokerID {
+		respondEphemeral(s, i, i18n.T(h.Locales.ForInteraction(i), "buttons.reroll_forbidden", i18n.Args{"user": btn.InvokerID}))
+		return
+	}
+	if allowed, retryAfter := h.Limiter.Allow(userID); !allowed {
+		respondEphemeral(s, i, i18n.T(h.Locales.ForInteraction(i), "rate_limited", i18n.Args{"retry": retryAfter.Round(time.Second)}))
+		return
+	}
+
+	current, err := h.Buttons.Store.GetEntry(ctx, btn.EntryID)
+	if errors.Is(err, storage.ErrEntryNotFound) {
+		respondEphemeral(s, i, i18n.T(h.Locales.ForInteraction(i), "buttons.reroll_deleted", nil))
+		return
+	}
+	if err != nil {
+		h.respondUnavailable(s, i, "", err)
+		return
+	}
+
+	var entry storage.Entry
+	for range maxRerollPicks {
+		entry, err = h.ContentService.GetRandomEntry(current.Category, h.Locales.ForInteraction(i))
+		if err != nil || entry.ID != current.ID {
+			break
+		}
+	}
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound), errors.Is(err, services.ErrEmptyCategory):
+		respondEphemeral(s, i, i18n.T(h.Locales.ForInteraction(i), "command.no_content", i18n.Args{"command": current.Category}))
+		return
+	case err != nil:
+		h.respondUnavailable(s, i, current.Category, err)
+		return
+	}
+
+	btn.EntryID = entry.ID
+	h.updateReply(s, i, plainText(entry), h.Mentions.For(i.GuildID, entry, userID), h.contentComponents(h.Locales.ForGuild(i.GuildID), btn, h.Buttons.votes(ctx, entry.ID), false))
+	h.Buttons.setShown(originalInteractionID(i), entry.ID)
+
+	logger.Logger.Info("Content rerolled",
+		zap.String("category", current.Category),
+		zap.Int64("entry_id", entry.ID),
+		zap.String("user_id", userID),
+		zap.String("channel_id", i.ChannelID))
+}
+
+// vote records a vote on the entry of a reply and shows the new tally
+func (h *InteractionHandler) vote(ctx context.Context, s DiscordAPI, i *discordgo.InteractionCreate, btn contentButton, value int) {
+	userID := interactionUserID(i)
+	votes, err := h.Buttons.Store.Vote(ctx, btn.EntryID, userID, value, h.Buttons.now())
+	if errors.Is(err, storage.ErrEntryNotFound) {
+		respondEphemeral(s, i, i18n.T(h.Locales.ForInteraction(i), "buttons.vote_deleted", nil))
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to record vote",
+			zap.Int64("entry_id", btn.EntryID),
+			zap.String("user_id", userID),
+			zap.Error(err))
+		respondEphemeral(s, i, i18n.T(h.Locales.ForInteraction(i), "unavailable", nil))
+		return
+	}
+
+	logger.Logger.Info("Vote recorded",
+		zap.Int64("entry_id", btn.EntryID),
+		zap.Int("value", value),
+		zap.String("user_id", userID))
+	h.updateReply(s, i, messageContent(i), nil, h.contentComponents(h.Locales.ForGuild(i.GuildID), btn, votes, false))
+}
+
+// deleteReply deletes a reply at the request of the user who ran the command
+func (h *InteractionHandler) deleteReply(s DiscordAPI, i *discordgo.InteractionCreate, btn contentButton) {
+	userID := interactionUserID(i)
+	if !h.Buttons.Delete || userID != btn.InvokerID {
+		respondEphemeral(s, i, i18n.T(h.Locales.ForInteraction(i), "buttons.delete_forbidden", nil))
+		return
+	}
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}); err != nil {
+		logger.Logger.Error("Failed to acknowledge delete", zap.Error(err))
+		return
+	}
+	if err := s.ChannelMessageDelete(i.ChannelID, i.Message.ID); err != nil {
+		logger.Logger.Error("Failed to delete content reply",
+			zap.String("channel_id", i.ChannelID),
+			zap.String("message_id", i.Message.ID),
+			zap.Error(err))
+		return
+	}
+	h.Buttons.forget(originalInteractionID(i))
+
+	logger.Logger.Info("Content reply deleted",
+		zap.Int64("entry_id", btn.EntryID),
+		zap.String("user_id", userID),
+		zap.String("channel_id", i.ChannelID))
+}
+
+// updateReply edits the message of a button in response to the press.
+// Content too long for the message is attached as a file instead. mentions
+// is nil when the content is unchanged, as edits only ping new mentions.
+func (h *InteractionHandler) updateReply(s DiscordAPI, i *discordgo.InteractionCreate, content string, mentions *discordgo.MessageAllowedMentions, components []discordgo.MessageComponent) {
+	data := &discordgo.InteractionResponseData{
+		Content:         content,
+		Components:      components,
+		AllowedMentions: mentions,
+	}
+	if utf8.RuneCountInString(content) > chunk.Limit {
+		data.Content = ""
+		data.Files = []*discordgo.File{contentFile(content)}
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: data,
+	})
+	if err != nil {
+		logger.Logger.Error("Failed to update content reply", zap.Error(err))
+	}
+}
+
+// messageContent returns the content of the message a button is on
+func messageContent(i *discordgo.InteractionCreate) string {
+	if i.Message == nil {
+		return ""
+	}
+	return i.Message.Content
+}
+
+// originalInteractionID returns the ID of the command interaction that
+// created the message a button is on
+func originalInteractionID(i *discordgo.InteractionCreate) string {
+	if i.Message == nil || i.Message.Interaction == nil {
+		return ""
+	}
+	return i.Message.Interaction.ID
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// testButtonsNow is the time seen by the content buttons in tests
+var testButtonsNow = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+// setupTestContentButtons creates an interaction handler with content
+// buttons on a memory store holding a rarely picked wooper entry #1 and a
+// likely one #2. The returned function fires the pending button timeout.
+func setupTestContentButtons(t *testing.T) (*InteractionHandler, *storage.MemoryStore, func()) {
+	err := logger.Init()
+	if err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(func() {
+		logger.Close()
+	})
+
+	store := storage.NewMemoryStore()
+	store.AddEntry(context.Background(), storage.Entry{Category: "wooper", Content: "Wooper!", Weight: 1})
+	store.AddEntry(context.Background(), storage.Entry{Category: "wooper", Content: "Wooper wooper!", Weight: 1_000_000})
+
+	handler := NewInteractionHandler(services.NewDatabaseServiceWithStore(store))
+	handler.SaveButton = true
+	handler.Buttons = NewContentButtons(store, NewButtonSigner([]byte("secret")), 10*time.Minute, true)
+	handler.Buttons.now = func() time.Time { return testButtonsNow }
+
+	var timeout func()
+	handler.Buttons.afterFunc = func(d time.Duration, f func()) {
+		if d != 10*time.Minute {
+			t.Errorf("Expected the buttons to time out after 10m, got %s", d)
+		}
+		timeout = f
+	}
+	return handler, store, func() {
+		if timeout == nil {
+			t.Fatal("Expected a pending button timeout")
+		}
+		timeout()
+	}
+}
+
+// newTestButtonInteraction builds a press on a button of the /command reply
+// "Wooper!" made by interaction-1
+func newTestButtonInteraction(userID, customID string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-2",
+			Type:      discordgo.InteractionMessageComponent,
+			ChannelID: "channel-1",
+			GuildID:   "guild-1",
+			Member:    &discordgo.Member{User: &discordgo.User{ID: userID, Username: "tester"}},
+			Message: &discordgo.Message{
+				ID:          "message-1",
+				Content:     "Wooper!",
+				Interaction: &discordgo.MessageInteraction{ID: "interaction-1"},
+			},
+			Data: discordgo.MessageComponentInteractionData{
+				CustomID:      customID,
+				ComponentType: discordgo.ButtonComponent,
+			},
+		},
+	}
+}
+
+// buttonsOf returns the buttons of the first row of components
+func buttonsOf(t *testing.T, components []discordgo.MessageComponent) []discordgo.Button {
+	t.Helper()
+	if len(components) != 1 {
+		t.Fatalf("Expected a single row of buttons, got %+v", components)
+	}
+	var buttons []discordgo.Button
+	for _, c := range components[0].(discordgo.ActionsRow).Components {
+		buttons = append(buttons, c.(discordgo.Button))
+	}
+	return buttons
+}
+
+// TestButtonSigner tests that only custom IDs signed with the same key verify.
+func TestButtonSigner(t *testing.T) {
+	signer := NewButtonSigner([]byte("secret"))
+	signed := signer.Sign("content:up:1:user-1:abc")
+
+	if got, ok := signer.Verify(signed); !ok || got != "content:up:1:user-1:abc" {
+		t.Errorf("Verify(%q) = %q, %v", signed, got, ok)
+	}
+	if len(signed) > 100 {
+		t.Errorf("Signed custom ID is longer than Discord allows: %d", len(signed))
+	}
+
+	forged := strings.Replace(signed, ":1:", ":2:", 1)
+	if _, ok := signer.Verify(forged); ok {
+		t.Errorf("Expected %q to be rejected", forged)
+	}
+	if _, ok := NewButtonSigner([]byte("other")).Verify(signed); ok {
+		t.Error("Expected a signature made with another key to be rejected")
+	}
+	if _, ok := signer.Verify("unsigned"); ok {
+		t.Error("Expected a custom ID without signature to be rejected")
+	}
+}
+
+// TestParseContentButton tests decoding content button custom IDs.
+func TestParseContentButton(t *testing.T) {
+	btn := contentButton{Action: contentReroll, EntryID: 42, InvokerID: "123456789012345678", Issued: testButtonsNow}
+	got, ok := parseContentButton(btn.customID())
+	if !ok || got.Action != btn.Action || got.EntryID != 42 || got.InvokerID != btn.InvokerID || !got.Issued.Equal(btn.Issued) {
+		t.Errorf("parseContentButton(%q) = %+v, %v", btn.customID(), got, ok)
+	}
+
+	for _, customID := range []string{
+		"content:boost:1:user-1:abc",
+		"content:up:x:user-1:abc",
+		"content:up:1:user-1:!",
+		"content:up:1:user-1",
+	} {
+		if _, ok := parseContentButton(customID); ok {
+			t.Errorf("Expected %q to be rejected", customID)
+		}
+	}
+}
+
+// TestInteractionHandler_ContentButtons tests the buttons added to a
+// /command reply and their timeout.
+func TestInteractionHandler_ContentButtons(t *testing.T) {
+	handler, store, timeout := setupTestContentButtons(t)
+	store.Vote(context.Background(), 2, "user-3", 1, testButtonsNow)
+	fake := newFakeDiscord()
+
+	handler.HandleInteraction(fake, newTestCommandInteraction("wooper"))
+
+	resp := lastResponse(t, fake)
+	if resp.Data.Content != "Wooper wooper!" {
+		t.Fatalf("Expected the likely entry, got %q", resp.Data.Content)
+	}
+	buttons := buttonsOf(t, resp.Data.Components)
+	var labels []string
+	for _, b := range buttons {
+		labels = append(labels, b.Emoji.Name+b.Label)
+	}
+	if got := strings.Join(labels, ","); got != "🔁Reroll,👍1,👎,⭐Save,Delete" {
+		t.Errorf("Unexpected buttons %s", got)
+	}
+	if !strings.HasPrefix(buttons[0].CustomID, "content:reroll:2:user-1:") || buttons[3].CustomID != "favorite:save:2" {
+		t.Errorf("Unexpected custom IDs %q and %q", buttons[0].CustomID, buttons[3].CustomID)
+	}
+
+	// A reroll after the reply was sent must be reflected when the buttons
+	// are disabled
+	handler.Buttons.setShown("interaction-1", 1)
+	timeout()
+
+	edits := fake.responseEdits()
+	if len(edits) != 1 || edits[0].Content != nil {
+		t.Fatalf("Expected the buttons edited without the content, got %+v", edits)
+	}
+	for _, b := range buttonsOf(t, *edits[0].Components) {
+		if !b.Disabled {
+			t.Errorf("Expected button %s to be disabled", b.Label)
+		}
+	}
+	if id := buttonsOf(t, *edits[0].Components)[3].CustomID; id != "favorite:save:1" {
+		t.Errorf("Expected the disabled buttons of the rerolled entry, got %s", id)
+	}
+}
+
+// TestInteractionHandler_ContentButtonPress tests pressing the buttons of a
+// /command reply showing entry #1 for user-1.
+func TestInteractionHandler_ContentButtonPress(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		userID string
+		// age is how long ago the reply was sent
+		age      time.Duration
+		deleted  bool
+		customID string
+
+		wantReply    string
+		wantContent  string
+		wantLabels   []string
+		wantDisabled bool
+		wantDeleted  bool
+	}{
+		{
+			name:        "vote up",
+			action:      contentUp,
+			userID:      "user-2",
+			wantContent: "Wooper!",
+			wantLabels:  []string{"Reroll", "2", "", "Save", "Delete"},
+		},
+		{
+			name:        "vote down",
+			action:      contentDown,
+			userID:      "user-2",
+			wantContent: "Wooper!",
+			wantLabels:  []string{"Reroll", "1", "1", "Save", "Delete"},
+		},
+		{
+			name:        "withdraw vote",
+			action:      contentUp,
+			userID:      "user-3",
+			wantContent: "Wooper!",
+			wantLabels:  []string{"Reroll", "", "", "Save", "Delete"},
+		},
+		{
+			name:      "vote on a deleted entry",
+			action:    contentUp,
+			userID:    "user-2",
+			deleted:   true,
+			wantReply: "This entry was deleted.",
+		},
+		{
+			name:        "reroll",
+			action:      contentReroll,
+			userID:      "user-1",
+			wantContent: "Wooper wooper!",
+			wantLabels:  []string{"Reroll", "", "", "Save", "Delete"},
+		},
+		{
+			name:      "reroll by someone else",
+			action:    contentReroll,
+			userID:    "user-2",
+			wantReply: "Only <@user-1> can reroll this.",
+		},
+		{
+			name:      "reroll a deleted entry",
+			action:    contentReroll,
+			userID:    "user-1",
+			deleted:   true,
+			wantReply: "This entry was deleted, run /command again.",
+		},
+		{
+			name:        "delete",
+			action:      contentDelete,
+			userID:      "user-1",
+			wantDeleted: true,
+		},
+		{
+			name:      "delete by someone else",
+			action:    contentDelete,
+			userID:    "user-2",
+			wantReply: "Only the person who ran the command can delete this.",
+		},
+		{
+			name:         "expired",
+			action:       contentUp,
+			userID:       "user-2",
+			age:          11 * time.Minute,
+			wantContent:  "Wooper!",
+			wantLabels:   []string{"Reroll", "1", "", "Save", "Delete"},
+			wantDisabled: true,
+		},
+		{
+			name:      "forged",
+			userID:    "user-2",
+			customID:  "content:reroll:1:user-1:0:AAAAAAAAAAAAAAAA",
+			wantReply: "This button is not valid.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, store, _ := setupTestContentButtons(t)
+			ctx := context.Background()
+			store.Vote(ctx, 1, "user-3", 1, testButtonsNow)
+			if tt.deleted {
+				store.ApplyChanges(ctx, storage.ChangeSet{Delete: []int64{1}})
+			}
+			customID := tt.customID
+			if customID == "" {
+				btn := contentButton{Action: tt.action, EntryID: 1, InvokerID: "user-1", Issued: testButtonsNow.Add(-tt.age)}
+				customID = handler.Buttons.Signer.Sign(btn.customID())
+			}
+			fake := newFakeDiscord()
+
+			handler.HandleInteraction(fake, newTestButtonInteraction(tt.userID, customID))
+
+			resp := lastResponse(t, fake)
+			switch {
+			case tt.wantReply != "":
+				if resp.Data.Content != tt.wantReply || resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+					t.Errorf("Expected ephemeral reply %q, got %+v", tt.wantReply, resp.Data)
+				}
+			case tt.wantDeleted:
+				if resp.Type != discordgo.InteractionResponseDeferredMessageUpdate {
+					t.Errorf("Expected a deferred update, got %+v", resp)
+				}
+			default:
+				if resp.Type != discordgo.InteractionResponseUpdateMessage || resp.Data.Content != tt.wantContent {
+					t.Fatalf("Expected the reply updated to %q, got %+v", tt.wantContent, resp)
+				}
+				buttons := buttonsOf(t, resp.Data.Components)
+				for i, b := range buttons {
+					if i < len(tt.wantLabels) && b.Label != tt.wantLabels[i] {
+						t.Errorf("Expected button %d labeled %q, got %q", i, tt.wantLabels[i], b.Label)
+					}
+					if b.Disabled != tt.wantDisabled {
+						t.Errorf("Expected button %q disabled %v", b.Label, tt.wantDisabled)
+					}
+				}
+			}
+
+			deleted := fake.deletedMessages()
+			if tt.wantDeleted != (len(deleted) == 1) {
+				t.Errorf("Expected deleted %v, got %+v", tt.wantDeleted, deleted)
+			} else if tt.wantDeleted && deleted[0].MessageID != "message-1" {
+				t.Errorf("Expected the reply deleted, got %+v", deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// maxFormLength is the longest content the entry modals take, so that an
+// entry fits in a single Discord message. Longer entries can be imported.
+const maxFormLength = 2000
+
+// Custom IDs of the entry modals: entryAddModalID adds an entry, and
+// entryEditModalPrefix is followed by the ID of the entry to edit, e.g.
+// entry:edit:42
+const (
+	entryAddModalID      = "entry:add"
+	entryEditModalPrefix = "entry:edit:"
+)
+
+// Custom IDs of the text inputs of the entry modals
+const (
+	entryCategoryInput = "category"
+	entryContentInput  = "content"
+	entryTagsInput     = "tags"
+	entryLocaleInput   = "locale"
+)
+
+// entryModal builds a modal editing the category, content, tags and locale
+// of an entry, prefilled from entry
+func entryModal(locale, customID, title string, entry storage.Entry) *discordgo.InteractionResponse {
+	input := func(input discordgo.TextInput) discordgo.MessageComponent {
+		return discordgo.ActionsRow{Components: []discordgo.MessageComponent{input}}
+	}
+
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: customID,
+			Title:    title,
+			Components: []discordgo.MessageComponent{
+				input(discordgo.TextInput{
+					CustomID:  entryCategoryInput,
+					Label:     i18n.T(locale, "entry.category", nil),
+					Style:     discordgo.TextInputShort,
+					Value:     entry.Category,
+					Required:  true,
+					MaxLength: 100,
+				}),
+				input(discordgo.TextInput{
+					CustomID:  entryContentInput,
+					Label:     i18n.T(locale, "entry.content", nil),
+					Style:     discordgo.TextInputParagraph,
+					Value:     entry.Content,
+					Required:  true,
+					MaxLength: maxFormLength,
+				}),
+				input(discordgo.TextInput{
+					CustomID:    entryTagsInput,
+					Label:       i18n.T(locale, "entry.tags", nil),
+					Style:       discordgo.TextInputShort,
+					Placeholder: i18n.T(locale, "entry.tags_placeholder", nil),
+					Value:       strings.Join(entry.Tags, ", "),
+					MaxLength:   200,
+				}),
+				input(discordgo.TextInput{
+					CustomID:    entryLocaleInput,
+					Label:       i18n.T(locale, "entry.locale", nil),
+					Style:       discordgo.TextInputShort,
+					Placeholder: i18n.T(locale, "entry.locale_placeholder", nil),
+					Value:       entry.Locale,
+					MaxLength:   16,
+				}),
+			},
+		},
+	}
+}
+
+// promptAdd opens the modal adding an entry, in the category given as an
+// option if any
+func (h *ContentAdminHandler) promptAdd(s DiscordAPI, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	var entry storage.Entry
+	for _, opt := range options {
+		if opt.Name == "category" {
+			entry.Category = opt.StringValue()
+		}
+	}
+
+	locale := h.Locales.ForInteraction(i)
+	err := s.InteractionRespond(i.Interaction, entryModal(locale, entryAddModalID, i18n.T(locale, "entry.add_title", nil), entry))
+	if err != nil {
+		logger.Logger.Error("Failed to open add entry modal", zap.Error(err))
+	}
+}
+
+// promptEdit opens the modal editing an entry, prefilled with the entry
+func (h *ContentAdminHandler) promptEdit(s DiscordAPI, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	id := int64Option(options, "id")
+	locale := h.Locales.ForInteraction(i)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	entry, err := h.Store.GetEntry(ctx, id)
+	if errors.Is(err, storage.ErrEntryNotFound) {
+		respondEphemeral(s, i, i18n.T(locale, "entry.not_found", i18n.Args{"id": id}))
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to load entry", zap.Int64("entry_id", id), zap.Error(err))
+		respondEphemeral(s, i, i18n.T(locale, "unavailable", nil))
+		return
+	}
+
+	customID := entryEditModalPrefix + strconv.FormatInt(id, 10)
+	err = s.InteractionRespond(i.Interaction, entryModal(locale, customID, i18n.T(locale, "entry.edit_title", i18n.Args{"id": id}), entry))
+	if err != nil {
+		logger.Logger.Error("Failed to open edit entry modal", zap.Int64("entry_id", id), zap.Error(err))
+	}
+}
+
+// modalValues returns the values of the text inputs of a modal by custom ID
+func modalValues(data discordgo.ModalSubmitInteractionData) map[string]string {
+	values := map[string]string{}
+	for _, row := range data.Components {
+		actions, ok := row.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, component := range actions.Components {
+			if input, ok := component.(*discordgo.TextInput); ok {
+				values[input.CustomID] = input.Value
+			}
+		}
+	}
+	return values
+}
+
+// submitEntry adds or updates the entry submitted through an entry modal,
+// in any category, new ones included. Invalid entries are refused
+// ephemerally, so that the moderator can fix them and submit again.
+func (h *InteractionHandler) submitEntry(s DiscordAPI, i *discordgo.InteractionCreate) {
+	if h.Store == nil {
+		return
+	}
+	customID := i.ModalSubmitData().CustomID
+	var editID int64
+	if raw, ok := strings.CutPrefix(customID, entryEditModalPrefix); ok {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return
+		}
+		editID = id
+	} else if customID != entryAddModalID {
+		return
+	}
+
+	locale := h.Locales.ForInteraction(i)
+	if !isContentAdmin(i) {
+		logger.Logger.Warn("Unauthorized entry submission",
+			zap.String("user_id", interactionUserID(i)),
+			zap.String("guild_id", i.GuildID))
+		respondEphemeral(s, i, i18n.T(locale, "entry.forbidden", nil))
+		return
+	}
+
+	values := modalValues(i.ModalSubmitData())
+	submitted := storage.Entry{
+		Category: strings.TrimSpace(values[entryCategoryInput]),
+		Content:  strings.TrimRight(values[entryContentInput], " \t\r\n"),
+		Tags:     storage.NormalizeTags(strings.Split(values[entryTagsInput], ",")),
+		Locale:   strings.TrimSpace(values[entryLocaleInput]),
+	}
+	if n := utf8.RuneCountInString(submitted.Content); n > maxFormLength {
+		respondEphemeral(s, i, i18n.T(locale, "entry.too_long", i18n.Args{"max": maxFormLength, "count": n}))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	entry := submitted
+	if editID != 0 {
+		current, err := h.Store.GetEntry(ctx, editID)
+		if errors.Is(err, storage.ErrEntryNotFound) {
+			respondEphemeral(s, i, i18n.T(locale, "entry.not_found", i18n.Args{"id": editID}))
+			return
+		}
+		if err != nil {
+			h.respondUnavailable(s, i, submitted.Category, err)
+			return
+		}
+		// The weight and type are kept, as the modal doesn't show them
+		entry = current
+		entry.Category, entry.Content, entry.Tags, entry.Locale = submitted.Category, submitted.Content, submitted.Tags, submitted.Locale
+	}
+	if err := entry.Validate(); err != nil {
+		respondEphemeral(s, i, i18n.T(locale, "entry.invalid", i18n.Args{"error": err}))
+		return
+	}
+
+	// Saving through the content service refreshes its categories, which
+	// a new category adds to
+	actor := interactionUserID(i)
+	var err error
+	if editID != 0 {
+		err = h.ContentService.UpdateEntry(entry, actor)
+	} else {
+		entry.ID, err = h.ContentService.AddEntry(entry)
+	}
+	// The entry can be deleted while the form is open
+	if errors.Is(err, storage.ErrEntryNotFound) {
+		respondEphemeral(s, i, i18n.T(locale, "entry.not_found", i18n.Args{"id": editID}))
+		return
+	}
+	if err != nil {
+		h.respondUnavailable(s, i, entry.Category, err)
+		return
+	}
+
+	log := logger.Logger.With(
+		zap.Int64("id", entry.ID),
+		zap.String("category", entry.Category),
+		zap.String("user_id", actor),
+		zap.String("guild_id", i.GuildID))
+	if editID != 0 {
+		log.Info("Entry updated via modal")
+		respondEphemeral(s, i, i18n.T(locale, "entry.updated", i18n.Args{"id": entry.ID, "category": entry.Category}))
+		return
+	}
+	log.Info("Entry added via modal")
+	respondEphemeral(s, i, i18n.T(locale, "entry.added", i18n.Args{"id": entry.ID, "category": entry.Category}))
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// newTestModalSubmit builds the submission of an entry modal
+func newTestModalSubmit(permissions int64, customID, category, content, tags, locale string) *discordgo.InteractionCreate {
+	input := func(id, value string) discordgo.MessageComponent {
+		return &discordgo.ActionsRow{Components: []discordgo.MessageComponent{&discordgo.TextInput{CustomID: id, Value: value}}}
+	}
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-2",
+			Type:      discordgo.InteractionModalSubmit,
+			ChannelID: "channel-1",
+			GuildID:   "guild-1",
+			Member: &discordgo.Member{
+				User:        &discordgo.User{ID: "user-1", Username: "moderator"},
+				Permissions: permissions,
+			},
+			Data: discordgo.ModalSubmitInteractionData{
+				CustomID: customID,
+				Components: []discordgo.MessageComponent{
+					input(entryCategoryInput, category),
+					input(entryContentInput, content),
+					input(entryTagsInput, tags),
+					input(entryLocaleInput, locale),
+				},
+			},
+		},
+	}
+}
+
+// modalInputs returns the values of the text inputs of a modal response
+func modalInputs(t *testing.T, resp *discordgo.InteractionResponse) map[string]string {
+	t.Helper()
+	if resp.Type != discordgo.InteractionResponseModal {
+		t.Fatalf("Expected a modal, got %+v", resp)
+	}
+	values := map[string]string{}
+	for _, row := range resp.Data.Components {
+		input := row.(discordgo.ActionsRow).Components[0].(discordgo.TextInput)
+		values[input.CustomID] = input.Value
+	}
+	return values
+}
+
+// TestContentAdminHandler_Forms tests opening the add and edit modals.
+func TestContentAdminHandler_Forms(t *testing.T) {
+	t.Run("add", func(t *testing.T) {
+		handler, _ := setupTestContentAdminHandler(t)
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestContentInteraction(discordgo.PermissionManageServer, "add", stringOption("category", "wooper")))
+
+		resp := lastResponse(t, fake)
+		values := modalInputs(t, resp)
+		if resp.Data.CustomID != entryAddModalID || values[entryCategoryInput] != "wooper" || values[entryContentInput] != "" {
+			t.Errorf("Expected an empty entry in wooper, got %s %v", resp.Data.CustomID, values)
+		}
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		handler, store := setupTestContentAdminHandler(t)
+		id, _ := store.AddEntry(context.Background(), storage.Entry{Category: "wooper", Content: "Wooper\nwooper!", Tags: []string{"cute", "blue"}, Locale: "fr"})
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestContentInteraction(discordgo.PermissionManageServer, "edit", intOption("id", int(id))))
+
+		resp := lastResponse(t, fake)
+		values := modalInputs(t, resp)
+		want := map[string]string{
+			entryCategoryInput: "wooper",
+			entryContentInput:  "Wooper\nwooper!",
+			entryTagsInput:     "blue, cute",
+			entryLocaleInput:   "fr",
+		}
+		if resp.Data.CustomID != "entry:edit:2" || resp.Data.Title != "Edit entry #2" {
+			t.Errorf("Unexpected modal %q %q", resp.Data.CustomID, resp.Data.Title)
+		}
+		for input, value := range want {
+			if values[input] != value {
+				t.Errorf("Expected %s prefilled with %q, got %q", input, value, values[input])
+			}
+		}
+	})
+
+	t.Run("edit unknown entry", func(t *testing.T) {
+		handler, _ := setupTestContentAdminHandler(t)
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestContentInteraction(discordgo.PermissionManageServer, "edit", intOption("id", 42)))
+
+		if resp := lastResponse(t, fake); resp.Data.Content != "Entry #42 not found." || resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+			t.Errorf("Expected an ephemeral not found reply, got %+v", resp.Data)
+		}
+	})
+}
+
+// TestInteractionHandler_SubmitEntry tests adding and editing entries from
+// modals, and the validation errors shown back to the moderator.
+func TestInteractionHandler_SubmitEntry(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions int64
+		customID    string
+		category    string
+		content     string
+		tags        string
+		locale      string
+		wantReply   string
+		want        []storage.Entry
+	}{
+		{
+			name:        "add",
+			permissions: discordgo.PermissionManageServer,
+			customID:    entryAddModalID,
+			category:    " cats ",
+			content:     "Miaou !\n",
+			tags:        "Cute, , french",
+			locale:      "fr",
+			wantReply:   "Added entry #2 to `cats`.",
+			want: []storage.Entry{
+				{ID: 1, Category: "cats", Content: "Meow", Weight: 3, Tags: []string{}, Type: storage.EntryText},
+				{ID: 2, Category: "cats", Content: "Miaou !", Weight: 1, Tags: []string{"cute", "french"}, Type: storage.EntryText, Locale: "fr"},
+			},
+		},
+		{
+			name:        "edit keeps the weight",
+			permissions: discordgo.PermissionManageServer,
+			customID:    "entry:edit:1",
+			category:    "mutsumi",
+			content:     "Meow\nmeow",
+			wantReply:   "Updated entry #1 in `mutsumi`.",
+			want: []storage.Entry{
+				{ID: 1, Category: "mutsumi", Content: "Meow\nmeow", Weight: 3, Tags: []string{}, Type: storage.EntryText},
+			},
+		},
+		{
+			name:        "edit deleted entry",
+			permissions: discordgo.PermissionManageServer,
+			customID:    "entry:edit:7",
+			category:    "cats",
+			content:     "Meow",
+			wantReply:   "Entry #7 not found.",
+		},
+		{
+			name:        "too long",
+			permissions: discordgo.PermissionManageServer,
+			customID:    entryAddModalID,
+			category:    "cats",
+			content:     strings.Repeat("é", maxFormLength+1),
+			wantReply:   "The form takes up to 2000 characters, this entry has 2001.",
+		},
+		{
+			name:        "empty",
+			permissions: discordgo.PermissionManageServer,
+			customID:    entryAddModalID,
+			category:    "cats",
+			content:     " \n ",
+			wantReply:   "This entry can't be saved: missing content",
+		},
+		{
+			name:        "bad template",
+			permissions: discordgo.PermissionManageServer,
+			customID:    entryAddModalID,
+			category:    "cats",
+			content:     "Meow {react:frog}",
+			wantReply:   `This entry can't be saved: invalid {react:} token: "frog" is not an emoji`,
+		},
+		{
+			name:        "new category",
+			permissions: discordgo.PermissionManageServer,
+			customID:    entryAddModalID,
+			category:    "dogs",
+			content:     "Woof",
+			wantReply:   "Added entry #2 to `dogs`.",
+			want: []storage.Entry{
+				{ID: 1, Category: "cats", Content: "Meow", Weight: 3, Tags: []string{}, Type: storage.EntryText},
+				{ID: 2, Category: "dogs", Content: "Woof", Weight: 1, Tags: []string{}, Type: storage.EntryText},
+			},
+		},
+		{
+			name:        "invalid category",
+			permissions: discordgo.PermissionManageServer,
+			customID:    entryAddModalID,
+			category:    "big dogs",
+			content:     "Woof",
+			wantReply:   `This entry can't be saved: category "big dogs" contains whitespace`,
+		},
+		{
+			name:      "not an admin",
+			customID:  entryAddModalID,
+			category:  "cats",
+			content:   "Meow",
+			wantReply: "You need the Manage Server permission to manage content.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupTestInteractionHandler(t)
+			store := storage.NewMemoryStore()
+			store.AddEntry(context.Background(), storage.Entry{Category: "cats", Content: "Meow", Weight: 3})
+			handler.Store = store
+			handler.ContentService = services.NewDatabaseServiceWithStore(store)
+			fake := newFakeDiscord()
+
+			handler.HandleInteraction(fake, newTestModalSubmit(tt.permissions, tt.customID, tt.category, tt.content, tt.tags, tt.locale))
+
+			resp := lastResponse(t, fake)
+			if resp.Data.Content != tt.wantReply || resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+				t.Errorf("Expected ephemeral reply %q, got %+v", tt.wantReply, resp.Data)
+			}
+			if tt.want == nil {
+				return
+			}
+			entries, _ := store.ListEntries(context.Background(), "")
+			if !slices.EqualFunc(entries, tt.want, func(a, b storage.Entry) bool {
+				return a.ID == b.ID && a.Category == b.Category && a.Content == b.Content && a.Weight == b.Weight &&
+					slices.Equal(a.Tags, b.Tags) && a.Type == b.Type && a.Locale == b.Locale
+			}) {
+				t.Errorf("Expected entries %+v, got %+v", tt.want, entries)
+			}
+		})
+	}
+
+	t.Run("edit is recorded in the history", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		store := storage.NewMemoryStore()
+		id, _ := store.AddContent(context.Background(), "cats", "Meow")
+		handler.Store = store
+		handler.ContentService = services.NewDatabaseServiceWithStore(store)
+
+		handler.HandleInteraction(newFakeDiscord(), newTestModalSubmit(discordgo.PermissionManageServer, "entry:edit:1", "cats", "Purr", "", ""))
+
+		history, err := store.EntryHistory(context.Background(), id)
+		if err != nil || len(history) != 1 || history[0].Actor != "user-1" || history[0].After.Content != "Purr" {
+			t.Errorf("Expected the edit by user-1 in the history, got %+v, %v", history, err)
+		}
+	})
+
+	t.Run("entry deleted while the form is open", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		ctx := context.Background()
+		store := storage.NewMemoryStore()
+		store.AddContent(ctx, "cats", "Meow")
+		// The service sees the entry deleted after the handler loaded it
+		deleted := storage.NewMemoryStore()
+		deleted.AddContent(ctx, "cats", "Meow")
+		deleted.ApplyChanges(ctx, storage.ChangeSet{Delete: []int64{1}, Actor: "user-2"})
+		handler.Store = store
+		handler.ContentService = services.NewDatabaseServiceWithStore(deleted)
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestModalSubmit(discordgo.PermissionManageServer, "entry:edit:1", "cats", "Purr", "", ""))
+
+		if resp := lastResponse(t, fake); resp.Data.Content != "Entry #1 not found." {
+			t.Errorf("Expected the entry reported missing, got %q", resp.Data.Content)
+		}
+		if history, _ := deleted.EntryHistory(ctx, 1); len(history) != 1 {
+			t.Errorf("Expected only the deletion in the history, got %+v", history)
+		}
+	})
+
+	t.Run("new category is listed at once", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		store := storage.NewMemoryStore()
+		store.AddContent(context.Background(), "cats", "Meow")
+		service := services.NewDatabaseServiceWithStore(store)
+		service.SetCategoryCacheTTL(time.Hour)
+		handler.Store = store
+		handler.ContentService = service
+		if _, err := service.GetAvailableCategories(); err != nil {
+			t.Fatalf("Failed to cache the categories: %v", err)
+		}
+
+		handler.HandleInteraction(newFakeDiscord(), newTestModalSubmit(discordgo.PermissionManageServer, entryAddModalID, "dogs", "Woof", "", ""))
+
+		categories, err := service.GetAvailableCategories()
+		if err != nil || !slices.Equal(categories, []string{"cats", "dogs"}) {
+			t.Errorf("Expected the new category listed, got %v, %v", categories, err)
+		}
+	})
+}
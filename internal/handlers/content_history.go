@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"mutsumi-bot/internal/audit"
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// maxHistoryLength keeps /content history within a Discord message
+const maxHistoryLength = 1900
+
+// history lists the revisions of an entry, newest first, as many as fit
+func (h *ContentAdminHandler) history(ctx context.Context, locale string, options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.WebhookEdit {
+	id := int64Option(options, "id")
+
+	revisions, err := h.Store.EntryHistory(ctx, id)
+	if errors.Is(err, storage.ErrEntryNotFound) {
+		return textEdit(i18n.T(locale, "entry.not_found", i18n.Args{"id": id}))
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to load entry history", zap.Int64("entry_id", id), zap.Error(err))
+		return textEdit(i18n.T(locale, "unavailable", nil))
+	}
+	if len(revisions) == 0 {
+		return textEdit(i18n.T(locale, "content.no_history", i18n.Args{"id": id}))
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.T(locale, "content.history", i18n.Args{"id": id}))
+	shown := 0
+	for j := len(revisions) - 1; j >= 0; j-- {
+		line := "\n" + audit.Describe(revisions[j])
+		if b.Len()+len(line) > maxHistoryLength {
+			break
+		}
+		b.WriteString(line)
+		shown++
+	}
+	if shown < len(revisions) {
+		b.WriteString("\n" + i18n.N(locale, "content.older_revisions", len(revisions)-shown, nil))
+	}
+	return textEdit(b.String())
+}
+
+// restore undeletes an entry or reverts it to before a revision
+func (h *ContentAdminHandler) restore(ctx context.Context, locale string, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.WebhookEdit {
+	id := int64Option(options, "id")
+	revisionID := int64Option(options, "revision")
+	actor := interactionUserID(i)
+
+	rev, err := h.Store.RestoreEntry(ctx, id, revisionID, actor, time.Now())
+	switch {
+	case errors.Is(err, storage.ErrEntryNotFound):
+		return textEdit(i18n.T(locale, "entry.not_found", i18n.Args{"id": id}))
+	case errors.Is(err, storage.ErrEntryNotDeleted):
+		return textEdit(i18n.T(locale, "content.not_deleted", i18n.Args{"id": id}))
+	case errors.Is(err, storage.ErrRevisionNotFound):
+		return textEdit(i18n.T(locale, "content.revision_not_found", i18n.Args{"id": id, "revision": revisionID}))
+	case err != nil:
+		logger.Logger.Error("Failed to restore entry", zap.Int64("entry_id", id), zap.Error(err))
+		return textEdit(i18n.T(locale, "unavailable", nil))
+	}
+	h.invalidateCategories()
+
+	logger.Logger.Info("Entry restored via slash command",
+		zap.Int64("entry_id", id),
+		zap.Int64("revision_id", revisionID),
+		zap.String("user_id", actor))
+	return textEdit(i18n.T(locale, "content.restored", i18n.Args{"revision": audit.Describe(rev)}))
+}
+
+// int64Option returns the value of an integer option, or 0 when it is unset
+func int64Option(options []*discordgo.ApplicationCommandInteractionDataOption, name string) int64 {
+	for _, opt := range options {
+		if opt.Name == name {
+			return opt.IntValue()
+		}
+	}
+	return 0
+}
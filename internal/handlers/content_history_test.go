@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// TestContentAdminHandler_HistoryRestore tests listing the history of a
+// deleted entry and restoring it.
+func TestContentAdminHandler_HistoryRestore(t *testing.T) {
+	ctx := context.Background()
+	handler, store := setupTestContentAdminHandler(t)
+	store.ApplyChanges(ctx, storage.ChangeSet{
+		Update: []storage.Entry{{ID: 1, Category: "wooper", Content: "Wooper wooper!"}},
+		Actor:  "user-2",
+	})
+	store.ApplyChanges(ctx, storage.ChangeSet{Delete: []int64{1}, Actor: "user-2"})
+
+	steps := []struct {
+		name      string
+		sub       string
+		options   []*discordgo.ApplicationCommandInteractionDataOption
+		wantReply []string
+	}{
+		{
+			name:      "history",
+			sub:       "history",
+			options:   []*discordgo.ApplicationCommandInteractionDataOption{intOption("id", 1)},
+			wantReply: []string{"History of entry #1:\n**#2** delete of entry #1", "**#1** update of entry #1 in `wooper` by user-2", `> content: "Wooper!" → "Wooper wooper!"`},
+		},
+		{
+			name:      "history of unknown entry",
+			sub:       "history",
+			options:   []*discordgo.ApplicationCommandInteractionDataOption{intOption("id", 9)},
+			wantReply: []string{"Entry #9 not found."},
+		},
+		{
+			name:      "undelete",
+			sub:       "restore",
+			options:   []*discordgo.ApplicationCommandInteractionDataOption{intOption("id", 1)},
+			wantReply: []string{"Restored: **#3** restore of entry #1 in `wooper` by user-1"},
+		},
+		{
+			name:      "undelete a live entry",
+			sub:       "restore",
+			options:   []*discordgo.ApplicationCommandInteractionDataOption{intOption("id", 1)},
+			wantReply: []string{"Entry #1 is not deleted."},
+		},
+		{
+			name:      "revert a change",
+			sub:       "restore",
+			options:   []*discordgo.ApplicationCommandInteractionDataOption{intOption("id", 1), intOption("revision", 1)},
+			wantReply: []string{"**#4** restore of entry #1", `> content: "Wooper wooper!" → "Wooper!"`},
+		},
+		{
+			name:      "unknown revision",
+			sub:       "restore",
+			options:   []*discordgo.ApplicationCommandInteractionDataOption{intOption("id", 1), intOption("revision", 9)},
+			wantReply: []string{"Entry #1 has no revision #9."},
+		},
+	}
+
+	for _, step := range steps {
+		discord := newFakeDiscord()
+		handler.HandleInteraction(discord, newTestContentInteraction(discordgo.PermissionManageServer, step.sub, step.options...))
+
+		edits := discord.responseEdits()
+		if len(edits) != 1 || edits[0].Content == nil {
+			t.Fatalf("%s: expected one edit, got %+v", step.name, edits)
+		}
+		for _, want := range step.wantReply {
+			if !strings.Contains(*edits[0].Content, want) {
+				t.Errorf("%s: expected reply containing %q, got:\n%s", step.name, want, *edits[0].Content)
+			}
+		}
+	}
+
+	if e, err := store.GetEntry(ctx, 1); err != nil || e.Content != "Wooper!" {
+		t.Errorf("Expected the entry restored and reverted, got %+v (%v)", e, err)
+	}
+}
+
+// TestContentAdminHandler_RestoreCategory tests that a category brought back
+// by a restore is listed at once.
+func TestContentAdminHandler_RestoreCategory(t *testing.T) {
+	ctx := context.Background()
+	handler, store := setupTestContentAdminHandler(t)
+	store.ApplyChanges(ctx, storage.ChangeSet{Delete: []int64{1}, Actor: "user-2"})
+	service := services.NewDatabaseServiceWithStore(store)
+	service.SetCategoryCacheTTL(time.Hour)
+	handler.Categories = service
+	if categories, err := service.GetAvailableCategories(); err != nil || len(categories) != 0 {
+		t.Fatalf("Expected no categories cached, got %v, %v", categories, err)
+	}
+
+	handler.HandleInteraction(newFakeDiscord(), newTestContentInteraction(discordgo.PermissionManageServer, "restore", intOption("id", 1)))
+
+	categories, err := service.GetAvailableCategories()
+	if err != nil || !slices.Equal(categories, []string{"wooper"}) {
+		t.Errorf("Expected the restored category listed, got %v, %v", categories, err)
+	}
+}
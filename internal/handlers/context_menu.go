@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"unicode/utf8"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// Names of the context menu commands, shown in the Apps menu of messages
+// and users
+const (
+	addToCategoryCommand = "Add to category…"
+	randomForUserCommand = "Random for this user"
+)
+
+// ContextMenuCommands returns the definitions of the message and user
+// context menu commands
+func ContextMenuCommands() []*discordgo.ApplicationCommand {
+	permissions := int64(adminPermissions)
+	dmPermission := false
+
+	return []*discordgo.ApplicationCommand{
+		{
+			Type:                     discordgo.MessageApplicationCommand,
+			Name:                     addToCategoryCommand,
+			DefaultMemberPermissions: &permissions,
+			DMPermission:             &dmPermission,
+		},
+		{
+			Type:         discordgo.UserApplicationCommand,
+			Name:         randomForUserCommand,
+			DMPermission: &dmPermission,
+		},
+	}
+}
+
+// promptAddEntry opens a modal prefilled with the text and attachments of
+// the target message, for a moderator to pick its category
+func (h *InteractionHandler) promptAddEntry(s DiscordAPI, i *discordgo.InteractionCreate) {
+	if h.Store == nil {
+		return
+	}
+	locale := h.Locales.ForInteraction(i)
+	if !isContentAdmin(i) {
+		logger.Logger.Warn("Unauthorized add to category",
+			zap.String("user_id", interactionUserID(i)),
+			zap.String("guild_id", i.GuildID))
+		respondEphemeral(s, i, i18n.T(locale, "entry.forbidden", nil))
+		return
+	}
+
+	data := i.ApplicationCommandData()
+	var content string
+	if data.Resolved != nil {
+		content = messageText(data.Resolved.Messages[data.TargetID])
+	}
+	switch {
+	case content == "":
+		respondEphemeral(s, i, i18n.T(locale, "context.add.empty", nil))
+		return
+	case utf8.RuneCountInString(content) > maxFormLength:
+		respondEphemeral(s, i, i18n.T(locale, "context.add.too_long", i18n.Args{"max": maxFormLength}))
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, entryModal(locale, entryAddModalID,
+		i18n.T(locale, "context.add.title", nil), storage.Entry{Content: content}))
+	if err != nil {
+		logger.Logger.Error("Failed to open add entry modal", zap.Error(err))
+	}
+}
+
+// messageText returns the text of a message followed by the URLs of its
+// attachments, one per line
+func messageText(m *discordgo.Message) string {
+	if m == nil {
+		return ""
+	}
+	lines := []string{}
+	if text := strings.TrimSpace(m.Content); text != "" {
+		lines = append(lines, text)
+	}
+	for _, a := range m.Attachments {
+		lines = append(lines, a.URL)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// randomForUser pings the target user with a random entry of a random
+// category
+func (h *InteractionHandler) randomForUser(s DiscordAPI, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	if data.Resolved == nil || data.Resolved.Users[data.TargetID] == nil {
+		return
+	}
+	target := data.Resolved.Users[data.TargetID]
+	locale := h.Locales.ForInteraction(i)
+	if target.Bot {
+		respondEphemeral(s, i, i18n.T(locale, "context.random.bot", nil))
+		return
+	}
+
+	categories, err := h.ContentService.GetAvailableCategories()
+	if err != nil {
+		h.respondUnavailable(s, i, "", err)
+		return
+	}
+	if len(categories) == 0 {
+		respondEphemeral(s, i, i18n.T(locale, "context.random.none", nil))
+		return
+	}
+	category := categories[rand.IntN(len(categories))]
+
+	entry, err := h.ContentService.GetRandomEntry(category, locale)
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound), errors.Is(err, services.ErrEmptyCategory):
+		respondEphemeral(s, i, i18n.T(locale, "command.no_content", i18n.Args{"command": category}))
+		return
+	case err != nil:
+		h.respondUnavailable(s, i, category, err)
+		return
+	}
+
+	response := &discordgo.InteractionResponseData{
+		Content:         plainText(entry),
+		AllowedMentions: h.Mentions.For(i.GuildID, entry, target.ID),
+	}
+	if h.SaveButton {
+		response.Components = saveButton(h.Locales.ForGuild(i.GuildID), entry.ID)
+	}
+	err = respondText(s, i, response, target.Mention()+" ", h.AttachmentThreshold)
+	if err != nil {
+		logger.Logger.Error("Failed to send content for user",
+			zap.String("category", category),
+			zap.String("target_id", target.ID),
+			zap.Error(err))
+		return
+	}
+	logger.Logger.Info("Content sent via user command",
+		zap.String("category", category),
+		zap.String("user_id", interactionUserID(i)),
+		zap.String("target_id", target.ID),
+		zap.String("guild_id", i.GuildID))
+}
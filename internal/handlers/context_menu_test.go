@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// newTestContextInteraction builds a context menu interaction on a message
+// or a user by a member with the given permissions
+func newTestContextInteraction(name string, permissions int64, resolved *discordgo.ApplicationCommandInteractionDataResolved, targetID string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-1",
+			Type:      discordgo.InteractionApplicationCommand,
+			ChannelID: "channel-1",
+			GuildID:   "guild-1",
+			Member: &discordgo.Member{
+				User:        &discordgo.User{ID: "user-1", Username: "moderator"},
+				Permissions: permissions,
+			},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name:     name,
+				TargetID: targetID,
+				Resolved: resolved,
+			},
+		},
+	}
+}
+
+// TestAddToCategory tests opening the add entry modal from a message.
+func TestAddToCategory(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions int64
+		message     *discordgo.Message
+		wantModal   string
+		wantReply   string
+	}{
+		{
+			name:        "text and attachment",
+			permissions: discordgo.PermissionManageServer,
+			message: &discordgo.Message{
+				ID:          "message-1",
+				Content:     " Wooper! ",
+				Attachments: []*discordgo.MessageAttachment{{URL: "https://cdn.example/wooper.png"}},
+			},
+			wantModal: "Wooper!\nhttps://cdn.example/wooper.png",
+		},
+		{
+			name:        "nothing to save",
+			permissions: discordgo.PermissionManageServer,
+			message:     &discordgo.Message{ID: "message-1"},
+			wantReply:   "This message has no text or attachment to save.",
+		},
+		{
+			name:        "too long",
+			permissions: discordgo.PermissionManageServer,
+			message:     &discordgo.Message{ID: "message-1", Content: strings.Repeat("a", maxFormLength+1)},
+			wantReply:   "This message is too long for the form, which takes up to 2000 characters.",
+		},
+		{
+			name:      "not an admin",
+			message:   &discordgo.Message{ID: "message-1", Content: "Wooper!"},
+			wantReply: "You need the Manage Server permission to manage content.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupTestInteractionHandler(t)
+			handler.Store = storage.NewMemoryStore()
+			fake := newFakeDiscord()
+			resolved := &discordgo.ApplicationCommandInteractionDataResolved{
+				Messages: map[string]*discordgo.Message{tt.message.ID: tt.message},
+			}
+
+			handler.HandleInteraction(fake, newTestContextInteraction(addToCategoryCommand, tt.permissions, resolved, tt.message.ID))
+
+			resp := lastResponse(t, fake)
+			if tt.wantReply != "" {
+				if resp.Data.Content != tt.wantReply || resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+					t.Errorf("Expected ephemeral reply %q, got %+v", tt.wantReply, resp.Data)
+				}
+				return
+			}
+			if resp.Type != discordgo.InteractionResponseModal || resp.Data.CustomID != entryAddModalID {
+				t.Fatalf("Expected the add entry modal, got %+v", resp)
+			}
+			content := resp.Data.Components[1].(discordgo.ActionsRow).Components[0].(discordgo.TextInput)
+			if content.Value != tt.wantModal {
+				t.Errorf("Expected the content prefilled with %q, got %q", tt.wantModal, content.Value)
+			}
+		})
+	}
+
+	t.Run("saves to a new category", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		store := storage.NewMemoryStore()
+		handler.Store = store
+		handler.ContentService = services.NewDatabaseServiceWithStore(store)
+		fake := newFakeDiscord()
+		message := &discordgo.Message{ID: "message-1", Content: "Ribbit!"}
+		resolved := &discordgo.ApplicationCommandInteractionDataResolved{
+			Messages: map[string]*discordgo.Message{message.ID: message},
+		}
+
+		handler.HandleInteraction(fake, newTestContextInteraction(addToCategoryCommand, discordgo.PermissionManageServer, resolved, message.ID))
+		values := modalInputs(t, lastResponse(t, fake))
+		handler.HandleInteraction(fake, newTestModalSubmit(discordgo.PermissionManageServer, entryAddModalID, "frogs", values[entryContentInput], "", ""))
+
+		entries, err := store.ListEntries(context.Background(), "frogs")
+		if err != nil || len(entries) != 1 || entries[0].Content != "Ribbit!" {
+			t.Errorf("Expected the message saved to frogs, got %+v, %v", entries, err)
+		}
+	})
+}
+
+// TestRandomForUser tests pinging a user with random content.
+func TestRandomForUser(t *testing.T) {
+	t.Run("pings the user", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		fake := newFakeDiscord()
+		resolved := &discordgo.ApplicationCommandInteractionDataResolved{
+			Users: map[string]*discordgo.User{"user-2": {ID: "user-2", Username: "friend"}},
+		}
+
+		handler.HandleInteraction(fake, newTestContextInteraction(randomForUserCommand, 0, resolved, "user-2"))
+
+		resp := lastResponse(t, fake)
+		if content := resp.Data.Content; content != "<@user-2> Cats content 1" && content != "<@user-2> Mutsumi content 1" {
+			t.Errorf("Expected content mentioning the user, got %q", content)
+		}
+		if mentions := resp.Data.AllowedMentions; mentions == nil || len(mentions.Users) != 1 || mentions.Users[0] != "user-2" || len(mentions.Parse) != 0 {
+			t.Errorf("Expected only the user to be pinged, got %+v", mentions)
+		}
+	})
+
+	t.Run("bot", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		fake := newFakeDiscord()
+		resolved := &discordgo.ApplicationCommandInteractionDataResolved{
+			Users: map[string]*discordgo.User{"bot-1": {ID: "bot-1", Bot: true}},
+		}
+
+		handler.HandleInteraction(fake, newTestContextInteraction(randomForUserCommand, 0, resolved, "bot-1"))
+
+		if resp := lastResponse(t, fake); resp.Data.Content != "Bots don't need content." {
+			t.Errorf("Expected the bot to be refused, got %q", resp.Data.Content)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// Delivery is where a /command reply is sent
+type Delivery string
+
+const (
+	// DeliverPublic replies in the channel
+	DeliverPublic Delivery = "public"
+	// DeliverEphemeral replies to the user who ran the command only
+	DeliverEphemeral Delivery = "ephemeral"
+	// DeliverDM sends the reply as a direct message to the user who ran
+	// the command
+	DeliverDM Delivery = "dm"
+)
+
+// DeliveryPolicy picks where /command replies are sent
+type DeliveryPolicy struct {
+	// Default is the delivery of categories without their own, public
+	// when empty
+	Default Delivery
+	// Categories overrides the delivery of some categories
+	Categories map[string]Delivery
+}
+
+// NewDeliveryPolicy builds a policy from the delivery mode names of the
+// configuration
+func NewDeliveryPolicy(mode string, categories map[string]string) *DeliveryPolicy {
+	p := &DeliveryPolicy{Default: Delivery(mode), Categories: make(map[string]Delivery, len(categories))}
+	for category, mode := range categories {
+		p.Categories[category] = Delivery(mode)
+	}
+	return p
+}
+
+// For returns the delivery of a category. private asks for a reply only
+// the user sees, which turns a public delivery into an ephemeral one.
+func (p *DeliveryPolicy) For(category string, private bool) Delivery {
+	delivery := DeliverPublic
+	if p != nil {
+		if d, ok := p.Categories[category]; ok {
+			delivery = d
+		} else if p.Default != "" {
+			delivery = p.Default
+		}
+	}
+	if private && delivery == DeliverPublic {
+		return DeliverEphemeral
+	}
+	return delivery
+}
+
+// deliverDM sends the content of a /command reply to the user who ran the
+// command. The reply is deferred first, since a DM can take several
+// requests, and then edited into a confirmation. When their DMs are closed
+// the content is shown ephemerally instead, after a note; content the DM
+// already started to deliver is not repeated.
+func (h *InteractionHandler) deliverDM(s DiscordAPI, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData) error {
+	locale := h.Locales.ForInteraction(i)
+	userID := interactionUserID(i)
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	channel, err := s.UserChannelCreate(userID)
+	if err == nil {
+		sent, err = sendText(s, channel.ID, data.Content, data.Components, data.AllowedMentions, h.AttachmentThreshold)
+	}
+	switch {
+	case err == nil:
+		_, err = s.InteractionResponseEdit(i.Interaction, textEdit(i18n.T(locale, "command.dm_sent", nil)))
+		return err
+	case sent > 0:
+		logger.Logger.Warn("Failed to DM the rest of the content",
+			zap.String("user_id", userID),
+			zap.Int("sent", sent),
+			zap.Error(err))
+		_, err = s.InteractionResponseEdit(i.Interaction, textEdit(i18n.T(locale, "command.dm_partial", nil)))
+		return err
+	}
+
+	logger.Logger.Info("Failed to DM content, replying ephemerally instead",
+		zap.String("user_id", userID),
+		zap.Error(err))
+	data.Flags = discordgo.MessageFlagsEphemeral
+	return editText(s, i, data, i18n.T(locale, "command.dm_closed", nil)+"\n", h.AttachmentThreshold)
+}
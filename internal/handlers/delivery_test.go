@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// TestDeliveryPolicy_For tests picking the delivery of a category.
+func TestDeliveryPolicy_For(t *testing.T) {
+	policy := NewDeliveryPolicy("ephemeral", map[string]string{"wooper": "public", "secrets": "dm"})
+
+	tests := []struct {
+		name     string
+		policy   *DeliveryPolicy
+		category string
+		private  bool
+		want     Delivery
+	}{
+		{name: "no policy", category: "wooper", want: DeliverPublic},
+		{name: "no policy, private", category: "wooper", private: true, want: DeliverEphemeral},
+		{name: "default", policy: policy, category: "cats", want: DeliverEphemeral},
+		{name: "category", policy: policy, category: "wooper", want: DeliverPublic},
+		{name: "category, private", policy: policy, category: "wooper", private: true, want: DeliverEphemeral},
+		{name: "dm stays dm", policy: policy, category: "secrets", private: true, want: DeliverDM},
+		{name: "empty default", policy: &DeliveryPolicy{}, category: "cats", want: DeliverPublic},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.For(tt.category, tt.private); got != tt.want {
+				t.Errorf("For(%q, %v) = %s, want %s", tt.category, tt.private, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestInteractionHandler_Delivery tests sending /command replies publicly,
+// ephemerally or by DM.
+func TestInteractionHandler_Delivery(t *testing.T) {
+	private := &discordgo.ApplicationCommandInteractionDataOption{Name: "private", Type: discordgo.ApplicationCommandOptionBoolean, Value: true}
+
+	t.Run("public", func(t *testing.T) {
+		handler, _, _ := setupTestContentButtons(t)
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestCommandInteraction("wooper"))
+
+		resp := lastResponse(t, fake)
+		if resp.Data.Flags&discordgo.MessageFlagsEphemeral != 0 || len(buttonsOf(t, resp.Data.Components)) != 5 {
+			t.Errorf("Expected a public reply with every button, got %+v", resp.Data)
+		}
+	})
+
+	t.Run("private option", func(t *testing.T) {
+		handler, _, _ := setupTestContentButtons(t)
+		fake := newFakeDiscord()
+		i := newTestCommandInteraction("wooper")
+		data := i.ApplicationCommandData()
+		data.Options = append(data.Options, private)
+		i.Data = data
+
+		handler.HandleInteraction(fake, i)
+
+		resp := lastResponse(t, fake)
+		buttons := buttonsOf(t, resp.Data.Components)
+		if resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 || len(buttons) != 1 || buttons[0].Label != "Save" {
+			t.Errorf("Expected an ephemeral reply with the Save button only, got %+v", resp.Data)
+		}
+		if len(handler.Buttons.shown) != 0 {
+			t.Errorf("Expected no buttons to watch, got %v", handler.Buttons.shown)
+		}
+	})
+
+	t.Run("dm", func(t *testing.T) {
+		handler, _, _ := setupTestContentButtons(t)
+		handler.Delivery = NewDeliveryPolicy("public", map[string]string{"wooper": "dm"})
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestCommandInteraction("wooper"))
+
+		sent := fake.sentComplexMessages()
+		if len(sent) != 1 || sent[0].ChannelID != "dm-user-1" || !strings.HasPrefix(sent[0].Data.Content, "Wooper") {
+			t.Fatalf("Expected the content sent to the DM channel, got %+v", sent)
+		}
+		if len(sent[0].Data.Components) != 1 {
+			t.Errorf("Expected the Save button on the DM, got %+v", sent[0].Data.Components)
+		}
+		if resp := lastResponse(t, fake); resp.Type != discordgo.InteractionResponseDeferredChannelMessageWithSource || resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+			t.Errorf("Expected an ephemeral deferred response, got %+v", resp)
+		}
+		if edits := fake.responseEdits(); len(edits) != 1 || *edits[0].Content != "Sent you a DM." {
+			t.Errorf("Expected the response edited into a confirmation, got %+v", edits)
+		}
+	})
+
+	t.Run("dms closed", func(t *testing.T) {
+		handler, _, _ := setupTestContentButtons(t)
+		handler.Delivery = NewDeliveryPolicy("dm", nil)
+		fake := newFakeDiscord()
+		fake.failSends = 1
+
+		handler.HandleInteraction(fake, newTestCommandInteraction("wooper"))
+
+		if resp := lastResponse(t, fake); resp.Type != discordgo.InteractionResponseDeferredChannelMessageWithSource || resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+			t.Errorf("Expected an ephemeral deferred response, got %+v", resp)
+		}
+		edits := fake.responseEdits()
+		if len(edits) != 1 || !strings.HasPrefix(*edits[0].Content, "I can't DM you, so here it is just for you:\nWooper") {
+			t.Fatalf("Expected the response edited into the content with a note, got %+v", edits)
+		}
+		if edits[0].Components == nil || len(*edits[0].Components) != 1 {
+			t.Errorf("Expected the Save button on the response, got %+v", edits[0].Components)
+		}
+	})
+
+	t.Run("long content with dms closed", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		handler.ContentService.(*mockContentService).addCommand("long", longContent)
+		handler.Delivery = NewDeliveryPolicy("dm", nil)
+		fake := newFakeDiscord()
+		fake.failSends = 1
+
+		handler.HandleInteraction(fake, newTestCommandInteraction("long"))
+
+		edits := fake.responseEdits()
+		followups := fake.followupMessages()
+		if len(edits) != 1 || len(followups) != 1 {
+			t.Fatalf("Expected an edit and a followup, got %d and %d", len(edits), len(followups))
+		}
+		if got := strings.TrimPrefix(*edits[0].Content, "I can't DM you, so here it is just for you:\n") + "\n\n" + followups[0].Content; got != longContent {
+			t.Errorf("Expected the edit and followup to add up to the entry, got %d characters", len(got))
+		}
+		if followups[0].Flags&discordgo.MessageFlagsEphemeral == 0 {
+			t.Error("Expected the followup to be ephemeral")
+		}
+	})
+
+	t.Run("dm fails partway", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		handler.ContentService.(*mockContentService).addCommand("long", longContent)
+		handler.Delivery = NewDeliveryPolicy("dm", nil)
+		fake := newFakeDiscord()
+		fake.passSends = 1
+		fake.failSends = 1
+
+		handler.HandleInteraction(fake, newTestCommandInteraction("long"))
+
+		if sent := fake.sentComplexMessages(); len(sent) != 1 {
+			t.Errorf("Expected the first part in the DM, got %d messages", len(sent))
+		}
+		if followups := fake.followupMessages(); len(followups) != 0 {
+			t.Errorf("Expected nothing resent ephemerally, got %d followups", len(followups))
+		}
+		if edits := fake.responseEdits(); len(edits) != 1 || *edits[0].Content != "Sent you part of it in a DM, but I couldn't send the rest." {
+			t.Errorf("Expected the response edited into a note, got %+v", edits)
+		}
+	})
+
+	t.Run("errors follow the delivery", func(t *testing.T) {
+		handler, _, _ := setupTestContentButtons(t)
+		handler.Delivery = NewDeliveryPolicy("ephemeral", nil)
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestCommandInteraction("dogs"))
+
+		if resp := lastResponse(t, fake); resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+			t.Errorf("Expected an ephemeral error, got %+v", resp.Data)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import "github.com/bwmarrin/discordgo"
+
+// DiscordAPI is the subset of the Discord session used by the handlers.
+// *discordgo.Session satisfies it; tests use an in-memory fake instead.
+type DiscordAPI interface {
+	// ChannelMessageSend sends a plain text message to a channel
+	ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+
+	// ChannelMessageSendComplex sends a message with embeds or components
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+
+	// ChannelMessageDelete deletes a message from a channel
+	ChannelMessageDelete(channelID, messageID string, options ...discordgo.RequestOption) error
+
+	// UserChannelCreate opens the DM channel with a user
+	UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+
+	// MessageReactionAdd adds a reaction to a message; emojiID is a Unicode
+	// emoji or name:id for a custom emoji
+	MessageReactionAdd(channelID, messageID, emojiID string, options ...discordgo.RequestOption) error
+
+	// InteractionRespond sends the initial response to an interaction
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+
+	// InteractionResponseEdit edits the response to an interaction, e.g. to
+	// replace a deferred response with the final result
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+
+	// FollowupMessageCreate sends another message in response to an
+	// interaction, after the initial response
+	FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// Ensure *discordgo.Session implements DiscordAPI
+var _ DiscordAPI = (*discordgo.Session)(nil)
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// errFakeSend is returned by fakeDiscord when a send is configured to fail
+var errFakeSend = errors.New("fake discord: send failed")
+
+// sentMessage is a channel message recorded by fakeDiscord
+type sentMessage struct {
+	ChannelID string
+	Content   string
+	// AllowedMentions is nil for plain messages
+	AllowedMentions *discordgo.MessageAllowedMentions
+}
+
+// sentComplexMessage is a message with embeds or components recorded by fakeDiscord
+type sentComplexMessage struct {
+	ChannelID string
+	Data      *discordgo.MessageSend
+}
+
+// deletedMessage is a message deletion recorded by fakeDiscord
+type deletedMessage struct {
+	ChannelID string
+	MessageID string
+}
+
+// addedReaction is a reaction recorded by fakeDiscord
+type addedReaction struct {
+	ChannelID string
+	MessageID string
+	Emoji     string
+}
+
+// fakeDiscord is an in-memory implementation of DiscordAPI for testing.
+// It records every successful message and interaction response.
+type fakeDiscord struct {
+	mu sync.Mutex
+
+	messages  []sentMessage
+	complex   []sentComplexMessage
+	posts     []sentMessage
+	deleted   []deletedMessage
+	reactions []addedReaction
+	responses []*discordgo.InteractionResponse
+	edits     []*discordgo.WebhookEdit
+	followups []*discordgo.WebhookParams
+
+	// permissions are the bot's known permissions by channel
+	permissions map[string]int64
+	// reactionErr is returned by MessageReactionAdd when set
+	reactionErr error
+
+	// failSends is the number of upcoming ChannelMessageSend calls that fail
+	failSends int
+	// passSends is the number of upcoming sends that succeed before
+	// failSends applies
+	passSends int
+	// failResponds is the number of upcoming InteractionRespond calls that fail
+	failResponds int
+}
+
+func newFakeDiscord() *fakeDiscord {
+	return &fakeDiscord{}
+}
+
+func (f *fakeDiscord) ChannelMessageSend(channelID string, content string, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failSend() {
+		return nil, errFakeSend
+	}
+
+	f.messages = append(f.messages, sentMessage{ChannelID: channelID, Content: content})
+	f.posts = append(f.posts, sentMessage{ChannelID: channelID, Content: content})
+	return &discordgo.Message{ChannelID: channelID, Content: content}, nil
+}
+
+func (f *fakeDiscord) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failSend() {
+		return nil, errFakeSend
+	}
+
+	f.complex = append(f.complex, sentComplexMessage{ChannelID: channelID, Data: data})
+	f.posts = append(f.posts, sentMessage{ChannelID: channelID, Content: data.Content, AllowedMentions: data.AllowedMentions})
+	return &discordgo.Message{ID: fmt.Sprintf("message-%d", len(f.complex)), ChannelID: channelID, Content: data.Content}, nil
+}
+
+func (f *fakeDiscord) ChannelMessageDelete(channelID, messageID string, _ ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.deleted = append(f.deleted, deletedMessage{ChannelID: channelID, MessageID: messageID})
+	return nil
+}
+
+// failSend reports whether the send being made fails. The caller holds mu.
+func (f *fakeDiscord) failSend() bool {
+	if f.passSends > 0 {
+		f.passSends--
+		return false
+	}
+	if f.failSends > 0 {
+		f.failSends--
+		return true
+	}
+	return false
+}
+
+// UserChannelCreate returns the DM channel "dm-<user ID>"
+func (f *fakeDiscord) UserChannelCreate(recipientID string, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	return &discordgo.Channel{ID: "dm-" + recipientID, Type: discordgo.ChannelTypeDM}, nil
+}
+
+func (f *fakeDiscord) MessageReactionAdd(channelID, messageID, emojiID string, _ ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.reactionErr != nil {
+		return f.reactionErr
+	}
+	f.reactions = append(f.reactions, addedReaction{ChannelID: channelID, MessageID: messageID, Emoji: emojiID})
+	return nil
+}
+
+// botChannelPermissions returns the permissions set for a channel
+func (f *fakeDiscord) botChannelPermissions(channelID string) (int64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	perms, ok := f.permissions[channelID]
+	return perms, ok
+}
+
+func (f *fakeDiscord) InteractionRespond(_ *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failResponds > 0 {
+		f.failResponds--
+		return errFakeSend
+	}
+
+	f.responses = append(f.responses, resp)
+	return nil
+}
+
+func (f *fakeDiscord) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.edits = append(f.edits, newresp)
+
+	msg := &discordgo.Message{ChannelID: interaction.ChannelID}
+	if newresp.Content != nil {
+		msg.Content = *newresp.Content
+	}
+	return msg, nil
+}
+
+func (f *fakeDiscord) FollowupMessageCreate(interaction *discordgo.Interaction, _ bool, data *discordgo.WebhookParams, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.followups = append(f.followups, data)
+	return &discordgo.Message{ID: fmt.Sprintf("followup-%d", len(f.followups)), ChannelID: interaction.ChannelID, Content: data.Content}, nil
+}
+
+// sentMessages returns a copy of the messages recorded so far
+func (f *fakeDiscord) sentMessages() []sentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentMessage(nil), f.messages...)
+}
+
+// postedMessages returns a copy of the plain and complex messages recorded
+// so far, in the order they were sent
+func (f *fakeDiscord) postedMessages() []sentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentMessage(nil), f.posts...)
+}
+
+// sentComplexMessages returns a copy of the complex messages recorded so far
+func (f *fakeDiscord) sentComplexMessages() []sentComplexMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentComplexMessage(nil), f.complex...)
+}
+
+// deletedMessages returns a copy of the message deletions recorded so far
+func (f *fakeDiscord) deletedMessages() []deletedMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]deletedMessage(nil), f.deleted...)
+}
+
+// addedReactions returns a copy of the reactions recorded so far
+func (f *fakeDiscord) addedReactions() []addedReaction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]addedReaction(nil), f.reactions...)
+}
+
+// interactionResponses returns a copy of the interaction responses recorded so far
+func (f *fakeDiscord) interactionResponses() []*discordgo.InteractionResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*discordgo.InteractionResponse(nil), f.responses...)
+}
+
+// responseEdits returns a copy of the interaction response edits recorded so far
+func (f *fakeDiscord) responseEdits() []*discordgo.WebhookEdit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*discordgo.WebhookEdit(nil), f.edits...)
+}
+
+// followupMessages returns a copy of the followup messages recorded so far
+func (f *fakeDiscord) followupMessages() []*discordgo.WebhookParams {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*discordgo.WebhookParams(nil), f.followups...)
+}
+
+// Ensure fakeDiscord implements DiscordAPI
+var _ DiscordAPI = (*fakeDiscord)(nil)
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// saveButtonPrefix starts the custom ID of the Save button, which is
+// followed by the entry ID, e.g. favorite:save:3
+const saveButtonPrefix = "favorite:save:"
+
+// maxFavoritesLength keeps /favorites list within a Discord message
+const maxFavoritesLength = 1900
+
+// maxFavoritePreview is the longest entry shown in /favorites list, in runes
+const maxFavoritePreview = 80
+
+// FavoritesCommand returns the definition of the /favorites command
+func FavoritesCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "favorites",
+		Description: "Browse the content you saved",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List your saved entries",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "random",
+				Description: "Post a random entry from your favorites",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove an entry from your favorites",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "Entry ID, see /favorites list",
+						Required:    true,
+					},
+				},
+			},
+		},
+	}
+}
+
+// FavoritesHandler handles the Save button of content replies and the
+// /favorites command
+type FavoritesHandler struct {
+	Store storage.Store
+	// AttachmentThreshold is the length above which a favorite is sent as
+	// a .txt file rather than split into messages, 0 to always split it
+	AttachmentThreshold int
+	// Mentions decides who favorites may ping, nil to never ping roles
+	Mentions *MentionPolicy
+	// Locales picks the language of replies, nil for DefaultLocale
+	Locales *Localizer
+
+	now func() time.Time
+}
+
+func NewFavoritesHandler(store storage.Store) *FavoritesHandler {
+	return &FavoritesHandler{Store: store, now: time.Now}
+}
+
+// OnInteractionCreate is the discordgo event handler for interactions
+func (h *FavoritesHandler) OnInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	h.HandleInteraction(s, i)
+}
+
+// HandleInteraction processes /favorites and Save button presses using the
+// given Discord API
+func (h *FavoritesHandler) HandleInteraction(s DiscordAPI, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		if i.ApplicationCommandData().Name == "favorites" {
+			h.handleCommand(s, i)
+		}
+	case discordgo.InteractionMessageComponent:
+		if strings.HasPrefix(i.MessageComponentData().CustomID, saveButtonPrefix) {
+			h.save(s, i)
+		}
+	}
+}
+
+// save adds the entry of a Save button to the favorites of the user
+func (h *FavoritesHandler) save(s DiscordAPI, i *discordgo.InteractionCreate) {
+	entryID, err := strconv.ParseInt(strings.TrimPrefix(i.MessageComponentData().CustomID, saveButtonPrefix), 10, 64)
+	if err != nil {
+		return
+	}
+	userID := interactionUserID(i)
+	locale := h.Locales.ForInteraction(i)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = h.Store.AddFavorite(ctx, userID, entryID, h.now())
+	switch {
+	case errors.Is(err, storage.ErrFavoriteExists):
+		respondEphemeral(s, i, i18n.T(locale, "favorites.exists", i18n.Args{"id": entryID}))
+	case errors.Is(err, storage.ErrEntryNotFound):
+		respondEphemeral(s, i, i18n.T(locale, "favorites.deleted", i18n.Args{"id": entryID}))
+	case err != nil:
+		logger.Logger.Error("Failed to save favorite",
+			zap.Int64("entry_id", entryID),
+			zap.String("user_id", userID),
+			zap.Error(err))
+		respondEphemeral(s, i, i18n.T(locale, "unavailable", nil))
+	default:
+		logger.Logger.Info("Favorite saved",
+			zap.Int64("entry_id", entryID),
+			zap.String("user_id", userID))
+		respondEphemeral(s, i, i18n.T(locale, "favorites.saved", i18n.Args{"id": entryID}))
+	}
+}
+
+// handleCommand runs a /favorites subcommand
+func (h *FavoritesHandler) handleCommand(s DiscordAPI, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+	sub := options[0]
+	locale := h.Locales.ForInteraction(i)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch sub.Name {
+	case "list":
+		respondEphemeral(s, i, h.list(ctx, locale, i))
+	case "random":
+		h.random(ctx, locale, s, i)
+	case "remove":
+		respondEphemeral(s, i, h.remove(ctx, locale, i, sub.Options))
+	}
+}
+
+// list describes the saved entries of the user, newest first, as many as fit
+func (h *FavoritesHandler) list(ctx context.Context, locale string, i *discordgo.InteractionCreate) string {
+	entries, err := h.Store.ListFavorites(ctx, interactionUserID(i))
+	if err != nil {
+		logger.Logger.Error("Failed to list favorites", zap.String("user_id", interactionUserID(i)), zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+	if len(entries) == 0 {
+		return i18n.T(locale, "favorites.empty", nil)
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.T(locale, "favorites.header", nil))
+	shown := 0
+	for _, e := range entries {
+		line := fmt.Sprintf("\n• #%d `%s` %s", e.ID, e.Category, shorten(plainText(e), maxFavoritePreview))
+		if b.Len()+len(line) > maxFavoritesLength {
+			break
+		}
+		b.WriteString(line)
+		shown++
+	}
+	if shown < len(entries) {
+		b.WriteString("\n" + i18n.T(locale, "favorites.more", i18n.Args{"count": len(entries) - shown}))
+	}
+	return b.String()
+}
+
+// random posts a random saved entry of the user in the channel
+func (h *FavoritesHandler) random(ctx context.Context, locale string, s DiscordAPI, i *discordgo.InteractionCreate) {
+	userID := interactionUserID(i)
+	entry, err := h.Store.RandomFavorite(ctx, userID)
+	if err != nil {
+		logger.Logger.Error("Failed to pick a favorite", zap.String("user_id", userID), zap.Error(err))
+		respondEphemeral(s, i, i18n.T(locale, "unavailable", nil))
+		return
+	}
+	if entry.ID == 0 {
+		respondEphemeral(s, i, i18n.T(locale, "favorites.empty", nil))
+		return
+	}
+
+	data := &discordgo.InteractionResponseData{
+		Content:         plainText(entry),
+		AllowedMentions: h.Mentions.For(i.GuildID, entry, userID),
+	}
+	err = respondText(s, i, data, "", h.AttachmentThreshold)
+	if err != nil {
+		logger.Logger.Error("Failed to send favorite",
+			zap.Int64("entry_id", entry.ID),
+			zap.String("user_id", userID),
+			zap.Error(err))
+	}
+}
+
+// remove unsaves an entry for the user
+func (h *FavoritesHandler) remove(ctx context.Context, locale string, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	id := int64Option(options, "id")
+	userID := interactionUserID(i)
+
+	err := h.Store.RemoveFavorite(ctx, userID, id)
+	if errors.Is(err, storage.ErrFavoriteNotFound) {
+		return i18n.T(locale, "favorites.not_found", i18n.Args{"id": id})
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to remove favorite", zap.Int64("entry_id", id), zap.String("user_id", userID), zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+
+	logger.Logger.Info("Favorite removed", zap.Int64("entry_id", id), zap.String("user_id", userID))
+	return i18n.T(locale, "favorites.removed", i18n.Args{"id": id})
+}
+
+// saveButton returns a row with the ⭐ Save button added to content
+// replies, labelled in locale
+func saveButton(locale string, entryID int64) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{saveButtonComponent(locale, entryID)}},
+	}
+}
+
+// saveButtonComponent returns the ⭐ Save button of an entry, labelled in
+// locale
+func saveButtonComponent(locale string, entryID int64) discordgo.Button {
+	return discordgo.Button{
+		Label:    i18n.T(locale, "buttons.save", nil),
+		Emoji:    discordgo.ComponentEmoji{Name: "⭐"},
+		Style:    discordgo.SecondaryButton,
+		CustomID: saveButtonPrefix + strconv.FormatInt(entryID, 10),
+	}
+}
+
+// shorten cuts s to at most n runes on a single line
+func shorten(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// setupTestFavoritesHandler creates a favorites handler on a memory store
+// with two wooper entries
+func setupTestFavoritesHandler(t *testing.T) (*FavoritesHandler, *storage.MemoryStore) {
+	err := logger.Init()
+	if err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(func() {
+		logger.Close()
+	})
+
+	store := storage.NewMemoryStore()
+	store.AddContent(context.Background(), "wooper", "Wooper!")
+	store.AddContent(context.Background(), "wooper", "Wooper wooper!")
+
+	handler := NewFavoritesHandler(store)
+	handler.now = func() time.Time { return time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC) }
+	return handler, store
+}
+
+// newTestSaveInteraction builds a Save button press
+func newTestSaveInteraction(customID string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-1",
+			Type:      discordgo.InteractionMessageComponent,
+			ChannelID: "channel-1",
+			GuildID:   "guild-1",
+			Member:    &discordgo.Member{User: &discordgo.User{ID: "user-1", Username: "fan"}},
+			Data: discordgo.MessageComponentInteractionData{
+				CustomID:      customID,
+				ComponentType: discordgo.ButtonComponent,
+			},
+		},
+	}
+}
+
+// newTestFavoritesInteraction builds a /favorites interaction sent in DMs
+func newTestFavoritesInteraction(sub string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-2",
+			Type:      discordgo.InteractionApplicationCommand,
+			ChannelID: "dm-user-1",
+			User:      &discordgo.User{ID: "user-1", Username: "fan"},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: "favorites",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: sub, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options},
+				},
+			},
+		},
+	}
+}
+
+// TestFavoritesHandler_Save tests the Save button.
+func TestFavoritesHandler_Save(t *testing.T) {
+	tests := []struct {
+		name      string
+		customID  string
+		saved     bool
+		deleted   bool
+		wantReply string
+		wantSaved int
+	}{
+		{
+			name:      "saved",
+			customID:  "favorite:save:1",
+			wantReply: "Saved entry #1 to your favorites.",
+			wantSaved: 1,
+		},
+		{
+			name:      "already saved",
+			customID:  "favorite:save:1",
+			saved:     true,
+			wantReply: "Entry #1 is already in your favorites.",
+			wantSaved: 1,
+		},
+		{
+			name:      "deleted entry",
+			customID:  "favorite:save:1",
+			deleted:   true,
+			wantReply: "Entry #1 was deleted and can't be saved.",
+		},
+		{
+			name:      "unknown entry",
+			customID:  "favorite:save:9",
+			wantReply: "Entry #9 was deleted and can't be saved.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, store := setupTestFavoritesHandler(t)
+			ctx := context.Background()
+			if tt.saved {
+				store.AddFavorite(ctx, "user-1", 1, time.Now())
+			}
+			if tt.deleted {
+				store.ApplyChanges(ctx, storage.ChangeSet{Delete: []int64{1}})
+			}
+			fake := newFakeDiscord()
+
+			handler.HandleInteraction(fake, newTestSaveInteraction(tt.customID))
+
+			resp := lastResponse(t, fake)
+			if !strings.HasPrefix(resp.Data.Content, tt.wantReply) {
+				t.Errorf("Expected reply starting with %q, got %q", tt.wantReply, resp.Data.Content)
+			}
+			if resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+				t.Error("Expected an ephemeral reply")
+			}
+			if saved, _ := store.ListFavorites(ctx, "user-1"); len(saved) != tt.wantSaved {
+				t.Errorf("Expected %d favorites, got %+v", tt.wantSaved, saved)
+			}
+		})
+	}
+}
+
+// TestFavoritesHandler_Command tests /favorites list, random and remove.
+func TestFavoritesHandler_Command(t *testing.T) {
+	tests := []struct {
+		name          string
+		favorites     []int64
+		deleted       []int64
+		interaction   *discordgo.InteractionCreate
+		wantReply     string
+		wantEphemeral bool
+		wantSaved     int
+	}{
+		{
+			name:          "list",
+			favorites:     []int64{1, 2},
+			deleted:       []int64{1},
+			interaction:   newTestFavoritesInteraction("list"),
+			wantReply:     "Your favorites:\n• #2 `wooper` Wooper wooper!",
+			wantEphemeral: true,
+			wantSaved:     1,
+		},
+		{
+			name:          "list empty",
+			interaction:   newTestFavoritesInteraction("list"),
+			wantReply:     "You have no favorites yet. Press ⭐ Save on a reply to add one.",
+			wantEphemeral: true,
+		},
+		{
+			name:        "random skips deleted entries",
+			favorites:   []int64{1, 2},
+			deleted:     []int64{2},
+			interaction: newTestFavoritesInteraction("random"),
+			wantReply:   "Wooper!",
+			wantSaved:   1,
+		},
+		{
+			name:          "random with only deleted entries",
+			favorites:     []int64{1},
+			deleted:       []int64{1},
+			interaction:   newTestFavoritesInteraction("random"),
+			wantReply:     "You have no favorites yet. Press ⭐ Save on a reply to add one.",
+			wantEphemeral: true,
+		},
+		{
+			name:          "remove",
+			favorites:     []int64{1, 2},
+			interaction:   newTestFavoritesInteraction("remove", intOption("id", 1)),
+			wantReply:     "Removed entry #1 from your favorites.",
+			wantEphemeral: true,
+			wantSaved:     1,
+		},
+		{
+			name:          "remove unsaved",
+			favorites:     []int64{2},
+			interaction:   newTestFavoritesInteraction("remove", intOption("id", 1)),
+			wantReply:     "Entry #1 is not in your favorites.",
+			wantEphemeral: true,
+			wantSaved:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, store := setupTestFavoritesHandler(t)
+			ctx := context.Background()
+			for _, id := range tt.favorites {
+				store.AddFavorite(ctx, "user-1", id, time.Now())
+			}
+			store.ApplyChanges(ctx, storage.ChangeSet{Delete: tt.deleted})
+			fake := newFakeDiscord()
+
+			handler.HandleInteraction(fake, tt.interaction)
+
+			resp := lastResponse(t, fake)
+			if resp.Data.Content != tt.wantReply {
+				t.Errorf("Expected reply %q, got %q", tt.wantReply, resp.Data.Content)
+			}
+			if ephemeral := resp.Data.Flags&discordgo.MessageFlagsEphemeral != 0; ephemeral != tt.wantEphemeral {
+				t.Errorf("Expected ephemeral %v, got %v", tt.wantEphemeral, ephemeral)
+			}
+			if saved, _ := store.ListFavorites(ctx, "user-1"); len(saved) != tt.wantSaved {
+				t.Errorf("Expected %d live favorites, got %+v", tt.wantSaved, saved)
+			}
+		})
+	}
+}
+
+// TestSaveButton tests that both content handlers add the Save button to
+// their replies when enabled.
+func TestSaveButton(t *testing.T) {
+	wantCustomID := "favorite:save:1"
+	customID := func(components []discordgo.MessageComponent) string {
+		if len(components) != 1 {
+			return ""
+		}
+		row := components[0].(discordgo.ActionsRow)
+		return row.Components[0].(discordgo.Button).CustomID
+	}
+
+	t.Run("message handler", func(t *testing.T) {
+		handler := setupTestHandler(t)
+		handler.SaveButton = true
+		fake := newFakeDiscord()
+
+		handler.HandleMessage(fake, newTestMessage("!mutsumi"))
+
+		sent := fake.sentComplexMessages()
+		if len(sent) != 1 || sent[0].Data.Content != "Mutsumi content 1" || customID(sent[0].Data.Components) != wantCustomID {
+			t.Errorf("Expected the reply with a Save button, got %+v", sent)
+		}
+		if plain := fake.sentMessages(); len(plain) != 0 {
+			t.Errorf("Expected no reply without the button, got %+v", plain)
+		}
+	})
+
+	t.Run("interaction handler", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		handler.SaveButton = true
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestCommandInteraction("mutsumi"))
+
+		resp := lastResponse(t, fake)
+		if resp.Data.Content != "Mutsumi content 1" || customID(resp.Data.Components) != wantCustomID {
+			t.Errorf("Expected the reply with a Save button, got %+v", resp.Data)
+		}
+	})
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"strings"
 	"time"
 
+	"mutsumi-bot/internal/i18n"
 	"mutsumi-bot/internal/logger"
 	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
 
 	"github.com/bwmarrin/discordgo"
 	"go.uber.org/zap"
@@ -14,25 +17,97 @@ import (
 
 type InteractionHandler struct {
 	ContentService services.ContentService
+	// Limiter limits commands per user, nil for no limit
+	Limiter *RateLimiter
+	// SaveButton adds a ⭐ Save button to content replies, handled by
+	// FavoritesHandler
+	SaveButton bool
+	// Buttons adds Reroll, vote and Delete buttons to content replies, nil
+	// for none
+	Buttons *ContentButtons
+	// Locales picks the language of replies, nil for the locale of the user
+	Locales *Localizer
+	// Store saves the entries added with the Add to category… message
+	// command, nil to ignore it
+	Store storage.Store
+	// Delivery picks where /command replies are sent, nil to reply in the
+	// channel unless the user asks for a private reply
+	Delivery *DeliveryPolicy
+	// AttachmentThreshold is the length above which content is sent as a
+	// .txt file rather than split into messages, 0 to always split it
+	AttachmentThreshold int
+	// Mentions decides who content may ping, nil to never ping roles
+	Mentions *MentionPolicy
 }
 
 func NewInteractionHandler(contentService services.ContentService) *InteractionHandler {
 	return &InteractionHandler{ContentService: contentService}
 }
 
+// OnInteractionCreate is the discordgo event handler for interactions
 func (h *InteractionHandler) OnInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if i.ApplicationCommandData().Name == "command" {
-		h.handleCommand(s, i)
+	h.HandleInteraction(s, i)
+}
+
+// HandleInteraction processes an interaction using the given Discord API
+func (h *InteractionHandler) HandleInteraction(s DiscordAPI, i *discordgo.InteractionCreate) {
+	if i.Type == discordgo.InteractionMessageComponent {
+		if h.Buttons != nil && strings.HasPrefix(i.MessageComponentData().CustomID, contentButtonPrefix) {
+			h.handleButton(s, i)
+		}
+		return
+	}
+	if i.Type == discordgo.InteractionModalSubmit {
+		h.submitEntry(s, i)
+		return
+	}
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+
+	name := i.ApplicationCommandData().Name
+	switch name {
+	case addToCategoryCommand:
+		h.promptAddEntry(s, i)
+		return
+	case "command", randomForUserCommand:
+	default:
+		return
+	}
+
+	if allowed, retryAfter := h.Limiter.Allow(interactionUserID(i)); !allowed {
+		logger.Logger.Info("Slash command rate limited",
+			zap.String("user_id", interactionUserID(i)),
+			zap.Duration("retry_after", retryAfter))
+		respondEphemeral(s, i, i18n.T(h.Locales.ForInteraction(i), "rate_limited", i18n.Args{"retry": retryAfter.Round(time.Second)}))
+		return
 	}
+
+	if name == randomForUserCommand {
+		h.randomForUser(s, i)
+		return
+	}
+	h.handleCommand(s, i)
 }
 
-func (h *InteractionHandler) handleCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (h *InteractionHandler) handleCommand(s DiscordAPI, i *discordgo.InteractionCreate) {
 	startTime := time.Now()
 
-	// Get the category from the command options
+	// Get the category and delivery from the command options
 	var category string
-	if len(i.ApplicationCommandData().Options) > 0 {
-		category = i.ApplicationCommandData().Options[0].StringValue()
+	var private bool
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "command":
+			category = opt.StringValue()
+		case "private":
+			private = opt.BoolValue()
+		}
+	}
+	delivery := h.Delivery.For(category, private)
+	var flags discordgo.MessageFlags
+	if delivery != DeliverPublic {
+		flags = discordgo.MessageFlagsEphemeral
 	}
 
 	// Log the interaction
@@ -44,13 +119,20 @@ func (h *InteractionHandler) handleCommand(s *discordgo.Session, i *discordgo.In
 		zap.String("channel_id", i.ChannelID),
 		zap.String("guild_id", i.GuildID))
 
-	// Check if category exists
-	if !h.ContentService.HasCategory(category) {
-		availableCategories := h.ContentService.GetAvailableCategories()
-		message := fmt.Sprintf("Category '%s' not found. Available categories: %s",
-			category, strings.Join(availableCategories, ", "))
+	entry, err := h.ContentService.GetRandomEntry(category, h.Locales.ForInteraction(i))
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound):
+		availableCategories, err := h.ContentService.GetAvailableCategories()
+		if err != nil {
+			h.respondUnavailable(s, i, category, err)
+			return
+		}
+		message := i18n.T(h.Locales.ForInteraction(i), "command.not_found", i18n.Args{
+			"category":   category,
+			"categories": strings.Join(availableCategories, ", "),
+		})
 
-		logger.Logger.Warn("Invalid category requested",
+		logger.Logger.Info("Invalid category requested",
 			zap.String("category", category),
 			zap.Strings("available_categories", availableCategories),
 			zap.String("user", i.Member.User.Username))
@@ -58,15 +140,13 @@ func (h *InteractionHandler) handleCommand(s *discordgo.Session, i *discordgo.In
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: message,
+				Content:         message,
+				Flags:           flags,
+				AllowedMentions: noMentions(),
 			},
 		})
 		return
-	}
-
-	// Get random content
-	content := h.ContentService.GetRandomContent(category)
-	if content == "" {
+	case errors.Is(err, services.ErrEmptyCategory):
 		logger.Logger.Warn("No content available for command",
 			zap.String("command", category),
 			zap.String("user", i.Member.User.Username))
@@ -74,19 +154,49 @@ func (h *InteractionHandler) handleCommand(s *discordgo.Session, i *discordgo.In
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("No content available for `%s`", category),
+				Content:         i18n.T(h.Locales.ForInteraction(i), "command.no_content", i18n.Args{"command": category}),
+				Flags:           flags,
+				AllowedMentions: noMentions(),
 			},
 		})
 		return
+	case err != nil:
+		h.respondUnavailable(s, i, category, err)
+		return
 	}
 
-	// Send the content
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: content,
-		},
-	})
+	// Send the content, with reactions as emoji as there is no message to
+	// add them to. Reroll, vote and Delete buttons are for replies everyone
+	// sees, private replies only get the Save button.
+	data := &discordgo.InteractionResponseData{
+		Content:         plainText(entry),
+		Flags:           flags,
+		AllowedMentions: h.Mentions.For(i.GuildID, entry, interactionUserID(i)),
+	}
+	// Buttons everyone sees are labelled in the language of the server
+	buttonLocale := h.Locales.ForInteraction(i)
+	if delivery == DeliverPublic {
+		buttonLocale = h.Locales.ForGuild(i.GuildID)
+	}
+	var btn contentButton
+	withButtons := h.Buttons != nil && delivery == DeliverPublic
+	switch {
+	case withButtons:
+		btn = contentButton{EntryID: entry.ID, InvokerID: interactionUserID(i), Issued: h.Buttons.now()}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		data.Components = h.contentComponents(buttonLocale, btn, h.Buttons.votes(ctx, entry.ID), false)
+		cancel()
+	case h.SaveButton:
+		data.Components = saveButton(buttonLocale, entry.ID)
+	}
+	if delivery == DeliverDM {
+		err = h.deliverDM(s, i, data)
+	} else {
+		err = respondText(s, i, data, "", h.AttachmentThreshold)
+	}
+	if err == nil && withButtons {
+		h.watchButtons(s, i.Interaction, btn)
+	}
 
 	duration := time.Since(startTime)
 
@@ -102,6 +212,16 @@ func (h *InteractionHandler) handleCommand(s *discordgo.Session, i *discordgo.In
 			zap.String("user", i.Member.User.Username),
 			zap.String("user_id", i.Member.User.ID),
 			zap.String("channel_id", i.ChannelID),
+			zap.String("delivery", string(delivery)),
 			zap.Duration("duration", duration))
 	}
 }
+
+// respondUnavailable tells the user the bot can't reach its content right now
+func (h *InteractionHandler) respondUnavailable(s DiscordAPI, i *discordgo.InteractionCreate, category string, err error) {
+	logger.Logger.Error("Content unavailable",
+		zap.String("category", category),
+		zap.String("user_id", interactionUserID(i)),
+		zap.Error(err))
+	respondEphemeral(s, i, i18n.T(h.Locales.ForInteraction(i), "unavailable", nil))
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// setupTestInteractionHandler creates an interaction handler with mock content service
+func setupTestInteractionHandler(t *testing.T) *InteractionHandler {
+	err := logger.Init()
+	if err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(func() {
+		logger.Close()
+	})
+
+	mockService := newMockContentService()
+	mockService.addCommand("mutsumi", "Mutsumi content 1", "Mutsumi content 2")
+	mockService.addCommand("cats", "Cats content 1", "Cats content 2")
+
+	return NewInteractionHandler(mockService)
+}
+
+// newTestCommandInteraction builds a /command interaction for the given category
+func newTestCommandInteraction(category string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-1",
+			Type:      discordgo.InteractionApplicationCommand,
+			ChannelID: "channel-1",
+			GuildID:   "guild-1",
+			Member: &discordgo.Member{
+				User: &discordgo.User{ID: "user-1", Username: "tester"},
+			},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: "command",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{
+						Name:  "command",
+						Type:  discordgo.ApplicationCommandOptionString,
+						Value: category,
+					},
+				},
+			},
+		},
+	}
+}
+
+// TestNewInteractionHandler tests the NewInteractionHandler constructor function.
+func TestNewInteractionHandler(t *testing.T) {
+	mockService := newMockContentService()
+
+	handler := NewInteractionHandler(mockService)
+
+	if handler == nil {
+		t.Fatalf("Expected handler but got nil")
+	}
+
+	if handler.ContentService != mockService {
+		t.Errorf("Expected content service %v but got %v", mockService, handler.ContentService)
+	}
+}
+
+// TestInteractionHandler_HandleInteraction tests the responses sent for slash commands.
+func TestInteractionHandler_HandleInteraction(t *testing.T) {
+	tests := []struct {
+		name          string
+		setup         func(m *mockContentService)
+		interaction   *discordgo.InteractionCreate
+		failResponds  int
+		wantResponses []string
+	}{
+		{
+			name:          "known category",
+			interaction:   newTestCommandInteraction("mutsumi"),
+			wantResponses: []string{"Mutsumi content 1"},
+		},
+		{
+			name:          "unknown category lists available categories",
+			interaction:   newTestCommandInteraction("dogs"),
+			wantResponses: []string{"Category 'dogs' not found. Available categories: cats, mutsumi"},
+		},
+		{
+			name:          "unknown category with no categories",
+			setup:         func(m *mockContentService) { m.commands = map[string][]string{} },
+			interaction:   newTestCommandInteraction("dogs"),
+			wantResponses: []string{"Category 'dogs' not found. Available categories: "},
+		},
+		{
+			name:          "category with empty content",
+			setup:         func(m *mockContentService) { m.addCommand("blank", "") },
+			interaction:   newTestCommandInteraction("blank"),
+			wantResponses: []string{"No content available for `blank`"},
+		},
+		{
+			name:          "database down",
+			setup:         func(m *mockContentService) { m.unavailable = true },
+			interaction:   newTestCommandInteraction("mutsumi"),
+			wantResponses: []string{i18n.T(i18n.DefaultLocale, "unavailable", nil)},
+		},
+		{
+			name:         "respond failure is not retried",
+			interaction:  newTestCommandInteraction("mutsumi"),
+			failResponds: 1,
+		},
+		{
+			name: "other command names are ignored",
+			interaction: func() *discordgo.InteractionCreate {
+				i := newTestCommandInteraction("mutsumi")
+				i.Data = discordgo.ApplicationCommandInteractionData{Name: "other"}
+				return i
+			}(),
+		},
+		{
+			name: "non command interactions are ignored",
+			interaction: &discordgo.InteractionCreate{
+				Interaction: &discordgo.Interaction{
+					Type: discordgo.InteractionMessageComponent,
+					Data: discordgo.MessageComponentInteractionData{CustomID: "button"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupTestInteractionHandler(t)
+			if tt.setup != nil {
+				tt.setup(handler.ContentService.(*mockContentService))
+			}
+
+			discord := newFakeDiscord()
+			discord.failResponds = tt.failResponds
+
+			handler.HandleInteraction(discord, tt.interaction)
+
+			responses := discord.interactionResponses()
+			if len(responses) != len(tt.wantResponses) {
+				t.Fatalf("Expected %d responses but got %d", len(tt.wantResponses), len(responses))
+			}
+			for i, want := range tt.wantResponses {
+				if responses[i].Type != discordgo.InteractionResponseChannelMessageWithSource {
+					t.Errorf("Response %d: expected type %v, got %v", i, discordgo.InteractionResponseChannelMessageWithSource, responses[i].Type)
+				}
+				if responses[i].Data == nil || responses[i].Data.Content != want {
+					t.Errorf("Response %d: expected %q, got %+v", i, want, responses[i].Data)
+				}
+			}
+		})
+	}
+}
+
+// TestInteractionHandler_RateLimit tests the ephemeral reply over the limit.
+func TestInteractionHandler_RateLimit(t *testing.T) {
+	handler := setupTestInteractionHandler(t)
+	handler.Limiter = NewRateLimiter(1, time.Minute)
+	discord := newFakeDiscord()
+
+	handler.HandleInteraction(discord, newTestCommandInteraction("mutsumi"))
+	handler.HandleInteraction(discord, newTestCommandInteraction("mutsumi"))
+
+	responses := discord.interactionResponses()
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 responses but got %d", len(responses))
+	}
+	limited := responses[1].Data
+	if limited.Flags&discordgo.MessageFlagsEphemeral == 0 || !strings.Contains(limited.Content, "too often") {
+		t.Errorf("Expected an ephemeral rate limit reply, got %+v", limited)
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// localeCacheTTL is how long the locale of a guild is cached, after which
+// changes made through another replica show up
+const localeCacheTTL = time.Minute
+
+// Localizer picks the locale of replies: the locale set for the guild with
+// /locale, else the Discord locale of the user for interactions, else
+// Default. A nil Localizer has no guild locales.
+type Localizer struct {
+	Store storage.Store
+	// Default is the locale of replies to messages in guilds without a
+	// locale, as messages don't carry the locale of their author
+	Default string
+
+	now    func() time.Time
+	mu     sync.Mutex
+	guilds map[string]cachedLocale
+	// sets counts changes made with set, so that a locale read before one
+	// isn't cached
+	sets uint64
+}
+
+// cachedLocale is the locale of a guild and when it was read
+type cachedLocale struct {
+	locale string
+	at     time.Time
+}
+
+func NewLocalizer(store storage.Store, defaultLocale string) *Localizer {
+	return &Localizer{Store: store, Default: defaultLocale, now: time.Now, guilds: map[string]cachedLocale{}}
+}
+
+// ForMessage returns the locale of replies to a message
+func (l *Localizer) ForMessage(m *discordgo.MessageCreate) string {
+	return l.ForGuild(m.GuildID)
+}
+
+// ForGuild returns the locale of messages in a guild that aren't replies to
+// an interaction
+func (l *Localizer) ForGuild(guildID string) string {
+	if l == nil {
+		return i18n.DefaultLocale
+	}
+	if locale := l.guild(guildID); locale != "" {
+		return locale
+	}
+	return l.Default
+}
+
+// ForInteraction returns the locale of replies to an interaction
+func (l *Localizer) ForInteraction(i *discordgo.InteractionCreate) string {
+	if locale := l.guild(i.GuildID); locale != "" {
+		return locale
+	}
+	if i.Locale != "" {
+		return string(i.Locale)
+	}
+	if l == nil {
+		return i18n.DefaultLocale
+	}
+	return l.Default
+}
+
+// guild returns the locale set for a guild, or "" when there is none or it
+// can't be read. The store is read without holding the lock, so that a
+// slow read doesn't hold up the replies of other guilds.
+func (l *Localizer) guild(guildID string) string {
+	if l == nil || guildID == "" {
+		return ""
+	}
+
+	l.mu.Lock()
+	cached, ok := l.guilds[guildID]
+	sets := l.sets
+	l.mu.Unlock()
+	if ok && l.now().Sub(cached.at) < localeCacheTTL {
+		return cached.locale
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	locale, err := l.Store.GuildLocale(ctx, guildID)
+	if err != nil {
+		logger.Logger.Warn("Failed to read guild locale", zap.String("guild_id", guildID), zap.Error(err))
+		return ""
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sets == sets {
+		l.guilds[guildID] = cachedLocale{locale: locale, at: l.now()}
+	}
+	return locale
+}
+
+// set stores the locale of a guild; "" clears it
+func (l *Localizer) set(ctx context.Context, guildID, locale string) error {
+	if err := l.Store.SetGuildLocale(ctx, guildID, locale); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.guilds[guildID] = cachedLocale{locale: locale, at: l.now()}
+	l.sets++
+	return nil
+}
+
+// LocaleCommand returns the definition of the /locale admin command
+func LocaleCommand() *discordgo.ApplicationCommand {
+	permissions := int64(adminPermissions)
+	dmPermission := false
+
+	locales := i18n.Default.Locales()
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(locales))
+	for i, locale := range locales {
+		choices[i] = &discordgo.ApplicationCommandOptionChoice{Name: i18n.T(locale, "language.name", nil), Value: locale}
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:                     "locale",
+		Description:              "Choose the language of replies in this server",
+		DefaultMemberPermissions: &permissions,
+		DMPermission:             &dmPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Reply in one language in this server",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "language",
+						Description: "Language of replies",
+						Required:    true,
+						Choices:     choices,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reset",
+				Description: "Reply in the language of each member",
+			},
+		},
+	}
+}
+
+// LocaleHandler handles the /locale admin command
+type LocaleHandler struct {
+	Localizer *Localizer
+}
+
+func NewLocaleHandler(localizer *Localizer) *LocaleHandler {
+	return &LocaleHandler{Localizer: localizer}
+}
+
+// OnInteractionCreate is the discordgo event handler for interactions
+func (h *LocaleHandler) OnInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	h.HandleInteraction(s, i)
+}
+
+// HandleInteraction processes a /locale interaction using the given Discord API
+func (h *LocaleHandler) HandleInteraction(s DiscordAPI, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand || i.ApplicationCommandData().Name != "locale" {
+		return
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 || i.GuildID == "" {
+		return
+	}
+	sub := options[0]
+
+	if !isContentAdmin(i) {
+		logger.Logger.Warn("Unauthorized locale command",
+			zap.String("user_id", interactionUserID(i)),
+			zap.String("guild_id", i.GuildID))
+		respondEphemeral(s, i, i18n.T(h.Localizer.ForInteraction(i), "locale.forbidden", nil))
+		return
+	}
+
+	var locale string
+	if sub.Name == "set" {
+		var requested string
+		for _, opt := range sub.Options {
+			if opt.Name == "language" {
+				requested = opt.StringValue()
+			}
+		}
+		if locale = i18n.Default.Match(requested); locale == "" {
+			respondEphemeral(s, i, i18n.T(h.Localizer.ForInteraction(i), "locale.unknown", i18n.Args{
+				"locale":  requested,
+				"locales": strings.Join(i18n.Default.Locales(), ", "),
+			}))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := h.Localizer.set(ctx, i.GuildID, locale); err != nil {
+		logger.Logger.Error("Failed to set guild locale",
+			zap.String("guild_id", i.GuildID),
+			zap.String("locale", locale),
+			zap.Error(err))
+		respondEphemeral(s, i, i18n.T(h.Localizer.ForInteraction(i), "unavailable", nil))
+		return
+	}
+
+	logger.Logger.Info("Guild locale changed via slash command",
+		zap.String("guild_id", i.GuildID),
+		zap.String("locale", locale),
+		zap.String("user_id", interactionUserID(i)))
+	if locale == "" {
+		respondEphemeral(s, i, i18n.T(string(i.Locale), "locale.reset", nil))
+	} else {
+		respondEphemeral(s, i, i18n.T(locale, "locale.set", i18n.Args{"language": i18n.T(locale, "language.name", nil)}))
+	}
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// newTestLocaleInteraction builds a /locale interaction by a member with the
+// given permissions and Discord locale
+func newTestLocaleInteraction(permissions int64, locale discordgo.Locale, sub string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-1",
+			Type:      discordgo.InteractionApplicationCommand,
+			ChannelID: "channel-1",
+			GuildID:   "guild-1",
+			Locale:    locale,
+			Member: &discordgo.Member{
+				User:        &discordgo.User{ID: "user-1", Username: "admin"},
+				Permissions: permissions,
+			},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: "locale",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: sub, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options},
+				},
+			},
+		},
+	}
+}
+
+// TestLocaleHandler tests setting and resetting the locale of a guild.
+func TestLocaleHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions int64
+		locale      discordgo.Locale
+		current     string
+		sub         string
+		options     []*discordgo.ApplicationCommandInteractionDataOption
+		wantReply   string
+		wantLocale  string
+	}{
+		{
+			name:        "set",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "set",
+			options:     []*discordgo.ApplicationCommandInteractionDataOption{stringOption("language", "fr")},
+			wantReply:   "La langue des réponses sur ce serveur est désormais : Français.",
+			wantLocale:  "fr",
+		},
+		{
+			name:        "reset",
+			permissions: discordgo.PermissionManageServer,
+			locale:      discordgo.Japanese,
+			current:     "fr",
+			sub:         "reset",
+			wantReply:   "このサーバーでの返信は各メンバーの言語に従います。",
+		},
+		{
+			name:        "unknown language",
+			permissions: discordgo.PermissionManageServer,
+			current:     "ja",
+			sub:         "set",
+			options:     []*discordgo.ApplicationCommandInteractionDataOption{stringOption("language", "tlh")},
+			wantReply:   "不明な言語 `tlh` です。利用できる言語：en-US, fr, ja",
+			wantLocale:  "ja",
+		},
+		{
+			name:       "not an admin",
+			locale:     discordgo.French,
+			sub:        "set",
+			options:    []*discordgo.ApplicationCommandInteractionDataOption{stringOption("language", "ja")},
+			wantReply:  "Il faut la permission Gérer le serveur pour changer la langue.",
+			wantLocale: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestHandler(t)
+			store := storage.NewMemoryStore()
+			store.SetGuildLocale(context.Background(), "guild-1", tt.current)
+			handler := NewLocaleHandler(NewLocalizer(store, i18n.DefaultLocale))
+			fake := newFakeDiscord()
+
+			handler.HandleInteraction(fake, newTestLocaleInteraction(tt.permissions, tt.locale, tt.sub, tt.options...))
+
+			resp := lastResponse(t, fake)
+			if resp.Data.Content != tt.wantReply || resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+				t.Errorf("Expected ephemeral reply %q, got %+v", tt.wantReply, resp.Data)
+			}
+			if locale, _ := store.GuildLocale(context.Background(), "guild-1"); locale != tt.wantLocale {
+				t.Errorf("Expected guild locale %q, got %q", tt.wantLocale, locale)
+			}
+		})
+	}
+}
+
+// TestLocalizedReplies tests that content replies follow the guild locale,
+// then the locale of the user.
+func TestLocalizedReplies(t *testing.T) {
+	t.Run("help in the guild locale", func(t *testing.T) {
+		handler := setupTestHandler(t)
+		store := storage.NewMemoryStore()
+		store.SetGuildLocale(context.Background(), "guild-1", "fr")
+		handler.Locales = NewLocalizer(store, i18n.DefaultLocale)
+		fake := newFakeDiscord()
+
+		handler.HandleMessage(fake, newTestMessage("!help"))
+
+		want := "Commandes disponibles :\n• `!cats` (2 entrées)\n• `!mutsumi` (2 entrées)\n"
+		if sent := fake.postedMessages(); len(sent) != 1 || sent[0].Content != want {
+			t.Errorf("Expected the French help %q, got %+v", want, sent)
+		}
+	})
+
+	t.Run("default locale without a guild locale", func(t *testing.T) {
+		handler := setupTestHandler(t)
+		handler.Locales = NewLocalizer(storage.NewMemoryStore(), "ja")
+		fake := newFakeDiscord()
+
+		handler.HandleMessage(fake, newTestMessage("!help"))
+
+		want := "利用できるコマンド：\n• `!cats`（2 件）\n• `!mutsumi`（2 件）\n"
+		if sent := fake.postedMessages(); len(sent) != 1 || sent[0].Content != want {
+			t.Errorf("Expected the Japanese help %q, got %+v", want, sent)
+		}
+	})
+
+	tests := []struct {
+		name        string
+		guildLocale string
+		userLocale  discordgo.Locale
+		want        string
+	}{
+		{
+			name:       "user locale",
+			userLocale: discordgo.French,
+			want:       "Catégorie 'dogs' introuvable. Catégories disponibles : cats, mutsumi",
+		},
+		{
+			name:        "guild locale first",
+			guildLocale: "ja",
+			userLocale:  discordgo.French,
+			want:        "カテゴリ「dogs」が見つかりません。利用できるカテゴリ：cats, mutsumi",
+		},
+		{
+			name:       "unsupported user locale",
+			userLocale: discordgo.German,
+			want:       "Category 'dogs' not found. Available categories: cats, mutsumi",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupTestInteractionHandler(t)
+			store := storage.NewMemoryStore()
+			store.SetGuildLocale(context.Background(), "guild-1", tt.guildLocale)
+			handler.Locales = NewLocalizer(store, i18n.DefaultLocale)
+			i := newTestCommandInteraction("dogs")
+			i.Locale = tt.userLocale
+			fake := newFakeDiscord()
+
+			handler.HandleInteraction(fake, i)
+
+			if resp := lastResponse(t, fake); resp.Data.Content != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, resp.Data.Content)
+			}
+		})
+	}
+}
+
+// TestLocalizedHandlerReplies tests that the replies of the other commands
+// follow the locale of the user too.
+func TestLocalizedHandlerReplies(t *testing.T) {
+	t.Run("favorites", func(t *testing.T) {
+		handler, store := setupTestFavoritesHandler(t)
+		handler.Locales = NewLocalizer(store, i18n.DefaultLocale)
+		i := newTestFavoritesInteraction("list")
+		i.Locale = discordgo.French
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, i)
+
+		want := "Vous n'avez pas encore de favoris. Appuyez sur ⭐ Enregistrer sous une réponse pour en ajouter un."
+		if resp := lastResponse(t, fake); resp.Data.Content != want {
+			t.Errorf("Expected %q, got %q", want, resp.Data.Content)
+		}
+	})
+
+	t.Run("schedule in the guild locale", func(t *testing.T) {
+		handler, store := setupTestScheduleHandler(t)
+		store.SetGuildLocale(context.Background(), "guild-1", "ja")
+		handler.Locales = NewLocalizer(store, i18n.DefaultLocale)
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestScheduleInteraction(discordgo.PermissionManageServer, "list"))
+
+		want := "スケジュールはありません。/schedule add で追加できます。"
+		if resp := lastResponse(t, fake); resp.Data.Content != want {
+			t.Errorf("Expected %q, got %q", want, resp.Data.Content)
+		}
+	})
+
+	t.Run("top", func(t *testing.T) {
+		setupTestHandler(t)
+		store := storage.NewMemoryStore()
+		handler := NewTopHandler(store)
+		handler.Locales = NewLocalizer(store, "fr")
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestTopInteraction())
+
+		if want := "Il n'y a pas encore de contenu."; lastResponse(t, fake).Data.Content != want {
+			t.Errorf("Expected %q, got %q", want, lastResponse(t, fake).Data.Content)
+		}
+	})
+
+	t.Run("buttons in the guild locale", func(t *testing.T) {
+		handler, store, _ := setupTestContentButtons(t)
+		store.SetGuildLocale(context.Background(), "guild-1", "fr")
+		handler.Locales = NewLocalizer(store, i18n.DefaultLocale)
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestCommandInteraction("wooper"))
+
+		var labels []string
+		for _, button := range buttonsOf(t, lastResponse(t, fake).Data.Components) {
+			if button.Label != "" && button.Emoji.Name != "👍" && button.Emoji.Name != "👎" {
+				labels = append(labels, button.Label)
+			}
+		}
+		if want := []string{"Relancer", "Enregistrer", "Supprimer"}; !slices.Equal(labels, want) {
+			t.Errorf("Expected the labels %v, got %v", want, labels)
+		}
+	})
+
+	t.Run("content import errors", func(t *testing.T) {
+		handler, store := setupTestContentAdminHandler(t)
+		handler.Locales = NewLocalizer(store, i18n.DefaultLocale)
+		i := newTestContentInteraction(discordgo.PermissionAdministrator, "import")
+		i = withAttachment(i, &discordgo.MessageAttachment{ID: "att-1", Filename: "seed.txt", URL: "http://localhost", Size: 64})
+		i.Locale = discordgo.French
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, i)
+
+		want := "Impossible d'importer `seed.txt`, un fichier .json, .yaml ou .csv est attendu."
+		if edits := fake.responseEdits(); len(edits) != 1 || *edits[0].Content != want {
+			t.Errorf("Expected %q, got %+v", want, edits)
+		}
+	})
+}
+
+// TestLocalizedContent tests that content is requested in the locale of
+// the reply.
+func TestLocalizedContent(t *testing.T) {
+	store := storage.NewMemoryStore()
+	store.SetGuildLocale(context.Background(), "guild-1", "fr")
+
+	handler := setupTestHandler(t)
+	handler.Locales = NewLocalizer(store, i18n.DefaultLocale)
+	handler.HandleMessage(newFakeDiscord(), newTestMessage("!cats"))
+	if got := handler.ContentService.(*mockContentService).locales; len(got) != 1 || got[0] != "fr" {
+		t.Errorf("Expected content in the guild locale, got %q", got)
+	}
+
+	interactions := setupTestInteractionHandler(t)
+	interactions.Locales = NewLocalizer(storage.NewMemoryStore(), i18n.DefaultLocale)
+	i := newTestCommandInteraction("cats")
+	i.Locale = discordgo.Japanese
+	interactions.HandleInteraction(newFakeDiscord(), i)
+	if got := interactions.ContentService.(*mockContentService).locales; len(got) != 1 || got[0] != "ja" {
+		t.Errorf("Expected content in the user locale, got %q", got)
+	}
+}
+
+// TestLocalizer_Cache tests that guild locales are cached for a while.
+func TestLocalizer_Cache(t *testing.T) {
+	setupTestHandler(t)
+	store := storage.NewMemoryStore()
+	localizer := NewLocalizer(store, i18n.DefaultLocale)
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	localizer.now = func() time.Time { return now }
+	m := newTestMessage("!help")
+
+	store.SetGuildLocale(context.Background(), "guild-1", "fr")
+	if got := localizer.ForMessage(m); got != "fr" {
+		t.Fatalf("Expected fr, got %q", got)
+	}
+
+	// Changed by another replica
+	store.SetGuildLocale(context.Background(), "guild-1", "ja")
+	if got := localizer.ForMessage(m); got != "fr" {
+		t.Errorf("Expected the cached locale, got %q", got)
+	}
+	now = now.Add(localeCacheTTL)
+	if got := localizer.ForMessage(m); got != "ja" {
+		t.Errorf("Expected the new locale once the cache expired, got %q", got)
+	}
+}
+
+// TestCommandLocalizations tests that every slash command is translated in
+// every locale.
+func TestCommandLocalizations(t *testing.T) {
+	commands := []*discordgo.ApplicationCommand{
+		ContentAdminCommand(),
+		ScheduleCommand(),
+		TriggerCommand(),
+		FavoritesCommand(),
+		TopCommand(),
+		SuggestCommand(),
+		LocaleCommand(),
+		MentionsCommand(),
+	}
+
+	var check func(path string, descriptions map[discordgo.Locale]string, options []*discordgo.ApplicationCommandOption)
+	check = func(path string, descriptions map[discordgo.Locale]string, options []*discordgo.ApplicationCommandOption) {
+		for _, locale := range i18n.Default.Locales()[1:] {
+			if descriptions[discordgo.Locale(locale)] == "" {
+				t.Errorf("%s has no %s description", path, locale)
+			}
+		}
+		for _, opt := range options {
+			check(path+" "+opt.Name, opt.DescriptionLocalizations, opt.Options)
+		}
+	}
+	for _, cmd := range commands {
+		i18n.LocalizeCommand(cmd)
+		if cmd.DescriptionLocalizations == nil {
+			t.Errorf("/%s has no description localizations", cmd.Name)
+			continue
+		}
+		check("/"+cmd.Name, *cmd.DescriptionLocalizations, cmd.Options)
+	}
+}
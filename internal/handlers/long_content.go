@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"mutsumi-bot/internal/chunk"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// contentFileName is the name of the file long content is sent as
+const contentFileName = "content.txt"
+
+// asFile reports whether text is long enough to be sent as a file rather
+// than split into messages. A threshold of 0 always splits it.
+func asFile(text string, threshold int) bool {
+	return threshold > 0 && utf8.RuneCountInString(text) > threshold
+}
+
+// contentFile returns a .txt file holding text
+func contentFile(text string) *discordgo.File {
+	return &discordgo.File{
+		Name:        contentFileName,
+		ContentType: "text/plain; charset=utf-8",
+		Reader:      strings.NewReader(text),
+	}
+}
+
+// sendText sends text to a channel, split into several messages when it is
+// too long for one, or as a .txt file above threshold. The components are
+// added to the last message, and every message pings only the mentions
+// allowed. It returns how many messages were sent, also when it fails
+// partway.
+func sendText(s DiscordAPI, channelID, text string, components []discordgo.MessageComponent, mentions *discordgo.MessageAllowedMentions, threshold int) (int, error) {
+	if asFile(text, threshold) {
+		_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Files:           []*discordgo.File{contentFile(text)},
+			Components:      components,
+			AllowedMentions: mentions,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	chunks := chunk.Split(text, chunk.Limit)
+	for n, part := range chunks {
+		message := &discordgo.MessageSend{Content: part, AllowedMentions: mentions}
+		if n == len(chunks)-1 {
+			message.Components = components
+		}
+		if _, err := s.ChannelMessageSendComplex(channelID, message); err != nil {
+			return n, err
+		}
+	}
+	return len(chunks), nil
+}
+
+// respondText responds to an interaction with data, whose content is
+// preceded by lead, e.g. a mention, and which pings only the mentions
+// allowed by data.AllowedMentions. Content too long for one message is
+// continued in followup messages, or sent as a .txt file with only lead as
+// text above threshold. The components stay on the initial response, which
+// is the message buttons edit.
+func respondText(s DiscordAPI, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData, lead string, threshold int) error {
+	rest := fitText(data, lead, threshold)
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+	if err != nil {
+		return err
+	}
+	return followupText(s, i, data, rest)
+}
+
+// editText is respondText for a deferred response, which it edits with data
+func editText(s DiscordAPI, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData, lead string, threshold int) error {
+	rest := fitText(data, lead, threshold)
+	edit := &discordgo.WebhookEdit{
+		Content:         &data.Content,
+		Files:           data.Files,
+		AllowedMentions: data.AllowedMentions,
+	}
+	if len(data.Components) > 0 {
+		edit.Components = &data.Components
+	}
+	if _, err := s.InteractionResponseEdit(i.Interaction, edit); err != nil {
+		return err
+	}
+	return followupText(s, i, data, rest)
+}
+
+// fitText puts lead and the start of the content of data in data, or the
+// content as a file above threshold, and returns the chunks left for
+// followup messages
+func fitText(data *discordgo.InteractionResponseData, lead string, threshold int) []string {
+	if asFile(data.Content, threshold) {
+		data.Files = []*discordgo.File{contentFile(data.Content)}
+		data.Content = strings.TrimSpace(lead)
+		return nil
+	}
+	chunks := chunk.Split(lead+data.Content, chunk.Limit)
+	data.Content = chunks[0]
+	return chunks[1:]
+}
+
+// followupText sends each of rest as a followup message with the flags and
+// allowed mentions of data
+func followupText(s DiscordAPI, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData, rest []string) error {
+	for _, part := range rest {
+		_, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content:         part,
+			Flags:           data.Flags,
+			AllowedMentions: data.AllowedMentions,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
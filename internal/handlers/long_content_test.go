@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// longContent is an entry of five paragraphs of 600 characters, too long
+// for a single message
+var longContent = strings.TrimSuffix(strings.Repeat(strings.Repeat("w", 600)+"\n\n", 5), "\n\n")
+
+// fileText returns the text of the only file of a message
+func fileText(t *testing.T, files []*discordgo.File) string {
+	t.Helper()
+	if len(files) != 1 || files[0].Name != contentFileName {
+		t.Fatalf("Expected a %s file, got %+v", contentFileName, files)
+	}
+	text, err := io.ReadAll(files[0].Reader)
+	if err != nil {
+		t.Fatalf("Failed to read the file: %v", err)
+	}
+	return string(text)
+}
+
+// TestMessageHandler_LongContent tests splitting long prefix command
+// replies and sending them as a file above the threshold.
+func TestMessageHandler_LongContent(t *testing.T) {
+	t.Run("split", func(t *testing.T) {
+		handler := setupTestHandler(t)
+		handler.ContentService.(*mockContentService).addCommand("long", longContent)
+		handler.SaveButton = true
+		handler.AttachmentThreshold = 5000
+		fake := newFakeDiscord()
+
+		handler.HandleMessage(fake, newTestMessage("!long"))
+
+		sent := fake.sentComplexMessages()
+		if len(sent) != 2 {
+			t.Fatalf("Expected 2 messages, got %d", len(sent))
+		}
+		if got := sent[0].Data.Content + "\n\n" + sent[1].Data.Content; got != longContent {
+			t.Errorf("Expected the chunks to add up to the entry, got %d characters", len(got))
+		}
+		if n := utf8.RuneCountInString(sent[0].Data.Content); n > 2000 {
+			t.Errorf("Expected chunks of at most 2000 characters, got %d", n)
+		}
+		if len(sent[0].Data.Components) != 0 || len(sent[1].Data.Components) != 1 {
+			t.Errorf("Expected the Save button on the last message only, got %+v and %+v", sent[0].Data.Components, sent[1].Data.Components)
+		}
+	})
+
+	t.Run("attachment", func(t *testing.T) {
+		handler := setupTestHandler(t)
+		handler.ContentService.(*mockContentService).addCommand("long", longContent)
+		handler.AttachmentThreshold = 2500
+		fake := newFakeDiscord()
+
+		handler.HandleMessage(fake, newTestMessage("!long"))
+
+		complex := fake.sentComplexMessages()
+		if len(complex) != 1 || len(fake.sentMessages()) != 0 {
+			t.Fatalf("Expected a single message with a file, got %+v", complex)
+		}
+		if text := fileText(t, complex[0].Data.Files); text != longContent {
+			t.Errorf("Expected the entry in the file, got %d characters", len(text))
+		}
+	})
+
+	t.Run("help with many categories", func(t *testing.T) {
+		handler := setupTestHandler(t)
+		mock := handler.ContentService.(*mockContentService)
+		for n := range 200 {
+			mock.addCommand(fmt.Sprintf("category-%03d", n), "Content")
+		}
+		fake := newFakeDiscord()
+
+		handler.HandleMessage(fake, newTestMessage("!help"))
+
+		sent := fake.postedMessages()
+		if len(sent) < 2 {
+			t.Fatalf("Expected the help split into several messages, got %d", len(sent))
+		}
+		for _, message := range sent {
+			if n := utf8.RuneCountInString(message.Content); n > 2000 {
+				t.Errorf("Expected messages of at most 2000 characters, got %d", n)
+			}
+		}
+		if last := sent[len(sent)-1].Content; !strings.Contains(last, "`!mutsumi`") {
+			t.Errorf("Expected the last categories in the last message, got %q", last)
+		}
+	})
+}
+
+// TestInteractionHandler_LongContent tests continuing long /command replies
+// in followup messages.
+func TestInteractionHandler_LongContent(t *testing.T) {
+	t.Run("followups", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		handler.ContentService.(*mockContentService).addCommand("long", longContent)
+		handler.SaveButton = true
+		fake := newFakeDiscord()
+		i := newTestCommandInteraction("long")
+		data := i.ApplicationCommandData()
+		data.Options = append(data.Options, &discordgo.ApplicationCommandInteractionDataOption{Name: "private", Type: discordgo.ApplicationCommandOptionBoolean, Value: true})
+		i.Data = data
+
+		handler.HandleInteraction(fake, i)
+
+		resp := lastResponse(t, fake)
+		followups := fake.followupMessages()
+		if len(followups) != 1 {
+			t.Fatalf("Expected a followup, got %d", len(followups))
+		}
+		if got := resp.Data.Content + "\n\n" + followups[0].Content; got != longContent {
+			t.Errorf("Expected the response and followup to add up to the entry, got %d characters", len(got))
+		}
+		if len(resp.Data.Components) != 1 {
+			t.Errorf("Expected the Save button on the response, got %+v", resp.Data.Components)
+		}
+		if followups[0].Flags&discordgo.MessageFlagsEphemeral == 0 {
+			t.Error("Expected the followup of a private reply to be ephemeral")
+		}
+	})
+
+	t.Run("attachment keeps the mention", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		mock := handler.ContentService.(*mockContentService)
+		mock.commands = map[string][]string{"long": {longContent}}
+		handler.AttachmentThreshold = 2500
+		fake := newFakeDiscord()
+		resolved := &discordgo.ApplicationCommandInteractionDataResolved{
+			Users: map[string]*discordgo.User{"user-2": {ID: "user-2", Username: "friend"}},
+		}
+
+		handler.HandleInteraction(fake, newTestContextInteraction(randomForUserCommand, 0, resolved, "user-2"))
+
+		resp := lastResponse(t, fake)
+		if resp.Data.Content != "<@user-2>" || fileText(t, resp.Data.Files) != longContent {
+			t.Errorf("Expected the mention with the entry attached, got %q", resp.Data.Content)
+		}
+	})
+
+	t.Run("reroll to a long entry", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		fake := newFakeDiscord()
+
+		handler.updateReply(fake, newTestButtonInteraction("user-1", "content:reroll"), longContent, nil, nil)
+
+		resp := lastResponse(t, fake)
+		if resp.Type != discordgo.InteractionResponseUpdateMessage || resp.Data.Content != "" || fileText(t, resp.Data.Files) != longContent {
+			t.Errorf("Expected the reply replaced by a file, got %+v", resp.Data)
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// roleMentionsCacheTTL is how long the /mentions setting of a guild is
+// cached, after which changes made through another replica show up
+const roleMentionsCacheTTL = time.Minute
+
+// MentionPolicy decides who content sent by the bot may ping. Entries ping
+// nobody but the users passed to For, e.g. the user who asked for them, so
+// that an entry containing @everyone or a role mention doesn't ping the
+// whole server. Guilds can let entries added by admins ping roles with
+// /mentions; approved suggestions never do, and neither does @everyone. A
+// nil MentionPolicy has no guild overrides.
+type MentionPolicy struct {
+	Store storage.Store
+
+	now    func() time.Time
+	mu     sync.Mutex
+	guilds map[string]cachedRoleMentions
+	// sets counts changes made with set, so that a setting read before one
+	// isn't cached
+	sets uint64
+}
+
+// cachedRoleMentions is the /mentions setting of a guild and when it was
+// read
+type cachedRoleMentions struct {
+	allowed bool
+	at      time.Time
+}
+
+func NewMentionPolicy(store storage.Store) *MentionPolicy {
+	return &MentionPolicy{Store: store, now: time.Now, guilds: map[string]cachedRoleMentions{}}
+}
+
+// noMentions returns allowed mentions that ping nobody, for messages quoting
+// several entries such as the /top leaderboard
+func noMentions() *discordgo.MessageAllowedMentions {
+	return &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}}
+}
+
+// For returns the allowed mentions of an entry sent in a guild: the users
+// given, and roles when the guild allows them and an admin added the entry
+func (p *MentionPolicy) For(guildID string, entry storage.Entry, users ...string) *discordgo.MessageAllowedMentions {
+	allowed := noMentions()
+	for _, user := range users {
+		if user != "" {
+			allowed.Users = append(allowed.Users, user)
+		}
+	}
+	if entry.SuggestedBy == "" && p.roleMentions(guildID) {
+		allowed.Parse = append(allowed.Parse, discordgo.AllowedMentionTypeRoles)
+	}
+	return allowed
+}
+
+// ForGuild returns the allowed mentions of an entry posted in a guild
+// without a user asking for it, e.g. on a schedule
+func (p *MentionPolicy) ForGuild(guildID string, entry storage.Entry) *discordgo.MessageAllowedMentions {
+	return p.For(guildID, entry)
+}
+
+// roleMentions reports whether a guild lets entries added by admins ping
+// roles, false when it can't be read. The lock isn't held while reading the
+// store, so a slow database only delays the guilds missing from the cache.
+func (p *MentionPolicy) roleMentions(guildID string) bool {
+	if p == nil || guildID == "" {
+		return false
+	}
+
+	p.mu.Lock()
+	cached, ok := p.guilds[guildID]
+	sets := p.sets
+	p.mu.Unlock()
+	if ok && p.now().Sub(cached.at) < roleMentionsCacheTTL {
+		return cached.allowed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	allowed, err := p.Store.GuildRoleMentions(ctx, guildID)
+	if err != nil {
+		logger.Logger.Warn("Failed to read guild role mentions", zap.String("guild_id", guildID), zap.Error(err))
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sets == sets {
+		p.guilds[guildID] = cachedRoleMentions{allowed: allowed, at: p.now()}
+	}
+	return allowed
+}
+
+// set stores whether a guild lets entries added by admins ping roles
+func (p *MentionPolicy) set(ctx context.Context, guildID string, allowed bool) error {
+	if err := p.Store.SetGuildRoleMentions(ctx, guildID, allowed); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.guilds[guildID] = cachedRoleMentions{allowed: allowed, at: p.now()}
+	p.sets++
+	return nil
+}
+
+// MentionsCommand returns the definition of the /mentions admin command
+func MentionsCommand() *discordgo.ApplicationCommand {
+	permissions := int64(adminPermissions)
+	dmPermission := false
+
+	return &discordgo.ApplicationCommand{
+		Name:                     "mentions",
+		Description:              "Choose whether content can ping roles in this server",
+		DefaultMemberPermissions: &permissions,
+		DMPermission:             &dmPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "roles",
+				Description: "Let entries added by admins ping roles",
+				Required:    true,
+			},
+		},
+	}
+}
+
+// MentionsHandler handles the /mentions admin command
+type MentionsHandler struct {
+	Mentions *MentionPolicy
+	// Locales picks the language of replies, nil for DefaultLocale
+	Locales *Localizer
+}
+
+func NewMentionsHandler(mentions *MentionPolicy) *MentionsHandler {
+	return &MentionsHandler{Mentions: mentions}
+}
+
+// OnInteractionCreate is the discordgo event handler for interactions
+func (h *MentionsHandler) OnInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	h.HandleInteraction(s, i)
+}
+
+// HandleInteraction processes a /mentions interaction using the given
+// Discord API
+func (h *MentionsHandler) HandleInteraction(s DiscordAPI, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand || i.ApplicationCommandData().Name != "mentions" || i.GuildID == "" {
+		return
+	}
+	locale := h.Locales.ForInteraction(i)
+
+	if !isContentAdmin(i) {
+		logger.Logger.Warn("Unauthorized mentions command",
+			zap.String("user_id", interactionUserID(i)),
+			zap.String("guild_id", i.GuildID))
+		respondEphemeral(s, i, i18n.T(locale, "mentions.forbidden", nil))
+		return
+	}
+
+	var allowed bool
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "roles" {
+			allowed = opt.BoolValue()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := h.Mentions.set(ctx, i.GuildID, allowed); err != nil {
+		logger.Logger.Error("Failed to set guild role mentions",
+			zap.String("guild_id", i.GuildID),
+			zap.Bool("allowed", allowed),
+			zap.Error(err))
+		respondEphemeral(s, i, i18n.T(locale, "unavailable", nil))
+		return
+	}
+
+	logger.Logger.Info("Guild role mentions changed via slash command",
+		zap.String("guild_id", i.GuildID),
+		zap.Bool("allowed", allowed),
+		zap.String("user_id", interactionUserID(i)))
+	if allowed {
+		respondEphemeral(s, i, i18n.T(locale, "mentions.roles_allowed", nil))
+	} else {
+		respondEphemeral(s, i, i18n.T(locale, "mentions.roles_denied", nil))
+	}
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// newTestMentionsInteraction builds a /mentions interaction by a member with
+// the given permissions
+func newTestMentionsInteraction(permissions int64, roles bool) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-1",
+			Type:      discordgo.InteractionApplicationCommand,
+			ChannelID: "channel-1",
+			GuildID:   "guild-1",
+			Member: &discordgo.Member{
+				User:        &discordgo.User{ID: "user-1", Username: "admin"},
+				Permissions: permissions,
+			},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: "mentions",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: "roles", Type: discordgo.ApplicationCommandOptionBoolean, Value: roles},
+				},
+			},
+		},
+	}
+}
+
+// TestMentionPolicy_For tests who entries may ping.
+func TestMentionPolicy_For(t *testing.T) {
+	setupTestHandler(t)
+	store := storage.NewMemoryStore()
+	store.SetGuildRoleMentions(context.Background(), "guild-roles", true)
+	policy := NewMentionPolicy(store)
+	admin := storage.Entry{ID: 1, Content: "@everyone <@&role-1> wooper!"}
+	suggested := storage.Entry{ID: 2, Content: "<@&role-1> wooper!", SuggestedBy: "user-2"}
+
+	tests := []struct {
+		name      string
+		policy    *MentionPolicy
+		guildID   string
+		entry     storage.Entry
+		users     []string
+		wantUsers []string
+		wantRoles bool
+	}{
+		{name: "nobody by default", policy: policy, guildID: "guild-1", entry: admin},
+		{name: "the invoker", policy: policy, guildID: "guild-1", entry: admin, users: []string{"user-1"}, wantUsers: []string{"user-1"}},
+		{name: "no user outside interactions", policy: policy, guildID: "guild-1", entry: admin, users: []string{""}},
+		{name: "roles allowed for admin entries", policy: policy, guildID: "guild-roles", entry: admin, users: []string{"user-1"}, wantUsers: []string{"user-1"}, wantRoles: true},
+		{name: "never for suggested entries", policy: policy, guildID: "guild-roles", entry: suggested},
+		{name: "not in DMs", policy: policy, entry: admin},
+		{name: "nil policy", guildID: "guild-roles", entry: admin, users: []string{"user-1"}, wantUsers: []string{"user-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.For(tt.guildID, tt.entry, tt.users...)
+			if got == nil || got.Parse == nil {
+				t.Fatalf("Expected explicit allowed mentions, got %+v", got)
+			}
+			if !slices.Equal(got.Users, tt.wantUsers) {
+				t.Errorf("Expected users %v, got %v", tt.wantUsers, got.Users)
+			}
+			wantParse := []discordgo.AllowedMentionType{}
+			if tt.wantRoles {
+				wantParse = append(wantParse, discordgo.AllowedMentionTypeRoles)
+			}
+			if !slices.Equal(got.Parse, wantParse) {
+				t.Errorf("Expected to parse %v, got %v", wantParse, got.Parse)
+			}
+		})
+	}
+}
+
+// TestMentionPolicy_Cache tests that the setting of a guild is cached until
+// it expires, and refreshed when changed with /mentions.
+func TestMentionPolicy_Cache(t *testing.T) {
+	setupTestHandler(t)
+	store := storage.NewMemoryStore()
+	policy := NewMentionPolicy(store)
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	policy.now = func() time.Time { return now }
+	entry := storage.Entry{ID: 1, Content: "<@&role-1>"}
+
+	if len(policy.ForGuild("guild-1", entry).Parse) != 0 {
+		t.Fatal("Expected no role mentions by default")
+	}
+	store.SetGuildRoleMentions(context.Background(), "guild-1", true)
+	if len(policy.ForGuild("guild-1", entry).Parse) != 0 {
+		t.Error("Expected the cached setting before it expires")
+	}
+	now = now.Add(roleMentionsCacheTTL)
+	if len(policy.ForGuild("guild-1", entry).Parse) != 1 {
+		t.Error("Expected the new setting once the cache expired")
+	}
+
+	if err := policy.set(context.Background(), "guild-1", false); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if len(policy.ForGuild("guild-1", entry).Parse) != 0 {
+		t.Error("Expected the setting changed through the policy at once")
+	}
+}
+
+// TestMentionsHandler tests allowing and disallowing role mentions in a
+// guild.
+func TestMentionsHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions int64
+		current     bool
+		roles       bool
+		wantReply   string
+		wantAllowed bool
+	}{
+		{
+			name:        "allow",
+			permissions: discordgo.PermissionManageServer,
+			roles:       true,
+			wantReply:   "Entries added by admins can now ping roles in this server. Suggested entries and @everyone never ping.",
+			wantAllowed: true,
+		},
+		{
+			name:        "disallow",
+			permissions: discordgo.PermissionManageServer,
+			current:     true,
+			wantReply:   "Entries no longer ping roles in this server.",
+		},
+		{
+			name:      "not an admin",
+			roles:     true,
+			wantReply: "You need the Manage Server permission to change mentions.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestHandler(t)
+			store := storage.NewMemoryStore()
+			store.SetGuildRoleMentions(context.Background(), "guild-1", tt.current)
+			handler := NewMentionsHandler(NewMentionPolicy(store))
+			fake := newFakeDiscord()
+
+			handler.HandleInteraction(fake, newTestMentionsInteraction(tt.permissions, tt.roles))
+
+			resp := lastResponse(t, fake)
+			if resp.Data.Content != tt.wantReply || resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+				t.Errorf("Expected ephemeral reply %q, got %+v", tt.wantReply, resp.Data)
+			}
+			if allowed, _ := store.GuildRoleMentions(context.Background(), "guild-1"); allowed != tt.wantAllowed {
+				t.Errorf("Expected role mentions %v, got %v", tt.wantAllowed, allowed)
+			}
+		})
+	}
+}
+
+// TestAllowedMentions tests that content replies only ping what the policy
+// allows.
+func TestAllowedMentions(t *testing.T) {
+	store := storage.NewMemoryStore()
+	store.SetGuildRoleMentions(context.Background(), "guild-1", true)
+
+	t.Run("prefix command", func(t *testing.T) {
+		handler := setupTestHandler(t)
+		fake := newFakeDiscord()
+
+		handler.HandleMessage(fake, newTestMessage("!mutsumi"))
+
+		sent := fake.postedMessages()
+		if len(sent) != 1 || sent[0].AllowedMentions == nil {
+			t.Fatalf("Expected a reply with allowed mentions, got %+v", sent)
+		}
+		if mentions := sent[0].AllowedMentions; len(mentions.Parse) != 0 || !slices.Equal(mentions.Users, []string{"user-1"}) {
+			t.Errorf("Expected only the author to be pinged, got %+v", mentions)
+		}
+	})
+
+	t.Run("slash command with role mentions", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		handler.Mentions = NewMentionPolicy(store)
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestCommandInteraction("mutsumi"))
+
+		mentions := lastResponse(t, fake).Data.AllowedMentions
+		if mentions == nil || !slices.Equal(mentions.Parse, []discordgo.AllowedMentionType{discordgo.AllowedMentionTypeRoles}) || !slices.Equal(mentions.Users, []string{"user-1"}) {
+			t.Errorf("Expected the invoker and roles to be pinged, got %+v", mentions)
+		}
+	})
+
+	t.Run("unknown category pings no one", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestCommandInteraction("@everyone"))
+
+		resp := lastResponse(t, fake)
+		if !strings.Contains(resp.Data.Content, "@everyone") {
+			t.Fatalf("Expected the not found reply, got %q", resp.Data.Content)
+		}
+		if mentions := resp.Data.AllowedMentions; mentions == nil || mentions.Parse == nil || len(mentions.Parse) != 0 || len(mentions.Users) != 0 {
+			t.Errorf("Expected no mentions allowed, got %+v", mentions)
+		}
+	})
+
+	t.Run("empty category pings no one", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		handler.ContentService.(*mockContentService).addCommand("@here", "")
+		fake := newFakeDiscord()
+
+		handler.HandleInteraction(fake, newTestCommandInteraction("@here"))
+
+		if mentions := lastResponse(t, fake).Data.AllowedMentions; mentions == nil || mentions.Parse == nil || len(mentions.Parse) != 0 {
+			t.Errorf("Expected no mentions allowed, got %+v", mentions)
+		}
+	})
+
+	t.Run("random for user", func(t *testing.T) {
+		handler := setupTestInteractionHandler(t)
+		fake := newFakeDiscord()
+		resolved := &discordgo.ApplicationCommandInteractionDataResolved{
+			Users: map[string]*discordgo.User{"user-2": {ID: "user-2", Username: "friend"}},
+		}
+
+		handler.HandleInteraction(fake, newTestContextInteraction(randomForUserCommand, 0, resolved, "user-2"))
+
+		mentions := lastResponse(t, fake).Data.AllowedMentions
+		if mentions == nil || len(mentions.Parse) != 0 || !slices.Equal(mentions.Users, []string{"user-2"}) {
+			t.Errorf("Expected only the target to be pinged, got %+v", mentions)
+		}
+	})
+}
@@ -1,26 +1,70 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"mutsumi-bot/internal/i18n"
 	"mutsumi-bot/internal/logger"
 	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/triggers"
 
 	"github.com/bwmarrin/discordgo"
 	"go.uber.org/zap"
 )
 
+// DefaultPrefix starts text commands when no prefix is set
+const DefaultPrefix = "!"
+
 type MessageHandler struct {
 	ContentService services.ContentService
+	// Limiter limits commands per user, nil for no limit
+	Limiter *RateLimiter
+	// Triggers answers messages without the prefix, nil to disable triggers
+	Triggers *triggers.Matcher
+	// SaveButton adds a ⭐ Save button to text replies, handled by
+	// FavoritesHandler
+	SaveButton bool
+	// Locales picks the language of replies, nil for DefaultLocale
+	Locales *Localizer
+	// AttachmentThreshold is the length above which content is sent as a
+	// .txt file rather than split into messages, 0 to always split it
+	AttachmentThreshold int
+	// Mentions decides who content may ping, nil to never ping roles
+	Mentions *MentionPolicy
+
+	// prefix starts text commands, DefaultPrefix when unset
+	prefix atomic.Pointer[string]
 }
 
 func NewMessageHandler(contentService services.ContentService) *MessageHandler {
 	return &MessageHandler{ContentService: contentService}
 }
 
+// SetPrefix changes the prefix of text commands; it is safe to call while
+// messages are being handled
+func (h *MessageHandler) SetPrefix(prefix string) {
+	h.prefix.Store(&prefix)
+}
+
+// Prefix returns the prefix of text commands
+func (h *MessageHandler) Prefix() string {
+	if p := h.prefix.Load(); p != nil && *p != "" {
+		return *p
+	}
+	return DefaultPrefix
+}
+
+// OnMessageCreate is the discordgo event handler for new messages
 func (h *MessageHandler) OnMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	h.HandleMessage(s, m)
+}
+
+// HandleMessage processes a message using the given Discord API
+func (h *MessageHandler) HandleMessage(s DiscordAPI, m *discordgo.MessageCreate) {
 	if m.Author == nil || m.Author.Bot {
 		return
 	}
@@ -35,9 +79,20 @@ func (h *MessageHandler) OnMessageCreate(s *discordgo.Session, m *discordgo.Mess
 		zap.String("guild_id", m.GuildID),
 		zap.String("content", content))
 
-	// Check if message starts with ! and has a valid category
-	if strings.HasPrefix(content, "!") {
-		category := strings.TrimPrefix(content, "!")
+	prefix := h.Prefix()
+
+	// Check if message starts with the prefix and has a valid category
+	if strings.HasPrefix(content, prefix) {
+		category := strings.TrimPrefix(content, prefix)
+
+		if allowed, retryAfter := h.Limiter.Allow(m.Author.ID); !allowed {
+			logger.Logger.Info("Command rate limited",
+				zap.String("category", category),
+				zap.String("user", m.Author.Username),
+				zap.String("user_id", m.Author.ID),
+				zap.Duration("retry_after", retryAfter))
+			return
+		}
 
 		// Log command attempt
 		logger.Logger.Info("Command received",
@@ -48,19 +103,26 @@ func (h *MessageHandler) OnMessageCreate(s *discordgo.Session, m *discordgo.Mess
 			zap.String("channel_id", m.ChannelID),
 			zap.String("guild_id", m.GuildID))
 
-		if h.ContentService.HasCategory(category) {
-			startTime := time.Now()
-
-			content := h.ContentService.GetRandomContent(category)
-			if content == "" {
-				logger.Logger.Warn("No content available for command",
-					zap.String("command", category),
-					zap.String("user", m.Author.Username))
-				_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("no content available for `!%s`", category))
-				return
-			}
-
-			_, err := s.ChannelMessageSend(m.ChannelID, content)
+		startTime := time.Now()
+		entry, err := h.ContentService.GetRandomEntry(category, h.Locales.ForMessage(m))
+		switch {
+		case errors.Is(err, services.ErrCategoryNotFound) && (category == "help" || category == "list"):
+			h.sendHelp(s, m, prefix)
+		case errors.Is(err, services.ErrCategoryNotFound):
+			logger.Logger.Info("Unknown command received",
+				zap.String("command", content),
+				zap.String("category", category),
+				zap.String("user", m.Author.Username),
+				zap.String("user_id", m.Author.ID))
+		case errors.Is(err, services.ErrEmptyCategory):
+			logger.Logger.Warn("No content available for command",
+				zap.String("command", category),
+				zap.String("user", m.Author.Username))
+			_, _ = s.ChannelMessageSend(m.ChannelID, i18n.T(h.Locales.ForMessage(m), "prefix.no_content", i18n.Args{"command": prefix + category}))
+		case err != nil:
+			h.replyUnavailable(s, m, category, err)
+		default:
+			err := sendResponse(s, m, h.Locales.ForMessage(m), entry, h.SaveButton, h.Mentions.For(m.GuildID, entry, m.Author.ID), h.AttachmentThreshold)
 			duration := time.Since(startTime)
 
 			if err != nil {
@@ -69,7 +131,7 @@ func (h *MessageHandler) OnMessageCreate(s *discordgo.Session, m *discordgo.Mess
 					zap.String("user", m.Author.Username),
 					zap.Duration("duration", duration),
 					zap.Error(err))
-				_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("failed to send content for `!%s`: %v", category, err))
+				_, _ = s.ChannelMessageSend(m.ChannelID, i18n.T(h.Locales.ForMessage(m), "prefix.send_failed", i18n.Args{"command": prefix + category}))
 			} else {
 				logger.Logger.Info("Content sent successfully",
 					zap.String("command", category),
@@ -78,38 +140,96 @@ func (h *MessageHandler) OnMessageCreate(s *discordgo.Session, m *discordgo.Mess
 					zap.String("channel_id", m.ChannelID),
 					zap.Duration("duration", duration))
 			}
-		} else if category == "help" || category == "list" {
-			// Show available categories
-			logger.Logger.Info("Help command requested",
-				zap.String("user", m.Author.Username),
-				zap.String("user_id", m.Author.ID))
+		}
+	} else if h.Triggers != nil {
+		h.handleTrigger(s, m, content)
+	}
+}
 
-			categories := h.ContentService.GetAvailableCategories()
-			if len(categories) == 0 {
-				logger.Logger.Warn("No categories available for help",
-					zap.String("user", m.Author.Username))
-				_, _ = s.ChannelMessageSend(m.ChannelID, "no commands available")
-				return
-			}
+// handleTrigger replies to a message matching one of the guild's triggers.
+// Failures are only logged, as the user didn't ask for a reply.
+func (h *MessageHandler) handleTrigger(s DiscordAPI, m *discordgo.MessageCreate, content string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	trigger, ok, err := h.Triggers.Match(ctx, m.GuildID, m.ChannelID, content)
+	if err != nil {
+		logger.Logger.Error("Failed to match triggers",
+			zap.String("guild_id", m.GuildID),
+			zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
 
-			message := "Available commands:\n"
-			for _, cat := range categories {
-				count := h.ContentService.GetContentCount(cat)
-				message += fmt.Sprintf("• `!%s` (%d entries)\n", cat, count)
-			}
+	log := logger.Logger.With(
+		zap.Int64("trigger_id", trigger.ID),
+		zap.String("category", trigger.Category),
+		zap.String("user_id", m.Author.ID),
+		zap.String("channel_id", m.ChannelID),
+		zap.String("guild_id", m.GuildID))
 
-			logger.Logger.Info("Help response sent",
-				zap.String("user", m.Author.Username),
-				zap.Int("categories_count", len(categories)))
+	entry, err := h.ContentService.GetRandomEntry(trigger.Category, h.Locales.ForMessage(m))
+	if err != nil {
+		log.Warn("No content for trigger", zap.Error(err))
+		return
+	}
+	if err := sendResponse(s, m, h.Locales.ForMessage(m), entry, h.SaveButton, h.Mentions.For(m.GuildID, entry, m.Author.ID), h.AttachmentThreshold); err != nil {
+		log.Error("Failed to send trigger reply", zap.Error(err))
+		return
+	}
+	log.Info("Trigger reply sent")
+}
 
-			_, _ = s.ChannelMessageSend(m.ChannelID, message)
-		} else {
-			// Unknown command
-			logger.Logger.Info("Unknown command received",
-				zap.String("command", content),
-				zap.String("category", category),
-				zap.String("user", m.Author.Username),
-				zap.String("user_id", m.Author.ID))
+// sendHelp lists the available categories with their number of entries
+func (h *MessageHandler) sendHelp(s DiscordAPI, m *discordgo.MessageCreate, prefix string) {
+	logger.Logger.Info("Help command requested",
+		zap.String("user", m.Author.Username),
+		zap.String("user_id", m.Author.ID))
+
+	locale := h.Locales.ForMessage(m)
+	categories, err := h.ContentService.GetAvailableCategories()
+	if err != nil {
+		h.replyUnavailable(s, m, "help", err)
+		return
+	}
+	if len(categories) == 0 {
+		logger.Logger.Warn("No categories available for help",
+			zap.String("user", m.Author.Username))
+		_, _ = s.ChannelMessageSend(m.ChannelID, i18n.T(locale, "help.none", nil))
+		return
+	}
+
+	message := i18n.T(locale, "help.header", nil) + "\n"
+	for _, cat := range categories {
+		count, err := h.ContentService.GetContentCount(cat)
+		if err != nil {
+			h.replyUnavailable(s, m, "help", err)
+			return
 		}
+		message += i18n.N(locale, "help.category", count, i18n.Args{"command": prefix + cat}) + "\n"
 	}
+
+	// Servers with many categories outgrow a single message
+	if _, err := sendText(s, m.ChannelID, message, nil, noMentions(), 0); err != nil {
+		logger.Logger.Error("Failed to send help",
+			zap.String("user", m.Author.Username),
+			zap.Error(err))
+		return
+	}
+
+	logger.Logger.Info("Help response sent",
+		zap.String("user", m.Author.Username),
+		zap.Int("categories_count", len(categories)))
+}
+
+// replyUnavailable tells the user the bot can't reach its content right now
+func (h *MessageHandler) replyUnavailable(s DiscordAPI, m *discordgo.MessageCreate, category string, err error) {
+	logger.Logger.Error("Content unavailable",
+		zap.String("category", category),
+		zap.String("user", m.Author.Username),
+		zap.String("user_id", m.Author.ID),
+		zap.Error(err))
+	_, _ = s.ChannelMessageSend(m.ChannelID, i18n.T(h.Locales.ForMessage(m), "unavailable", nil))
 }
@@ -1,9 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"mutsumi-bot/internal/i18n"
 	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+	"mutsumi-bot/internal/triggers"
+
+	"github.com/bwmarrin/discordgo"
 )
 
 // setupTestHandler creates a message handler with mock content service
@@ -55,16 +63,235 @@ func TestMessageHandler_ContentServiceIntegration(t *testing.T) {
 	}
 
 	// Test that the content service has commands
-	commands := handler.ContentService.GetAvailableCategories()
+	commands, err := handler.ContentService.GetAvailableCategories()
+	if err != nil {
+		t.Fatalf("Failed to get categories: %v", err)
+	}
 	if len(commands) == 0 {
 		t.Errorf("Expected commands but got none")
 	}
 
 	// Test that we can get random content
 	for _, command := range commands {
-		content := handler.ContentService.GetRandomContent(command)
-		if content == "" {
-			t.Errorf("Expected content for command %s but got empty", command)
+		content, err := handler.ContentService.GetRandomContent(command, "")
+		if err != nil || content == "" {
+			t.Errorf("Expected content for command %s but got %q, %v", command, content, err)
+		}
+	}
+}
+
+// newTestMessage builds a MessageCreate event authored by a regular user
+func newTestMessage(content string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			ID:        "message-1",
+			ChannelID: "channel-1",
+			GuildID:   "guild-1",
+			Content:   content,
+			Author: &discordgo.User{
+				ID:       "user-1",
+				Username: "tester",
+			},
+		},
+	}
+}
+
+// TestMessageHandler_HandleMessage tests the replies sent for prefixed commands.
+func TestMessageHandler_HandleMessage(t *testing.T) {
+	tests := []struct {
+		name         string
+		setup        func(m *mockContentService)
+		prefix       string
+		message      *discordgo.MessageCreate
+		failSends    int
+		wantMessages []string
+		wantContains []string
+	}{
+		{
+			name:         "known category",
+			message:      newTestMessage("!mutsumi"),
+			wantMessages: []string{"Mutsumi content 1"},
+		},
+		{
+			name:         "surrounding whitespace is ignored",
+			message:      newTestMessage("  !cats  "),
+			wantMessages: []string{"Cats content 1"},
+		},
+		{
+			name:         "help lists categories with counts",
+			message:      newTestMessage("!help"),
+			wantContains: []string{"Available commands:", "`!cats` (2 entries)", "`!mutsumi` (2 entries)"},
+		},
+		{
+			name:         "list is an alias of help",
+			message:      newTestMessage("!list"),
+			wantContains: []string{"Available commands:", "`!cats` (2 entries)"},
+		},
+		{
+			name:         "help with no categories",
+			setup:        func(m *mockContentService) { m.commands = map[string][]string{} },
+			message:      newTestMessage("!help"),
+			wantMessages: []string{"no commands available"},
+		},
+		{
+			name:    "unknown command is ignored",
+			message: newTestMessage("!dogs"),
+		},
+		{
+			name:    "message without prefix is ignored",
+			message: newTestMessage("mutsumi"),
+		},
+		{
+			name: "message from a bot is ignored",
+			message: func() *discordgo.MessageCreate {
+				m := newTestMessage("!mutsumi")
+				m.Author.Bot = true
+				return m
+			}(),
+		},
+		{
+			name:    "message without author is ignored",
+			message: &discordgo.MessageCreate{Message: &discordgo.Message{Content: "!mutsumi"}},
+		},
+		{
+			name:         "custom prefix",
+			prefix:       "?",
+			message:      newTestMessage("?mutsumi"),
+			wantMessages: []string{"Mutsumi content 1"},
+		},
+		{
+			name:         "help uses the custom prefix",
+			prefix:       "m!",
+			message:      newTestMessage("m!help"),
+			wantContains: []string{"`m!cats` (2 entries)"},
+		},
+		{
+			name:    "default prefix is ignored with a custom prefix",
+			prefix:  "?",
+			message: newTestMessage("!mutsumi"),
+		},
+		{
+			name:         "category with empty content",
+			setup:        func(m *mockContentService) { m.addCommand("blank", "") },
+			message:      newTestMessage("!blank"),
+			wantMessages: []string{"no content available for `!blank`"},
+		},
+		{
+			name:         "database down",
+			setup:        func(m *mockContentService) { m.unavailable = true },
+			message:      newTestMessage("!mutsumi"),
+			wantMessages: []string{i18n.T(i18n.DefaultLocale, "unavailable", nil)},
+		},
+		{
+			name:         "help with database down",
+			setup:        func(m *mockContentService) { m.unavailable = true },
+			message:      newTestMessage("!help"),
+			wantMessages: []string{i18n.T(i18n.DefaultLocale, "unavailable", nil)},
+		},
+		{
+			name:         "send failure is reported to the channel without the API error",
+			message:      newTestMessage("!mutsumi"),
+			failSends:    1,
+			wantMessages: []string{"couldn't send content for `!mutsumi`, try again later"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupTestHandler(t)
+			if tt.setup != nil {
+				tt.setup(handler.ContentService.(*mockContentService))
+			}
+			handler.SetPrefix(tt.prefix)
+
+			discord := newFakeDiscord()
+			discord.failSends = tt.failSends
+
+			handler.HandleMessage(discord, tt.message)
+
+			sent := discord.postedMessages()
+			if tt.wantContains != nil {
+				if len(sent) != 1 {
+					t.Fatalf("Expected 1 message but got %d: %v", len(sent), sent)
+				}
+				for _, want := range tt.wantContains {
+					if !strings.Contains(sent[0].Content, want) {
+						t.Errorf("Expected message to contain %q, got %q", want, sent[0].Content)
+					}
+				}
+				return
+			}
+
+			if len(sent) != len(tt.wantMessages) {
+				t.Fatalf("Expected %d messages but got %d: %v", len(tt.wantMessages), len(sent), sent)
+			}
+			for i, want := range tt.wantMessages {
+				if sent[i].Content != want {
+					t.Errorf("Message %d: expected %q, got %q", i, want, sent[i].Content)
+				}
+				if sent[i].ChannelID != tt.message.ChannelID {
+					t.Errorf("Message %d: expected channel %s, got %s", i, tt.message.ChannelID, sent[i].ChannelID)
+				}
+			}
+		})
+	}
+}
+
+// TestMessageHandler_RateLimit tests that commands over the limit are ignored.
+func TestMessageHandler_RateLimit(t *testing.T) {
+	handler := setupTestHandler(t)
+	handler.Limiter = NewRateLimiter(2, time.Minute)
+	discord := newFakeDiscord()
+
+	for i := 0; i < 3; i++ {
+		handler.HandleMessage(discord, newTestMessage("!mutsumi"))
+	}
+	other := newTestMessage("!cats")
+	other.Author.ID = "user-2"
+	handler.HandleMessage(discord, other)
+
+	if sent := discord.postedMessages(); len(sent) != 3 {
+		t.Errorf("Expected 3 messages (2 for user-1, 1 for user-2), got %d: %v", len(sent), sent)
+	}
+}
+
+// TestMessageHandler_Triggers tests replies to messages without the prefix.
+func TestMessageHandler_Triggers(t *testing.T) {
+	handler := setupTestHandler(t)
+	store := storage.NewMemoryStore()
+	store.AddTrigger(context.Background(), storage.Trigger{
+		GuildID: "guild-1", Kind: storage.TriggerWord, Pattern: "mutsumi", Category: "mutsumi", Cooldown: time.Minute, Chance: 100,
+	})
+	store.AddTrigger(context.Background(), storage.Trigger{
+		GuildID: "guild-1", Kind: storage.TriggerPhrase, Pattern: "no content", Category: "unknown", Chance: 100,
+	})
+	handler.Triggers = triggers.NewMatcher(store, time.Minute)
+
+	bot := newTestMessage("mutsumi is here")
+	bot.Author.Bot = true
+
+	steps := []struct {
+		name     string
+		message  *discordgo.MessageCreate
+		wantSent int
+	}{
+		{"bots are ignored", bot, 0},
+		{"no match", newTestMessage("hello there"), 0},
+		{"prefixed commands are not triggers", newTestMessage("!cats"), 1},
+		{"match", newTestMessage("I like Mutsumi!"), 1},
+		{"cooldown", newTestMessage("mutsumi again"), 0},
+		{"category without content", newTestMessage("there is no content"), 0},
+	}
+	for _, step := range steps {
+		discord := newFakeDiscord()
+		handler.HandleMessage(discord, step.message)
+
+		sent := discord.postedMessages()
+		if len(sent) != step.wantSent {
+			t.Errorf("%s: expected %d messages, got %v", step.name, step.wantSent, sent)
+		}
+		if step.name == "match" && len(sent) == 1 && !strings.HasPrefix(sent[0].Content, "Mutsumi content") {
+			t.Errorf("%s: expected mutsumi content, got %q", step.name, sent[0].Content)
 		}
 	}
 }
@@ -1,15 +1,24 @@
 package handlers
 
-import "mutsumi-bot/internal/services"
+import (
+	"sort"
+
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+)
 
 // mockContentService is a mock implementation of ContentService for testing
 type mockContentService struct {
-	commands map[string][]string // command -> list of content entries
+	commands    map[string][]string          // command -> list of content entries
+	types       map[string]storage.EntryType // command -> type of its entries, text by default
+	unavailable bool                         // simulates a database outage
+	locales     []string                     // locales content was requested in
 }
 
 func newMockContentService() *mockContentService {
 	return &mockContentService{
 		commands: make(map[string][]string),
+		types:    make(map[string]storage.EntryType),
 	}
 }
 
@@ -17,33 +26,69 @@ func (m *mockContentService) addCommand(command string, content ...string) {
 	m.commands[command] = content
 }
 
-func (m *mockContentService) GetRandomContent(command string) string {
+func (m *mockContentService) GetRandomContent(command, locale string) (string, error) {
+	m.locales = append(m.locales, locale)
+	if m.unavailable {
+		return "", services.ErrUnavailable
+	}
 	contents, exists := m.commands[command]
 	if !exists || len(contents) == 0 {
-		return ""
+		return "", services.ErrCategoryNotFound
+	}
+	if contents[0] == "" {
+		return "", services.ErrEmptyCategory
 	}
 	// Return first content for deterministic testing
-	return contents[0]
+	return contents[0], nil
+}
+
+// addTypedCommand adds a command whose entries have the given type
+func (m *mockContentService) addTypedCommand(command string, entryType storage.EntryType, content ...string) {
+	m.commands[command] = content
+	m.types[command] = entryType
+}
+
+func (m *mockContentService) GetRandomEntry(command, locale string) (storage.Entry, error) {
+	content, err := m.GetRandomContent(command, locale)
+	if err != nil {
+		return storage.Entry{}, err
+	}
+	return storage.Entry{ID: 1, Category: command, Content: content, Type: m.types[command]}, nil
 }
 
-func (m *mockContentService) GetContentCount(command string) int {
-	if contents, exists := m.commands[command]; exists {
-		return len(contents)
+func (m *mockContentService) GetContentCount(command string) (int, error) {
+	if m.unavailable {
+		return 0, services.ErrUnavailable
 	}
-	return 0
+	return len(m.commands[command]), nil
 }
 
-func (m *mockContentService) GetAvailableCategories() []string {
+func (m *mockContentService) GetAvailableCategories() ([]string, error) {
+	if m.unavailable {
+		return nil, services.ErrUnavailable
+	}
 	var commands []string
 	for cmd := range m.commands {
 		commands = append(commands, cmd)
 	}
-	return commands
+	// Sort for deterministic output in tests
+	sort.Strings(commands)
+	return commands, nil
 }
 
-func (m *mockContentService) HasCategory(command string) bool {
-	_, exists := m.commands[command]
-	return exists && len(m.commands[command]) > 0
+func (m *mockContentService) AddEntry(entry storage.Entry) (int64, error) {
+	if m.unavailable {
+		return 0, services.ErrUnavailable
+	}
+	m.commands[entry.Category] = append(m.commands[entry.Category], entry.Content)
+	return int64(len(m.commands[entry.Category])), nil
+}
+
+func (m *mockContentService) UpdateEntry(entry storage.Entry, actor string) error {
+	if m.unavailable {
+		return services.ErrUnavailable
+	}
+	return nil
 }
 
 // Ensure mockContentService implements ContentService
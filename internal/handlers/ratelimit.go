@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter limits how many commands each user can run per window. A nil
+// RateLimiter, or one with a limit of 0, allows everything.
+type RateLimiter struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	users  map[string]*userWindow
+}
+
+// userWindow counts the commands of a user in the current window
+type userWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter allows limit commands per user per window. A limit of 0
+// disables the limiter until SetLimit enables it.
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+		users:  make(map[string]*userWindow),
+	}
+}
+
+// SetLimit changes the limit and window, starting new windows for every user
+func (r *RateLimiter) SetLimit(limit int, window time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limit = limit
+	r.window = window
+	clear(r.users)
+}
+
+// Allow records a command by userID. When the user is over the limit it
+// returns false and how long until the next command is allowed.
+func (r *RateLimiter) Allow(userID string) (bool, time.Duration) {
+	if r == nil {
+		return true, 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.limit <= 0 || r.window <= 0 {
+		return true, 0
+	}
+
+	now := r.now()
+	w, ok := r.users[userID]
+	if !ok || now.Sub(w.start) >= r.window {
+		r.prune(now)
+		r.users[userID] = &userWindow{start: now, count: 1}
+		return true, 0
+	}
+
+	if w.count >= r.limit {
+		return false, w.start.Add(r.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// prune forgets users whose window has ended, once the map gets large
+func (r *RateLimiter) prune(now time.Time) {
+	if len(r.users) < 1024 {
+		return
+	}
+	for id, w := range r.users {
+		if now.Sub(w.start) >= r.window {
+			delete(r.users, id)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRateLimiter tests the per-user command limit.
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(2, 10*time.Second)
+	limiter.now = func() time.Time { return now }
+
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		user      string
+		wantAllow bool
+		wantRetry time.Duration
+	}{
+		{name: "first command", user: "alice", wantAllow: true},
+		{name: "second command", advance: time.Second, user: "alice", wantAllow: true},
+		{name: "over the limit", advance: time.Second, user: "alice", wantAllow: false, wantRetry: 8 * time.Second},
+		{name: "other user", user: "bob", wantAllow: true},
+		{name: "next window", advance: 8 * time.Second, user: "alice", wantAllow: true},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		allowed, retry := limiter.Allow(step.user)
+		if allowed != step.wantAllow || retry != step.wantRetry {
+			t.Errorf("%s: expected (%v, %s), got (%v, %s)", step.name, step.wantAllow, step.wantRetry, allowed, retry)
+		}
+	}
+}
+
+// TestRateLimiter_Disabled tests that nil and zero limiters allow everything.
+func TestRateLimiter_Disabled(t *testing.T) {
+	for name, limiter := range map[string]*RateLimiter{"nil": nil, "zero": NewRateLimiter(0, time.Minute)} {
+		for i := 0; i < 100; i++ {
+			if allowed, _ := limiter.Allow("alice"); !allowed {
+				t.Fatalf("Expected a %s limiter to allow every command", name)
+			}
+		}
+	}
+}
+
+// TestRateLimiter_SetLimit tests changing the limit at runtime.
+func TestRateLimiter_SetLimit(t *testing.T) {
+	limiter := NewRateLimiter(0, time.Minute)
+	limiter.Allow("alice")
+
+	limiter.SetLimit(1, time.Minute)
+	if allowed, _ := limiter.Allow("alice"); !allowed {
+		t.Fatalf("Expected the first command after enabling the limit to be allowed")
+	}
+	if allowed, _ := limiter.Allow("alice"); allowed {
+		t.Errorf("Expected the second command to be limited")
+	}
+
+	limiter.SetLimit(0, time.Minute)
+	if allowed, _ := limiter.Allow("alice"); !allowed {
+		t.Errorf("Expected commands to be allowed once the limit is disabled")
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// reactionPermissions are needed to add a new reaction to a message
+const reactionPermissions = discordgo.PermissionAddReactions | discordgo.PermissionReadMessageHistory
+
+// permissionSource is implemented by Discord APIs that know the bot's
+// permissions without a request, such as the test fake
+type permissionSource interface {
+	botChannelPermissions(channelID string) (int64, bool)
+}
+
+// botPermissions returns the bot's cached permissions in a channel and
+// whether they are known
+func botPermissions(s DiscordAPI, channelID string) (int64, bool) {
+	switch s := s.(type) {
+	case *discordgo.Session:
+		if s.State == nil || s.State.User == nil {
+			return 0, false
+		}
+		perms, err := s.State.UserChannelPermissions(s.State.User.ID, channelID)
+		return perms, err == nil
+	case permissionSource:
+		return s.botChannelPermissions(channelID)
+	}
+	return 0, false
+}
+
+// canReact reports whether the bot may add reactions in a channel. When its
+// permissions are not cached it assumes so and lets Discord decide.
+func canReact(s DiscordAPI, channelID string) bool {
+	perms, known := botPermissions(s, channelID)
+	return !known || perms&reactionPermissions == reactionPermissions
+}
+
+// isMissingPermissions reports whether a Discord API error is a permission error
+func isMissingPermissions(err error) bool {
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) {
+		return false
+	}
+	if restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeMissingPermissions {
+		return true
+	}
+	return restErr.Response != nil && restErr.Response.StatusCode == http.StatusForbidden
+}
+
+// entryResponse parses the response of an entry, falling back to its raw
+// content when it is invalid
+func entryResponse(entry storage.Entry) storage.Response {
+	response, err := entry.Response()
+	if err != nil {
+		logger.Logger.Warn("Sending invalid entry as text",
+			zap.Int64("entry_id", entry.ID),
+			zap.Error(err))
+		return storage.Response{Text: entry.Content}
+	}
+	return response
+}
+
+// plainText renders an entry as a single message, with its reactions written
+// as emoji, for replies that have no message to react to
+func plainText(entry storage.Entry) string {
+	return entryResponse(entry).Plain()
+}
+
+// sendResponse delivers an entry in reply to a message: its reactions are
+// added to the message and its text is sent to the channel, with a Save
+// button labelled in locale when withSave is set. Reactions the bot isn't allowed to add are
+// sent as emoji with the text instead. The text pings only the mentions
+// allowed, and is split or attached as a file above attachThreshold, see
+// sendText.
+func sendResponse(s DiscordAPI, m *discordgo.MessageCreate, locale string, entry storage.Entry, withSave bool, mentions *discordgo.MessageAllowedMentions, attachThreshold int) error {
+	response := entryResponse(entry)
+
+	var unsent []storage.Reaction
+	if len(response.Reactions) > 0 && !canReact(s, m.ChannelID) {
+		logger.Logger.Info("Missing permission to add reactions, sending them as text",
+			zap.String("channel_id", m.ChannelID))
+		unsent = response.Reactions
+	} else {
+		for i, reaction := range response.Reactions {
+			err := s.MessageReactionAdd(m.ChannelID, m.ID, reaction.APIName())
+			if err == nil {
+				continue
+			}
+			if isMissingPermissions(err) {
+				logger.Logger.Info("Missing permission to add reactions, sending them as text",
+					zap.String("channel_id", m.ChannelID),
+					zap.Error(err))
+				unsent = response.Reactions[i:]
+				break
+			}
+			// e.g. a custom emoji of a guild the bot is not in
+			logger.Logger.Warn("Failed to add reaction",
+				zap.String("emoji", reaction.APIName()),
+				zap.Int64("entry_id", entry.ID),
+				zap.Error(err))
+		}
+	}
+
+	text := storage.Response{Text: response.Text, Reactions: unsent}.Plain()
+	if text == "" {
+		return nil
+	}
+	var components []discordgo.MessageComponent
+	if withSave && entry.ID != 0 {
+		components = saveButton(locale, entry.ID)
+	}
+	_, err := sendText(s, m.ChannelID, text, components, mentions, attachThreshold)
+	return err
+}
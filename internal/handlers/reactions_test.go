@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// TestMessageHandler_Reactions tests replying with reactions and templates.
+func TestMessageHandler_Reactions(t *testing.T) {
+	forbidden := &discordgo.RESTError{
+		Response: &http.Response{StatusCode: http.StatusForbidden},
+		Message:  &discordgo.APIErrorMessage{Code: discordgo.ErrCodeMissingPermissions, Message: "Missing Permissions"},
+	}
+
+	tests := []struct {
+		name          string
+		entryType     storage.EntryType
+		content       string
+		permissions   map[string]int64
+		reactionErr   error
+		wantReactions []string
+		wantMessages  []string
+	}{
+		{
+			name:          "reaction entry",
+			entryType:     storage.EntryReaction,
+			content:       "🐸 <:wooper:123>",
+			wantReactions: []string{"🐸", "wooper:123"},
+		},
+		{
+			name:          "reaction and text template",
+			entryType:     storage.EntryText,
+			content:       "{react:🐸} Wooper!",
+			wantReactions: []string{"🐸"},
+			wantMessages:  []string{"Wooper!"},
+		},
+		{
+			name:          "permission granted",
+			entryType:     storage.EntryReaction,
+			content:       "🐸",
+			permissions:   map[string]int64{"channel-1": discordgo.PermissionSendMessages | reactionPermissions},
+			wantReactions: []string{"🐸"},
+		},
+		{
+			name:         "missing permission is known",
+			entryType:    storage.EntryText,
+			content:      "{react:🐸} Wooper!",
+			permissions:  map[string]int64{"channel-1": discordgo.PermissionSendMessages | discordgo.PermissionAddReactions},
+			wantMessages: []string{"Wooper! 🐸"},
+		},
+		{
+			name:         "missing permission reported by discord",
+			entryType:    storage.EntryReaction,
+			content:      "🐸 wooper:123",
+			reactionErr:  forbidden,
+			wantMessages: []string{"🐸 <:wooper:123>"},
+		},
+		{
+			name:        "other reaction errors are skipped",
+			entryType:   storage.EntryReaction,
+			content:     "wooper:123",
+			reactionErr: errors.New("unknown emoji"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupTestHandler(t)
+			handler.ContentService.(*mockContentService).addTypedCommand("wooper", tt.entryType, tt.content)
+			discord := newFakeDiscord()
+			discord.permissions = tt.permissions
+			discord.reactionErr = tt.reactionErr
+
+			handler.HandleMessage(discord, newTestMessage("!wooper"))
+
+			var reactions []string
+			for _, r := range discord.addedReactions() {
+				if r.ChannelID != "channel-1" || r.MessageID != "message-1" {
+					t.Errorf("Expected reactions on the command message, got %+v", r)
+				}
+				reactions = append(reactions, r.Emoji)
+			}
+			if !slices.Equal(reactions, tt.wantReactions) {
+				t.Errorf("Expected reactions %v, got %v", tt.wantReactions, reactions)
+			}
+
+			var messages []string
+			for _, m := range discord.postedMessages() {
+				messages = append(messages, m.Content)
+			}
+			if !slices.Equal(messages, tt.wantMessages) {
+				t.Errorf("Expected messages %q, got %q", tt.wantMessages, messages)
+			}
+		})
+	}
+}
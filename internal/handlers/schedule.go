@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/scheduler"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// maxSchedulesPerGuild limits the schedules a guild can have
+const maxSchedulesPerGuild = 25
+
+// ScheduleCommand returns the definition of the /schedule admin command
+func ScheduleCommand() *discordgo.ApplicationCommand {
+	permissions := int64(adminPermissions)
+	dmPermission := false
+
+	return &discordgo.ApplicationCommand{
+		Name:                     "schedule",
+		Description:              "Post random content on a schedule",
+		DefaultMemberPermissions: &permissions,
+		DMPermission:             &dmPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Post a random entry of a category on a cron schedule",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "category",
+						Description: "Category to post from",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "cron",
+						Description: "minute hour day month weekday, e.g. 0 9 * * * for every day at 09:00",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "timezone",
+						Description: "IANA time zone, e.g. Europe/Paris (default UTC)",
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "Channel to post in (default this channel)",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the schedules of this server",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove a schedule",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "Schedule ID, see /schedule list",
+						Required:    true,
+					},
+				},
+			},
+		},
+	}
+}
+
+// ScheduleHandler handles the /schedule admin command
+type ScheduleHandler struct {
+	Store          storage.Store
+	ContentService services.ContentService
+	// Locales picks the language of replies, nil for DefaultLocale
+	Locales *Localizer
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+func NewScheduleHandler(store storage.Store, contentService services.ContentService) *ScheduleHandler {
+	return &ScheduleHandler{Store: store, ContentService: contentService, now: time.Now}
+}
+
+// OnInteractionCreate is the discordgo event handler for interactions
+func (h *ScheduleHandler) OnInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	h.HandleInteraction(s, i)
+}
+
+// HandleInteraction processes a /schedule interaction using the given Discord API
+func (h *ScheduleHandler) HandleInteraction(s DiscordAPI, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand || i.ApplicationCommandData().Name != "schedule" {
+		return
+	}
+	locale := h.Locales.ForInteraction(i)
+
+	if !isContentAdmin(i) {
+		logger.Logger.Warn("Unauthorized schedule command",
+			zap.String("user_id", interactionUserID(i)),
+			zap.String("guild_id", i.GuildID))
+		respondEphemeral(s, i, i18n.T(locale, "schedule.forbidden", nil))
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+	sub := options[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reply string
+	switch sub.Name {
+	case "add":
+		reply = h.add(ctx, locale, i, sub.Options)
+	case "list":
+		reply = h.list(ctx, locale, i)
+	case "remove":
+		reply = h.remove(ctx, locale, i, sub.Options)
+	default:
+		return
+	}
+	respondEphemeral(s, i, reply)
+}
+
+// add validates and stores a new schedule
+func (h *ScheduleHandler) add(ctx context.Context, locale string, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	sc := storage.Schedule{
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		Timezone:  "UTC",
+		CreatedBy: interactionUserID(i),
+	}
+	for _, opt := range options {
+		switch opt.Name {
+		case "category":
+			sc.Category = strings.TrimSpace(opt.StringValue())
+		case "cron":
+			sc.Spec = strings.TrimSpace(opt.StringValue())
+		case "timezone":
+			sc.Timezone = strings.TrimSpace(opt.StringValue())
+		case "channel":
+			if id, ok := opt.Value.(string); ok {
+				sc.ChannelID = id
+			}
+		}
+	}
+
+	categories, err := h.ContentService.GetAvailableCategories()
+	if err != nil {
+		logger.Logger.Error("Failed to list categories for schedule", zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+	if !slices.Contains(categories, sc.Category) {
+		return i18n.T(locale, "command.not_found", i18n.Args{
+			"category":   sc.Category,
+			"categories": strings.Join(categories, ", "),
+		})
+	}
+
+	sc, err = scheduler.Prepare(sc, h.now())
+	if err != nil {
+		return i18n.T(locale, "schedule.invalid", i18n.Args{"error": err})
+	}
+
+	existing, err := h.Store.ListSchedules(ctx, sc.GuildID)
+	if err != nil {
+		logger.Logger.Error("Failed to list schedules", zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+	if len(existing) >= maxSchedulesPerGuild {
+		return i18n.T(locale, "schedule.limit", i18n.Args{"count": len(existing)})
+	}
+
+	id, err := h.Store.AddSchedule(ctx, sc)
+	if err != nil {
+		logger.Logger.Error("Failed to add schedule", zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+
+	logger.Logger.Info("Schedule added via slash command",
+		zap.Int64("schedule_id", id),
+		zap.String("guild_id", sc.GuildID),
+		zap.String("channel_id", sc.ChannelID),
+		zap.String("category", sc.Category),
+		zap.String("spec", sc.Spec),
+		zap.String("timezone", sc.Timezone),
+		zap.String("user_id", sc.CreatedBy))
+
+	return i18n.T(locale, "schedule.added", i18n.Args{
+		"id":       id,
+		"category": sc.Category,
+		"channel":  sc.ChannelID,
+		"next":     sc.NextRun.Unix(),
+	})
+}
+
+// list describes the schedules of the guild
+func (h *ScheduleHandler) list(ctx context.Context, locale string, i *discordgo.InteractionCreate) string {
+	schedules, err := h.Store.ListSchedules(ctx, i.GuildID)
+	if err != nil {
+		logger.Logger.Error("Failed to list schedules", zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+	if len(schedules) == 0 {
+		return i18n.T(locale, "schedule.none", nil)
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.T(locale, "schedule.header", nil) + "\n")
+	for _, sc := range schedules {
+		b.WriteString(i18n.T(locale, "schedule.item", i18n.Args{
+			"id":       sc.ID,
+			"category": sc.Category,
+			"channel":  sc.ChannelID,
+			"spec":     sc.Spec,
+			"timezone": sc.Timezone,
+			"next":     sc.NextRun.Unix(),
+		}) + "\n")
+	}
+	return b.String()
+}
+
+// remove deletes a schedule of the guild
+func (h *ScheduleHandler) remove(ctx context.Context, locale string, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	var id int64
+	for _, opt := range options {
+		if opt.Name == "id" {
+			id = opt.IntValue()
+		}
+	}
+
+	err := h.Store.RemoveSchedule(ctx, i.GuildID, id)
+	if errors.Is(err, storage.ErrScheduleNotFound) {
+		return i18n.T(locale, "schedule.not_found", i18n.Args{"id": id})
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to remove schedule", zap.Int64("schedule_id", id), zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+
+	logger.Logger.Info("Schedule removed via slash command",
+		zap.Int64("schedule_id", id),
+		zap.String("guild_id", i.GuildID),
+		zap.String("user_id", interactionUserID(i)))
+	return i18n.T(locale, "schedule.removed", i18n.Args{"id": id})
+}
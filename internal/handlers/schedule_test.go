@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// setupTestScheduleHandler creates a schedule handler on a memory store with wooper content
+func setupTestScheduleHandler(t *testing.T) (*ScheduleHandler, *storage.MemoryStore) {
+	err := logger.Init()
+	if err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(func() {
+		logger.Close()
+	})
+
+	store := storage.NewMemoryStore()
+	store.AddContent(context.Background(), "wooper", "Wooper!")
+
+	handler := NewScheduleHandler(store, services.NewDatabaseServiceWithStore(store))
+	handler.now = func() time.Time { return time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC) }
+	return handler, store
+}
+
+// newTestScheduleInteraction builds a /schedule interaction for a subcommand
+func newTestScheduleInteraction(permissions int64, sub string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	i := newTestContentInteraction(permissions, sub, options...)
+	data := i.Data.(discordgo.ApplicationCommandInteractionData)
+	data.Name = "schedule"
+	i.Data = data
+	return i
+}
+
+// stringOption builds a string option of a subcommand
+func stringOption(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+}
+
+// TestScheduleHandler tests adding, listing and removing schedules.
+func TestScheduleHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions int64
+		sub         string
+		options     []*discordgo.ApplicationCommandInteractionDataOption
+		wantReply   string
+		wantCount   int
+	}{
+		{
+			name:        "add",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "add",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				stringOption("category", "wooper"), stringOption("cron", "0 9 * * *"), stringOption("timezone", "Europe/Paris"),
+				{Name: "channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "channel-2"},
+			},
+			// 09:00 in Paris is 08:00 UTC in March
+			wantReply: "Schedule #2 added: `wooper` in <#channel-2>, next post <t:1772524800:F>.",
+			wantCount: 2,
+		},
+		{
+			name:        "add with an unknown category",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "add",
+			options:     []*discordgo.ApplicationCommandInteractionDataOption{stringOption("category", "dogs"), stringOption("cron", "0 9 * * *")},
+			wantReply:   "Category 'dogs' not found. Available categories: wooper",
+			wantCount:   1,
+		},
+		{
+			name:        "add with an invalid cron expression",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "add",
+			options:     []*discordgo.ApplicationCommandInteractionDataOption{stringOption("category", "wooper"), stringOption("cron", "every day")},
+			wantReply:   "Invalid schedule: invalid cron expression",
+			wantCount:   1,
+		},
+		{
+			name:        "list",
+			permissions: discordgo.PermissionAdministrator,
+			sub:         "list",
+			wantReply:   "• #1 `wooper` in <#channel-1>, `@daily` UTC, next <t:1772496000:R>",
+			wantCount:   1,
+		},
+		{
+			name:        "remove",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "remove",
+			options:     []*discordgo.ApplicationCommandInteractionDataOption{{Name: "id", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(1)}},
+			wantReply:   "Schedule #1 removed.",
+			wantCount:   0,
+		},
+		{
+			name:        "remove an unknown schedule",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "remove",
+			options:     []*discordgo.ApplicationCommandInteractionDataOption{{Name: "id", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(42)}},
+			wantReply:   "Schedule #42 not found.",
+			wantCount:   1,
+		},
+		{
+			name:        "non admins are rejected",
+			permissions: discordgo.PermissionSendMessages,
+			sub:         "remove",
+			options:     []*discordgo.ApplicationCommandInteractionDataOption{{Name: "id", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(1)}},
+			wantReply:   "Manage Server",
+			wantCount:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, store := setupTestScheduleHandler(t)
+			ctx := context.Background()
+			store.AddSchedule(ctx, storage.Schedule{
+				GuildID: "guild-1", ChannelID: "channel-1", Category: "wooper",
+				Spec: "@daily", Timezone: "UTC", NextRun: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+			})
+			discord := newFakeDiscord()
+
+			handler.HandleInteraction(discord, newTestScheduleInteraction(tt.permissions, tt.sub, tt.options...))
+
+			responses := discord.interactionResponses()
+			if len(responses) != 1 {
+				t.Fatalf("Expected 1 response, got %d", len(responses))
+			}
+			if responses[0].Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+				t.Errorf("Expected an ephemeral response")
+			}
+			if !strings.Contains(responses[0].Data.Content, tt.wantReply) {
+				t.Errorf("Expected a reply containing %q, got %q", tt.wantReply, responses[0].Data.Content)
+			}
+
+			schedules, _ := store.ListSchedules(ctx, "guild-1")
+			if len(schedules) != tt.wantCount {
+				t.Errorf("Expected %d schedules, got %+v", tt.wantCount, schedules)
+			}
+		})
+	}
+}
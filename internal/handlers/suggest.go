@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// suggestionButtonPrefix starts the custom ID of the review buttons, which
+// is followed by the action and the suggestion ID, e.g. suggestion:approve:3
+const suggestionButtonPrefix = "suggestion:"
+
+// maxSuggestionLength is the longest suggestion, a Discord message
+const maxSuggestionLength = 2000
+
+// Embed colors of the suggestion states
+const (
+	suggestionPendingColor  = 0xf1c40f
+	suggestionApprovedColor = 0x2ecc71
+	suggestionRejectedColor = 0xe74c3c
+)
+
+// SuggestCommand returns the definition of the /suggest command
+func SuggestCommand() *discordgo.ApplicationCommand {
+	dmPermission := false
+
+	return &discordgo.ApplicationCommand{
+		Name:         "suggest",
+		Description:  "Suggest new content for a category",
+		DMPermission: &dmPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "category",
+				Description: "Category to add the content to",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "content",
+				Description: "Text, link or {react:emoji} to suggest",
+				Required:    true,
+				MaxLength:   maxSuggestionLength,
+			},
+		},
+	}
+}
+
+// SuggestionHandler handles /suggest and the review buttons of the
+// suggestions posted to the review channel
+type SuggestionHandler struct {
+	Store          storage.Store
+	ContentService services.ContentService
+	// ReviewChannelID is where suggestions are posted for review
+	ReviewChannelID string
+	// MaxPending is how many suggestions a user can have awaiting review
+	MaxPending int
+	// DailyLimit is how many suggestions a user can submit per 24 hours
+	DailyLimit int
+	// Locales picks the language of replies, nil for DefaultLocale
+	Locales *Localizer
+
+	now func() time.Time
+}
+
+func NewSuggestionHandler(store storage.Store, contentService services.ContentService, reviewChannelID string, maxPending, dailyLimit int) *SuggestionHandler {
+	return &SuggestionHandler{
+		Store:           store,
+		ContentService:  contentService,
+		ReviewChannelID: reviewChannelID,
+		MaxPending:      maxPending,
+		DailyLimit:      dailyLimit,
+		now:             time.Now,
+	}
+}
+
+// OnInteractionCreate is the discordgo event handler for interactions
+func (h *SuggestionHandler) OnInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	h.HandleInteraction(s, i)
+}
+
+// HandleInteraction processes /suggest and review button presses using the
+// given Discord API
+func (h *SuggestionHandler) HandleInteraction(s DiscordAPI, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		if i.ApplicationCommandData().Name == "suggest" {
+			h.suggest(s, i)
+		}
+	case discordgo.InteractionMessageComponent:
+		if strings.HasPrefix(i.MessageComponentData().CustomID, suggestionButtonPrefix) {
+			h.review(s, i)
+		}
+	}
+}
+
+// suggest stores a suggestion and posts it to the review channel
+func (h *SuggestionHandler) suggest(s DiscordAPI, i *discordgo.InteractionCreate) {
+	locale := h.Locales.ForInteraction(i)
+	sg := storage.Suggestion{
+		GuildID:   i.GuildID,
+		UserID:    interactionUserID(i),
+		CreatedAt: h.now(),
+	}
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "category":
+			sg.Category = strings.TrimSpace(opt.StringValue())
+		case "content":
+			sg.Content = strings.TrimSpace(opt.StringValue())
+		}
+	}
+
+	if err := (storage.Entry{Category: sg.Category, Content: sg.Content}).Validate(); err != nil {
+		respondEphemeral(s, i, i18n.T(locale, "suggestion.invalid", i18n.Args{"error": err}))
+		return
+	}
+	if utf8.RuneCountInString(sg.Content) > maxSuggestionLength {
+		respondEphemeral(s, i, i18n.T(locale, "suggestion.too_long", i18n.Args{"max": maxSuggestionLength}))
+		return
+	}
+
+	categories, err := h.ContentService.GetAvailableCategories()
+	if err != nil {
+		logger.Logger.Error("Failed to list categories for suggestion", zap.Error(err))
+		respondEphemeral(s, i, i18n.T(locale, "unavailable", nil))
+		return
+	}
+	if !slices.Contains(categories, sg.Category) {
+		respondEphemeral(s, i, i18n.T(locale, "command.not_found", i18n.Args{
+			"category":   sg.Category,
+			"categories": strings.Join(categories, ", "),
+		}))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if reply, err := h.checkLimits(ctx, locale, sg.UserID); err != nil {
+		logger.Logger.Error("Failed to count suggestions", zap.String("user_id", sg.UserID), zap.Error(err))
+		respondEphemeral(s, i, i18n.T(locale, "unavailable", nil))
+		return
+	} else if reply != "" {
+		respondEphemeral(s, i, reply)
+		return
+	}
+
+	sg.ID, err = h.Store.AddSuggestion(ctx, sg)
+	if err != nil {
+		logger.Logger.Error("Failed to add suggestion", zap.Error(err))
+		respondEphemeral(s, i, i18n.T(locale, "unavailable", nil))
+		return
+	}
+	sg.Status = storage.SuggestionPending
+
+	log := logger.Logger.With(
+		zap.Int64("suggestion_id", sg.ID),
+		zap.String("category", sg.Category),
+		zap.String("user_id", sg.UserID),
+		zap.String("guild_id", sg.GuildID))
+
+	_, err = s.ChannelMessageSendComplex(h.ReviewChannelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{suggestionEmbed(h.Locales.ForGuild(sg.GuildID), sg)},
+		Components: suggestionButtons(h.Locales.ForGuild(sg.GuildID), sg.ID),
+	})
+	if err != nil {
+		log.Error("Failed to post suggestion for review", zap.Error(err))
+		// Nobody can review it, so it must not count as pending
+		if _, err := h.Store.ReviewSuggestion(ctx, sg.ID, storage.SuggestionRejected, "", h.now()); err != nil {
+			log.Error("Failed to drop unposted suggestion", zap.Error(err))
+		}
+		respondEphemeral(s, i, i18n.T(locale, "suggestion.send_failed", nil))
+		return
+	}
+
+	log.Info("Suggestion submitted")
+	respondEphemeral(s, i, i18n.T(locale, "suggestion.sent", i18n.Args{"id": sg.ID, "category": sg.Category}))
+}
+
+// checkLimits returns why a user can't submit another suggestion, or an
+// empty string when they can
+func (h *SuggestionHandler) checkLimits(ctx context.Context, locale, userID string) (string, error) {
+	pending, err := h.Store.CountSuggestions(ctx, userID, storage.SuggestionPending, time.Time{})
+	if err != nil {
+		return "", err
+	}
+	if pending >= h.MaxPending {
+		return i18n.T(locale, "suggestion.max_pending", i18n.Args{"count": pending}), nil
+	}
+
+	recent, err := h.Store.CountSuggestions(ctx, userID, "", h.now().Add(-24*time.Hour))
+	if err != nil {
+		return "", err
+	}
+	if recent >= h.DailyLimit {
+		return i18n.T(locale, "suggestion.daily_limit", i18n.Args{"count": h.DailyLimit}), nil
+	}
+	return "", nil
+}
+
+// review approves or rejects a suggestion from its review buttons
+func (h *SuggestionHandler) review(s DiscordAPI, i *discordgo.InteractionCreate) {
+	status, id, ok := parseSuggestionButton(i.MessageComponentData().CustomID)
+	if !ok {
+		return
+	}
+
+	reviewerID := interactionUserID(i)
+	locale := h.Locales.ForInteraction(i)
+	if !isContentAdmin(i) {
+		logger.Logger.Warn("Unauthorized suggestion review",
+			zap.Int64("suggestion_id", id),
+			zap.String("user_id", reviewerID),
+			zap.String("guild_id", i.GuildID))
+		respondEphemeral(s, i, i18n.T(locale, "suggestion.forbidden", nil))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sg, err := h.Store.ReviewSuggestion(ctx, id, status, reviewerID, h.now())
+	switch {
+	case errors.Is(err, storage.ErrSuggestionNotFound):
+		respondEphemeral(s, i, i18n.T(locale, "suggestion.not_found", i18n.Args{"id": id}))
+		return
+	case errors.Is(err, storage.ErrSuggestionReviewed):
+		reply := "suggestion.already_rejected"
+		if sg.Status == storage.SuggestionApproved {
+			reply = "suggestion.already_approved"
+		}
+		respondEphemeral(s, i, i18n.T(locale, reply, i18n.Args{"id": id}))
+		return
+	case err != nil:
+		logger.Logger.Error("Failed to review suggestion", zap.Int64("suggestion_id", id), zap.Error(err))
+		respondEphemeral(s, i, i18n.T(locale, "unavailable", nil))
+		return
+	}
+
+	log := logger.Logger.With(
+		zap.Int64("suggestion_id", sg.ID),
+		zap.String("status", string(sg.Status)),
+		zap.String("category", sg.Category),
+		zap.String("reviewer_id", reviewerID))
+	log.Info("Suggestion reviewed", zap.Int64("entry_id", sg.EntryID))
+
+	// Replace the buttons with the outcome so it can't be reviewed twice
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{suggestionEmbed(h.Locales.ForGuild(i.GuildID), sg)},
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		log.Error("Failed to update suggestion message", zap.Error(err))
+	}
+
+	if sg.Status == storage.SuggestionApproved {
+		h.notifyApproved(s, sg, log)
+	}
+}
+
+// notifyApproved tells the submitter their suggestion is live, quoting it.
+// The quote can take the message over the Discord limit, so it is split
+// like long content. Users can close their DMs, so failures are only logged.
+func (h *SuggestionHandler) notifyApproved(s DiscordAPI, sg storage.Suggestion, log *zap.Logger) {
+	channel, err := s.UserChannelCreate(sg.UserID)
+	if err != nil {
+		log.Warn("Failed to open DM with submitter", zap.Error(err))
+		return
+	}
+	message := i18n.T(h.Locales.ForGuild(sg.GuildID), "suggestion.approved_dm", i18n.Args{"id": sg.ID, "category": sg.Category})
+	_, err = sendText(s, channel.ID, message+"\n>>> "+sg.Content, nil, noMentions(), 0)
+	if err != nil {
+		log.Warn("Failed to DM submitter", zap.Error(err))
+	}
+}
+
+// parseSuggestionButton parses the custom ID of a review button
+func parseSuggestionButton(customID string) (storage.SuggestionStatus, int64, bool) {
+	action, rawID, ok := strings.Cut(strings.TrimPrefix(customID, suggestionButtonPrefix), ":")
+	if !ok {
+		return "", 0, false
+	}
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	switch action {
+	case "approve":
+		return storage.SuggestionApproved, id, true
+	case "reject":
+		return storage.SuggestionRejected, id, true
+	}
+	return "", 0, false
+}
+
+// suggestionButtons returns the Approve and Reject buttons of a suggestion
+func suggestionButtons(locale string, id int64) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    i18n.T(locale, "suggestion.approve", nil),
+				Style:    discordgo.SuccessButton,
+				CustomID: fmt.Sprintf("%sapprove:%d", suggestionButtonPrefix, id),
+			},
+			discordgo.Button{
+				Label:    i18n.T(locale, "suggestion.reject", nil),
+				Style:    discordgo.DangerButton,
+				CustomID: fmt.Sprintf("%sreject:%d", suggestionButtonPrefix, id),
+			},
+		}},
+	}
+}
+
+// suggestionEmbed describes a suggestion and its review state
+func suggestionEmbed(locale string, sg storage.Suggestion) *discordgo.MessageEmbed {
+	color := suggestionPendingColor
+	status := i18n.T(locale, "suggestion.pending", nil)
+	switch sg.Status {
+	case storage.SuggestionApproved:
+		color = suggestionApprovedColor
+		status = i18n.T(locale, "suggestion.approved_by", i18n.Args{"reviewer": sg.ReviewerID, "id": sg.EntryID})
+	case storage.SuggestionRejected:
+		color = suggestionRejectedColor
+		status = i18n.T(locale, "suggestion.rejected_by", i18n.Args{"reviewer": sg.ReviewerID})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       i18n.T(locale, "suggestion.title", i18n.Args{"id": sg.ID}),
+		Description: sg.Content,
+		Color:       color,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: i18n.T(locale, "entry.category", nil), Value: "`" + sg.Category + "`", Inline: true},
+			{Name: i18n.T(locale, "suggestion.submitted_by", nil), Value: "<@" + sg.UserID + ">", Inline: true},
+			{Name: i18n.T(locale, "suggestion.status", nil), Value: status},
+		},
+		Timestamp: sg.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// testSuggestionNow is the time seen by the suggestion handler in tests
+var testSuggestionNow = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+// setupTestSuggestionHandler creates a suggestion handler on a memory store
+// with wooper content, allowing 2 pending and 3 daily suggestions
+func setupTestSuggestionHandler(t *testing.T) (*SuggestionHandler, *storage.MemoryStore) {
+	err := logger.Init()
+	if err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(func() {
+		logger.Close()
+	})
+
+	store := storage.NewMemoryStore()
+	store.AddContent(context.Background(), "wooper", "Wooper!")
+
+	handler := NewSuggestionHandler(store, services.NewDatabaseServiceWithStore(store), "review-channel", 2, 3)
+	handler.now = func() time.Time { return testSuggestionNow }
+	return handler, store
+}
+
+// newTestSuggestInteraction builds a /suggest interaction from a user
+func newTestSuggestInteraction(category, content string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-1",
+			Type:      discordgo.InteractionApplicationCommand,
+			ChannelID: "channel-1",
+			GuildID:   "guild-1",
+			Member:    &discordgo.Member{User: &discordgo.User{ID: "user-2", Username: "fan"}},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: "suggest",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					stringOption("category", category),
+					stringOption("content", content),
+				},
+			},
+		},
+	}
+}
+
+// newTestReviewInteraction builds a review button press
+func newTestReviewInteraction(permissions int64, customID string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-2",
+			Type:      discordgo.InteractionMessageComponent,
+			ChannelID: "review-channel",
+			GuildID:   "guild-1",
+			Member: &discordgo.Member{
+				User:        &discordgo.User{ID: "user-1", Username: "admin"},
+				Permissions: permissions,
+			},
+			Data: discordgo.MessageComponentInteractionData{
+				CustomID:      customID,
+				ComponentType: discordgo.ButtonComponent,
+			},
+		},
+	}
+}
+
+// lastResponse returns the content of the last interaction response
+func lastResponse(t *testing.T, fake *fakeDiscord) *discordgo.InteractionResponse {
+	t.Helper()
+	responses := fake.interactionResponses()
+	if len(responses) == 0 {
+		t.Fatal("Expected an interaction response")
+	}
+	return responses[len(responses)-1]
+}
+
+// TestSuggestionHandler_Suggest tests validation, limits and posting to the
+// review channel.
+func TestSuggestionHandler_Suggest(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []time.Time
+		// reviewed is how many of the existing suggestions were reviewed
+		reviewed  int
+		category  string
+		content   string
+		wantReply string
+		wantPost  bool
+	}{
+		{
+			name:      "submitted",
+			category:  "wooper",
+			content:   " Wooper wooper! ",
+			wantReply: "Thanks! Your suggestion #1 for `wooper` was sent to the moderators.",
+			wantPost:  true,
+		},
+		{
+			name:      "longest suggestion in accented letters",
+			category:  "wooper",
+			content:   strings.Repeat("é", maxSuggestionLength),
+			wantReply: "Thanks! Your suggestion #1 for `wooper` was sent to the moderators.",
+			wantPost:  true,
+		},
+		{
+			name:      "too long",
+			category:  "wooper",
+			content:   strings.Repeat("é", maxSuggestionLength+1),
+			wantReply: "Invalid suggestion: content is longer than 2000 characters",
+		},
+		{
+			name:      "unknown category",
+			category:  "cats",
+			content:   "Meow",
+			wantReply: "Category 'cats' not found. Available categories: wooper",
+		},
+		{
+			name:      "empty content",
+			category:  "wooper",
+			content:   "  ",
+			wantReply: "Invalid suggestion: missing content",
+		},
+		{
+			name:      "too many pending",
+			existing:  []time.Time{testSuggestionNow.Add(-72 * time.Hour), testSuggestionNow.Add(-48 * time.Hour)},
+			category:  "wooper",
+			content:   "Wooper!",
+			wantReply: "You already have 2 suggestions awaiting review",
+		},
+		{
+			name:      "daily limit",
+			existing:  []time.Time{testSuggestionNow.Add(-time.Hour), testSuggestionNow.Add(-2 * time.Hour), testSuggestionNow.Add(-3 * time.Hour)},
+			reviewed:  2,
+			category:  "wooper",
+			content:   "Wooper!",
+			wantReply: "You can submit up to 3 suggestions per day",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, store := setupTestSuggestionHandler(t)
+			ctx := context.Background()
+			for i, at := range tt.existing {
+				id, _ := store.AddSuggestion(ctx, storage.Suggestion{UserID: "user-2", Category: "wooper", Content: "Wooper!", CreatedAt: at})
+				if i < tt.reviewed {
+					store.ReviewSuggestion(ctx, id, storage.SuggestionRejected, "user-1", at)
+				}
+			}
+			fake := newFakeDiscord()
+
+			handler.HandleInteraction(fake, newTestSuggestInteraction(tt.category, tt.content))
+
+			resp := lastResponse(t, fake)
+			if !strings.Contains(resp.Data.Content, tt.wantReply) {
+				t.Errorf("Expected reply containing %q, got %q", tt.wantReply, resp.Data.Content)
+			}
+			if resp.Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+				t.Error("Expected an ephemeral reply")
+			}
+
+			posts := fake.sentComplexMessages()
+			if !tt.wantPost {
+				if len(posts) != 0 {
+					t.Errorf("Expected no review post, got %+v", posts)
+				}
+				return
+			}
+			if len(posts) != 1 || posts[0].ChannelID != "review-channel" {
+				t.Fatalf("Expected one post in the review channel, got %+v", posts)
+			}
+			embed := posts[0].Data.Embeds[0]
+			if embed.Description != strings.TrimSpace(tt.content) || embed.Title != "Suggestion #1" {
+				t.Errorf("Unexpected review embed %+v", embed)
+			}
+			row := posts[0].Data.Components[0].(discordgo.ActionsRow)
+			if len(row.Components) != 2 || row.Components[0].(discordgo.Button).CustomID != "suggestion:approve:1" {
+				t.Errorf("Unexpected review buttons %+v", row.Components)
+			}
+			if sg, err := store.GetSuggestion(ctx, 1); err != nil || sg.Status != storage.SuggestionPending || sg.UserID != "user-2" {
+				t.Errorf("Expected a pending suggestion, got %+v (%v)", sg, err)
+			}
+		})
+	}
+}
+
+// TestSuggestionHandler_PostFailure tests that a suggestion which can't be
+// posted for review doesn't stay pending.
+func TestSuggestionHandler_PostFailure(t *testing.T) {
+	handler, store := setupTestSuggestionHandler(t)
+	fake := newFakeDiscord()
+	fake.failSends = 1
+
+	handler.HandleInteraction(fake, newTestSuggestInteraction("wooper", "Wooper!"))
+
+	if resp := lastResponse(t, fake); !strings.Contains(resp.Data.Content, "couldn't send your suggestion") {
+		t.Errorf("Expected a failure reply, got %q", resp.Data.Content)
+	}
+	if n, _ := store.CountSuggestions(context.Background(), "user-2", storage.SuggestionPending, time.Time{}); n != 0 {
+		t.Errorf("Expected no pending suggestion, got %d", n)
+	}
+}
+
+// TestSuggestionHandler_Review tests the Approve and Reject buttons.
+func TestSuggestionHandler_Review(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions int64
+		customID    string
+		reviewed    bool
+		wantStatus  storage.SuggestionStatus
+		wantReply   string
+		wantEntries int
+		wantDM      bool
+	}{
+		{
+			name:        "approve",
+			permissions: discordgo.PermissionManageServer,
+			customID:    "suggestion:approve:1",
+			wantStatus:  storage.SuggestionApproved,
+			wantEntries: 2,
+			wantDM:      true,
+		},
+		{
+			name:        "reject",
+			permissions: discordgo.PermissionManageServer,
+			customID:    "suggestion:reject:1",
+			wantStatus:  storage.SuggestionRejected,
+			wantEntries: 1,
+		},
+		{
+			name:        "not an admin",
+			customID:    "suggestion:approve:1",
+			wantStatus:  storage.SuggestionPending,
+			wantReply:   "You need the Manage Server permission to review suggestions.",
+			wantEntries: 1,
+		},
+		{
+			name:        "already reviewed",
+			permissions: discordgo.PermissionAdministrator,
+			customID:    "suggestion:approve:1",
+			reviewed:    true,
+			wantStatus:  storage.SuggestionRejected,
+			wantReply:   "Suggestion #1 was already rejected.",
+			wantEntries: 1,
+		},
+		{
+			name:        "unknown suggestion",
+			permissions: discordgo.PermissionManageServer,
+			customID:    "suggestion:approve:9",
+			wantStatus:  storage.SuggestionPending,
+			wantReply:   "Suggestion #9 not found.",
+			wantEntries: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, store := setupTestSuggestionHandler(t)
+			ctx := context.Background()
+			id, _ := store.AddSuggestion(ctx, storage.Suggestion{GuildID: "guild-1", UserID: "user-2", Category: "wooper", Content: "Wooper wooper!"})
+			if tt.reviewed {
+				store.ReviewSuggestion(ctx, id, storage.SuggestionRejected, "user-3", testSuggestionNow)
+			}
+			fake := newFakeDiscord()
+
+			handler.HandleInteraction(fake, newTestReviewInteraction(tt.permissions, tt.customID))
+
+			resp := lastResponse(t, fake)
+			if tt.wantReply != "" {
+				if resp.Data.Content != tt.wantReply {
+					t.Errorf("Expected reply %q, got %q", tt.wantReply, resp.Data.Content)
+				}
+			} else {
+				if resp.Type != discordgo.InteractionResponseUpdateMessage || len(resp.Data.Components) != 0 {
+					t.Errorf("Expected the review message updated without buttons, got %+v", resp)
+				}
+				if status := resp.Data.Embeds[0].Fields[2].Value; !strings.Contains(status, "<@user-1>") {
+					t.Errorf("Expected the reviewer in the status, got %q", status)
+				}
+			}
+
+			if sg, _ := store.GetSuggestion(ctx, id); sg.Status != tt.wantStatus {
+				t.Errorf("Expected status %s, got %s", tt.wantStatus, sg.Status)
+			}
+			if n, _ := store.CountContent(ctx, "wooper"); n != tt.wantEntries {
+				t.Errorf("Expected %d wooper entries, got %d", tt.wantEntries, n)
+			}
+
+			sent := fake.postedMessages()
+			if tt.wantDM {
+				if len(sent) != 1 || sent[0].ChannelID != "dm-user-2" || !strings.Contains(sent[0].Content, "was approved") {
+					t.Errorf("Expected a DM to the submitter, got %+v", sent)
+				}
+			} else if len(sent) != 0 {
+				t.Errorf("Expected no DM, got %+v", sent)
+			}
+		})
+	}
+}
+
+// TestSuggestionHandler_ApprovedLongDM tests that the DM quoting a long
+// suggestion is split to fit Discord messages.
+func TestSuggestionHandler_ApprovedLongDM(t *testing.T) {
+	handler, store := setupTestSuggestionHandler(t)
+	content := strings.Repeat("Wooper! ", maxSuggestionLength/8)
+	store.AddSuggestion(context.Background(), storage.Suggestion{GuildID: "guild-1", UserID: "user-2", Category: "wooper", Content: content})
+	fake := newFakeDiscord()
+
+	handler.HandleInteraction(fake, newTestReviewInteraction(discordgo.PermissionManageServer, "suggestion:approve:1"))
+
+	sent := fake.postedMessages()
+	if len(sent) < 2 {
+		t.Fatalf("Expected the DM split in several messages, got %d", len(sent))
+	}
+	var text strings.Builder
+	for _, m := range sent {
+		if m.ChannelID != "dm-user-2" || utf8.RuneCountInString(m.Content) > 2000 {
+			t.Errorf("Expected DMs of at most 2000 characters, got %d in %s", utf8.RuneCountInString(m.Content), m.ChannelID)
+		}
+		text.WriteString(m.Content)
+	}
+	if got := strings.Count(text.String(), "Wooper!"); got != maxSuggestionLength/8 {
+		t.Errorf("Expected the whole suggestion quoted, got %d of %d words", got, maxSuggestionLength/8)
+	}
+}
+
+// TestParseSuggestionButton tests parsing review button custom IDs.
+func TestParseSuggestionButton(t *testing.T) {
+	tests := []struct {
+		customID   string
+		wantStatus storage.SuggestionStatus
+		wantID     int64
+		wantOK     bool
+	}{
+		{"suggestion:approve:12", storage.SuggestionApproved, 12, true},
+		{"suggestion:reject:3", storage.SuggestionRejected, 3, true},
+		{"suggestion:delete:3", "", 0, false},
+		{"suggestion:approve:x", "", 0, false},
+		{"suggestion:approve", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		status, id, ok := parseSuggestionButton(tt.customID)
+		if status != tt.wantStatus || id != tt.wantID || ok != tt.wantOK {
+			t.Errorf("parseSuggestionButton(%q) = %s, %d, %v", tt.customID, status, id, ok)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// maxTopEntries is how many entries /top ranks
+const maxTopEntries = 10
+
+// maxTopPreview is the longest entry shown in /top, in runes
+const maxTopPreview = 80
+
+// TopCommand returns the definition of the /top command
+func TopCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "top",
+		Description: "Show the best rated content",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "category",
+				Description: "Category to rank, all categories when empty",
+			},
+		},
+	}
+}
+
+// TopHandler handles the /top leaderboard
+type TopHandler struct {
+	Store storage.Store
+	// Locales picks the language of replies, nil for DefaultLocale
+	Locales *Localizer
+}
+
+func NewTopHandler(store storage.Store) *TopHandler {
+	return &TopHandler{Store: store}
+}
+
+// OnInteractionCreate is the discordgo event handler for interactions
+func (h *TopHandler) OnInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	h.HandleInteraction(s, i)
+}
+
+// HandleInteraction processes /top using the given Discord API
+func (h *TopHandler) HandleInteraction(s DiscordAPI, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand || i.ApplicationCommandData().Name != "top" {
+		return
+	}
+
+	locale := h.Locales.ForInteraction(i)
+	var category string
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "category" {
+			category = strings.TrimSpace(opt.StringValue())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stats, err := h.Store.EntryStats(ctx, category, maxTopEntries)
+	if err != nil {
+		logger.Logger.Error("Failed to rank entries", zap.String("category", category), zap.Error(err))
+		respondEphemeral(s, i, i18n.T(locale, "unavailable", nil))
+		return
+	}
+	if len(stats) == 0 {
+		if category != "" {
+			respondEphemeral(s, i, i18n.T(locale, "top.not_found", i18n.Args{"category": category}))
+		} else {
+			respondEphemeral(s, i, i18n.T(locale, "top.empty", nil))
+		}
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		// Entries quoted in the leaderboard ping nobody
+		Data: &discordgo.InteractionResponseData{Content: leaderboard(locale, category, stats), AllowedMentions: noMentions()},
+	})
+	if err != nil {
+		logger.Logger.Error("Failed to send leaderboard", zap.String("category", category), zap.Error(err))
+	}
+}
+
+// leaderboard lists stats, sorted by rating
+func leaderboard(locale, category string, stats []storage.EntryStats) string {
+	var b strings.Builder
+	if category != "" {
+		b.WriteString(i18n.T(locale, "top.header_category", i18n.Args{"category": category}))
+	} else {
+		b.WriteString(i18n.T(locale, "top.header", nil))
+	}
+	for rank, st := range stats {
+		fmt.Fprintf(&b, "\n%d. #%d ", rank+1, st.ID)
+		if category == "" {
+			fmt.Fprintf(&b, "`%s` ", st.Category)
+		}
+		fmt.Fprintf(&b, "%s — 👍 %d 👎 %d · %s",
+			shorten(plainText(st.Entry), maxTopPreview), st.Up, st.Down, i18n.N(locale, "top.plays", st.Plays, nil))
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// newTestTopInteraction builds a /top interaction
+func newTestTopInteraction(options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "interaction-1",
+			Type:      discordgo.InteractionApplicationCommand,
+			ChannelID: "channel-1",
+			GuildID:   "guild-1",
+			Member:    &discordgo.Member{User: &discordgo.User{ID: "user-1", Username: "tester"}},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name:    "top",
+				Options: options,
+			},
+		},
+	}
+}
+
+// TestTopHandler tests the /top leaderboard, with plays counted by /command.
+func TestTopHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		empty         bool
+		options       []*discordgo.ApplicationCommandInteractionDataOption
+		wantReply     string
+		wantEphemeral bool
+	}{
+		{
+			name:    "category",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{stringOption("category", "wooper")},
+			wantReply: "Top content in `wooper`:" +
+				"\n1. #2 Wooper wooper! — 👍 2 👎 0 · 0 plays" +
+				"\n2. #1 Wooper! — 👍 1 👎 1 · 1 play",
+		},
+		{
+			name: "all categories",
+			wantReply: "Top content:" +
+				"\n1. #2 `wooper` Wooper wooper! — 👍 2 👎 0 · 0 plays" +
+				"\n2. #1 `wooper` Wooper! — 👍 1 👎 1 · 1 play" +
+				"\n3. #4 `mutsumi` Mutsumi! — 👍 0 👎 0 · 0 plays",
+		},
+		{
+			name:          "missing category",
+			options:       []*discordgo.ApplicationCommandInteractionDataOption{stringOption("category", "missing")},
+			wantReply:     "Category `missing` not found.",
+			wantEphemeral: true,
+		},
+		{
+			name:          "no content",
+			empty:         true,
+			wantReply:     "There is no content yet.",
+			wantEphemeral: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := logger.Init()
+			if err != nil {
+				t.Fatalf("Failed to initialize logger: %v", err)
+			}
+			t.Cleanup(func() {
+				logger.Close()
+			})
+
+			store := storage.NewMemoryStore()
+			ctx := context.Background()
+			if !tt.empty {
+				at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+				store.AddContent(ctx, "wooper", "Wooper!")
+				store.AddContent(ctx, "wooper", "Wooper wooper!")
+				store.AddContent(ctx, "wooper", "Old wooper")
+				store.AddContent(ctx, "mutsumi", "Mutsumi!")
+				// Only entry #1 is live while /command runs
+				store.ApplyChanges(ctx, storage.ChangeSet{Delete: []int64{2, 3}})
+				NewInteractionHandler(services.NewDatabaseServiceWithStore(store)).
+					HandleInteraction(newFakeDiscord(), newTestCommandInteraction("wooper"))
+				store.RestoreEntry(ctx, 2, 0, "admin", at)
+
+				store.Vote(ctx, 1, "user-1", 1, at)
+				store.Vote(ctx, 1, "user-2", -1, at)
+				store.Vote(ctx, 2, "user-1", 1, at)
+				store.Vote(ctx, 2, "user-2", 1, at)
+			}
+			fake := newFakeDiscord()
+
+			NewTopHandler(store).HandleInteraction(fake, newTestTopInteraction(tt.options...))
+
+			resp := lastResponse(t, fake)
+			if resp.Data.Content != tt.wantReply {
+				t.Errorf("Expected reply %q, got %q", tt.wantReply, resp.Data.Content)
+			}
+			if ephemeral := resp.Data.Flags&discordgo.MessageFlagsEphemeral != 0; ephemeral != tt.wantEphemeral {
+				t.Errorf("Expected ephemeral %v, got %v", tt.wantEphemeral, ephemeral)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"mutsumi-bot/internal/i18n"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+	"mutsumi-bot/internal/triggers"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// maxTriggersPerGuild limits the triggers a guild can have
+const maxTriggersPerGuild = 50
+
+// defaultTriggerCooldown is the cooldown of a trigger added without one
+const defaultTriggerCooldown = 30 * time.Second
+
+// TriggerCommand returns the definition of the /trigger admin command
+func TriggerCommand() *discordgo.ApplicationCommand {
+	permissions := int64(adminPermissions)
+	dmPermission := false
+	minCooldown, minChance := 0.0, 1.0
+
+	kinds := make([]*discordgo.ApplicationCommandOptionChoice, len(triggers.Kinds))
+	for i, kind := range triggers.Kinds {
+		kinds[i] = &discordgo.ApplicationCommandOptionChoice{Name: string(kind), Value: string(kind)}
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:                     "trigger",
+		Description:              "Reply with random content to messages matching a pattern",
+		DefaultMemberPermissions: &permissions,
+		DMPermission:             &dmPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Reply with a random entry of a category to matching messages",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "kind",
+						Description: "word, phrase or regex",
+						Required:    true,
+						Choices:     kinds,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "pattern",
+						Description: "Word, phrase or regular expression to match",
+						Required:    true,
+						MaxLength:   triggers.MaxPatternLength,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "category",
+						Description: "Category to reply from",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "Only match in this channel (default every channel)",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "cooldown",
+						Description: "Seconds between two replies in a channel (default 30)",
+						MinValue:    &minCooldown,
+						MaxValue:    86400,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "chance",
+						Description: "Percentage of matching messages to reply to (default 100)",
+						MinValue:    &minChance,
+						MaxValue:    100,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the triggers of this server",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove a trigger",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "Trigger ID, see /trigger list",
+						Required:    true,
+					},
+				},
+			},
+		},
+	}
+}
+
+// TriggerHandler handles the /trigger admin command
+type TriggerHandler struct {
+	Store          storage.Store
+	ContentService services.ContentService
+	// Locales picks the language of replies, nil for DefaultLocale
+	Locales *Localizer
+	// Matcher is invalidated when the triggers of a guild change
+	Matcher *triggers.Matcher
+}
+
+func NewTriggerHandler(store storage.Store, contentService services.ContentService, matcher *triggers.Matcher) *TriggerHandler {
+	return &TriggerHandler{Store: store, ContentService: contentService, Matcher: matcher}
+}
+
+// OnInteractionCreate is the discordgo event handler for interactions
+func (h *TriggerHandler) OnInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	h.HandleInteraction(s, i)
+}
+
+// HandleInteraction processes a /trigger interaction using the given Discord API
+func (h *TriggerHandler) HandleInteraction(s DiscordAPI, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand || i.ApplicationCommandData().Name != "trigger" {
+		return
+	}
+	locale := h.Locales.ForInteraction(i)
+
+	if !isContentAdmin(i) {
+		logger.Logger.Warn("Unauthorized trigger command",
+			zap.String("user_id", interactionUserID(i)),
+			zap.String("guild_id", i.GuildID))
+		respondEphemeral(s, i, i18n.T(locale, "trigger.forbidden", nil))
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+	sub := options[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reply string
+	switch sub.Name {
+	case "add":
+		reply = h.add(ctx, locale, i, sub.Options)
+	case "list":
+		reply = h.list(ctx, locale, i)
+	case "remove":
+		reply = h.remove(ctx, locale, i, sub.Options)
+	default:
+		return
+	}
+	respondEphemeral(s, i, reply)
+}
+
+// add validates and stores a new trigger
+func (h *TriggerHandler) add(ctx context.Context, locale string, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	tr := storage.Trigger{
+		GuildID:   i.GuildID,
+		Cooldown:  defaultTriggerCooldown,
+		Chance:    100,
+		CreatedBy: interactionUserID(i),
+	}
+	for _, opt := range options {
+		switch opt.Name {
+		case "kind":
+			tr.Kind = storage.TriggerKind(opt.StringValue())
+		case "pattern":
+			tr.Pattern = strings.TrimSpace(opt.StringValue())
+		case "category":
+			tr.Category = strings.TrimSpace(opt.StringValue())
+		case "channel":
+			if id, ok := opt.Value.(string); ok {
+				tr.ChannelID = id
+			}
+		case "cooldown":
+			tr.Cooldown = time.Duration(opt.IntValue()) * time.Second
+		case "chance":
+			tr.Chance = int(opt.IntValue())
+		}
+	}
+
+	if _, err := triggers.Compile(tr.Kind, tr.Pattern); err != nil {
+		return i18n.T(locale, "trigger.invalid", i18n.Args{"error": err})
+	}
+	if tr.Chance < 1 || tr.Chance > 100 {
+		return i18n.T(locale, "trigger.invalid_chance", nil)
+	}
+	if tr.Cooldown < 0 {
+		return i18n.T(locale, "trigger.invalid_cooldown", nil)
+	}
+
+	categories, err := h.ContentService.GetAvailableCategories()
+	if err != nil {
+		logger.Logger.Error("Failed to list categories for trigger", zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+	if !slices.Contains(categories, tr.Category) {
+		return i18n.T(locale, "command.not_found", i18n.Args{
+			"category":   tr.Category,
+			"categories": strings.Join(categories, ", "),
+		})
+	}
+
+	existing, err := h.Store.ListTriggers(ctx, tr.GuildID)
+	if err != nil {
+		logger.Logger.Error("Failed to list triggers", zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+	if len(existing) >= maxTriggersPerGuild {
+		return i18n.T(locale, "trigger.limit", i18n.Args{"count": len(existing)})
+	}
+
+	id, err := h.Store.AddTrigger(ctx, tr)
+	if err != nil {
+		logger.Logger.Error("Failed to add trigger", zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+	h.Matcher.Invalidate(tr.GuildID)
+
+	logger.Logger.Info("Trigger added via slash command",
+		zap.Int64("trigger_id", id),
+		zap.String("guild_id", tr.GuildID),
+		zap.String("channel_id", tr.ChannelID),
+		zap.String("kind", string(tr.Kind)),
+		zap.String("pattern", tr.Pattern),
+		zap.String("category", tr.Category),
+		zap.String("user_id", tr.CreatedBy))
+
+	tr.ID = id
+	return i18n.T(locale, "trigger.added", i18n.Args{"trigger": describeTrigger(locale, tr)})
+}
+
+// list describes the triggers of the guild
+func (h *TriggerHandler) list(ctx context.Context, locale string, i *discordgo.InteractionCreate) string {
+	stored, err := h.Store.ListTriggers(ctx, i.GuildID)
+	if err != nil {
+		logger.Logger.Error("Failed to list triggers", zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+	if len(stored) == 0 {
+		return i18n.T(locale, "trigger.none", nil)
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.T(locale, "trigger.header", nil) + "\n")
+	for _, tr := range stored {
+		b.WriteString("• " + describeTrigger(locale, tr) + "\n")
+	}
+	return b.String()
+}
+
+// remove deletes a trigger of the guild
+func (h *TriggerHandler) remove(ctx context.Context, locale string, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	var id int64
+	for _, opt := range options {
+		if opt.Name == "id" {
+			id = opt.IntValue()
+		}
+	}
+
+	err := h.Store.RemoveTrigger(ctx, i.GuildID, id)
+	if errors.Is(err, storage.ErrTriggerNotFound) {
+		return i18n.T(locale, "trigger.not_found", i18n.Args{"id": id})
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to remove trigger", zap.Int64("trigger_id", id), zap.Error(err))
+		return i18n.T(locale, "unavailable", nil)
+	}
+	h.Matcher.Invalidate(i.GuildID)
+
+	logger.Logger.Info("Trigger removed via slash command",
+		zap.Int64("trigger_id", id),
+		zap.String("guild_id", i.GuildID),
+		zap.String("user_id", interactionUserID(i)))
+	return i18n.T(locale, "trigger.removed", i18n.Args{"id": id})
+}
+
+// describeTrigger formats a trigger on one line
+func describeTrigger(locale string, tr storage.Trigger) string {
+	where := i18n.T(locale, "trigger.every_channel", nil)
+	if tr.ChannelID != "" {
+		where = "<#" + tr.ChannelID + ">"
+	}
+	return i18n.T(locale, "trigger.item", i18n.Args{
+		"id":       tr.ID,
+		"kind":     tr.Kind,
+		"pattern":  tr.Pattern,
+		"category": tr.Category,
+		"channel":  where,
+		"cooldown": tr.Cooldown,
+		"chance":   tr.Chance,
+	})
+}
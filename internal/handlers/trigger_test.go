@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+	"mutsumi-bot/internal/triggers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// setupTestTriggerHandler creates a trigger handler on a memory store with wooper content
+func setupTestTriggerHandler(t *testing.T) (*TriggerHandler, *storage.MemoryStore) {
+	err := logger.Init()
+	if err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(func() {
+		logger.Close()
+	})
+
+	store := storage.NewMemoryStore()
+	store.AddContent(context.Background(), "wooper", "Wooper!")
+
+	handler := NewTriggerHandler(store, services.NewDatabaseServiceWithStore(store), triggers.NewMatcher(store, time.Hour))
+	return handler, store
+}
+
+// newTestTriggerInteraction builds a /trigger interaction for a subcommand
+func newTestTriggerInteraction(permissions int64, sub string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	i := newTestContentInteraction(permissions, sub, options...)
+	data := i.Data.(discordgo.ApplicationCommandInteractionData)
+	data.Name = "trigger"
+	i.Data = data
+	return i
+}
+
+// intOption builds an integer option of a subcommand
+func intOption(name string, value int) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionInteger, Value: float64(value)}
+}
+
+// TestTriggerHandler tests adding, listing and removing triggers.
+func TestTriggerHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions int64
+		sub         string
+		options     []*discordgo.ApplicationCommandInteractionDataOption
+		wantReply   string
+		wantCount   int
+	}{
+		{
+			name:        "add",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "add",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				stringOption("kind", "phrase"), stringOption("pattern", " good morning "), stringOption("category", "wooper"),
+				{Name: "channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "channel-2"},
+				intOption("cooldown", 90), intOption("chance", 50),
+			},
+			wantReply: "Trigger added: #2 phrase `good morning` → `wooper` in <#channel-2>, cooldown 1m30s, chance 50%",
+			wantCount: 2,
+		},
+		{
+			name:        "add with defaults",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "add",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				stringOption("kind", "regex"), stringOption("pattern", "^w+oo+per$"), stringOption("category", "wooper"),
+			},
+			wantReply: "in every channel, cooldown 30s, chance 100%",
+			wantCount: 2,
+		},
+		{
+			name:        "add with an invalid regex",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "add",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				stringOption("kind", "regex"), stringOption("pattern", "(wooper"), stringOption("category", "wooper"),
+			},
+			wantReply: "Invalid trigger: invalid regular expression",
+			wantCount: 1,
+		},
+		{
+			name:        "add with an unknown category",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "add",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				stringOption("kind", "word"), stringOption("pattern", "dog"), stringOption("category", "dogs"),
+			},
+			wantReply: "Category 'dogs' not found. Available categories: wooper",
+			wantCount: 1,
+		},
+		{
+			name:        "list",
+			permissions: discordgo.PermissionAdministrator,
+			sub:         "list",
+			wantReply:   "• #1 word `wooper` → `wooper` in every channel, cooldown 0s, chance 100%",
+			wantCount:   1,
+		},
+		{
+			name:        "remove",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "remove",
+			options:     []*discordgo.ApplicationCommandInteractionDataOption{intOption("id", 1)},
+			wantReply:   "Trigger #1 removed.",
+			wantCount:   0,
+		},
+		{
+			name:        "remove an unknown trigger",
+			permissions: discordgo.PermissionManageServer,
+			sub:         "remove",
+			options:     []*discordgo.ApplicationCommandInteractionDataOption{intOption("id", 42)},
+			wantReply:   "Trigger #42 not found.",
+			wantCount:   1,
+		},
+		{
+			name:        "non admins are rejected",
+			permissions: discordgo.PermissionSendMessages,
+			sub:         "remove",
+			options:     []*discordgo.ApplicationCommandInteractionDataOption{intOption("id", 1)},
+			wantReply:   "Manage Server",
+			wantCount:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, store := setupTestTriggerHandler(t)
+			ctx := context.Background()
+			store.AddTrigger(ctx, storage.Trigger{GuildID: "guild-1", Kind: storage.TriggerWord, Pattern: "wooper", Category: "wooper", Chance: 100})
+			discord := newFakeDiscord()
+
+			handler.HandleInteraction(discord, newTestTriggerInteraction(tt.permissions, tt.sub, tt.options...))
+
+			responses := discord.interactionResponses()
+			if len(responses) != 1 {
+				t.Fatalf("Expected 1 response, got %d", len(responses))
+			}
+			if responses[0].Data.Flags&discordgo.MessageFlagsEphemeral == 0 {
+				t.Errorf("Expected an ephemeral response")
+			}
+			if !strings.Contains(responses[0].Data.Content, tt.wantReply) {
+				t.Errorf("Expected a reply containing %q, got %q", tt.wantReply, responses[0].Data.Content)
+			}
+
+			stored, _ := store.ListTriggers(ctx, "guild-1")
+			if len(stored) != tt.wantCount {
+				t.Errorf("Expected %d triggers, got %+v", tt.wantCount, stored)
+			}
+		})
+	}
+}
+
+// TestTriggerHandler_Invalidate tests that changes apply to the matcher at once.
+func TestTriggerHandler_Invalidate(t *testing.T) {
+	handler, _ := setupTestTriggerHandler(t)
+	ctx := context.Background()
+
+	if _, ok, _ := handler.Matcher.Match(ctx, "guild-1", "channel-1", "wooper"); ok {
+		t.Fatalf("Expected no trigger to match yet")
+	}
+	handler.HandleInteraction(newFakeDiscord(), newTestTriggerInteraction(discordgo.PermissionManageServer, "add",
+		stringOption("kind", "word"), stringOption("pattern", "wooper"), stringOption("category", "wooper")))
+	if _, ok, _ := handler.Matcher.Match(ctx, "guild-1", "channel-1", "wooper"); !ok {
+		t.Errorf("Expected the new trigger to match")
+	}
+}
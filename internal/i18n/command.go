@@ -0,0 +1,57 @@
+package i18n
+
+import "github.com/bwmarrin/discordgo"
+
+// LocalizeCommand fills the name and description localizations of a slash
+// command and its options from the messages commands.<command>.name and
+// commands.<command>.description, then commands.<command>.<option>.name and
+// so on for options and subcommands. Only other locales than DefaultLocale
+// are added, as Discord shows the definition itself otherwise.
+func (c *Catalog) LocalizeCommand(cmd *discordgo.ApplicationCommand) {
+	id := "commands." + cmd.Name
+	names, descriptions := c.localizations(id)
+	if len(names) > 0 {
+		cmd.NameLocalizations = &names
+	}
+	if len(descriptions) > 0 {
+		cmd.DescriptionLocalizations = &descriptions
+	}
+	c.localizeOptions(id, cmd.Options)
+}
+
+// localizeOptions fills the localizations of options under id
+func (c *Catalog) localizeOptions(id string, options []*discordgo.ApplicationCommandOption) {
+	for _, opt := range options {
+		optionID := id + "." + opt.Name
+		names, descriptions := c.localizations(optionID)
+		if len(names) > 0 {
+			opt.NameLocalizations = names
+		}
+		if len(descriptions) > 0 {
+			opt.DescriptionLocalizations = descriptions
+		}
+		c.localizeOptions(optionID, opt.Options)
+	}
+}
+
+// localizations returns the translated name and description of id by
+// Discord locale
+func (c *Catalog) localizations(id string) (names, descriptions map[discordgo.Locale]string) {
+	names = map[discordgo.Locale]string{}
+	descriptions = map[discordgo.Locale]string{}
+	for _, locale := range c.Locales()[1:] {
+		if name, ok := c.translation(locale, id+".name"); ok {
+			names[discordgo.Locale(locale)] = name
+		}
+		if description, ok := c.translation(locale, id+".description"); ok {
+			descriptions[discordgo.Locale(locale)] = description
+		}
+	}
+	return names, descriptions
+}
+
+// LocalizeCommand localizes a slash command with the Default catalog, see
+// Catalog.LocalizeCommand
+func LocalizeCommand(cmd *discordgo.ApplicationCommand) {
+	Default.LocalizeCommand(cmd)
+}
@@ -0,0 +1,258 @@
+// Package i18n translates the replies of the bot. Messages are identified by
+// dotted IDs such as help.header and read from one YAML catalog per Discord
+// locale, e.g. fr.yaml. A message is either a string or, when it takes a
+// count, a map of plural forms (one, few, many, other). Messages contain
+// {name} placeholders that are filled in from Args.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLocale is the locale of the source messages, used for anything
+// missing from other catalogs
+const DefaultLocale = "en-US"
+
+//go:embed locales/*.yaml
+var locales embed.FS
+
+// Default is the catalog built into the bot
+var Default = mustLoad(locales)
+
+// pluralForms are the plural categories a message may define
+var pluralForms = []string{"zero", "one", "two", "few", "many", "other"}
+
+// Args are the values of the placeholders of a message, by name
+type Args map[string]any
+
+// Catalog holds the messages of every locale
+type Catalog struct {
+	// messages holds the messages of each locale by ID
+	messages map[string]map[string]message
+}
+
+// message is a translated message, plain or with plural forms
+type message struct {
+	text string
+	// forms holds the plural forms by category, nil for plain messages
+	forms map[string]string
+}
+
+// Load reads the catalogs of fsys, named after their locale, e.g.
+// locales/fr.yaml. The catalog of DefaultLocale is required.
+func Load(fsys fs.FS) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "locales/*.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{messages: map[string]map[string]message{}}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var tree map[string]any
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		messages := map[string]message{}
+		if err := flatten("", tree, messages); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		c.messages[strings.TrimSuffix(path.Base(file), ".yaml")] = messages
+	}
+	if _, ok := c.messages[DefaultLocale]; !ok {
+		return nil, fmt.Errorf("missing catalog for %s", DefaultLocale)
+	}
+	return c, nil
+}
+
+// mustLoad loads the built-in catalogs, which are checked by the tests
+func mustLoad(fsys fs.FS) *Catalog {
+	c, err := Load(fsys)
+	if err != nil {
+		panic("i18n: " + err.Error())
+	}
+	return c
+}
+
+// flatten adds the messages of a YAML tree under prefix, joining nested keys
+// with dots
+func flatten(prefix string, tree map[string]any, messages map[string]message) error {
+	for key, value := range tree {
+		id := key
+		if prefix != "" {
+			id = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case string:
+			messages[id] = message{text: v}
+		case map[string]any:
+			if forms, ok := pluralMessage(v); ok {
+				messages[id] = message{text: forms["other"], forms: forms}
+			} else if err := flatten(id, v, messages); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: expected a string or a map, got %T", id, value)
+		}
+	}
+	return nil
+}
+
+// pluralMessage returns the forms of a map holding only plural forms,
+// including other
+func pluralMessage(tree map[string]any) (map[string]string, bool) {
+	if _, ok := tree["other"]; !ok {
+		return nil, false
+	}
+	forms := make(map[string]string, len(tree))
+	for key, value := range tree {
+		s, ok := value.(string)
+		if !ok || !slices.Contains(pluralForms, key) {
+			return nil, false
+		}
+		forms[key] = s
+	}
+	return forms, true
+}
+
+// Locales returns the locales with a catalog, DefaultLocale first
+func (c *Catalog) Locales() []string {
+	locales := []string{DefaultLocale}
+	for locale := range c.messages {
+		if locale != DefaultLocale {
+			locales = append(locales, locale)
+		}
+	}
+	slices.Sort(locales[1:])
+	return locales
+}
+
+// Match returns the locale of the catalog serving locale: the same locale,
+// or else one of the same language, e.g. en-US for en-GB. It returns ""
+// when there is none.
+func (c *Catalog) Match(locale string) string {
+	if locale == "" {
+		return ""
+	}
+	var sameLanguage string
+	for _, candidate := range c.Locales() {
+		if strings.EqualFold(candidate, locale) {
+			return candidate
+		}
+		if sameLanguage == "" && strings.EqualFold(language(candidate), language(locale)) {
+			sameLanguage = candidate
+		}
+	}
+	return sameLanguage
+}
+
+// Message returns message id in locale with its placeholders filled in.
+// Messages missing from the catalog of locale are taken from DefaultLocale,
+// and unknown messages are returned as their ID.
+func (c *Catalog) Message(locale, id string, args Args) string {
+	msg, _ := c.lookup(locale, id)
+	return fill(msg.text, args)
+}
+
+// Plural returns the form of message id for count in locale, following the
+// plural rules of its language. The count fills the {count} placeholder.
+func (c *Catalog) Plural(locale, id string, count int, args Args) string {
+	msg, locale := c.lookup(locale, id)
+	text := msg.text
+	if form, ok := msg.forms[pluralForm(language(locale), count)]; ok {
+		text = form
+	}
+
+	filled := Args{"count": count}
+	for name, value := range args {
+		filled[name] = value
+	}
+	return fill(text, filled)
+}
+
+// lookup finds a message and the locale it was found in
+func (c *Catalog) lookup(locale, id string) (message, string) {
+	if matched := c.Match(locale); matched != "" {
+		if msg, ok := c.messages[matched][id]; ok {
+			return msg, matched
+		}
+	}
+	if msg, ok := c.messages[DefaultLocale][id]; ok {
+		return msg, DefaultLocale
+	}
+	return message{text: id}, DefaultLocale
+}
+
+// translation returns message id in locale without falling back to another
+// locale
+func (c *Catalog) translation(locale, id string) (string, bool) {
+	msg, ok := c.messages[locale][id]
+	return msg.text, ok
+}
+
+// fill replaces the {name} placeholders of text
+func fill(text string, args Args) string {
+	if len(args) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(args))
+	for name, value := range args {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// language returns the language of a locale, e.g. pt for pt-BR
+func language(locale string) string {
+	lang, _, _ := strings.Cut(locale, "-")
+	return lang
+}
+
+// pluralForm returns the plural category of n in a language. Languages are
+// grouped by their rules for whole numbers; add a group along with the
+// catalog of a language that needs one.
+func pluralForm(lang string, n int) string {
+	switch lang {
+	case "ja", "ko", "zh", "th", "vi", "id":
+		return "other"
+	case "fr", "pt":
+		if n == 0 || n == 1 {
+			return "one"
+		}
+	case "ru", "uk":
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+	}
+	return "other"
+}
+
+// T returns message id of the Default catalog in locale, see Catalog.Message
+func T(locale, id string, args Args) string {
+	return Default.Message(locale, id, args)
+}
+
+// N returns the plural form of message id of the Default catalog for count
+// in locale, see Catalog.Plural
+func N(locale, id string, count int, args Args) string {
+	return Default.Plural(locale, id, count, args)
+}
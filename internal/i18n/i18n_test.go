@@ -0,0 +1,229 @@
+package i18n
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// TestCatalog_Message tests locale matching, fallbacks and placeholders.
+func TestCatalog_Message(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale string
+		id     string
+		args   Args
+		want   string
+	}{
+		{
+			name:   "default locale",
+			locale: "en-US",
+			id:     "command.no_content",
+			args:   Args{"command": "wooper"},
+			want:   "No content available for `wooper`",
+		},
+		{
+			name:   "translated",
+			locale: "fr",
+			id:     "command.not_found",
+			args:   Args{"category": "dogs", "categories": "wooper"},
+			want:   "Catégorie 'dogs' introuvable. Catégories disponibles : wooper",
+		},
+		{
+			name:   "same language",
+			locale: "en-GB",
+			id:     "help.none",
+			want:   "no commands available",
+		},
+		{
+			name:   "unknown locale",
+			locale: "de",
+			id:     "help.none",
+			want:   "no commands available",
+		},
+		{
+			name: "no locale",
+			id:   "help.none",
+			want: "no commands available",
+		},
+		{
+			name:   "unknown message",
+			locale: "fr",
+			id:     "missing.message",
+			want:   "missing.message",
+		},
+		{
+			name:   "unknown placeholder",
+			locale: "en-US",
+			id:     "command.no_content",
+			want:   "No content available for `{command}`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := T(tt.locale, tt.id, tt.args); got != tt.want {
+				t.Errorf("T(%q, %q) = %q, want %q", tt.locale, tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCatalog_Plural tests the plural rules of each language.
+func TestCatalog_Plural(t *testing.T) {
+	tests := []struct {
+		locale string
+		count  int
+		want   string
+	}{
+		{"en-US", 1, "• `!wooper` (1 entry)"},
+		{"en-US", 0, "• `!wooper` (0 entries)"},
+		{"en-US", 2, "• `!wooper` (2 entries)"},
+		{"fr", 0, "• `!wooper` (0 entrée)"},
+		{"fr", 1, "• `!wooper` (1 entrée)"},
+		{"fr", 2, "• `!wooper` (2 entrées)"},
+		{"ja", 1, "• `!wooper`（1 件）"},
+	}
+
+	for _, tt := range tests {
+		if got := N(tt.locale, "help.category", tt.count, Args{"command": "!wooper"}); got != tt.want {
+			t.Errorf("N(%q, %d) = %q, want %q", tt.locale, tt.count, got, tt.want)
+		}
+	}
+
+	forms := map[int]string{1: "one", 2: "few", 5: "many", 11: "many", 22: "few", 101: "one"}
+	for n, want := range forms {
+		if got := pluralForm("ru", n); got != want {
+			t.Errorf("pluralForm(ru, %d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+// placeholder matches the placeholders of a message
+var placeholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// TestCatalogs checks that every translated message exists in the default
+// catalog with the same placeholders, and that command translations are
+// accepted by Discord.
+func TestCatalogs(t *testing.T) {
+	commandName := regexp.MustCompile(`^[-_\p{L}\p{N}]{1,32}$`)
+
+	for _, locale := range Default.Locales() {
+		for id, msg := range Default.messages[locale] {
+			if rest, ok := strings.CutPrefix(id, "commands."); ok {
+				switch {
+				case strings.HasSuffix(rest, ".name"):
+					if !commandName.MatchString(msg.text) || strings.ToLower(msg.text) != msg.text {
+						t.Errorf("%s: %s: %q is not a valid command name", locale, id, msg.text)
+					}
+				case strings.HasSuffix(rest, ".description"):
+					if n := utf8.RuneCountInString(msg.text); n == 0 || n > 100 {
+						t.Errorf("%s: %s: descriptions have 1 to 100 characters, got %d", locale, id, n)
+					}
+				default:
+					t.Errorf("%s: %s: expected a name or a description", locale, id)
+				}
+				continue
+			}
+
+			source, ok := Default.messages[DefaultLocale][id]
+			if !ok {
+				t.Errorf("%s: %s is not in the %s catalog", locale, id, DefaultLocale)
+				continue
+			}
+			want := strings.Join(placeholder.FindAllString(source.text, -1), " ")
+			texts := []string{msg.text}
+			for _, form := range msg.forms {
+				texts = append(texts, form)
+			}
+			for _, text := range texts {
+				got := placeholder.FindAllString(text, -1)
+				for _, p := range got {
+					if !strings.Contains(want, p) {
+						t.Errorf("%s: %s: unknown placeholder %s in %q", locale, id, p, text)
+					}
+				}
+			}
+		}
+	}
+
+	if got := Default.Locales(); len(got) < 2 || got[0] != DefaultLocale {
+		t.Errorf("Expected %s first among several locales, got %v", DefaultLocale, got)
+	}
+}
+
+// TestLoad tests loading invalid catalogs.
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name:    "missing default",
+			files:   fstest.MapFS{"locales/fr.yaml": {Data: []byte("a: b\n")}},
+			wantErr: "missing catalog for en-US",
+		},
+		{
+			name:    "invalid message",
+			files:   fstest.MapFS{"locales/en-US.yaml": {Data: []byte("a:\n  - b\n")}},
+			wantErr: "a: expected a string or a map",
+		},
+		{
+			name:    "invalid yaml",
+			files:   fstest.MapFS{"locales/en-US.yaml": {Data: []byte("a: [\n")}},
+			wantErr: "locales/en-US.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestLocalizeCommand tests filling the localizations of a command.
+func TestLocalizeCommand(t *testing.T) {
+	cmd := &discordgo.ApplicationCommand{
+		Name:        "favorites",
+		Description: "Browse the content you saved",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove an entry from your favorites",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "id", Description: "Entry ID"},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "untranslated", Description: "Not in any catalog"},
+		},
+	}
+
+	LocalizeCommand(cmd)
+
+	if cmd.NameLocalizations == nil || (*cmd.NameLocalizations)[discordgo.French] != "favoris" {
+		t.Errorf("Expected the French name, got %v", cmd.NameLocalizations)
+	}
+	if _, ok := (*cmd.DescriptionLocalizations)[discordgo.EnglishUS]; ok {
+		t.Error("Expected no localization for the default locale")
+	}
+	remove := cmd.Options[0]
+	if remove.NameLocalizations[discordgo.Japanese] != "削除" || remove.DescriptionLocalizations[discordgo.French] != "Retirer une entrée de vos favoris" {
+		t.Errorf("Unexpected subcommand localizations %v %v", remove.NameLocalizations, remove.DescriptionLocalizations)
+	}
+	if id := remove.Options[0]; id.NameLocalizations != nil || id.DescriptionLocalizations[discordgo.French] == "" {
+		t.Errorf("Expected only the description of the id option localized, got %v %v", id.NameLocalizations, id.DescriptionLocalizations)
+	}
+	if other := cmd.Options[1]; other.NameLocalizations != nil || other.DescriptionLocalizations != nil {
+		t.Errorf("Expected no localizations for an untranslated option, got %+v", other)
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"fmt"
 	"os"
 
 	"go.uber.org/zap"
@@ -9,29 +10,19 @@ import (
 
 var Logger *zap.Logger
 
+// level is the minimum level of Logger, changeable at runtime with SetLevel
+var level = zap.NewAtomicLevel()
+
 func Init() error {
 	config := zap.NewProductionConfig()
 
 	// Set log level from environment variable
-	level := os.Getenv("LOG_LEVEL")
-	if level == "" {
-		level = "info"
-	}
-
-	var zapLevel zapcore.Level
-	switch level {
-	case "debug":
-		zapLevel = zapcore.DebugLevel
-	case "info":
-		zapLevel = zapcore.InfoLevel
-	case "warn":
-		zapLevel = zapcore.WarnLevel
-	case "error":
-		zapLevel = zapcore.ErrorLevel
-	default:
+	zapLevel, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
 		zapLevel = zapcore.InfoLevel
 	}
-	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	level.SetLevel(zapLevel)
+	config.Level = level
 
 	// Configure output format
 	config.EncoderConfig.TimeKey = "timestamp"
@@ -41,7 +32,6 @@ func Init() error {
 	config.EncoderConfig.CallerKey = "caller"
 	config.EncoderConfig.StacktraceKey = "stacktrace"
 
-	var err error
 	Logger, err = config.Build()
 	if err != nil {
 		return err
@@ -50,6 +40,36 @@ func Init() error {
 	return nil
 }
 
+// ParseLevel parses debug, info, warn or error. An empty string is info.
+func ParseLevel(name string) (zapcore.Level, error) {
+	switch name {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info", "":
+		return zapcore.InfoLevel, nil
+	case "warn":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+	return zapcore.InfoLevel, fmt.Errorf("unknown log level %q", name)
+}
+
+// SetLevel changes the minimum level of Logger
+func SetLevel(name string) error {
+	l, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(l)
+	return nil
+}
+
+// Level returns the current minimum level of Logger
+func Level() zapcore.Level {
+	return level.Level()
+}
+
 func Close() {
 	if Logger != nil {
 		Logger.Sync()
@@ -3,6 +3,8 @@ package logger
 import (
 	"os"
 	"testing"
+
+	"go.uber.org/zap/zapcore"
 )
 
 // TestInit tests the logger initialization with various log levels.
@@ -79,3 +81,26 @@ func TestLogger_Logging(t *testing.T) {
 	Logger.Warn("Test warning message")
 	Logger.Error("Test error message")
 }
+
+// TestSetLevel tests changing the level of an initialized logger.
+func TestSetLevel(t *testing.T) {
+	os.Unsetenv("LOG_LEVEL")
+	if err := Init(); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer Close()
+
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !Logger.Core().Enabled(zapcore.DebugLevel) {
+		t.Errorf("Expected debug logs to be enabled")
+	}
+
+	if err := SetLevel("loud"); err == nil {
+		t.Errorf("Expected an error for an unknown level")
+	}
+	if Level() != zapcore.DebugLevel {
+		t.Errorf("Expected the level to stay debug, got %s", Level())
+	}
+}
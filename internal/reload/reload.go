@@ -0,0 +1,196 @@
+// Package reload applies configuration changes while the bot is running.
+// A reload is triggered by SIGHUP, by a change of the configuration file or
+// by the authenticated admin HTTP endpoint. Invalid configurations are
+// rejected and the running one is kept.
+package reload
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"mutsumi-bot/internal/config"
+	"mutsumi-bot/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// DefaultPollInterval is how often WatchFile checks the configuration file
+const DefaultPollInterval = 5 * time.Second
+
+// Reloader holds the running configuration and applies new ones
+type Reloader struct {
+	load  func() (config.Config, error)
+	apply func(config.Config)
+
+	mu      sync.Mutex
+	current config.Config
+}
+
+// New creates a reloader for the running configuration. load reads a new
+// configuration and apply puts its reloadable settings in effect.
+func New(current config.Config, load func() (config.Config, error), apply func(config.Config)) *Reloader {
+	return &Reloader{load: load, apply: apply, current: current}
+}
+
+// Current returns the running configuration
+func (r *Reloader) Current() config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Reload loads the configuration and applies the reloadable settings that
+// changed. Settings that need a restart are logged and ignored. source names
+// the trigger in logs.
+func (r *Reloader) Reload(source string) ([]config.Change, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.load()
+	if err != nil {
+		logger.Logger.Warn("Rejected configuration reload, keeping the running configuration",
+			zap.String("source", source),
+			zap.Error(err))
+		return nil, err
+	}
+
+	changes := r.current.Diff(next)
+	applied := 0
+	for _, c := range changes {
+		if !c.Reloadable {
+			logger.Logger.Warn("Configuration change requires a restart",
+				zap.String("source", source),
+				zap.String("key", c.Key),
+				zap.String("old", c.Old),
+				zap.String("new", c.New))
+			continue
+		}
+		applied++
+		logger.Logger.Info("Configuration changed",
+			zap.String("source", source),
+			zap.String("key", c.Key),
+			zap.String("old", c.Old),
+			zap.String("new", c.New))
+	}
+
+	if applied > 0 {
+		r.current = r.current.WithReloadable(next)
+		r.apply(r.current)
+	}
+	logger.Logger.Info("Configuration reloaded",
+		zap.String("source", source),
+		zap.Int("applied", applied),
+		zap.Int("pending_restart", len(changes)-applied))
+	return changes, nil
+}
+
+// WatchSignals starts reloading on every one of signals, usually SIGHUP,
+// until ctx is done
+func (r *Reloader) WatchSignals(ctx context.Context, signals ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				_, _ = r.Reload("signal")
+			}
+		}
+	}()
+}
+
+// WatchFile starts reloading whenever the modification time or size of path
+// changes, checking every interval until ctx is done
+func (r *Reloader) WatchFile(ctx context.Context, path string, interval time.Duration) {
+	last, _ := os.Stat(path)
+	go r.pollFile(ctx, path, last, interval)
+}
+
+// pollFile checks path every interval
+func (r *Reloader) pollFile(ctx context.Context, path string, last os.FileInfo, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if last != nil {
+				logger.Logger.Warn("Configuration file is unreadable", zap.String("path", path), zap.Error(err))
+			}
+			last = nil
+			continue
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+		_, _ = r.Reload("file")
+	}
+}
+
+// reloadResponse is the body returned by the admin endpoint
+type reloadResponse struct {
+	Changes  []config.Change  `json:"changes"`
+	Error    string           `json:"error,omitempty"`
+	Problems []config.Problem `json:"problems,omitempty"`
+}
+
+// Handler returns the admin endpoint: POST reloads the configuration and
+// responds with the changes. Requests must carry "Authorization: Bearer <token>".
+func (r *Reloader) Handler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !authorized(req, token) {
+			logger.Logger.Warn("Unauthorized configuration reload request", zap.String("remote_addr", req.RemoteAddr))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		changes, err := r.Reload("http")
+		resp := reloadResponse{Changes: changes}
+		status := http.StatusOK
+		if err != nil {
+			status = http.StatusUnprocessableEntity
+			resp.Error = err.Error()
+			var verr *config.ValidationError
+			if errors.As(err, &verr) {
+				resp.Problems = verr.Problems
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// authorized checks the bearer token in constant time
+func authorized(req *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
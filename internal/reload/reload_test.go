@@ -0,0 +1,232 @@
+package reload
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/config"
+	"mutsumi-bot/internal/logger"
+)
+
+// testReloader is a reloader whose next configuration is set by the test
+type testReloader struct {
+	*Reloader
+	mu      sync.Mutex
+	next    config.Config
+	loadErr error
+	applied []config.Config
+}
+
+// newTestReloader creates a reloader running the default configuration
+func newTestReloader(t *testing.T) *testReloader {
+	t.Helper()
+	if err := logger.Init(); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(logger.Close)
+
+	current := config.Default()
+	current.DatabaseConnection = "memory://"
+
+	tr := &testReloader{next: current}
+	tr.Reloader = New(current,
+		func() (config.Config, error) {
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			return tr.next, tr.loadErr
+		},
+		func(cfg config.Config) {
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			tr.applied = append(tr.applied, cfg)
+		})
+	return tr
+}
+
+func (tr *testReloader) setNext(fn func(*config.Config), err error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	fn(&tr.next)
+	tr.loadErr = err
+}
+
+func (tr *testReloader) appliedCount() int {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return len(tr.applied)
+}
+
+// TestReload tests which settings are applied and which are kept.
+func TestReload(t *testing.T) {
+	tr := newTestReloader(t)
+	tr.setNext(func(c *config.Config) {
+		c.LogLevel = "debug"
+		c.Bot.Prefix = "?"
+		c.HTTP.HealthPort = 9000
+	}, nil)
+
+	changes, err := tr.Reload("test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %+v", changes)
+	}
+
+	current := tr.Current()
+	if current.LogLevel != "debug" || current.Bot.Prefix != "?" {
+		t.Errorf("Expected reloadable settings to be applied, got %+v", current)
+	}
+	if current.HTTP.HealthPort != 8089 {
+		t.Errorf("Expected the health port to need a restart, got %d", current.HTTP.HealthPort)
+	}
+	if tr.appliedCount() != 1 {
+		t.Errorf("Expected apply to be called once, got %d", tr.appliedCount())
+	}
+
+	// Reloading the same configuration applies nothing
+	if _, err := tr.Reload("test"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tr.appliedCount() != 1 {
+		t.Errorf("Expected no apply without changes, got %d", tr.appliedCount())
+	}
+}
+
+// TestReload_Invalid tests that an invalid configuration keeps the running one.
+func TestReload_Invalid(t *testing.T) {
+	tr := newTestReloader(t)
+	tr.setNext(func(c *config.Config) { c.Bot.Prefix = "?" }, &config.ValidationError{
+		Problems: []config.Problem{{Field: "log_level", Message: "unknown level"}},
+	})
+
+	if _, err := tr.Reload("test"); err == nil {
+		t.Fatalf("Expected an error")
+	}
+	if tr.Current().Bot.Prefix != "!" || tr.appliedCount() != 0 {
+		t.Errorf("Expected the running configuration to be kept")
+	}
+}
+
+// TestHandler tests authentication and responses of the admin endpoint.
+func TestHandler(t *testing.T) {
+	const token = "0123456789abcdef"
+
+	tests := []struct {
+		name       string
+		method     string
+		auth       string
+		loadErr    error
+		wantStatus int
+		wantApply  int
+	}{
+		{name: "reload", method: http.MethodPost, auth: "Bearer " + token, wantStatus: http.StatusOK, wantApply: 1},
+		{name: "missing token", method: http.MethodPost, wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodPost, auth: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "wrong method", method: http.MethodGet, auth: "Bearer " + token, wantStatus: http.StatusMethodNotAllowed},
+		{
+			name:       "invalid configuration",
+			method:     http.MethodPost,
+			auth:       "Bearer " + token,
+			loadErr:    &config.ValidationError{Problems: []config.Problem{{Field: "bot.prefix", Message: "must be non-empty"}}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTestReloader(t)
+			tr.setNext(func(c *config.Config) { c.RateLimit.Commands = 3 }, tt.loadErr)
+
+			req := httptest.NewRequest(tt.method, "/admin/reload", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			tr.Handler(token).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			if tr.appliedCount() != tt.wantApply {
+				t.Errorf("Expected %d applies, got %d", tt.wantApply, tr.appliedCount())
+			}
+
+			if rec.Code == http.StatusOK || rec.Code == http.StatusUnprocessableEntity {
+				var resp reloadResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if tt.loadErr == nil && (len(resp.Changes) != 1 || resp.Changes[0].Key != "rate_limit.commands") {
+					t.Errorf("Unexpected changes %+v", resp.Changes)
+				}
+				if tt.loadErr != nil && len(resp.Problems) != 1 {
+					t.Errorf("Expected the problems in the response, got %+v", resp)
+				}
+			}
+		})
+	}
+}
+
+// TestWatchFile tests that writing the configuration file triggers a reload.
+func TestWatchFile(t *testing.T) {
+	tr := newTestReloader(t)
+	path := filepath.Join(t.TempDir(), "bot.yaml")
+	if err := os.WriteFile(path, []byte("log_level: info\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tr.setNext(func(c *config.Config) { c.LogLevel = "warn" }, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tr.WatchFile(ctx, path, 10*time.Millisecond)
+
+	time.Sleep(30 * time.Millisecond)
+	if tr.appliedCount() != 0 {
+		t.Fatalf("Expected no reload before the file changes")
+	}
+
+	if err := os.WriteFile(path, []byte("log_level: warn\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(time.Second, func() bool { return tr.appliedCount() == 1 }) {
+		t.Errorf("Expected a reload after the file changed")
+	}
+}
+
+// TestWatchSignals tests that SIGHUP triggers a reload.
+func TestWatchSignals(t *testing.T) {
+	tr := newTestReloader(t)
+	tr.setNext(func(c *config.Config) { c.Cache.CategoryTTL = time.Minute }, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tr.WatchSignals(ctx, syscall.SIGUSR1)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(time.Second, func() bool { return tr.appliedCount() == 1 }) {
+		t.Errorf("Expected a reload after the signal")
+	}
+}
+
+// waitFor polls cond until it is true or the timeout expires
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
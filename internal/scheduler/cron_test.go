@@ -0,0 +1,130 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParse_Errors tests that invalid expressions and time zones are rejected.
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		tz      string
+		wantErr string
+	}{
+		{"too few fields", "0 9 * *", "", "expected 5 fields"},
+		{"minute out of range", "60 9 * * *", "", "minute"},
+		{"backwards range", "0 17-9 * * *", "", "backwards"},
+		{"zero step", "*/0 * * * *", "", "step"},
+		{"unknown name", "0 9 * foo *", "", "month"},
+		{"unknown time zone", "0 9 * * *", "Mars/Olympus", "time zone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.spec, tt.tz)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestCron_Next tests the next run computed for various expressions.
+func TestCron_Next(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		spec  string
+		tz    string
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "daily later today",
+			spec:  "0 9 * * *",
+			after: time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC),
+			want:  time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "daily is strictly after",
+			spec:  "@daily",
+			after: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "time zone",
+			spec:  "0 9 * * *",
+			tz:    "Europe/Paris",
+			after: time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 1, 16, 9, 0, 0, 0, paris),
+		},
+		{
+			name:  "across daylight saving time",
+			spec:  "0 9 * * *",
+			tz:    "Europe/Paris",
+			after: time.Date(2026, 3, 28, 12, 0, 0, 0, paris),
+			want:  time.Date(2026, 3, 29, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "hour skipped by daylight saving time",
+			spec:  "30 2 * * *",
+			tz:    "Europe/Paris",
+			after: time.Date(2026, 3, 28, 12, 0, 0, 0, paris),
+			want:  time.Date(2026, 3, 30, 2, 30, 0, 0, paris),
+		},
+		{
+			name:  "steps and lists",
+			spec:  "*/15 9,18 * * *",
+			after: time.Date(2026, 3, 2, 9, 50, 0, 0, time.UTC),
+			want:  time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "weekday names and ranges",
+			spec:  "0 8 * * mon-fri",
+			after: time.Date(2026, 3, 6, 9, 0, 0, 0, time.UTC), // a Friday
+			want:  time.Date(2026, 3, 9, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "sunday as 7",
+			spec:  "0 8 * * 7",
+			after: time.Date(2026, 3, 6, 9, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 3, 8, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "day of month or day of week",
+			spec:  "0 0 13 * fri",
+			after: time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "leap day",
+			spec:  "0 0 29 feb *",
+			after: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "never matches",
+			spec:  "0 0 30 feb *",
+			after: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := Parse(tt.spec, tt.tz)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := cron.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
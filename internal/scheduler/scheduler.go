@@ -0,0 +1,257 @@
+// Package scheduler posts random content to channels on cron schedules.
+// Every replica runs a Scheduler, but only the one holding the scheduler
+// lock posts, so each run happens once.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mutsumi-bot/internal/chunk"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+// LockName is the storage lock held by the replica running schedules
+const LockName = "mutsumi-scheduler"
+
+// lateTolerance is how late a run can be and still count as on time.
+// Later runs, e.g. after a restart, are missed runs.
+const lateTolerance = 5 * time.Minute
+
+// MissedPolicy decides what happens to runs missed while no replica was running
+type MissedPolicy string
+
+const (
+	// MissedRunOnce posts once for any number of missed runs of a schedule
+	MissedRunOnce MissedPolicy = "once"
+	// MissedSkip drops missed runs and waits for the next one
+	MissedSkip MissedPolicy = "skip"
+)
+
+// MissedPolicies lists the supported missed run policies
+var MissedPolicies = []MissedPolicy{MissedRunOnce, MissedSkip}
+
+// ParseMissedPolicy returns the policy with the given name
+func ParseMissedPolicy(name string) (MissedPolicy, error) {
+	for _, p := range MissedPolicies {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown missed run policy %q, expected once or skip", name)
+}
+
+// Sender posts messages to channels; *discordgo.Session satisfies it
+type Sender interface {
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// Options configures a Scheduler
+type Options struct {
+	// PollInterval is how often due schedules are checked
+	PollInterval time.Duration
+	// MissedRuns handles runs missed while the bot was down
+	MissedRuns MissedPolicy
+	// Locale returns the locale of posts in a guild, used to prefer content
+	// in its language; nil posts content of any language
+	Locale func(guildID string) string
+	// Mentions returns who an entry posted in a guild may ping; nil posts
+	// ping nobody
+	Mentions func(guildID string, entry storage.Entry) *discordgo.MessageAllowedMentions
+}
+
+// Scheduler runs the stored schedules
+type Scheduler struct {
+	store   storage.Store
+	content services.ContentService
+	sender  Sender
+	opts    Options
+	now     func() time.Time
+
+	// lock is held while this replica is the leader
+	lock storage.Lock
+}
+
+// New creates a scheduler posting content from content through sender
+func New(store storage.Store, content services.ContentService, sender Sender, opts Options) *Scheduler {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 30 * time.Second
+	}
+	if opts.MissedRuns == "" {
+		opts.MissedRuns = MissedRunOnce
+	}
+	return &Scheduler{store: store, content: content, sender: sender, opts: opts, now: time.Now}
+}
+
+// Prepare validates the cron expression and time zone of a new schedule and
+// sets its first run after now
+func Prepare(sc storage.Schedule, now time.Time) (storage.Schedule, error) {
+	cron, err := Parse(sc.Spec, sc.Timezone)
+	if err != nil {
+		return sc, err
+	}
+	next := cron.Next(now)
+	if next.IsZero() {
+		return sc, fmt.Errorf("cron expression %q never matches", sc.Spec)
+	}
+	sc.NextRun = next.UTC()
+	return sc, nil
+}
+
+// Run checks for due schedules every poll interval until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+	defer s.resign()
+
+	for {
+		s.Tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick runs the due schedules if this replica is, or becomes, the leader
+func (s *Scheduler) Tick(ctx context.Context) {
+	if !s.lead(ctx) {
+		return
+	}
+
+	now := s.now()
+	due, err := s.store.DueSchedules(ctx, now)
+	if err != nil {
+		logger.Logger.Error("Failed to load due schedules", zap.Error(err))
+		return
+	}
+	for _, sc := range due {
+		s.run(ctx, sc, now)
+	}
+}
+
+// lead keeps or acquires the scheduler lock and reports whether it is held
+func (s *Scheduler) lead(ctx context.Context) bool {
+	if s.lock != nil {
+		err := s.lock.Check(ctx)
+		if err == nil {
+			return true
+		}
+		logger.Logger.Warn("Lost the scheduler lock", zap.Error(err))
+		s.resign()
+	}
+
+	lock, err := s.store.TryLock(ctx, LockName)
+	if errors.Is(err, storage.ErrLocked) {
+		logger.Logger.Debug("Another replica runs the schedules")
+		return false
+	}
+	if err != nil {
+		logger.Logger.Warn("Failed to take the scheduler lock", zap.Error(err))
+		return false
+	}
+	logger.Logger.Info("This replica now runs the schedules")
+	s.lock = lock
+	return true
+}
+
+// resign releases the scheduler lock if it is held
+func (s *Scheduler) resign() {
+	if s.lock == nil {
+		return
+	}
+	if err := s.lock.Release(); err != nil {
+		logger.Logger.Warn("Failed to release the scheduler lock", zap.Error(err))
+	}
+	s.lock = nil
+}
+
+// run handles one due schedule: it moves the schedule to its next run, then
+// posts unless the run was missed and missed runs are skipped. Moving first
+// means a run is never posted twice, even if posting fails.
+func (s *Scheduler) run(ctx context.Context, sc storage.Schedule, now time.Time) {
+	log := logger.Logger.With(
+		zap.Int64("schedule_id", sc.ID),
+		zap.String("guild_id", sc.GuildID),
+		zap.String("channel_id", sc.ChannelID),
+		zap.String("category", sc.Category))
+
+	cron, err := Parse(sc.Spec, sc.Timezone)
+	var next time.Time
+	if err == nil {
+		next = cron.Next(now)
+	}
+	if next.IsZero() {
+		log.Error("Removing schedule that can't run again", zap.String("spec", sc.Spec), zap.Error(err))
+		if err := s.store.RemoveSchedule(ctx, sc.GuildID, sc.ID); err != nil {
+			log.Error("Failed to remove schedule", zap.Error(err))
+		}
+		return
+	}
+
+	advanced, err := s.store.AdvanceSchedule(ctx, sc.ID, sc.NextRun, next)
+	if err != nil {
+		log.Error("Failed to advance schedule", zap.Error(err))
+		return
+	}
+	if !advanced {
+		log.Debug("Schedule run already handled")
+		return
+	}
+
+	if late := now.Sub(sc.NextRun); late > lateTolerance {
+		if s.opts.MissedRuns == MissedSkip {
+			log.Info("Skipped missed schedule run", zap.Time("due", sc.NextRun), zap.Time("next", next))
+			return
+		}
+		log.Info("Posting missed schedule run once", zap.Time("due", sc.NextRun), zap.Duration("late", late))
+	}
+
+	s.post(log, sc)
+}
+
+// post sends a random entry of the schedule's category
+func (s *Scheduler) post(log *zap.Logger, sc storage.Schedule) {
+	var locale string
+	if s.opts.Locale != nil {
+		locale = s.opts.Locale(sc.GuildID)
+	}
+	entry, err := s.content.GetRandomEntry(sc.Category, locale)
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound), errors.Is(err, services.ErrEmptyCategory):
+		log.Warn("Scheduled category has no content", zap.Error(err))
+		return
+	case err != nil:
+		log.Error("Content unavailable for scheduled post", zap.Error(err))
+		return
+	}
+
+	content := entry.Content
+	if response, err := entry.Response(); err == nil {
+		content = response.Plain()
+	} else {
+		log.Warn("Posting invalid entry as text", zap.Int64("entry_id", entry.ID), zap.Error(err))
+	}
+	mentions := &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}}
+	if s.opts.Mentions != nil {
+		mentions = s.opts.Mentions(sc.GuildID, entry)
+	}
+
+	// Long content is posted in several messages
+	for _, part := range chunk.Split(content, chunk.Limit) {
+		_, err := s.sender.ChannelMessageSendComplex(sc.ChannelID, &discordgo.MessageSend{Content: part, AllowedMentions: mentions})
+		if err != nil {
+			log.Error("Failed to send scheduled post", zap.Error(err))
+			return
+		}
+	}
+	log.Info("Scheduled post sent")
+}
@@ -0,0 +1,204 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeSender records the messages sent by the scheduler
+type fakeSender struct {
+	mu       sync.Mutex
+	messages []*discordgo.Message
+	mentions []*discordgo.MessageAllowedMentions
+}
+
+func (f *fakeSender) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	msg := &discordgo.Message{ChannelID: channelID, Content: data.Content}
+	f.messages = append(f.messages, msg)
+	f.mentions = append(f.mentions, data.AllowedMentions)
+	return msg, nil
+}
+
+func (f *fakeSender) sent() []*discordgo.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*discordgo.Message(nil), f.messages...)
+}
+
+// setupTestScheduler creates a scheduler on a memory store with wooper content
+func setupTestScheduler(t *testing.T, store *storage.MemoryStore, opts Options) (*Scheduler, *fakeSender) {
+	t.Helper()
+	if err := logger.Init(); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(logger.Close)
+
+	sender := &fakeSender{}
+	s := New(store, services.NewDatabaseServiceWithStore(store), sender, opts)
+	t.Cleanup(s.resign)
+	return s, sender
+}
+
+// addTestSchedule stores a daily 09:00 UTC schedule due at nextRun
+func addTestSchedule(t *testing.T, store storage.Store, category string, nextRun time.Time) int64 {
+	t.Helper()
+	id, err := store.AddSchedule(context.Background(), storage.Schedule{
+		GuildID: "guild-1", ChannelID: "channel-1", Category: category,
+		Spec: "0 9 * * *", Timezone: "UTC", NextRun: nextRun,
+	})
+	if err != nil {
+		t.Fatalf("AddSchedule: %v", err)
+	}
+	return id
+}
+
+// TestScheduler_Tick tests that due schedules post once and move to their next run.
+func TestScheduler_Tick(t *testing.T) {
+	ctx := context.Background()
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		missedRuns MissedPolicy
+		now        time.Time
+		category   string
+		wantPosts  int
+	}{
+		{name: "on time", now: due.Add(20 * time.Second), category: "wooper", wantPosts: 1},
+		{name: "not due yet", now: due.Add(-time.Minute), category: "wooper", wantPosts: 0},
+		{name: "missed run posts once", missedRuns: MissedRunOnce, now: due.Add(72 * time.Hour), category: "wooper", wantPosts: 1},
+		{name: "missed run skipped", missedRuns: MissedSkip, now: due.Add(72 * time.Hour), category: "wooper", wantPosts: 0},
+		{name: "category without content", now: due.Add(time.Second), category: "dogs", wantPosts: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemoryStore()
+			store.AddContent(ctx, "wooper", "Wooper!")
+			s, sender := setupTestScheduler(t, store, Options{MissedRuns: tt.missedRuns})
+			s.now = func() time.Time { return tt.now }
+			addTestSchedule(t, store, tt.category, due)
+
+			s.Tick(ctx)
+			s.Tick(ctx)
+
+			sent := sender.sent()
+			if len(sent) != tt.wantPosts {
+				t.Fatalf("Expected %d posts, got %+v", tt.wantPosts, sent)
+			}
+			if len(sent) > 0 && (sent[0].ChannelID != "channel-1" || sent[0].Content != "Wooper!") {
+				t.Errorf("Unexpected post %+v", sent[0])
+			}
+
+			schedules, _ := store.ListSchedules(ctx, "guild-1")
+			if !schedules[0].NextRun.After(tt.now) && tt.now.After(due) {
+				t.Errorf("Expected the next run after %v, got %v", tt.now, schedules[0].NextRun)
+			}
+		})
+	}
+}
+
+// TestScheduler_Leader tests that only the replica holding the lock posts,
+// and that another one takes over once it is released.
+func TestScheduler_Leader(t *testing.T) {
+	ctx := context.Background()
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	store := storage.NewMemoryStore()
+	store.AddContent(ctx, "wooper", "Wooper!")
+	first, firstSender := setupTestScheduler(t, store, Options{})
+	second, secondSender := setupTestScheduler(t, store, Options{})
+	now := due.Add(time.Second)
+	first.now = func() time.Time { return now }
+	second.now = func() time.Time { return now }
+
+	addTestSchedule(t, store, "wooper", due)
+	first.Tick(ctx)
+	second.Tick(ctx)
+	if len(firstSender.sent()) != 1 || len(secondSender.sent()) != 0 {
+		t.Fatalf("Expected only the leader to post, got %d and %d posts", len(firstSender.sent()), len(secondSender.sent()))
+	}
+
+	// The leader stops; the next run is posted by the other replica
+	first.resign()
+	now = due.Add(24*time.Hour + time.Second)
+	second.Tick(ctx)
+	first.Tick(ctx)
+	if len(firstSender.sent()) != 1 || len(secondSender.sent()) != 1 {
+		t.Errorf("Expected the other replica to take over, got %d and %d posts", len(firstSender.sent()), len(secondSender.sent()))
+	}
+}
+
+// TestScheduler_Mentions tests that posts ping nobody unless Mentions
+// allows it.
+func TestScheduler_Mentions(t *testing.T) {
+	ctx := context.Background()
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	roles := &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{discordgo.AllowedMentionTypeRoles}}
+
+	tests := []struct {
+		name     string
+		mentions func(guildID string, entry storage.Entry) *discordgo.MessageAllowedMentions
+		wantRole bool
+	}{
+		{name: "no policy"},
+		{
+			name: "policy",
+			mentions: func(guildID string, entry storage.Entry) *discordgo.MessageAllowedMentions {
+				if guildID != "guild-1" || entry.Content != "<@&role-1> Wooper!" {
+					t.Errorf("Unexpected guild %q or entry %+v", guildID, entry)
+				}
+				return roles
+			},
+			wantRole: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemoryStore()
+			store.AddContent(ctx, "wooper", "<@&role-1> Wooper!")
+			s, sender := setupTestScheduler(t, store, Options{Mentions: tt.mentions})
+			s.now = func() time.Time { return due.Add(time.Second) }
+			addTestSchedule(t, store, "wooper", due)
+
+			s.Tick(ctx)
+
+			if len(sender.mentions) != 1 || sender.mentions[0] == nil {
+				t.Fatalf("Expected a post with allowed mentions, got %+v", sender.mentions)
+			}
+			if got := len(sender.mentions[0].Parse) > 0; got != tt.wantRole {
+				t.Errorf("Expected role pings %v, got %+v", tt.wantRole, sender.mentions[0])
+			}
+		})
+	}
+}
+
+// TestPrepare tests validation and the first run of new schedules.
+func TestPrepare(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+	sc, err := Prepare(storage.Schedule{Spec: "0 9 * * *", Timezone: "Europe/Paris"}, now)
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	if want := time.Date(2026, 3, 3, 8, 0, 0, 0, time.UTC); !sc.NextRun.Equal(want) {
+		t.Errorf("Expected first run %v, got %v", want, sc.NextRun)
+	}
+
+	for _, spec := range []string{"0 0 31 feb *", "not cron"} {
+		if _, err := Prepare(storage.Schedule{Spec: spec}, now); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
@@ -1,189 +1,453 @@
 package services
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 )
 
 type DatabaseService struct {
-	db *sql.DB
+	store storage.Store
+
+	// queryTimeout bounds each database call, 0 disables it
+	queryTimeout time.Duration
+	// unavailable is set while database calls fail
+	unavailable atomic.Bool
+
+	// categories caches the category list for cacheTTL; categoriesGen
+	// counts invalidations so that a list read before one isn't cached
+	mu            sync.Mutex
+	cacheTTL      time.Duration
+	categories    []string
+	categoriesAt  time.Time
+	categoriesGen uint64
+
+	// selection is how random entries are picked, with the exploration
+	// bonus of SelectPopular
+	selection   SelectionMode
+	exploration float64
 }
 
-// NewDatabaseService creates a new database service with a PostgreSQL connection
+// NewDatabaseService creates a new database service. The storage backend
+// (PostgreSQL, SQLite or in-memory) is chosen by the connection string scheme.
 func NewDatabaseService(connectionString string) (*DatabaseService, error) {
-	logger.Logger.Info("Initializing database service")
+	return NewDatabaseServiceWithOptions(connectionString, storage.Options{})
+}
 
-	db, err := sql.Open("pgx", connectionString)
-	if err != nil {
-		logger.Logger.Error("Failed to open database connection", zap.Error(err))
-		return nil, fmt.Errorf("open database connection: %w", err)
-	}
+// NewDatabaseServiceWithOptions creates a new database service with
+// connection pool settings
+func NewDatabaseServiceWithOptions(connectionString string, opts storage.Options) (*DatabaseService, error) {
+	logger.Logger.Info("Initializing database service",
+		zap.String("backend", storage.Scheme(connectionString)))
 
-	// Test the connection
-	if err := db.Ping(); err != nil {
-		logger.Logger.Error("Failed to ping database", zap.Error(err))
-		db.Close()
-		return nil, fmt.Errorf("ping database: %w", err)
-	}
-
-	// Ensure the table exists
-	if err := ensureTableExists(db); err != nil {
-		logger.Logger.Error("Failed to ensure table exists", zap.Error(err))
-		db.Close()
-		return nil, fmt.Errorf("ensure table exists: %w", err)
+	store, err := storage.OpenWithOptions(context.Background(), connectionString, opts)
+	if err != nil {
+		logger.Logger.Error("Failed to open storage backend", zap.Error(err))
+		return nil, fmt.Errorf("open storage: %w", err)
 	}
 
-	service := &DatabaseService{db: db}
+	service := NewDatabaseServiceWithStore(store)
+	service.queryTimeout = opts.StatementTimeout
 
 	// Log available commands
-	commands := service.GetAvailableCategories()
+	commands, err := service.GetAvailableCategories()
+	if err != nil {
+		logger.Logger.Warn("Failed to list commands", zap.Error(err))
+	}
 	logger.Logger.Info("Database service initialized successfully",
 		zap.Int("total_commands", len(commands)))
 
 	return service, nil
 }
 
-// ensureTableExists creates the commands table if it doesn't exist
-func ensureTableExists(db *sql.DB) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS commands (
-		id SERIAL PRIMARY KEY,
-		command VARCHAR(255) NOT NULL,
-		content TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
+// NewDatabaseServiceWithStore creates a database service on top of an opened store
+func NewDatabaseServiceWithStore(store storage.Store) *DatabaseService {
+	return &DatabaseService{store: store, selection: SelectWeighted, exploration: DefaultExploration}
+}
 
-	CREATE INDEX IF NOT EXISTS idx_command ON commands(command);
-	`
+// context returns a context bounded by the query timeout
+func (s *DatabaseService) context() (context.Context, context.CancelFunc) {
+	if s.queryTimeout > 0 {
+		return context.WithTimeout(context.Background(), s.queryTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
 
-	_, err := db.Exec(query)
-	if err != nil {
-		return fmt.Errorf("create table: %w", err)
+// track records whether a database call succeeded and logs availability changes
+func (s *DatabaseService) track(err error) {
+	failed := err != nil && !errors.Is(err, storage.ErrEntryNotFound)
+	if s.unavailable.Swap(failed) == failed {
+		return
+	}
+	if failed {
+		logger.Logger.Warn("Database became unavailable", zap.Error(err))
+	} else {
+		logger.Logger.Info("Database is available again")
 	}
+}
+
+// Healthy reports whether the last database call succeeded
+func (s *DatabaseService) Healthy() bool {
+	return !s.unavailable.Load()
+}
 
-	logger.Logger.Info("Commands table ensured")
+// Ready returns storage.ErrUnavailable while database calls fail
+func (s *DatabaseService) Ready() error {
+	if !s.Healthy() {
+		return storage.ErrUnavailable
+	}
 	return nil
 }
 
-// getRandomContentInternal retrieves a random content entry for a given command (internal method)
-func (s *DatabaseService) getRandomContentInternal(command string) (string, error) {
-	query := `
-		SELECT content FROM commands
-		WHERE command = $1
-		ORDER BY RANDOM()
-		LIMIT 1
-	`
+// StartHealthCheck pings the database every interval until ctx is done, so
+// that Ready recovers without waiting for user traffic
+func (s *DatabaseService) StartHealthCheck(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = s.Ping()
+			}
+		}
+	}()
+}
+
+// SetCategoryCacheTTL caches the category list for ttl; 0 disables the cache
+func (s *DatabaseService) SetCategoryCacheTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cacheTTL = ttl
+	s.categories = nil
+	s.categoriesGen++
+}
+
+// SetSelection sets how random entries are picked; exploration is only used
+// by SelectPopular
+func (s *DatabaseService) SetSelection(mode SelectionMode, exploration float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.selection = mode
+	s.exploration = exploration
+}
 
-	var content string
-	err := s.db.QueryRow(query, command).Scan(&content)
-	if err == sql.ErrNoRows {
-		logger.Logger.Debug("No content found for command", zap.String("command", command))
-		return "", nil
+// cachedCategories returns the category list, from the cache when it is
+// fresh. The lock isn't held while querying, so a slow database doesn't
+// block the callers served from the cache.
+func (s *DatabaseService) cachedCategories() ([]string, error) {
+	s.mu.Lock()
+	if s.cacheTTL > 0 && s.categories != nil && time.Since(s.categoriesAt) < s.cacheTTL {
+		categories := s.categories
+		s.mu.Unlock()
+		return categories, nil
 	}
+	gen := s.categoriesGen
+	s.mu.Unlock()
+
+	ctx, cancel := s.context()
+	defer cancel()
+	categories, err := s.store.Categories(ctx)
+	s.track(err)
 	if err != nil {
-		logger.Logger.Error("Failed to query database", zap.String("command", command), zap.Error(err))
-		return "", fmt.Errorf("query database: %w", err)
+		return nil, err
 	}
 
-	logger.Logger.Debug("Retrieved content for command",
-		zap.String("command", command),
-		zap.String("content", content))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cacheTTL > 0 && s.categoriesGen == gen {
+		s.categories = categories
+		s.categoriesAt = time.Now()
+	}
+	return categories, nil
+}
 
-	return content, nil
+// InvalidateCategories drops the cached category list after a write, also
+// for writes made directly through the store
+func (s *DatabaseService) InvalidateCategories() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.categories = nil
+	s.categoriesGen++
 }
 
-// getContentCountInternal returns the number of content entries for a command (internal method)
-func (s *DatabaseService) getContentCountInternal(command string) (int, error) {
-	query := `SELECT COUNT(*) FROM commands WHERE command = $1`
+// Store returns the underlying storage backend
+func (s *DatabaseService) Store() storage.Store {
+	return s.store
+}
 
-	var count int
-	err := s.db.QueryRow(query, command).Scan(&count)
-	if err != nil {
-		logger.Logger.Error("Failed to count content", zap.String("command", command), zap.Error(err))
-		return 0, fmt.Errorf("count content: %w", err)
+// Ping checks the database connection
+func (s *DatabaseService) Ping() error {
+	if s.store == nil {
+		return fmt.Errorf("database connection is nil")
 	}
+	ctx, cancel := s.context()
+	defer cancel()
+	err := s.store.Ping(ctx)
+	s.track(err)
+	return err
+}
 
-	return count, nil
+// Close closes the database connection
+func (s *DatabaseService) Close() error {
+	if s.store != nil {
+		return s.store.Close()
+	}
+	return nil
 }
 
-// GetAvailableCategories returns all unique commands from the database
-// Implements ContentService interface
-func (s *DatabaseService) GetAvailableCategories() []string {
-	query := `SELECT DISTINCT command FROM commands ORDER BY command`
+// ContentService interface methods
+
+// unavailable wraps a failed database call in ErrUnavailable
+func unavailable(err error) error {
+	if errors.Is(err, ErrUnavailable) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUnavailable, err)
+}
 
-	rows, err := s.db.Query(query)
+// GetRandomContent returns a random content string for the given command,
+// preferring entries in locale. Reactions are written as emoji, as there is
+// no message to add them to.
+func (s *DatabaseService) GetRandomContent(command, locale string) (string, error) {
+	entry, err := s.GetRandomEntry(command, locale)
+	if err != nil {
+		return "", err
+	}
+	response, err := entry.Response()
 	if err != nil {
-		logger.Logger.Error("Failed to query commands", zap.Error(err))
-		return []string{}
+		logger.Logger.Warn("Sending invalid entry as text",
+			zap.Int64("entry_id", entry.ID),
+			zap.Error(err))
+		return entry.Content, nil
 	}
-	defer rows.Close()
+	return response.Plain(), nil
+}
 
-	var commands []string
-	for rows.Next() {
-		var command string
-		if err := rows.Scan(&command); err != nil {
-			logger.Logger.Error("Failed to scan command", zap.Error(err))
-			continue
+// GetRandomEntry returns a random entry for the given command, preferring
+// entries in locale as storage.PreferLocale does
+func (s *DatabaseService) GetRandomEntry(command, locale string) (storage.Entry, error) {
+	s.mu.Lock()
+	cached := s.cacheTTL > 0
+	mode, exploration := s.selection, s.exploration
+	s.mu.Unlock()
+	if cached {
+		categories, err := s.cachedCategories()
+		if err != nil {
+			return storage.Entry{}, unavailable(err)
+		}
+		if !slices.Contains(categories, command) {
+			return storage.Entry{}, ErrCategoryNotFound
 		}
-		commands = append(commands, command)
 	}
 
-	if err := rows.Err(); err != nil {
-		logger.Logger.Error("Error iterating rows", zap.Error(err))
-		return []string{}
+	ctx, cancel := s.context()
+	defer cancel()
+	var entry storage.Entry
+	var err error
+	if mode == SelectPopular {
+		entry, err = s.store.PopularEntry(ctx, command, locale, exploration)
+	} else {
+		entry, err = s.store.RandomEntry(ctx, command, locale)
+	}
+	s.track(err)
+	if err != nil {
+		return storage.Entry{}, unavailable(err)
+	}
+	if entry.Content != "" {
+		logger.Logger.Debug("Retrieved content for command",
+			zap.String("command", command),
+			zap.String("locale", entry.Locale),
+			zap.String("type", string(entry.Type)),
+			zap.String("content", entry.Content))
+		if err := s.store.RecordPlay(ctx, entry.ID); err != nil && !errors.Is(err, storage.ErrEntryNotFound) {
+			logger.Logger.Warn("Failed to record play", zap.Int64("entry_id", entry.ID), zap.Error(err))
+		}
+		return entry, nil
 	}
 
-	return commands
+	// Nothing was picked: either the category doesn't exist or its
+	// entries are blank
+	count, err := s.GetContentCount(command)
+	if err != nil {
+		return storage.Entry{}, err
+	}
+	if count == 0 {
+		return storage.Entry{}, ErrCategoryNotFound
+	}
+	return storage.Entry{}, ErrEmptyCategory
 }
 
-// HasCategory checks if a command exists in the database
-func (s *DatabaseService) HasCategory(command string) bool {
-	count, err := s.getContentCountInternal(command)
+// GetContentCount returns the number of content entries for a command
+func (s *DatabaseService) GetContentCount(command string) (int, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	count, err := s.store.CountContent(ctx, command)
+	s.track(err)
 	if err != nil {
-		logger.Logger.Error("Failed to check category", zap.String("command", command), zap.Error(err))
-		return false
+		return 0, unavailable(err)
 	}
-	return count > 0
+	return count, nil
 }
 
-// Ping checks the database connection
-func (s *DatabaseService) Ping() error {
-	if s.db == nil {
-		return fmt.Errorf("database connection is nil")
+// GetAvailableCategories returns all unique commands from the database
+func (s *DatabaseService) GetAvailableCategories() ([]string, error) {
+	commands, err := s.cachedCategories()
+	if err != nil {
+		return nil, unavailable(err)
 	}
-	return s.db.Ping()
+	return slices.Clone(commands), nil
 }
 
-// Close closes the database connection
-func (s *DatabaseService) Close() error {
-	if s.db != nil {
-		return s.db.Close()
+// Admin methods, used by the offline CLI and admin commands
+
+// CategoryCount is the number of entries in a category
+type CategoryCount struct {
+	Category string
+	Count    int
+}
+
+// ListEntries returns the entries of a category, or all entries when category is empty
+func (s *DatabaseService) ListEntries(category string) ([]storage.Entry, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.store.ListEntries(ctx, category)
+}
+
+// GetEntry returns a single entry by ID
+func (s *DatabaseService) GetEntry(id int64) (storage.Entry, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.store.GetEntry(ctx, id)
+}
+
+// AddEntry validates and stores a new entry and returns its ID
+func (s *DatabaseService) AddEntry(entry storage.Entry) (int64, error) {
+	if err := entry.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid entry: %w", err)
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	id, err := s.store.AddEntry(ctx, entry)
+	if err != nil {
+		logger.Logger.Error("Failed to add entry", zap.String("command", entry.Category), zap.Error(err))
+		return 0, err
+	}
+	s.InvalidateCategories()
+
+	logger.Logger.Info("Entry added",
+		zap.Int64("id", id),
+		zap.String("command", entry.Category))
+	return id, nil
+}
+
+// UpdateEntry validates and saves the changes to an existing entry on
+// behalf of actor. It fails with storage.ErrEntryNotFound if the entry does
+// not exist or was deleted.
+func (s *DatabaseService) UpdateEntry(entry storage.Entry, actor string) error {
+	if err := entry.Validate(); err != nil {
+		return fmt.Errorf("invalid entry: %w", err)
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	if _, err := s.store.GetEntry(ctx, entry.ID); err != nil {
+		return fmt.Errorf("entry %d: %w", entry.ID, err)
+	}
+	if err := s.store.ApplyChanges(ctx, storage.ChangeSet{Update: []storage.Entry{entry}, Actor: actor}); err != nil {
+		logger.Logger.Error("Failed to update entry", zap.Int64("id", entry.ID), zap.Error(err))
+		return err
 	}
+	s.InvalidateCategories()
+
+	logger.Logger.Info("Entry updated",
+		zap.Int64("id", entry.ID),
+		zap.String("command", entry.Category),
+		zap.String("actor", actor))
 	return nil
 }
 
-// ContentService interface methods
+// RemoveEntries soft-deletes entries by ID on behalf of actor. It fails
+// without deleting anything if any of the IDs does not exist.
+func (s *DatabaseService) RemoveEntries(actor string, ids ...int64) error {
+	ctx, cancel := s.context()
+	defer cancel()
+	for _, id := range ids {
+		if _, err := s.store.GetEntry(ctx, id); err != nil {
+			return fmt.Errorf("entry %d: %w", id, err)
+		}
+	}
+
+	if err := s.store.ApplyChanges(ctx, storage.ChangeSet{Delete: ids, Actor: actor}); err != nil {
+		logger.Logger.Error("Failed to remove entries", zap.Int64s("ids", ids), zap.Error(err))
+		return err
+	}
+	s.InvalidateCategories()
 
-// GetRandomContent returns a random content string for the given command
-func (s *DatabaseService) GetRandomContent(command string) string {
-	content, err := s.getRandomContentInternal(command)
+	logger.Logger.Info("Entries removed", zap.Int64s("ids", ids), zap.String("actor", actor))
+	return nil
+}
+
+// EntryHistory returns the revisions of an entry, oldest first
+func (s *DatabaseService) EntryHistory(id int64) ([]storage.Revision, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.store.EntryHistory(ctx, id)
+}
+
+// RestoreEntry undeletes an entry, or reverts it to before a revision when
+// revisionID is not 0, on behalf of actor
+func (s *DatabaseService) RestoreEntry(id, revisionID int64, actor string) (storage.Revision, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	rev, err := s.store.RestoreEntry(ctx, id, revisionID, actor, time.Now())
 	if err != nil {
-		logger.Logger.Error("Failed to get random content", zap.String("command", command), zap.Error(err))
-		return ""
+		return storage.Revision{}, err
 	}
-	return content
+	s.InvalidateCategories()
+
+	logger.Logger.Info("Entry restored",
+		zap.Int64("id", id),
+		zap.Int64("revision_id", revisionID),
+		zap.String("actor", actor))
+	return rev, nil
 }
 
-// GetContentCount returns the number of content entries for a command
-func (s *DatabaseService) GetContentCount(command string) int {
-	count, err := s.getContentCountInternal(command)
+// CategoryCounts returns every category with its number of entries
+func (s *DatabaseService) CategoryCounts() ([]CategoryCount, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	categories, err := s.store.Categories(ctx)
 	if err != nil {
-		return 0
+		return nil, err
+	}
+
+	counts := make([]CategoryCount, 0, len(categories))
+	for _, category := range categories {
+		count, err := s.store.CountContent(ctx, category)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, CategoryCount{Category: category, Count: count})
 	}
-	return count
+	return counts, nil
+}
+
+// SchemaVersion returns the schema version of the database
+func (s *DatabaseService) SchemaVersion() (int, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.store.SchemaVersion(ctx)
 }
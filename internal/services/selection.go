@@ -0,0 +1,15 @@
+package services
+
+// SelectionMode is how random entries are picked
+type SelectionMode string
+
+const (
+	// SelectWeighted picks entries in proportion to their weight
+	SelectWeighted SelectionMode = "weighted"
+	// SelectPopular also favors entries people voted up, see
+	// storage.EntryStats.PopularWeight
+	SelectPopular SelectionMode = "popular"
+)
+
+// DefaultExploration is the bonus of unplayed entries with SelectPopular
+const DefaultExploration = 0.5
@@ -1,16 +1,46 @@
 package services
 
-// ContentService defines the interface for services that provide content retrieval
+import (
+	"errors"
+
+	"mutsumi-bot/internal/storage"
+)
+
+var (
+	// ErrCategoryNotFound is returned for a category that has no entries
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrEmptyCategory is returned for a category whose entries have no content
+	ErrEmptyCategory = errors.New("category has no content")
+	// ErrUnavailable is returned while the content backend can't be reached
+	ErrUnavailable = storage.ErrUnavailable
+)
+
+// ContentService defines the interface for services that provide and save content
 type ContentService interface {
-	// GetRandomContent returns a random content string for the given command
-	GetRandomContent(command string) string
+	// GetRandomContent returns a random content string for the given command,
+	// with reactions written as emoji. Entries in the language of locale are
+	// preferred, then entries without a locale; an empty locale skips
+	// localized entries unless there are no others. It fails with
+	// ErrCategoryNotFound, ErrEmptyCategory or ErrUnavailable.
+	GetRandomContent(command, locale string) (string, error)
+
+	// GetRandomEntry returns a random entry for the given command, for
+	// callers that deliver reaction entries and templates themselves.
+	// It picks and fails like GetRandomContent.
+	GetRandomEntry(command, locale string) (storage.Entry, error)
 
 	// GetContentCount returns the number of content entries for a command
-	GetContentCount(command string) int
+	GetContentCount(command string) (int, error)
 
 	// GetAvailableCategories returns all available commands
-	GetAvailableCategories() []string
+	GetAvailableCategories() ([]string, error)
+
+	// AddEntry validates and saves a new entry, which may start a new
+	// category, and returns its ID
+	AddEntry(entry storage.Entry) (int64, error)
 
-	// HasCategory checks if a command exists
-	HasCategory(command string) bool
+	// UpdateEntry validates and saves the changes to an existing entry on
+	// behalf of actor. It fails with storage.ErrEntryNotFound if the entry
+	// does not exist or was deleted.
+	UpdateEntry(entry storage.Entry, actor string) error
 }
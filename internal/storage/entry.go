@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultWeight is the selection weight of entries that don't set one
+const DefaultWeight = 1
+
+// MaxContentLength is the number of characters an entry can have. Entries
+// longer than a Discord message are split or sent as a file when served.
+const MaxContentLength = 20000
+
+// ErrEntryNotFound is returned when no entry has the requested ID
+var ErrEntryNotFound = errors.New("entry not found")
+
+// Entry is a single piece of content in a category
+type Entry struct {
+	ID       int64
+	Category string
+	Content  string
+	// Weight is the relative chance of the entry being picked
+	Weight int
+	Tags   []string
+	// Type is how the entry is delivered, EntryText when empty
+	Type EntryType
+	// Locale is the language of the entry, e.g. fr or pt-BR, or empty for
+	// entries that suit every language
+	Locale string
+	// SuggestedBy is the user whose approved suggestion the entry is, empty
+	// for entries added by admins. It is kept when the entry is edited.
+	SuggestedBy string
+}
+
+// localePattern matches the Discord locales entries are tagged with
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})?$`)
+
+// Validate checks that an entry can be stored and served
+func (e Entry) Validate() error {
+	switch {
+	case e.Category == "":
+		return errors.New("missing category")
+	case strings.ContainsAny(e.Category, " \t\r\n"):
+		return fmt.Errorf("category %q contains whitespace", e.Category)
+	case strings.TrimSpace(e.Content) == "":
+		return errors.New("missing content")
+	case !utf8.ValidString(e.Content):
+		return errors.New("content is not valid UTF-8")
+	case utf8.RuneCountInString(e.Content) > MaxContentLength:
+		return fmt.Errorf("content has %d characters, at most %d are allowed", utf8.RuneCountInString(e.Content), MaxContentLength)
+	case e.Weight < 0:
+		return fmt.Errorf("negative weight %d", e.Weight)
+	case e.Locale != "" && !localePattern.MatchString(e.Locale):
+		return fmt.Errorf("invalid locale %q, expected e.g. fr or pt-BR", e.Locale)
+	}
+
+	for _, tag := range e.Tags {
+		if strings.Contains(tag, ",") {
+			return fmt.Errorf("tag %q contains a comma", tag)
+		}
+	}
+	if _, err := e.Response(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ChangeSet is a batch of entry changes applied atomically. Updates and
+// deletes are recorded in the history of their entries; deleted entries are
+// kept and can be restored.
+type ChangeSet struct {
+	Add    []Entry
+	Update []Entry
+	Delete []int64
+	// Actor is who makes the changes, recorded in the history
+	Actor string
+	// At is when the changes are made, the current time when zero
+	At time.Time
+}
+
+// Empty reports whether the change set contains no changes
+func (c ChangeSet) Empty() bool {
+	return len(c.Add) == 0 && len(c.Update) == 0 && len(c.Delete) == 0
+}
+
+// NormalizeTags lowercases, trims, de-duplicates and sorts tags
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// joinTags encodes tags for a single text column
+func joinTags(tags []string) string {
+	return strings.Join(NormalizeTags(tags), ",")
+}
+
+// splitTags decodes a tags column written by joinTags
+func splitTags(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+// weightedPick returns the index of a random item, chosen proportionally to
+// its weight. Weights below 1 count as 1. It returns -1 for an empty slice.
+func weightedPick(weights []int) int {
+	total := 0
+	for _, w := range weights {
+		total += max(w, 1)
+	}
+	if total == 0 {
+		return -1
+	}
+
+	n := rand.IntN(total)
+	for i, w := range weights {
+		n -= max(w, 1)
+		if n < 0 {
+			return i
+		}
+	}
+	return len(weights) - 1
+}
+
+// randomEntry picks one of entries with equal chances, or returns the zero
+// Entry when there are none
+func randomEntry(entries []Entry) Entry {
+	if len(entries) == 0 {
+		return Entry{}
+	}
+	return entries[rand.IntN(len(entries))]
+}
+
+// PreferLocale returns the entries best suited to a reader of locale: those
+// in the language of locale, else those without a locale, else all of them
+// so that the category still answers. An empty locale prefers entries
+// without a locale.
+func PreferLocale(entries []Entry, locale string) []Entry {
+	var matching, neutral []Entry
+	for _, e := range entries {
+		switch {
+		case e.Locale == "":
+			neutral = append(neutral, e)
+		case locale != "" && sameLanguage(e.Locale, locale):
+			matching = append(matching, e)
+		}
+	}
+	switch {
+	case len(matching) > 0:
+		return matching
+	case len(neutral) > 0:
+		return neutral
+	}
+	return entries
+}
+
+// sameLanguage reports whether two locales share a language, e.g. pt-BR and
+// pt-PT
+func sameLanguage(a, b string) bool {
+	langA, _, _ := strings.Cut(a, "-")
+	langB, _, _ := strings.Cut(b, "-")
+	return strings.EqualFold(langA, langB)
+}
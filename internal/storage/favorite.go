@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrFavoriteNotFound is returned when a user hasn't saved the requested
+	// entry
+	ErrFavoriteNotFound = errors.New("favorite not found")
+	// ErrFavoriteExists is returned when a user saves an entry twice
+	ErrFavoriteExists = errors.New("favorite already saved")
+)
+
+// favorite is an entry saved by a user, as kept by MemoryStore
+type favorite struct {
+	UserID  string
+	EntryID int64
+	SavedAt time.Time
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrRevisionNotFound is returned when an entry has no revision with the
+	// requested ID
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrEntryNotDeleted is returned when undeleting an entry that is live
+	ErrEntryNotDeleted = errors.New("entry is not deleted")
+)
+
+// RevisionAction is the kind of change recorded by a revision
+type RevisionAction string
+
+const (
+	RevisionUpdate  RevisionAction = "update"
+	RevisionDelete  RevisionAction = "delete"
+	RevisionRestore RevisionAction = "restore"
+)
+
+// Revision is a recorded change to an entry. Times are stored with second
+// precision.
+type Revision struct {
+	ID      int64
+	EntryID int64
+	Action  RevisionAction
+	// Actor is who made the change: a Discord user ID, or e.g. "cli"
+	Actor string
+	At    time.Time
+	// Before is the entry before the change
+	Before Entry
+	// After is the entry after the change, the zero Entry for deletes
+	After Entry
+}
+
+// Diff describes the fields changed by the revision, one per line
+func (r Revision) Diff() []string {
+	if r.After.ID == 0 {
+		return nil
+	}
+	var diff []string
+	if r.Before.Category != r.After.Category {
+		diff = append(diff, fmt.Sprintf("category: %s → %s", r.Before.Category, r.After.Category))
+	}
+	if r.Before.Content != r.After.Content {
+		diff = append(diff, fmt.Sprintf("content: %q → %q", r.Before.Content, r.After.Content))
+	}
+	if r.Before.Weight != r.After.Weight {
+		diff = append(diff, fmt.Sprintf("weight: %d → %d", r.Before.Weight, r.After.Weight))
+	}
+	if !slices.Equal(r.Before.Tags, r.After.Tags) {
+		diff = append(diff, fmt.Sprintf("tags: [%s] → [%s]", strings.Join(r.Before.Tags, ", "), strings.Join(r.After.Tags, ", ")))
+	}
+	if r.Before.Type.OrDefault() != r.After.Type.OrDefault() {
+		diff = append(diff, fmt.Sprintf("type: %s → %s", r.Before.Type.OrDefault(), r.After.Type.OrDefault()))
+	}
+	if r.Before.Locale != r.After.Locale {
+		diff = append(diff, fmt.Sprintf("locale: %s → %s", localeName(r.Before.Locale), localeName(r.After.Locale)))
+	}
+	return diff
+}
+
+// snapshot is the stored form of an entry in a revision
+type snapshot struct {
+	Category string   `json:"category"`
+	Content  string   `json:"content"`
+	Weight   int      `json:"weight"`
+	Tags     []string `json:"tags,omitempty"`
+	Type     string   `json:"type"`
+	Locale   string   `json:"locale,omitempty"`
+}
+
+// encodeSnapshot stores an entry as JSON, or as an empty string for the
+// zero Entry
+func encodeSnapshot(e Entry) (string, error) {
+	if e.ID == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(snapshot{
+		Category: e.Category,
+		Content:  e.Content,
+		Weight:   e.Weight,
+		Tags:     e.Tags,
+		Type:     string(e.Type.OrDefault()),
+		Locale:   e.Locale,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode revision: %w", err)
+	}
+	return string(b), nil
+}
+
+// decodeSnapshot reads an entry stored by encodeSnapshot
+func decodeSnapshot(id int64, raw string) (Entry, error) {
+	if raw == "" {
+		return Entry{}, nil
+	}
+	var s snapshot
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		return Entry{}, fmt.Errorf("decode revision: %w", err)
+	}
+	return Entry{ID: id, Category: s.Category, Content: s.Content, Weight: s.Weight, Tags: NormalizeTags(s.Tags), Type: EntryType(s.Type), Locale: s.Locale}, nil
+}
+
+// localeName is how a locale shows in a diff, "any" for entries without one
+func localeName(locale string) string {
+	if locale == "" {
+		return "any"
+	}
+	return locale
+}
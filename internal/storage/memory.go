@@ -0,0 +1,767 @@
+package storage
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps content in process memory. Nothing is persisted, which
+// makes it suitable for tests and throwaway deployments.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries []Entry
+	lastID  int64
+	// deleted holds soft-deleted entries by ID
+	deleted map[int64]Entry
+
+	revisions      []Revision
+	lastRevisionID int64
+	mirrored       map[int64]bool
+
+	schedules      []Schedule
+	lastScheduleID int64
+
+	triggers      []Trigger
+	lastTriggerID int64
+
+	suggestions      []Suggestion
+	lastSuggestionID int64
+
+	favorites []favorite
+	// votes holds the vote of each user by entry ID
+	votes map[int64]map[string]int
+	// plays counts how many times each entry was served, by entry ID
+	plays map[int64]int
+	// locales holds the locale of each guild that set one
+	locales map[string]string
+	// roleMentions holds the guilds that let entries added by admins ping
+	// roles
+	roleMentions map[string]bool
+
+	locks localLocks
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{deleted: map[int64]Entry{}, mirrored: map[int64]bool{}, votes: map[int64]map[string]int{}, plays: map[int64]int{}, locales: map[string]string{}, roleMentions: map[string]bool{}}
+}
+
+// RandomContent returns the content of a random entry of a category
+func (m *MemoryStore) RandomContent(ctx context.Context, category string) (string, error) {
+	e, err := m.RandomEntry(ctx, category, "")
+	return e.Content, err
+}
+
+// RandomEntry returns a random entry of a category, preferring entries in
+// locale
+func (m *MemoryStore) RandomEntry(_ context.Context, category, locale string) (Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []Entry
+	for _, e := range m.entries {
+		if e.Category == category {
+			entries = append(entries, e)
+		}
+	}
+	entries = PreferLocale(entries, locale)
+
+	weights := make([]int, len(entries))
+	for i, e := range entries {
+		weights[i] = e.Weight
+	}
+	i := weightedPick(weights)
+	if i < 0 {
+		return Entry{}, nil
+	}
+	e := entries[i]
+	e.Tags = append([]string{}, e.Tags...)
+	return e, nil
+}
+
+// CountContent returns the number of entries in a category
+func (m *MemoryStore) CountContent(_ context.Context, category string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, e := range m.entries {
+		if e.Category == category {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Categories returns all categories with at least one entry
+func (m *MemoryStore) Categories(_ context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	categories := []string{}
+	for _, e := range m.entries {
+		if !seen[e.Category] {
+			seen[e.Category] = true
+			categories = append(categories, e.Category)
+		}
+	}
+	sort.Strings(categories)
+	return categories, nil
+}
+
+// AddContent stores a new entry in a category
+func (m *MemoryStore) AddContent(_ context.Context, category, content string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.add(Entry{Category: category, Content: content, Weight: DefaultWeight}), nil
+}
+
+// AddEntry stores a new entry
+func (m *MemoryStore) AddEntry(_ context.Context, e Entry) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.add(e), nil
+}
+
+// GetEntry returns the entry with the given ID
+func (m *MemoryStore) GetEntry(_ context.Context, id int64) (Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.entries {
+		if e.ID == id {
+			e.Tags = append([]string{}, e.Tags...)
+			return e, nil
+		}
+	}
+	return Entry{}, ErrEntryNotFound
+}
+
+// add stores an entry under a new ID; the caller must hold the write lock
+func (m *MemoryStore) add(e Entry) int64 {
+	m.lastID++
+	e.ID = m.lastID
+	m.entries = append(m.entries, normalizeEntry(e))
+	return e.ID
+}
+
+// normalizeEntry applies the defaults the SQL backends apply on write
+func normalizeEntry(e Entry) Entry {
+	e.Weight = max(e.Weight, DefaultWeight)
+	e.Tags = NormalizeTags(e.Tags)
+	e.Type = e.Type.OrDefault()
+	return e
+}
+
+// record appends a revision; the caller must hold the write lock
+func (m *MemoryStore) record(rev Revision) Revision {
+	m.lastRevisionID++
+	rev.ID = m.lastRevisionID
+	rev.At = truncateSecond(rev.At)
+	rev.Before.Tags = append([]string{}, rev.Before.Tags...)
+	if rev.After.ID != 0 {
+		rev.After.Tags = append([]string{}, rev.After.Tags...)
+	}
+	m.revisions = append(m.revisions, rev)
+	return rev
+}
+
+// ListEntries returns the entries of a category, or of all categories
+func (m *MemoryStore) ListEntries(_ context.Context, category string) ([]Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := []Entry{}
+	for _, e := range m.entries {
+		if category == "" || e.Category == category {
+			e.Tags = append([]string{}, e.Tags...)
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Category != entries[j].Category {
+			return entries[i].Category < entries[j].Category
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// ApplyChanges adds, updates and soft-deletes entries under a single lock
+func (m *MemoryStore) ApplyChanges(_ context.Context, changes ChangeSet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	at := changes.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	deleted := make(map[int64]bool, len(changes.Delete))
+	for _, id := range changes.Delete {
+		deleted[id] = true
+	}
+	updated := make(map[int64]Entry, len(changes.Update))
+	for _, e := range changes.Update {
+		updated[e.ID] = e
+	}
+
+	kept := m.entries[:0]
+	for _, e := range m.entries {
+		if deleted[e.ID] {
+			m.deleted[e.ID] = e
+			m.record(Revision{EntryID: e.ID, Action: RevisionDelete, Actor: changes.Actor, At: at, Before: e})
+			continue
+		}
+		if u, ok := updated[e.ID]; ok {
+			before := e
+			u.ID, u.SuggestedBy = e.ID, e.SuggestedBy
+			e = normalizeEntry(u)
+			m.record(Revision{EntryID: e.ID, Action: RevisionUpdate, Actor: changes.Actor, At: at, Before: before, After: e})
+		}
+		kept = append(kept, e)
+	}
+	m.entries = kept
+
+	for _, e := range changes.Add {
+		m.add(e)
+	}
+	return nil
+}
+
+// EntryHistory returns the revisions of an entry
+func (m *MemoryStore) EntryHistory(_ context.Context, id int64) ([]Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, _, ok := m.lookup(id); !ok {
+		return nil, ErrEntryNotFound
+	}
+	revisions := []Revision{}
+	for _, rev := range m.revisions {
+		if rev.EntryID == id {
+			revisions = append(revisions, rev)
+		}
+	}
+	return revisions, nil
+}
+
+// RestoreEntry undeletes an entry or reverts it to before a revision
+func (m *MemoryStore) RestoreEntry(_ context.Context, id, revisionID int64, actor string, at time.Time) (Revision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, deleted, ok := m.lookup(id)
+	if !ok {
+		return Revision{}, ErrEntryNotFound
+	}
+
+	target := current
+	if revisionID == 0 {
+		if !deleted {
+			return Revision{}, ErrEntryNotDeleted
+		}
+	} else {
+		i := slices.IndexFunc(m.revisions, func(rev Revision) bool { return rev.ID == revisionID && rev.EntryID == id })
+		if i < 0 {
+			return Revision{}, ErrRevisionNotFound
+		}
+		target = m.revisions[i].Before
+	}
+
+	after := normalizeEntry(target)
+	if deleted {
+		delete(m.deleted, id)
+		m.entries = append(m.entries, after)
+	} else {
+		for i := range m.entries {
+			if m.entries[i].ID == id {
+				m.entries[i] = after
+			}
+		}
+	}
+	return m.record(Revision{EntryID: id, Action: RevisionRestore, Actor: actor, At: at, Before: current, After: after}), nil
+}
+
+// lookup finds an entry, deleted or not; the caller must hold the lock
+func (m *MemoryStore) lookup(id int64) (Entry, bool, bool) {
+	if e, ok := m.deleted[id]; ok {
+		return e, true, true
+	}
+	for _, e := range m.entries {
+		if e.ID == id {
+			return e, false, true
+		}
+	}
+	return Entry{}, false, false
+}
+
+// PendingRevisions returns revisions not mirrored yet
+func (m *MemoryStore) PendingRevisions(_ context.Context, since time.Time, limit int) ([]Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	since = truncateSecond(since)
+	revisions := []Revision{}
+	for _, rev := range m.revisions {
+		if len(revisions) == limit {
+			break
+		}
+		if !m.mirrored[rev.ID] && !rev.At.Before(since) {
+			revisions = append(revisions, rev)
+		}
+	}
+	return revisions, nil
+}
+
+// MarkRevisionsMirrored marks revisions as mirrored
+func (m *MemoryStore) MarkRevisionsMirrored(_ context.Context, ids []int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range ids {
+		m.mirrored[id] = true
+	}
+	return nil
+}
+
+// AddSchedule stores a new schedule
+func (m *MemoryStore) AddSchedule(_ context.Context, sc Schedule) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastScheduleID++
+	sc.ID = m.lastScheduleID
+	sc.NextRun, sc.LastRun = truncateSecond(sc.NextRun), truncateSecond(sc.LastRun)
+	m.schedules = append(m.schedules, sc)
+	return sc.ID, nil
+}
+
+// ListSchedules returns the schedules of a guild, or of all guilds
+func (m *MemoryStore) ListSchedules(_ context.Context, guildID string) ([]Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	schedules := []Schedule{}
+	for _, sc := range m.schedules {
+		if guildID == "" || sc.GuildID == guildID {
+			schedules = append(schedules, sc)
+		}
+	}
+	return schedules, nil
+}
+
+// RemoveSchedule deletes a schedule of a guild
+func (m *MemoryStore) RemoveSchedule(_ context.Context, guildID string, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, sc := range m.schedules {
+		if sc.ID == id && sc.GuildID == guildID {
+			m.schedules = append(m.schedules[:i:i], m.schedules[i+1:]...)
+			return nil
+		}
+	}
+	return ErrScheduleNotFound
+}
+
+// DueSchedules returns the schedules due at or before now
+func (m *MemoryStore) DueSchedules(_ context.Context, now time.Time) ([]Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	due := []Schedule{}
+	for _, sc := range m.schedules {
+		if !sc.NextRun.After(now) {
+			due = append(due, sc)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextRun.Before(due[j].NextRun) })
+	return due, nil
+}
+
+// AdvanceSchedule moves a schedule due at due to its next run
+func (m *MemoryStore) AdvanceSchedule(_ context.Context, id int64, due, next time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, sc := range m.schedules {
+		if sc.ID == id && sc.NextRun.Equal(truncateSecond(due)) {
+			m.schedules[i].NextRun = truncateSecond(next)
+			m.schedules[i].LastRun = truncateSecond(due)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AddTrigger stores a new trigger
+func (m *MemoryStore) AddTrigger(_ context.Context, tr Trigger) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastTriggerID++
+	tr.ID = m.lastTriggerID
+	tr.Cooldown = tr.Cooldown.Truncate(time.Second)
+	m.triggers = append(m.triggers, tr)
+	return tr.ID, nil
+}
+
+// ListTriggers returns the triggers of a guild, or of all guilds
+func (m *MemoryStore) ListTriggers(_ context.Context, guildID string) ([]Trigger, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	triggers := []Trigger{}
+	for _, tr := range m.triggers {
+		if guildID == "" || tr.GuildID == guildID {
+			triggers = append(triggers, tr)
+		}
+	}
+	return triggers, nil
+}
+
+// RemoveTrigger deletes a trigger of a guild
+func (m *MemoryStore) RemoveTrigger(_ context.Context, guildID string, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, tr := range m.triggers {
+		if tr.ID == id && tr.GuildID == guildID {
+			m.triggers = append(m.triggers[:i:i], m.triggers[i+1:]...)
+			return nil
+		}
+	}
+	return ErrTriggerNotFound
+}
+
+// AddSuggestion stores a new pending suggestion
+func (m *MemoryStore) AddSuggestion(_ context.Context, sg Suggestion) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastSuggestionID++
+	sg.ID = m.lastSuggestionID
+	sg.Status = SuggestionPending
+	sg.ReviewerID, sg.EntryID, sg.ReviewedAt = "", 0, time.Time{}
+	sg.CreatedAt = truncateSecond(sg.CreatedAt)
+	m.suggestions = append(m.suggestions, sg)
+	return sg.ID, nil
+}
+
+// GetSuggestion returns the suggestion with the given ID
+func (m *MemoryStore) GetSuggestion(_ context.Context, id int64) (Suggestion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, sg := range m.suggestions {
+		if sg.ID == id {
+			return sg, nil
+		}
+	}
+	return Suggestion{}, ErrSuggestionNotFound
+}
+
+// CountSuggestions counts the suggestions of a user created since a time
+func (m *MemoryStore) CountSuggestions(_ context.Context, userID string, status SuggestionStatus, since time.Time) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	since = truncateSecond(since)
+	count := 0
+	for _, sg := range m.suggestions {
+		if sg.UserID == userID && !sg.CreatedAt.Before(since) && (status == "" || sg.Status == status) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ReviewSuggestion approves or rejects a pending suggestion
+func (m *MemoryStore) ReviewSuggestion(_ context.Context, id int64, status SuggestionStatus, reviewerID string, at time.Time) (Suggestion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, sg := range m.suggestions {
+		if sg.ID != id {
+			continue
+		}
+		if sg.Status != SuggestionPending {
+			return sg, ErrSuggestionReviewed
+		}
+		sg.Status, sg.ReviewerID, sg.ReviewedAt = status, reviewerID, truncateSecond(at)
+		if status == SuggestionApproved {
+			sg.EntryID = m.add(Entry{Category: sg.Category, Content: sg.Content, SuggestedBy: sg.UserID})
+		}
+		m.suggestions[i] = sg
+		return sg, nil
+	}
+	return Suggestion{}, ErrSuggestionNotFound
+}
+
+// AddFavorite saves a live entry for a user
+func (m *MemoryStore) AddFavorite(_ context.Context, userID string, entryID int64, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, deleted, ok := m.lookup(entryID); !ok || deleted {
+		return ErrEntryNotFound
+	}
+	for _, f := range m.favorites {
+		if f.UserID == userID && f.EntryID == entryID {
+			return ErrFavoriteExists
+		}
+	}
+	m.favorites = append(m.favorites, favorite{UserID: userID, EntryID: entryID, SavedAt: truncateSecond(at)})
+	return nil
+}
+
+// ListFavorites returns the live entries saved by a user
+func (m *MemoryStore) ListFavorites(_ context.Context, userID string) ([]Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var saved []favorite
+	for _, f := range m.favorites {
+		if f.UserID == userID {
+			saved = append(saved, f)
+		}
+	}
+	sort.Slice(saved, func(i, j int) bool {
+		if !saved[i].SavedAt.Equal(saved[j].SavedAt) {
+			return saved[i].SavedAt.After(saved[j].SavedAt)
+		}
+		return saved[i].EntryID > saved[j].EntryID
+	})
+
+	entries := []Entry{}
+	for _, f := range saved {
+		if e, deleted, ok := m.lookup(f.EntryID); ok && !deleted {
+			e.Tags = append([]string{}, e.Tags...)
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// RandomFavorite returns a random live entry saved by a user
+func (m *MemoryStore) RandomFavorite(ctx context.Context, userID string) (Entry, error) {
+	entries, err := m.ListFavorites(ctx, userID)
+	if err != nil {
+		return Entry{}, err
+	}
+	return randomEntry(entries), nil
+}
+
+// RemoveFavorite unsaves an entry for a user
+func (m *MemoryStore) RemoveFavorite(_ context.Context, userID string, entryID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, f := range m.favorites {
+		if f.UserID == userID && f.EntryID == entryID {
+			m.favorites = append(m.favorites[:i:i], m.favorites[i+1:]...)
+			return nil
+		}
+	}
+	return ErrFavoriteNotFound
+}
+
+// Vote records or withdraws the vote of a user on a live entry
+func (m *MemoryStore) Vote(_ context.Context, entryID int64, userID string, value int, _ time.Time) (Votes, error) {
+	if value != 1 && value != -1 {
+		return Votes{}, ErrInvalidVote
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, deleted, ok := m.lookup(entryID); !ok || deleted {
+		return Votes{}, ErrEntryNotFound
+	}
+	if m.votes[entryID] == nil {
+		m.votes[entryID] = map[string]int{}
+	}
+	if m.votes[entryID][userID] == value {
+		delete(m.votes[entryID], userID)
+	} else {
+		m.votes[entryID][userID] = value
+	}
+	return m.tally(entryID), nil
+}
+
+// EntryVotes returns the tally of the votes on an entry
+func (m *MemoryStore) EntryVotes(_ context.Context, entryID int64) (Votes, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.tally(entryID), nil
+}
+
+// tally counts the votes on an entry; the caller must hold the lock
+func (m *MemoryStore) tally(entryID int64) Votes {
+	var v Votes
+	for _, value := range m.votes[entryID] {
+		if value > 0 {
+			v.Up++
+		} else {
+			v.Down++
+		}
+	}
+	return v
+}
+
+// RecordPlay counts that a live entry was served
+func (m *MemoryStore) RecordPlay(_ context.Context, entryID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, deleted, ok := m.lookup(entryID); !ok || deleted {
+		return ErrEntryNotFound
+	}
+	m.plays[entryID]++
+	return nil
+}
+
+// EntryStats returns the best rated live entries of a category, or of all
+// categories, with their plays and votes
+func (m *MemoryStore) EntryStats(ctx context.Context, category string, limit int) ([]EntryStats, error) {
+	stats := m.entryStats(ctx, category)
+	SortByRating(stats)
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats, nil
+}
+
+// entryStats returns the live entries of a category, or of all categories,
+// with their plays and votes, in the order of ListEntries
+func (m *MemoryStore) entryStats(ctx context.Context, category string) []EntryStats {
+	entries, _ := m.ListEntries(ctx, category)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make([]EntryStats, 0, len(entries))
+	for _, e := range entries {
+		stats = append(stats, EntryStats{Entry: e, Votes: m.tally(e.ID), Plays: m.plays[e.ID]})
+	}
+	return stats
+}
+
+// PopularEntry returns a random entry of a category, chosen proportionally
+// to its popular weight among the entries preferred for locale
+func (m *MemoryStore) PopularEntry(ctx context.Context, category, locale string, exploration float64) (Entry, error) {
+	all := m.entryStats(ctx, category)
+	if category == "" || len(all) == 0 {
+		return Entry{}, nil
+	}
+
+	entries := make([]Entry, len(all))
+	for i, st := range all {
+		entries[i] = st.Entry
+	}
+	preferred := make(map[int64]bool, len(all))
+	for _, e := range PreferLocale(entries, locale) {
+		preferred[e.ID] = true
+	}
+	var stats []EntryStats
+	total := 0.0
+	for _, st := range all {
+		if preferred[st.ID] {
+			stats = append(stats, st)
+			total += st.PopularWeight(exploration)
+		}
+	}
+
+	n := rand.Float64() * total
+	for _, st := range stats {
+		n -= st.PopularWeight(exploration)
+		if n < 0 {
+			return st.Entry, nil
+		}
+	}
+	return stats[len(stats)-1].Entry, nil
+}
+
+// GuildLocale returns the locale set for a guild
+func (m *MemoryStore) GuildLocale(_ context.Context, guildID string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.locales[guildID], nil
+}
+
+// SetGuildLocale sets the locale of a guild
+func (m *MemoryStore) SetGuildLocale(_ context.Context, guildID, locale string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if locale == "" {
+		delete(m.locales, guildID)
+	} else {
+		m.locales[guildID] = locale
+	}
+	return nil
+}
+
+// GuildRoleMentions reports whether entries added by admins may ping roles
+// in a guild
+func (m *MemoryStore) GuildRoleMentions(_ context.Context, guildID string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.roleMentions[guildID], nil
+}
+
+// SetGuildRoleMentions sets whether entries added by admins may ping roles
+// in a guild
+func (m *MemoryStore) SetGuildRoleMentions(_ context.Context, guildID string, allowed bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if allowed {
+		m.roleMentions[guildID] = true
+	} else {
+		delete(m.roleMentions, guildID)
+	}
+	return nil
+}
+
+// TryLock acquires a lock shared by the users of this store
+func (m *MemoryStore) TryLock(_ context.Context, name string) (Lock, error) {
+	return m.locks.tryLock(name)
+}
+
+// truncateSecond drops sub-second precision like the SQL backends do
+func truncateSecond(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.Truncate(time.Second).UTC()
+}
+
+// SchemaVersion always reports the latest version, as there is no schema
+func (m *MemoryStore) SchemaVersion(_ context.Context) (int, error) {
+	return LatestSchemaVersion(), nil
+}
+
+// Ping always succeeds
+func (m *MemoryStore) Ping(_ context.Context) error {
+	return nil
+}
+
+// Close is a no-op
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// Ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
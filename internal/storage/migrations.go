@@ -0,0 +1,292 @@
+package storage
+
+// migration is a schema change applied once, in order, to SQL backends.
+// Statements are written per dialect where the syntax differs.
+type migration struct {
+	version  int
+	name     string
+	postgres string
+	sqlite   string
+}
+
+// migrations lists every schema change. Append new entries; never edit or
+// reorder applied ones.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create commands table",
+		postgres: `
+			CREATE TABLE IF NOT EXISTS commands (
+				id SERIAL PRIMARY KEY,
+				command VARCHAR(255) NOT NULL,
+				content TEXT NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_command ON commands(command);
+		`,
+		sqlite: `
+			CREATE TABLE IF NOT EXISTS commands (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				command TEXT NOT NULL,
+				content TEXT NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_command ON commands(command);
+		`,
+	},
+	{
+		version: 2,
+		name:    "add entry weights and tags",
+		postgres: `
+			ALTER TABLE commands ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 1;
+			ALTER TABLE commands ADD COLUMN IF NOT EXISTS tags TEXT NOT NULL DEFAULT '';
+		`,
+		sqlite: `
+			ALTER TABLE commands ADD COLUMN weight INTEGER NOT NULL DEFAULT 1;
+			ALTER TABLE commands ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+		`,
+	},
+	{
+		version: 3,
+		name:    "create schedules table",
+		postgres: `
+			CREATE TABLE IF NOT EXISTS schedules (
+				id SERIAL PRIMARY KEY,
+				guild_id VARCHAR(32) NOT NULL,
+				channel_id VARCHAR(32) NOT NULL,
+				category VARCHAR(255) NOT NULL,
+				spec VARCHAR(255) NOT NULL,
+				timezone VARCHAR(64) NOT NULL,
+				created_by VARCHAR(32) NOT NULL DEFAULT '',
+				next_run BIGINT NOT NULL,
+				last_run BIGINT NOT NULL DEFAULT 0,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_schedules_next_run ON schedules(next_run);
+		`,
+		sqlite: `
+			CREATE TABLE IF NOT EXISTS schedules (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				guild_id TEXT NOT NULL,
+				channel_id TEXT NOT NULL,
+				category TEXT NOT NULL,
+				spec TEXT NOT NULL,
+				timezone TEXT NOT NULL,
+				created_by TEXT NOT NULL DEFAULT '',
+				next_run INTEGER NOT NULL,
+				last_run INTEGER NOT NULL DEFAULT 0,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_schedules_next_run ON schedules(next_run);
+		`,
+	},
+	{
+		version: 4,
+		name:    "create triggers table",
+		postgres: `
+			CREATE TABLE IF NOT EXISTS triggers (
+				id SERIAL PRIMARY KEY,
+				guild_id VARCHAR(32) NOT NULL,
+				channel_id VARCHAR(32) NOT NULL DEFAULT '',
+				kind VARCHAR(16) NOT NULL,
+				pattern TEXT NOT NULL,
+				category VARCHAR(255) NOT NULL,
+				cooldown_seconds INTEGER NOT NULL DEFAULT 0,
+				chance INTEGER NOT NULL DEFAULT 100,
+				created_by VARCHAR(32) NOT NULL DEFAULT '',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_triggers_guild ON triggers(guild_id);
+		`,
+		sqlite: `
+			CREATE TABLE IF NOT EXISTS triggers (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				guild_id TEXT NOT NULL,
+				channel_id TEXT NOT NULL DEFAULT '',
+				kind TEXT NOT NULL,
+				pattern TEXT NOT NULL,
+				category TEXT NOT NULL,
+				cooldown_seconds INTEGER NOT NULL DEFAULT 0,
+				chance INTEGER NOT NULL DEFAULT 100,
+				created_by TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_triggers_guild ON triggers(guild_id);
+		`,
+	},
+	{
+		version: 5,
+		name:    "add entry types",
+		postgres: `
+			ALTER TABLE commands ADD COLUMN IF NOT EXISTS entry_type VARCHAR(16) NOT NULL DEFAULT 'text';
+		`,
+		sqlite: `
+			ALTER TABLE commands ADD COLUMN entry_type TEXT NOT NULL DEFAULT 'text';
+		`,
+	},
+	{
+		version: 6,
+		name:    "create suggestions table",
+		postgres: `
+			CREATE TABLE IF NOT EXISTS suggestions (
+				id SERIAL PRIMARY KEY,
+				guild_id VARCHAR(32) NOT NULL DEFAULT '',
+				user_id VARCHAR(32) NOT NULL,
+				category VARCHAR(255) NOT NULL,
+				content TEXT NOT NULL,
+				status VARCHAR(16) NOT NULL DEFAULT 'pending',
+				reviewer_id VARCHAR(32) NOT NULL DEFAULT '',
+				entry_id INTEGER NOT NULL DEFAULT 0,
+				created_at BIGINT NOT NULL,
+				reviewed_at BIGINT NOT NULL DEFAULT 0
+			);
+			CREATE INDEX IF NOT EXISTS idx_suggestions_user ON suggestions(user_id, created_at);
+		`,
+		sqlite: `
+			CREATE TABLE IF NOT EXISTS suggestions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				guild_id TEXT NOT NULL DEFAULT '',
+				user_id TEXT NOT NULL,
+				category TEXT NOT NULL,
+				content TEXT NOT NULL,
+				status TEXT NOT NULL DEFAULT 'pending',
+				reviewer_id TEXT NOT NULL DEFAULT '',
+				entry_id INTEGER NOT NULL DEFAULT 0,
+				created_at INTEGER NOT NULL,
+				reviewed_at INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE INDEX IF NOT EXISTS idx_suggestions_user ON suggestions(user_id, created_at);
+		`,
+	},
+	{
+		version: 7,
+		name:    "add soft deletes and content history",
+		postgres: `
+			ALTER TABLE commands ADD COLUMN deleted_at BIGINT NOT NULL DEFAULT 0;
+			CREATE TABLE IF NOT EXISTS content_history (
+				id SERIAL PRIMARY KEY,
+				entry_id INTEGER NOT NULL,
+				action VARCHAR(16) NOT NULL,
+				actor VARCHAR(64) NOT NULL DEFAULT '',
+				at BIGINT NOT NULL,
+				before_entry TEXT NOT NULL DEFAULT '',
+				after_entry TEXT NOT NULL DEFAULT '',
+				mirrored BOOLEAN NOT NULL DEFAULT FALSE
+			);
+			CREATE INDEX IF NOT EXISTS idx_content_history_entry ON content_history(entry_id);
+		`,
+		sqlite: `
+			ALTER TABLE commands ADD COLUMN deleted_at INTEGER NOT NULL DEFAULT 0;
+			CREATE TABLE IF NOT EXISTS content_history (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				entry_id INTEGER NOT NULL,
+				action TEXT NOT NULL,
+				actor TEXT NOT NULL DEFAULT '',
+				at INTEGER NOT NULL,
+				before_entry TEXT NOT NULL DEFAULT '',
+				after_entry TEXT NOT NULL DEFAULT '',
+				mirrored BOOLEAN NOT NULL DEFAULT FALSE
+			);
+			CREATE INDEX IF NOT EXISTS idx_content_history_entry ON content_history(entry_id);
+		`,
+	},
+	{
+		version: 8,
+		name:    "create favorites table",
+		postgres: `
+			CREATE TABLE IF NOT EXISTS favorites (
+				user_id VARCHAR(32) NOT NULL,
+				entry_id INTEGER NOT NULL,
+				saved_at BIGINT NOT NULL,
+				PRIMARY KEY (user_id, entry_id)
+			);
+		`,
+		sqlite: `
+			CREATE TABLE IF NOT EXISTS favorites (
+				user_id TEXT NOT NULL,
+				entry_id INTEGER NOT NULL,
+				saved_at INTEGER NOT NULL,
+				PRIMARY KEY (user_id, entry_id)
+			);
+		`,
+	},
+	{
+		version: 9,
+		name:    "create votes table",
+		postgres: `
+			CREATE TABLE IF NOT EXISTS votes (
+				entry_id INTEGER NOT NULL,
+				user_id VARCHAR(32) NOT NULL,
+				value SMALLINT NOT NULL,
+				voted_at BIGINT NOT NULL,
+				PRIMARY KEY (entry_id, user_id)
+			);
+		`,
+		sqlite: `
+			CREATE TABLE IF NOT EXISTS votes (
+				entry_id INTEGER NOT NULL,
+				user_id TEXT NOT NULL,
+				value INTEGER NOT NULL,
+				voted_at INTEGER NOT NULL,
+				PRIMARY KEY (entry_id, user_id)
+			);
+		`,
+	},
+	{
+		version: 10,
+		name:    "add entry play counts",
+		postgres: `
+			ALTER TABLE commands ADD COLUMN IF NOT EXISTS plays INTEGER NOT NULL DEFAULT 0;
+		`,
+		sqlite: `
+			ALTER TABLE commands ADD COLUMN plays INTEGER NOT NULL DEFAULT 0;
+		`,
+	},
+	{
+		version: 11,
+		name:    "create guild settings table",
+		postgres: `
+			CREATE TABLE IF NOT EXISTS guild_settings (
+				guild_id VARCHAR(32) PRIMARY KEY,
+				locale VARCHAR(16) NOT NULL DEFAULT ''
+			);
+		`,
+		sqlite: `
+			CREATE TABLE IF NOT EXISTS guild_settings (
+				guild_id TEXT PRIMARY KEY,
+				locale TEXT NOT NULL DEFAULT ''
+			);
+		`,
+	},
+	{
+		version: 12,
+		name:    "add entry locales",
+		postgres: `
+			ALTER TABLE commands ADD COLUMN IF NOT EXISTS locale VARCHAR(16) NOT NULL DEFAULT '';
+		`,
+		sqlite: `
+			ALTER TABLE commands ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+		`,
+	},
+	{
+		version: 13,
+		name:    "add entry suggesters and guild role mentions",
+		postgres: `
+			ALTER TABLE commands ADD COLUMN IF NOT EXISTS suggested_by VARCHAR(32) NOT NULL DEFAULT '';
+			UPDATE commands SET suggested_by = s.user_id
+				FROM suggestions s WHERE s.entry_id = commands.id AND s.status = 'approved';
+			ALTER TABLE guild_settings ADD COLUMN IF NOT EXISTS role_mentions BOOLEAN NOT NULL DEFAULT FALSE;
+		`,
+		sqlite: `
+			ALTER TABLE commands ADD COLUMN suggested_by TEXT NOT NULL DEFAULT '';
+			UPDATE commands SET suggested_by = COALESCE(
+				(SELECT user_id FROM suggestions WHERE entry_id = commands.id AND status = 'approved'), '');
+			ALTER TABLE guild_settings ADD COLUMN role_mentions INTEGER NOT NULL DEFAULT 0;
+		`,
+	},
+}
+
+// LatestSchemaVersion is the schema version after all migrations are applied
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
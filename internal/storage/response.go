@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// EntryType is how an entry is delivered
+type EntryType string
+
+const (
+	// EntryText entries are sent as a message. Their content is a template
+	// where {react:EMOJI} adds a reaction instead of text, see Response.
+	EntryText EntryType = "text"
+	// EntryReaction entries are one or more emoji, separated by spaces,
+	// added as reactions to the message that asked for them
+	EntryReaction EntryType = "reaction"
+)
+
+// EntryTypes lists the supported entry types
+var EntryTypes = []EntryType{EntryText, EntryReaction}
+
+// OrDefault returns EntryText for an unset type
+func (t EntryType) OrDefault() EntryType {
+	if t == "" {
+		return EntryText
+	}
+	return t
+}
+
+// reactToken matches a {react:EMOJI} template token
+var reactToken = regexp.MustCompile(`\{react:([^{}\s]+)\}`)
+
+// customEmoji matches a custom guild emoji as written in messages,
+// <:name:id> or <a:name:id>, or as name:id
+var customEmoji = regexp.MustCompile(`^(?:<(a?):([\w~]{2,32}):(\d{1,20})>|([\w~]{2,32}):(\d{1,20}))$`)
+
+// Reaction is an emoji added to a message, either a Unicode emoji or a
+// custom guild emoji with an ID
+type Reaction struct {
+	// Name is the Unicode emoji, or the name of a custom emoji
+	Name     string
+	ID       string
+	Animated bool
+}
+
+// ParseReaction parses a Unicode emoji or a custom emoji written as
+// <:name:id>, <a:name:id> or name:id
+func ParseReaction(s string) (Reaction, error) {
+	s = strings.TrimSpace(s)
+	if m := customEmoji.FindStringSubmatch(s); m != nil {
+		if m[2] != "" {
+			return Reaction{Name: m[2], ID: m[3], Animated: m[1] == "a"}, nil
+		}
+		return Reaction{Name: m[4], ID: m[5]}, nil
+	}
+	if !isUnicodeEmoji(s) {
+		return Reaction{}, fmt.Errorf("%q is not an emoji", s)
+	}
+	return Reaction{Name: s}, nil
+}
+
+// isUnicodeEmoji loosely checks for a Unicode emoji: a short run of
+// symbols with no letters or spaces. Keycaps such as 1️⃣ contain a digit.
+func isUnicodeEmoji(s string) bool {
+	if s == "" || len(s) > 32 {
+		return false
+	}
+	symbol := false
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsSpace(r):
+			return false
+		case r > unicode.MaxASCII:
+			symbol = true
+		}
+	}
+	return symbol
+}
+
+// APIName is the emoji as expected by the reactions API
+func (r Reaction) APIName() string {
+	if r.ID == "" {
+		return r.Name
+	}
+	return r.Name + ":" + r.ID
+}
+
+// String is the emoji as written in a message
+func (r Reaction) String() string {
+	switch {
+	case r.ID == "":
+		return r.Name
+	case r.Animated:
+		return "<a:" + r.Name + ":" + r.ID + ">"
+	default:
+		return "<:" + r.Name + ":" + r.ID + ">"
+	}
+}
+
+// Response is what an entry sends in reply to a message
+type Response struct {
+	Text      string
+	Reactions []Reaction
+}
+
+// Response parses the content of the entry according to its type
+func (e Entry) Response() (Response, error) {
+	switch e.Type.OrDefault() {
+	case EntryReaction:
+		var r Response
+		for _, field := range strings.Fields(e.Content) {
+			reaction, err := ParseReaction(field)
+			if err != nil {
+				return Response{}, err
+			}
+			r.Reactions = append(r.Reactions, reaction)
+		}
+		if len(r.Reactions) == 0 {
+			return Response{}, fmt.Errorf("reaction entry has no emoji")
+		}
+		return r, nil
+	case EntryText:
+		var r Response
+		for _, m := range reactToken.FindAllStringSubmatch(e.Content, -1) {
+			reaction, err := ParseReaction(m[1])
+			if err != nil {
+				return Response{}, fmt.Errorf("invalid {react:} token: %w", err)
+			}
+			r.Reactions = append(r.Reactions, reaction)
+		}
+		r.Text = strings.TrimSpace(reactToken.ReplaceAllString(e.Content, ""))
+		return r, nil
+	default:
+		return Response{}, fmt.Errorf("unknown entry type %q, expected text or reaction", e.Type)
+	}
+}
+
+// Plain renders the response as a single message, for replies that have
+// no message to react to, such as slash commands and scheduled posts
+func (r Response) Plain() string {
+	parts := make([]string, 0, len(r.Reactions)+1)
+	if r.Text != "" {
+		parts = append(parts, r.Text)
+	}
+	for _, reaction := range r.Reactions {
+		parts = append(parts, reaction.String())
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// ErrScheduleNotFound is returned when no schedule has the requested ID
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// ErrLocked is returned by TryLock while another holder has the lock
+var ErrLocked = errors.New("lock is held elsewhere")
+
+// Schedule posts a random entry of a category to a channel whenever its
+// cron expression matches. Times are stored with second precision.
+type Schedule struct {
+	ID        int64
+	GuildID   string
+	ChannelID string
+	Category  string
+	// Spec is the cron expression, evaluated in Timezone
+	Spec     string
+	Timezone string
+	// CreatedBy is the ID of the user who added the schedule
+	CreatedBy string
+	// NextRun is when the schedule is due next
+	NextRun time.Time
+	// LastRun is the last due time that was handled, zero before the first
+	LastRun time.Time
+}
+
+// Lock is a lock held through TryLock
+type Lock interface {
+	// Check returns an error once the lock is lost, e.g. because the
+	// database connection holding it broke
+	Check(ctx context.Context) error
+	// Release gives up the lock
+	Release() error
+}
+
+// localLocks are locks shared by the users of a single store, for backends
+// that are never shared between processes
+type localLocks struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// tryLock acquires the named lock unless it is held
+func (l *localLocks) tryLock(name string) (Lock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[name] {
+		return nil, ErrLocked
+	}
+	if l.held == nil {
+		l.held = make(map[string]bool)
+	}
+	l.held[name] = true
+	return &localLock{locks: l, name: name}, nil
+}
+
+// localLock is a lock held in a localLocks
+type localLock struct {
+	locks *localLocks
+	name  string
+	once  sync.Once
+}
+
+// Check always succeeds, as a local lock can't be lost
+func (l *localLock) Check(_ context.Context) error {
+	return nil
+}
+
+// Release frees the lock for the next caller of TryLock
+func (l *localLock) Release() error {
+	l.once.Do(func() {
+		l.locks.mu.Lock()
+		defer l.locks.mu.Unlock()
+		delete(l.locks.held, l.name)
+	})
+	return nil
+}
+
+// lockKey maps a lock name to a PostgreSQL advisory lock key
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// unixTime converts a stored unix time, where 0 means unset
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
+
+// unixSeconds converts a time for storage, where the zero time is 0
+func unixSeconds(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,1100 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mutsumi-bot/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// dialect identifies the SQL flavour spoken by a sqlStore
+type dialect int
+
+const (
+	dialectPostgres dialect = iota
+	dialectSQLite
+)
+
+func (d dialect) String() string {
+	if d == dialectSQLite {
+		return "sqlite"
+	}
+	return "postgres"
+}
+
+// sqlStore implements Store on top of database/sql. Queries use $n
+// placeholders, which both PostgreSQL and SQLite understand.
+type sqlStore struct {
+	db      *sql.DB
+	dialect dialect
+
+	// locks backs TryLock on SQLite, which is not shared between processes
+	locks localLocks
+}
+
+// newSQLStore pings the database and applies pending migrations unless
+// migrate is false
+func newSQLStore(ctx context.Context, db *sql.DB, d dialect, migrate bool) (*sqlStore, error) {
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%w: ping: %v", ErrUnavailable, err)
+	}
+
+	s := &sqlStore{db: db, dialect: d}
+	if !migrate {
+		return s, nil
+	}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate schema: %w", err)
+	}
+
+	return s, nil
+}
+
+// migrate applies every migration newer than the current schema version
+func (s *sqlStore) migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations table: %w", err)
+	}
+
+	for _, m := range migrations {
+		applied, err := s.applyMigration(ctx, m)
+		if err != nil {
+			return fmt.Errorf("apply migration %d (%s): %w", m.version, m.name, err)
+		}
+		if applied {
+			logger.Logger.Info("Applied schema migration",
+				zap.String("dialect", s.dialect.String()),
+				zap.Int("version", m.version),
+				zap.String("name", m.name))
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs a single migration in a transaction unless it has
+// already been applied. It reports whether the migration ran.
+func (s *sqlStore) applyMigration(ctx context.Context, m migration) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Serialize migrations across replicas sharing the same database
+	if s.dialect == dialectPostgres {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(8675309)`); err != nil {
+			return false, fmt.Errorf("lock schema: %w", err)
+		}
+	}
+
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations WHERE version = $1`, m.version).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check schema version: %w", err)
+	}
+	if exists > 0 {
+		return false, nil
+	}
+
+	stmt := m.postgres
+	if s.dialect == dialectSQLite {
+		stmt = m.sqlite
+	}
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.version, m.name); err != nil {
+		return false, fmt.Errorf("record schema version: %w", err)
+	}
+
+	return true, tx.Commit()
+}
+
+// RandomContent returns the content of a random entry of a category
+func (s *sqlStore) RandomContent(ctx context.Context, category string) (string, error) {
+	e, err := s.RandomEntry(ctx, category, "")
+	return e.Content, err
+}
+
+// RandomEntry returns a random entry of a category, chosen proportionally to
+// entry weights among the entries preferred for locale, see PreferLocale
+func (s *sqlStore) RandomEntry(ctx context.Context, category, locale string) (Entry, error) {
+	if category == "" {
+		return Entry{}, nil
+	}
+	e, err := s.pickEntry(ctx, `FROM commands c`, `CASE WHEN c.weight < 1 THEN 1 ELSE c.weight END`, category, locale)
+	if err != nil {
+		return Entry{}, fmt.Errorf("query random entry: %w", err)
+	}
+	return e, nil
+}
+
+// pickEntry picks a live entry of category among the rows of from, which
+// names the entry c, in proportion to the SQL expression weight and
+// preferring entries in the language of locale as PreferLocale does. The
+// database picks it: ordering by -ln(u)/weight, with u uniform in (0, 1],
+// puts each entry first with a probability proportional to its weight.
+func (s *sqlStore) pickEntry(ctx context.Context, from, weight, category, locale string, args ...any) (Entry, error) {
+	uniform := `(1 - random())`
+	if s.dialect == dialectSQLite {
+		// random() is a 64-bit integer in SQLite, keep the 53 bits of a double
+		uniform = `(((random() & 9007199254740991) + 1) / 9007199254740992.0)`
+	}
+	// Without a locale, entries without one rank first as they match ''
+	lang, _, _ := strings.Cut(strings.ToLower(locale), "-")
+
+	entries, err := s.queryEntries(ctx, `
+		SELECT c.id, c.command, c.content, c.weight, c.tags, c.entry_type, c.locale, c.suggested_by `+from+`
+		WHERE c.command = $1 AND c.deleted_at = 0
+		ORDER BY
+			CASE
+				WHEN LOWER(c.locale) = $2 OR LOWER(c.locale) LIKE ($2 || '-%') THEN 0
+				WHEN c.locale = '' THEN 1
+				ELSE 2
+			END,
+			-ln(`+uniform+`) / (`+weight+`)
+		LIMIT 1`, append([]any{category, lang}, args...)...)
+	if err != nil || len(entries) == 0 {
+		return Entry{}, err
+	}
+	return entries[0], nil
+}
+
+// CountContent returns the number of entries in a category
+func (s *sqlStore) CountContent(ctx context.Context, category string) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM commands WHERE command = $1 AND deleted_at = 0`, category).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count content: %w", err)
+	}
+	return count, nil
+}
+
+// Categories returns all categories with at least one entry
+func (s *sqlStore) Categories(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT command FROM commands WHERE deleted_at = 0 ORDER BY command`)
+	if err != nil {
+		return nil, fmt.Errorf("query categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []string{}
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, fmt.Errorf("scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// AddContent stores a new entry in a category
+func (s *sqlStore) AddContent(ctx context.Context, category, content string) (int64, error) {
+	return s.AddEntry(ctx, Entry{Category: category, Content: content})
+}
+
+// AddEntry stores a new entry
+func (s *sqlStore) AddEntry(ctx context.Context, e Entry) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO commands (command, content, weight, tags, entry_type, locale, suggested_by) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		e.Category, e.Content, max(e.Weight, DefaultWeight), joinTags(e.Tags), string(e.Type.OrDefault()), e.Locale, e.SuggestedBy).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert content: %w", err)
+	}
+	return id, nil
+}
+
+// GetEntry returns the entry with the given ID
+func (s *sqlStore) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	e, deleted, err := queryEntry(ctx, s.db, id)
+	if err == nil && deleted {
+		return Entry{}, ErrEntryNotFound
+	}
+	return e, err
+}
+
+// rowQuerier runs single-row queries on a database or in a transaction
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// queryEntry returns an entry, deleted or not, and whether it is deleted
+func queryEntry(ctx context.Context, q rowQuerier, id int64) (Entry, bool, error) {
+	var e Entry
+	var tags, entryType string
+	var deletedAt int64
+	err := q.QueryRowContext(ctx,
+		`SELECT id, command, content, weight, tags, entry_type, locale, suggested_by, deleted_at FROM commands WHERE id = $1`, id).
+		Scan(&e.ID, &e.Category, &e.Content, &e.Weight, &tags, &entryType, &e.Locale, &e.SuggestedBy, &deletedAt)
+	if err == sql.ErrNoRows {
+		return Entry{}, false, ErrEntryNotFound
+	}
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("query entry: %w", err)
+	}
+	e.Tags = splitTags(tags)
+	e.Type = EntryType(entryType)
+	return e, deletedAt != 0, nil
+}
+
+// ListEntries returns the entries of a category, or of all categories
+func (s *sqlStore) ListEntries(ctx context.Context, category string) ([]Entry, error) {
+	query := `SELECT id, command, content, weight, tags, entry_type, locale, suggested_by FROM commands WHERE deleted_at = 0`
+	var args []any
+	if category != "" {
+		query += ` AND command = $1`
+		args = append(args, category)
+	}
+	query += ` ORDER BY command, id`
+	return s.queryEntries(ctx, query, args...)
+}
+
+// queryEntries runs a query selecting the id, command, content, weight,
+// tags, entry_type, locale and suggested_by of entries
+func (s *sqlStore) queryEntries(ctx context.Context, query string, args ...any) ([]Entry, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		var tags, entryType string
+		if err := rows.Scan(&e.ID, &e.Category, &e.Content, &e.Weight, &tags, &entryType, &e.Locale, &e.SuggestedBy); err != nil {
+			return nil, fmt.Errorf("scan entry: %w", err)
+		}
+		e.Tags = splitTags(tags)
+		e.Type = EntryType(entryType)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ApplyChanges adds, updates and deletes entries in a single transaction
+func (s *sqlStore) ApplyChanges(ctx context.Context, changes ChangeSet) error {
+	if changes.Empty() {
+		return nil
+	}
+
+	at := changes.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range changes.Delete {
+		before, deleted, err := queryEntry(ctx, tx, id)
+		if errors.Is(err, ErrEntryNotFound) || deleted {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("delete entry %d: %w", id, err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE commands SET deleted_at = $1 WHERE id = $2`, unixSeconds(at), id); err != nil {
+			return fmt.Errorf("delete entry %d: %w", id, err)
+		}
+		rev := Revision{EntryID: id, Action: RevisionDelete, Actor: changes.Actor, At: at, Before: before}
+		if _, err := insertRevision(ctx, tx, rev); err != nil {
+			return err
+		}
+	}
+	for _, e := range changes.Update {
+		before, deleted, err := queryEntry(ctx, tx, e.ID)
+		if errors.Is(err, ErrEntryNotFound) || deleted {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("update entry %d: %w", e.ID, err)
+		}
+		e.SuggestedBy = before.SuggestedBy
+		after, err := updateEntry(ctx, tx, e)
+		if err != nil {
+			return err
+		}
+		rev := Revision{EntryID: e.ID, Action: RevisionUpdate, Actor: changes.Actor, At: at, Before: before, After: after}
+		if _, err := insertRevision(ctx, tx, rev); err != nil {
+			return err
+		}
+	}
+	for _, e := range changes.Add {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO commands (command, content, weight, tags, entry_type, locale, suggested_by) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			e.Category, e.Content, max(e.Weight, DefaultWeight), joinTags(e.Tags), string(e.Type.OrDefault()), e.Locale, e.SuggestedBy)
+		if err != nil {
+			return fmt.Errorf("insert entry: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit changes: %w", err)
+	}
+	return nil
+}
+
+// updateEntry sets the fields of a live or deleted entry but its suggester,
+// undeleting it, and returns it as stored
+func updateEntry(ctx context.Context, tx *sql.Tx, e Entry) (Entry, error) {
+	e.Weight = max(e.Weight, DefaultWeight)
+	e.Tags = NormalizeTags(e.Tags)
+	e.Type = e.Type.OrDefault()
+	_, err := tx.ExecContext(ctx,
+		`UPDATE commands SET command = $1, content = $2, weight = $3, tags = $4, entry_type = $5, locale = $6, deleted_at = 0 WHERE id = $7`,
+		e.Category, e.Content, e.Weight, joinTags(e.Tags), string(e.Type), e.Locale, e.ID)
+	if err != nil {
+		return Entry{}, fmt.Errorf("update entry %d: %w", e.ID, err)
+	}
+	return e, nil
+}
+
+// insertRevision records a revision in the content history and returns its ID
+func insertRevision(ctx context.Context, tx *sql.Tx, rev Revision) (int64, error) {
+	before, err := encodeSnapshot(rev.Before)
+	if err != nil {
+		return 0, err
+	}
+	after, err := encodeSnapshot(rev.After)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO content_history (entry_id, action, actor, at, before_entry, after_entry) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		rev.EntryID, string(rev.Action), rev.Actor, unixSeconds(rev.At), before, after).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("record revision of entry %d: %w", rev.EntryID, err)
+	}
+	return id, nil
+}
+
+// revisionColumns are the columns scanned by queryRevisions, in order
+const revisionColumns = `id, entry_id, action, actor, at, before_entry, after_entry`
+
+// EntryHistory returns the revisions of an entry
+func (s *sqlStore) EntryHistory(ctx context.Context, id int64) ([]Revision, error) {
+	if _, _, err := queryEntry(ctx, s.db, id); err != nil {
+		return nil, err
+	}
+	return queryRevisions(ctx, s.db, `SELECT `+revisionColumns+` FROM content_history WHERE entry_id = $1 ORDER BY id`, id)
+}
+
+// RestoreEntry undeletes an entry or reverts it to before a revision
+func (s *sqlStore) RestoreEntry(ctx context.Context, id, revisionID int64, actor string, at time.Time) (Revision, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Revision{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, deleted, err := queryEntry(ctx, tx, id)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	target := current
+	if revisionID == 0 {
+		if !deleted {
+			return Revision{}, ErrEntryNotDeleted
+		}
+	} else {
+		revisions, err := queryRevisions(ctx, tx,
+			`SELECT `+revisionColumns+` FROM content_history WHERE id = $1 AND entry_id = $2`, revisionID, id)
+		if err != nil {
+			return Revision{}, err
+		}
+		if len(revisions) == 0 {
+			return Revision{}, ErrRevisionNotFound
+		}
+		target = revisions[0].Before
+		target.SuggestedBy = current.SuggestedBy
+	}
+
+	after, err := updateEntry(ctx, tx, target)
+	if err != nil {
+		return Revision{}, err
+	}
+	rev := Revision{EntryID: id, Action: RevisionRestore, Actor: actor, At: truncateSecond(at), Before: current, After: after}
+	if rev.ID, err = insertRevision(ctx, tx, rev); err != nil {
+		return Revision{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Revision{}, fmt.Errorf("commit restore: %w", err)
+	}
+	return rev, nil
+}
+
+// PendingRevisions returns revisions not mirrored yet
+func (s *sqlStore) PendingRevisions(ctx context.Context, since time.Time, limit int) ([]Revision, error) {
+	return queryRevisions(ctx, s.db,
+		`SELECT `+revisionColumns+` FROM content_history WHERE NOT mirrored AND at >= $1 ORDER BY id LIMIT $2`,
+		since.Unix(), limit)
+}
+
+// MarkRevisionsMirrored marks revisions as mirrored
+func (s *sqlStore) MarkRevisionsMirrored(ctx context.Context, ids []int64) error {
+	for _, id := range ids {
+		if _, err := s.db.ExecContext(ctx, `UPDATE content_history SET mirrored = TRUE WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("mark revision %d mirrored: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// querier runs queries on a database or in a transaction
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// queryRevisions runs a query selecting revisionColumns
+func queryRevisions(ctx context.Context, q querier, query string, args ...any) ([]Revision, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []Revision{}
+	for rows.Next() {
+		var rev Revision
+		var action, before, after string
+		var at int64
+		if err := rows.Scan(&rev.ID, &rev.EntryID, &action, &rev.Actor, &at, &before, &after); err != nil {
+			return nil, fmt.Errorf("scan revision: %w", err)
+		}
+		rev.Action = RevisionAction(action)
+		rev.At = unixTime(at)
+		if rev.Before, err = decodeSnapshot(rev.EntryID, before); err != nil {
+			return nil, err
+		}
+		if rev.After, err = decodeSnapshot(rev.EntryID, after); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// scheduleColumns are the columns scanned by scanSchedules, in order
+const scheduleColumns = `id, guild_id, channel_id, category, spec, timezone, created_by, next_run, last_run`
+
+// AddSchedule stores a new schedule
+func (s *sqlStore) AddSchedule(ctx context.Context, sc Schedule) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO schedules (guild_id, channel_id, category, spec, timezone, created_by, next_run, last_run)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		sc.GuildID, sc.ChannelID, sc.Category, sc.Spec, sc.Timezone, sc.CreatedBy,
+		unixSeconds(sc.NextRun), unixSeconds(sc.LastRun)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert schedule: %w", err)
+	}
+	return id, nil
+}
+
+// ListSchedules returns the schedules of a guild, or of all guilds
+func (s *sqlStore) ListSchedules(ctx context.Context, guildID string) ([]Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM schedules`
+	var args []any
+	if guildID != "" {
+		query += ` WHERE guild_id = $1`
+		args = append(args, guildID)
+	}
+	query += ` ORDER BY id`
+	return s.querySchedules(ctx, query, args...)
+}
+
+// RemoveSchedule deletes a schedule of a guild
+func (s *sqlStore) RemoveSchedule(ctx context.Context, guildID string, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM schedules WHERE id = $1 AND guild_id = $2`, id, guildID)
+	if err != nil {
+		return fmt.Errorf("delete schedule: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("delete schedule: %w", err)
+	} else if n == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+// DueSchedules returns the schedules due at or before now
+func (s *sqlStore) DueSchedules(ctx context.Context, now time.Time) ([]Schedule, error) {
+	return s.querySchedules(ctx,
+		`SELECT `+scheduleColumns+` FROM schedules WHERE next_run <= $1 ORDER BY next_run, id`, now.Unix())
+}
+
+// AdvanceSchedule moves a schedule due at due to its next run
+func (s *sqlStore) AdvanceSchedule(ctx context.Context, id int64, due, next time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE schedules SET next_run = $1, last_run = $2 WHERE id = $3 AND next_run = $2`,
+		unixSeconds(next), due.Unix(), id)
+	if err != nil {
+		return false, fmt.Errorf("advance schedule: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("advance schedule: %w", err)
+	}
+	return n > 0, nil
+}
+
+// querySchedules runs a query selecting scheduleColumns
+func (s *sqlStore) querySchedules(ctx context.Context, query string, args ...any) ([]Schedule, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []Schedule{}
+	for rows.Next() {
+		var sc Schedule
+		var nextRun, lastRun int64
+		err := rows.Scan(&sc.ID, &sc.GuildID, &sc.ChannelID, &sc.Category, &sc.Spec, &sc.Timezone, &sc.CreatedBy, &nextRun, &lastRun)
+		if err != nil {
+			return nil, fmt.Errorf("scan schedule: %w", err)
+		}
+		sc.NextRun, sc.LastRun = unixTime(nextRun), unixTime(lastRun)
+		schedules = append(schedules, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// AddTrigger stores a new trigger
+func (s *sqlStore) AddTrigger(ctx context.Context, tr Trigger) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO triggers (guild_id, channel_id, kind, pattern, category, cooldown_seconds, chance, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		tr.GuildID, tr.ChannelID, string(tr.Kind), tr.Pattern, tr.Category,
+		int64(tr.Cooldown/time.Second), tr.Chance, tr.CreatedBy).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert trigger: %w", err)
+	}
+	return id, nil
+}
+
+// ListTriggers returns the triggers of a guild, or of all guilds
+func (s *sqlStore) ListTriggers(ctx context.Context, guildID string) ([]Trigger, error) {
+	query := `SELECT id, guild_id, channel_id, kind, pattern, category, cooldown_seconds, chance, created_by FROM triggers`
+	var args []any
+	if guildID != "" {
+		query += ` WHERE guild_id = $1`
+		args = append(args, guildID)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query triggers: %w", err)
+	}
+	defer rows.Close()
+
+	triggers := []Trigger{}
+	for rows.Next() {
+		var tr Trigger
+		var kind string
+		var cooldown int64
+		err := rows.Scan(&tr.ID, &tr.GuildID, &tr.ChannelID, &kind, &tr.Pattern, &tr.Category, &cooldown, &tr.Chance, &tr.CreatedBy)
+		if err != nil {
+			return nil, fmt.Errorf("scan trigger: %w", err)
+		}
+		tr.Kind = TriggerKind(kind)
+		tr.Cooldown = time.Duration(cooldown) * time.Second
+		triggers = append(triggers, tr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate triggers: %w", err)
+	}
+	return triggers, nil
+}
+
+// RemoveTrigger deletes a trigger of a guild
+func (s *sqlStore) RemoveTrigger(ctx context.Context, guildID string, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM triggers WHERE id = $1 AND guild_id = $2`, id, guildID)
+	if err != nil {
+		return fmt.Errorf("delete trigger: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("delete trigger: %w", err)
+	} else if n == 0 {
+		return ErrTriggerNotFound
+	}
+	return nil
+}
+
+// suggestionColumns are the columns scanned by scanSuggestion, in order
+const suggestionColumns = `id, guild_id, user_id, category, content, status, reviewer_id, entry_id, created_at, reviewed_at`
+
+// AddSuggestion stores a new pending suggestion
+func (s *sqlStore) AddSuggestion(ctx context.Context, sg Suggestion) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO suggestions (guild_id, user_id, category, content, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		sg.GuildID, sg.UserID, sg.Category, sg.Content, string(SuggestionPending), unixSeconds(sg.CreatedAt)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert suggestion: %w", err)
+	}
+	return id, nil
+}
+
+// GetSuggestion returns the suggestion with the given ID
+func (s *sqlStore) GetSuggestion(ctx context.Context, id int64) (Suggestion, error) {
+	return scanSuggestion(s.db.QueryRowContext(ctx, `SELECT `+suggestionColumns+` FROM suggestions WHERE id = $1`, id))
+}
+
+// CountSuggestions counts the suggestions of a user created since a time
+func (s *sqlStore) CountSuggestions(ctx context.Context, userID string, status SuggestionStatus, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM suggestions WHERE user_id = $1 AND created_at >= $2`
+	args := []any{userID, since.Unix()}
+	if status != "" {
+		query += ` AND status = $3`
+		args = append(args, string(status))
+	}
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count suggestions: %w", err)
+	}
+	return count, nil
+}
+
+// ReviewSuggestion approves or rejects a pending suggestion
+func (s *sqlStore) ReviewSuggestion(ctx context.Context, id int64, status SuggestionStatus, reviewerID string, at time.Time) (Suggestion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Suggestion{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sg, err := scanSuggestion(tx.QueryRowContext(ctx, `SELECT `+suggestionColumns+` FROM suggestions WHERE id = $1`, id))
+	if err != nil {
+		return Suggestion{}, err
+	}
+	if sg.Status != SuggestionPending {
+		return sg, ErrSuggestionReviewed
+	}
+
+	// The status guard makes concurrent reviews fail instead of both
+	// approving
+	res, err := tx.ExecContext(ctx,
+		`UPDATE suggestions SET status = $1, reviewer_id = $2, reviewed_at = $3 WHERE id = $4 AND status = $5`,
+		string(status), reviewerID, unixSeconds(at), id, string(SuggestionPending))
+	if err != nil {
+		return Suggestion{}, fmt.Errorf("review suggestion: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return Suggestion{}, fmt.Errorf("review suggestion: %w", err)
+	} else if n == 0 {
+		return sg, ErrSuggestionReviewed
+	}
+
+	if status == SuggestionApproved {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO commands (command, content, weight, tags, entry_type, suggested_by) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+			sg.Category, sg.Content, DefaultWeight, "", string(EntryText), sg.UserID).Scan(&sg.EntryID)
+		if err != nil {
+			return Suggestion{}, fmt.Errorf("insert approved content: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE suggestions SET entry_id = $1 WHERE id = $2`, sg.EntryID, id); err != nil {
+			return Suggestion{}, fmt.Errorf("review suggestion: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Suggestion{}, fmt.Errorf("commit review: %w", err)
+	}
+	sg.Status, sg.ReviewerID, sg.ReviewedAt = status, reviewerID, truncateSecond(at)
+	return sg, nil
+}
+
+// scanSuggestion scans a row of suggestionColumns
+func scanSuggestion(row *sql.Row) (Suggestion, error) {
+	var sg Suggestion
+	var status string
+	var createdAt, reviewedAt int64
+	err := row.Scan(&sg.ID, &sg.GuildID, &sg.UserID, &sg.Category, &sg.Content, &status, &sg.ReviewerID, &sg.EntryID, &createdAt, &reviewedAt)
+	if err == sql.ErrNoRows {
+		return Suggestion{}, ErrSuggestionNotFound
+	}
+	if err != nil {
+		return Suggestion{}, fmt.Errorf("query suggestion: %w", err)
+	}
+	sg.Status = SuggestionStatus(status)
+	sg.CreatedAt, sg.ReviewedAt = unixTime(createdAt), unixTime(reviewedAt)
+	return sg, nil
+}
+
+// AddFavorite saves a live entry for a user
+func (s *sqlStore) AddFavorite(ctx context.Context, userID string, entryID int64, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, deleted, err := queryEntry(ctx, tx, entryID); err != nil {
+		return err
+	} else if deleted {
+		return ErrEntryNotFound
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO favorites (user_id, entry_id, saved_at) VALUES ($1, $2, $3) ON CONFLICT (user_id, entry_id) DO NOTHING`,
+		userID, entryID, unixSeconds(at))
+	if err != nil {
+		return fmt.Errorf("insert favorite: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("insert favorite: %w", err)
+	} else if n == 0 {
+		return ErrFavoriteExists
+	}
+	return tx.Commit()
+}
+
+// ListFavorites returns the live entries saved by a user
+func (s *sqlStore) ListFavorites(ctx context.Context, userID string) ([]Entry, error) {
+	return s.queryEntries(ctx,
+		`SELECT c.id, c.command, c.content, c.weight, c.tags, c.entry_type, c.locale, c.suggested_by
+		FROM favorites f JOIN commands c ON c.id = f.entry_id
+		WHERE f.user_id = $1 AND c.deleted_at = 0
+		ORDER BY f.saved_at DESC, c.id DESC`, userID)
+}
+
+// RandomFavorite returns a random live entry saved by a user
+func (s *sqlStore) RandomFavorite(ctx context.Context, userID string) (Entry, error) {
+	entries, err := s.ListFavorites(ctx, userID)
+	if err != nil {
+		return Entry{}, err
+	}
+	return randomEntry(entries), nil
+}
+
+// RemoveFavorite unsaves an entry for a user
+func (s *sqlStore) RemoveFavorite(ctx context.Context, userID string, entryID int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM favorites WHERE user_id = $1 AND entry_id = $2`, userID, entryID)
+	if err != nil {
+		return fmt.Errorf("delete favorite: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("delete favorite: %w", err)
+	} else if n == 0 {
+		return ErrFavoriteNotFound
+	}
+	return nil
+}
+
+// Vote records or withdraws the vote of a user on a live entry
+func (s *sqlStore) Vote(ctx context.Context, entryID int64, userID string, value int, at time.Time) (Votes, error) {
+	if value != 1 && value != -1 {
+		return Votes{}, ErrInvalidVote
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Votes{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, deleted, err := queryEntry(ctx, tx, entryID); err != nil {
+		return Votes{}, err
+	} else if deleted {
+		return Votes{}, ErrEntryNotFound
+	}
+
+	var current int
+	err = tx.QueryRowContext(ctx, `SELECT value FROM votes WHERE entry_id = $1 AND user_id = $2`, entryID, userID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return Votes{}, fmt.Errorf("query vote: %w", err)
+	}
+
+	if current == value {
+		_, err = tx.ExecContext(ctx, `DELETE FROM votes WHERE entry_id = $1 AND user_id = $2`, entryID, userID)
+	} else {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO votes (entry_id, user_id, value, voted_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (entry_id, user_id) DO UPDATE SET value = excluded.value, voted_at = excluded.voted_at`,
+			entryID, userID, value, unixSeconds(at))
+	}
+	if err != nil {
+		return Votes{}, fmt.Errorf("record vote: %w", err)
+	}
+
+	votes, err := queryVotes(ctx, tx, entryID)
+	if err != nil {
+		return Votes{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Votes{}, fmt.Errorf("commit vote: %w", err)
+	}
+	return votes, nil
+}
+
+// EntryVotes returns the tally of the votes on an entry
+func (s *sqlStore) EntryVotes(ctx context.Context, entryID int64) (Votes, error) {
+	return queryVotes(ctx, s.db, entryID)
+}
+
+// queryVotes counts the votes on an entry
+func queryVotes(ctx context.Context, q rowQuerier, entryID int64) (Votes, error) {
+	var v Votes
+	err := q.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(CASE WHEN value > 0 THEN 1 ELSE 0 END), 0), COALESCE(SUM(CASE WHEN value < 0 THEN 1 ELSE 0 END), 0)
+		FROM votes WHERE entry_id = $1`, entryID).Scan(&v.Up, &v.Down)
+	if err != nil {
+		return Votes{}, fmt.Errorf("count votes: %w", err)
+	}
+	return v, nil
+}
+
+// RecordPlay counts that a live entry was served
+func (s *sqlStore) RecordPlay(ctx context.Context, entryID int64) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE commands SET plays = plays + 1 WHERE id = $1 AND deleted_at = 0`, entryID)
+	if err != nil {
+		return fmt.Errorf("record play: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("record play: %w", err)
+	} else if n == 0 {
+		return ErrEntryNotFound
+	}
+	return nil
+}
+
+// EntryStats returns the best rated live entries of a category, or of all
+// categories, with their plays and votes. The order is SortByRating's.
+func (s *sqlStore) EntryStats(ctx context.Context, category string, limit int) ([]EntryStats, error) {
+	query := `SELECT c.id, c.command, c.content, c.weight, c.tags, c.entry_type, c.locale, c.suggested_by, c.plays,
+		COALESCE(v.up, 0), COALESCE(v.down, 0)
+		FROM commands c
+		LEFT JOIN (
+			SELECT entry_id, SUM(CASE WHEN value > 0 THEN 1 ELSE 0 END) AS up, SUM(CASE WHEN value < 0 THEN 1 ELSE 0 END) AS down
+			FROM votes GROUP BY entry_id
+		) v ON v.entry_id = c.id
+		WHERE c.deleted_at = 0`
+	var args []any
+	if category != "" {
+		query += ` AND c.command = $1`
+		args = append(args, category)
+	}
+	query += ` ORDER BY COALESCE(v.up, 0) - COALESCE(v.down, 0) DESC, COALESCE(v.up, 0) DESC, c.plays DESC, c.id`
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query entry stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []EntryStats{}
+	for rows.Next() {
+		var st EntryStats
+		var tags, entryType string
+		if err := rows.Scan(&st.ID, &st.Category, &st.Content, &st.Weight, &tags, &entryType, &st.Locale, &st.SuggestedBy, &st.Plays, &st.Up, &st.Down); err != nil {
+			return nil, fmt.Errorf("scan entry stats: %w", err)
+		}
+		st.Tags = splitTags(tags)
+		st.Type = EntryType(entryType)
+		stats = append(stats, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate entry stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// PopularEntry returns a random entry of a category, chosen proportionally
+// to its popular weight among the entries preferred for locale. The SQL
+// weight is EntryStats.PopularWeight.
+func (s *sqlStore) PopularEntry(ctx context.Context, category, locale string, exploration float64) (Entry, error) {
+	if category == "" {
+		return Entry{}, nil
+	}
+	from := `FROM commands c
+		LEFT JOIN (
+			SELECT v.entry_id, SUM(CASE WHEN v.value > 0 THEN 1 ELSE 0 END) AS up, SUM(CASE WHEN v.value < 0 THEN 1 ELSE 0 END) AS down
+			FROM votes v JOIN commands e ON e.id = v.entry_id
+			WHERE e.command = $1
+			GROUP BY v.entry_id
+		) v ON v.entry_id = c.id`
+	weight := `CASE WHEN c.weight < 1 THEN 1 ELSE c.weight END *
+		((COALESCE(v.up, 0) + 1.0) / (COALESCE(v.up, 0) + COALESCE(v.down, 0) + 2) + $3 / sqrt(c.plays + 1))`
+	e, err := s.pickEntry(ctx, from, weight, category, locale, exploration)
+	if err != nil {
+		return Entry{}, fmt.Errorf("query popular entry: %w", err)
+	}
+	return e, nil
+}
+
+// GuildLocale returns the locale set for a guild
+func (s *sqlStore) GuildLocale(ctx context.Context, guildID string) (string, error) {
+	var locale string
+	err := s.db.QueryRowContext(ctx, `SELECT locale FROM guild_settings WHERE guild_id = $1`, guildID).Scan(&locale)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("query guild locale: %w", err)
+	}
+	return locale, nil
+}
+
+// SetGuildLocale sets the locale of a guild
+func (s *sqlStore) SetGuildLocale(ctx context.Context, guildID, locale string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO guild_settings (guild_id, locale) VALUES ($1, $2)
+		ON CONFLICT (guild_id) DO UPDATE SET locale = excluded.locale`, guildID, locale)
+	if err != nil {
+		return fmt.Errorf("set guild locale: %w", err)
+	}
+	return nil
+}
+
+// GuildRoleMentions reports whether entries added by admins may ping roles
+// in a guild
+func (s *sqlStore) GuildRoleMentions(ctx context.Context, guildID string) (bool, error) {
+	var allowed bool
+	err := s.db.QueryRowContext(ctx, `SELECT role_mentions FROM guild_settings WHERE guild_id = $1`, guildID).Scan(&allowed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("query guild role mentions: %w", err)
+	}
+	return allowed, nil
+}
+
+// SetGuildRoleMentions sets whether entries added by admins may ping roles
+// in a guild
+func (s *sqlStore) SetGuildRoleMentions(ctx context.Context, guildID string, allowed bool) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO guild_settings (guild_id, role_mentions) VALUES ($1, $2)
+		ON CONFLICT (guild_id) DO UPDATE SET role_mentions = excluded.role_mentions`, guildID, allowed)
+	if err != nil {
+		return fmt.Errorf("set guild role mentions: %w", err)
+	}
+	return nil
+}
+
+// TryLock takes a PostgreSQL session advisory lock, held by a dedicated
+// connection until released. SQLite databases are not shared between
+// processes, so their locks only exclude users of this store.
+func (s *sqlStore) TryLock(ctx context.Context, name string) (Lock, error) {
+	if s.dialect == dialectSQLite {
+		return s.locks.tryLock(name)
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lock %s: %w", name, err)
+	}
+	key := lockKey(name)
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("lock %s: %w", name, err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, ErrLocked
+	}
+	return &advisoryLock{conn: conn, key: key}, nil
+}
+
+// advisoryLock is a PostgreSQL session advisory lock
+type advisoryLock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+// Check pings the connection holding the lock; the lock is lost with it
+func (l *advisoryLock) Check(ctx context.Context) error {
+	return l.conn.PingContext(ctx)
+}
+
+// Release unlocks and returns the connection to the pool
+func (l *advisoryLock) Release() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.key)
+	return errors.Join(err, l.conn.Close())
+}
+
+// SchemaVersion returns the highest applied migration, 0 when none was
+func (s *sqlStore) SchemaVersion(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'`
+	if s.dialect == dialectSQLite {
+		query = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`
+	}
+	var tables int
+	if err := s.db.QueryRowContext(ctx, query).Scan(&tables); err != nil {
+		return 0, fmt.Errorf("query schema_migrations table: %w", err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+
+	var version int
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("query schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Ping checks the database connection
+func (s *sqlStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close closes the database connection pool
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// Ensure sqlStore implements Store
+var _ Store = (*sqlStore)(nil)
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// openSQLite opens a SQLite database. The connection string is the path
+// after the scheme, e.g. sqlite:///var/lib/mutsumi/bot.db for an absolute
+// path, sqlite://bot.db for a relative one or sqlite://:memory:.
+func openSQLite(ctx context.Context, dsn string, opts Options) (Store, error) {
+	path, err := sqlitePath(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+
+	// SQLite allows a single writer, and every connection to :memory: would
+	// otherwise get its own empty database
+	db.SetMaxOpenConns(1)
+
+	store, err := newSQLStore(ctx, db, dialectSQLite, !opts.SkipMigrations)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// sqlitePath extracts the database path from a sqlite:// connection string
+func sqlitePath(dsn string) (string, error) {
+	_, rest, _ := strings.Cut(dsn, ":")
+	path := strings.TrimPrefix(rest, "//")
+	path, _, _ = strings.Cut(path, "?")
+	if path == "" {
+		return "", fmt.Errorf("missing database path in sqlite connection string")
+	}
+	return path, nil
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"math"
+	"slices"
+)
+
+// EntryStats is a live entry with how often it was served and voted on
+type EntryStats struct {
+	Entry
+	Votes
+	// Plays is how many times the entry was served
+	Plays int
+}
+
+// Score is the net rating of the entry, up votes minus down votes
+func (s EntryStats) Score() int {
+	return s.Up - s.Down
+}
+
+// Approval estimates the share of people who like the entry, between 0 and
+// 1. Entries without votes are at 0.5, and a few votes move it less than
+// many do.
+func (s EntryStats) Approval() float64 {
+	return float64(s.Up+1) / float64(s.Up+s.Down+2)
+}
+
+// PopularWeight is the selection weight of the entry when favoring popular
+// entries: its weight scaled by its approval, plus a bonus of exploration
+// that fades as the entry is played, so that new entries still come up
+func (s EntryStats) PopularWeight(exploration float64) float64 {
+	return float64(max(s.Weight, 1)) * (s.Approval() + exploration/math.Sqrt(float64(s.Plays+1)))
+}
+
+// SortByRating orders entries from the best rated: by score, then up votes,
+// then plays, then ID
+func SortByRating(stats []EntryStats) {
+	slices.SortStableFunc(stats, func(a, b EntryStats) int {
+		switch {
+		case a.Score() != b.Score():
+			return b.Score() - a.Score()
+		case a.Up != b.Up:
+			return b.Up - a.Up
+		case a.Plays != b.Plays:
+			return b.Plays - a.Plays
+		case a.ID < b.ID:
+			return -1
+		case a.ID > b.ID:
+			return 1
+		}
+		return 0
+	})
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrSuggestionNotFound is returned when no suggestion has the requested ID
+	ErrSuggestionNotFound = errors.New("suggestion not found")
+	// ErrSuggestionReviewed is returned when reviewing a suggestion that was
+	// already approved or rejected
+	ErrSuggestionReviewed = errors.New("suggestion already reviewed")
+)
+
+// SuggestionStatus is the review state of a suggestion
+type SuggestionStatus string
+
+const (
+	SuggestionPending  SuggestionStatus = "pending"
+	SuggestionApproved SuggestionStatus = "approved"
+	SuggestionRejected SuggestionStatus = "rejected"
+)
+
+// Suggestion is content submitted by a user, waiting for or past review.
+// Times are stored with second precision.
+type Suggestion struct {
+	ID       int64
+	GuildID  string
+	UserID   string
+	Category string
+	Content  string
+	Status   SuggestionStatus
+	// ReviewerID is the ID of the admin who approved or rejected it
+	ReviewerID string
+	// EntryID is the entry created when the suggestion was approved
+	EntryID    int64
+	CreatedAt  time.Time
+	ReviewedAt time.Time
+}
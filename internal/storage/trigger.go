@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTriggerNotFound is returned when no trigger has the requested ID
+var ErrTriggerNotFound = errors.New("trigger not found")
+
+// TriggerKind is how the pattern of a trigger is matched
+type TriggerKind string
+
+const (
+	// TriggerWord matches a single whole word, ignoring case
+	TriggerWord TriggerKind = "word"
+	// TriggerPhrase matches a sequence of whole words, ignoring case and
+	// the amount of whitespace between them
+	TriggerPhrase TriggerKind = "phrase"
+	// TriggerRegex matches a regular expression in RE2 syntax
+	TriggerRegex TriggerKind = "regex"
+)
+
+// Trigger replies with a random entry of a category to messages matching
+// its pattern, without a command prefix
+type Trigger struct {
+	ID      int64
+	GuildID string
+	// ChannelID limits the trigger to one channel, empty for every channel
+	ChannelID string
+	Kind      TriggerKind
+	Pattern   string
+	Category  string
+	// Cooldown is the minimum time between two replies of the trigger in a
+	// channel, stored with second precision
+	Cooldown time.Duration
+	// Chance is the percentage of matching messages that get a reply
+	Chance int
+	// CreatedBy is the ID of the user who added the trigger
+	CreatedBy string
+}
@@ -0,0 +1,12 @@
+package storage
+
+import "errors"
+
+// ErrInvalidVote is returned for a vote other than +1 or -1
+var ErrInvalidVote = errors.New("vote must be +1 or -1")
+
+// Votes is the tally of the votes on an entry
+type Votes struct {
+	Up   int
+	Down int
+}
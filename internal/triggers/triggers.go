@@ -0,0 +1,211 @@
+// Package triggers matches messages against the auto-responder triggers of
+// a guild. Compiled patterns are cached per guild, and the cooldown and
+// chance of every trigger are applied here.
+package triggers
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// DefaultCacheTTL is how long the triggers of a guild are cached. Changes
+// made through this process invalidate the cache at once; the TTL bounds how
+// long other replicas keep using old triggers.
+const DefaultCacheTTL = time.Minute
+
+// MaxPatternLength limits the length of a pattern
+const MaxPatternLength = 200
+
+// Kinds lists the supported trigger kinds
+var Kinds = []storage.TriggerKind{storage.TriggerWord, storage.TriggerPhrase, storage.TriggerRegex}
+
+// Compile validates the pattern of a trigger and returns its regular
+// expression. Word and phrase patterns only match whole words, ignoring
+// case; regex patterns are used as they are.
+func Compile(kind storage.TriggerKind, pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern is empty")
+	}
+	if len(pattern) > MaxPatternLength {
+		return nil, fmt.Errorf("pattern is longer than %d characters", MaxPatternLength)
+	}
+
+	switch kind {
+	case storage.TriggerWord:
+		if strings.ContainsFunc(pattern, isSpace) {
+			return nil, fmt.Errorf("a word can't contain spaces, use a phrase")
+		}
+		return wholeWords([]string{pattern}), nil
+	case storage.TriggerPhrase:
+		return wholeWords(strings.Fields(pattern)), nil
+	case storage.TriggerRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return re, nil
+	default:
+		return nil, fmt.Errorf("unknown trigger kind %q, expected word, phrase or regex", kind)
+	}
+}
+
+// wholeWords matches the words in sequence, separated by any whitespace and
+// not inside longer words
+func wholeWords(words []string) *regexp.Regexp {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = regexp.QuoteMeta(w)
+	}
+	const boundary = `[^\p{L}\p{N}_]`
+	return regexp.MustCompile(`(?i)(?:^|` + boundary + `)` + strings.Join(quoted, `\s+`) + `(?:$|` + boundary + `)`)
+}
+
+// isSpace reports whether r separates words
+func isSpace(r rune) bool {
+	return strings.ContainsRune(" \t\r\n", r)
+}
+
+// compiled is a trigger with its compiled pattern
+type compiled struct {
+	storage.Trigger
+	re *regexp.Regexp
+}
+
+// guildTriggers are the cached triggers of a guild
+type guildTriggers struct {
+	triggers []compiled
+	loaded   time.Time
+}
+
+// cooldownKey identifies a trigger in a channel
+type cooldownKey struct {
+	triggerID int64
+	channelID string
+}
+
+// Matcher finds the trigger answering a message
+type Matcher struct {
+	store storage.Store
+	ttl   time.Duration
+
+	mu     sync.Mutex
+	guilds map[string]guildTriggers
+	// cooldowns holds when each trigger can reply again in a channel
+	cooldowns map[cooldownKey]time.Time
+
+	// now and roll are replaced in tests; roll returns a number in [0, 100)
+	now  func() time.Time
+	roll func() int
+}
+
+// NewMatcher creates a matcher caching the triggers of each guild for ttl
+func NewMatcher(store storage.Store, ttl time.Duration) *Matcher {
+	return &Matcher{
+		store:     store,
+		ttl:       ttl,
+		guilds:    make(map[string]guildTriggers),
+		cooldowns: make(map[cooldownKey]time.Time),
+		now:       time.Now,
+		roll:      func() int { return rand.IntN(100) },
+	}
+}
+
+// Invalidate drops the cached triggers of a guild, e.g. after one changed
+func (m *Matcher) Invalidate(guildID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.guilds, guildID)
+}
+
+// Match returns the first trigger, by ID, that matches a message in a
+// channel, is not cooling down there and passes its chance. The cooldown of
+// the returned trigger starts at once.
+func (m *Matcher) Match(ctx context.Context, guildID, channelID, content string) (storage.Trigger, bool, error) {
+	if guildID == "" {
+		return storage.Trigger{}, false, nil
+	}
+	triggers, err := m.load(ctx, guildID)
+	if err != nil {
+		return storage.Trigger{}, false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for _, tr := range triggers {
+		if tr.ChannelID != "" && tr.ChannelID != channelID {
+			continue
+		}
+		if !tr.re.MatchString(content) {
+			continue
+		}
+		key := cooldownKey{triggerID: tr.ID, channelID: channelID}
+		if now.Before(m.cooldowns[key]) {
+			continue
+		}
+		if tr.Chance < 100 && m.roll() >= tr.Chance {
+			continue
+		}
+		if tr.Cooldown > 0 {
+			m.pruneCooldowns(now)
+			m.cooldowns[key] = now.Add(tr.Cooldown)
+		}
+		return tr.Trigger, true, nil
+	}
+	return storage.Trigger{}, false, nil
+}
+
+// load returns the compiled triggers of a guild, from the cache when fresh
+func (m *Matcher) load(ctx context.Context, guildID string) ([]compiled, error) {
+	m.mu.Lock()
+	cached, ok := m.guilds[guildID]
+	m.mu.Unlock()
+	if ok && m.now().Sub(cached.loaded) < m.ttl {
+		return cached.triggers, nil
+	}
+
+	stored, err := m.store.ListTriggers(ctx, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("load triggers: %w", err)
+	}
+	triggers := make([]compiled, 0, len(stored))
+	for _, tr := range stored {
+		re, err := Compile(tr.Kind, tr.Pattern)
+		if err != nil {
+			logger.Logger.Warn("Skipping invalid trigger",
+				zap.Int64("trigger_id", tr.ID),
+				zap.String("guild_id", guildID),
+				zap.Error(err))
+			continue
+		}
+		triggers = append(triggers, compiled{Trigger: tr, re: re})
+	}
+
+	m.mu.Lock()
+	m.guilds[guildID] = guildTriggers{triggers: triggers, loaded: m.now()}
+	m.mu.Unlock()
+	return triggers, nil
+}
+
+// pruneCooldowns drops expired cooldowns once there are many of them
+func (m *Matcher) pruneCooldowns(now time.Time) {
+	if len(m.cooldowns) < 1024 {
+		return
+	}
+	for key, until := range m.cooldowns {
+		if !now.Before(until) {
+			delete(m.cooldowns, key)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package triggers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/storage"
+)
+
+// TestCompile tests how each kind of pattern matches.
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      storage.TriggerKind
+		pattern   string
+		matches   []string
+		misses    []string
+		expectErr bool
+	}{
+		{
+			name:    "word",
+			kind:    storage.TriggerWord,
+			pattern: "wooper",
+			matches: []string{"wooper", "I love WOOPER!", "wooper, again", "(wooper)"},
+			misses:  []string{"woopers", "quagwooper", "woo per"},
+		},
+		{
+			name:    "word with symbols",
+			kind:    storage.TriggerWord,
+			pattern: "c++",
+			matches: []string{"c++", "I write C++ daily"},
+			misses:  []string{"c+", "cc++x"},
+		},
+		{
+			name:    "phrase",
+			kind:    storage.TriggerPhrase,
+			pattern: "good  morning",
+			matches: []string{"Good morning!", "well, good\tmorning to you"},
+			misses:  []string{"goodmorning", "good mornings", "morning good"},
+		},
+		{
+			name:    "regex",
+			kind:    storage.TriggerRegex,
+			pattern: `^w+o{2,}per$`,
+			matches: []string{"wooper", "wwwoooooper"},
+			misses:  []string{"Wooper", "a wooper"},
+		},
+		{name: "word with spaces", kind: storage.TriggerWord, pattern: "good morning", expectErr: true},
+		{name: "empty pattern", kind: storage.TriggerPhrase, pattern: "  ", expectErr: true},
+		{name: "invalid regex", kind: storage.TriggerRegex, pattern: "(wooper", expectErr: true},
+		{name: "unknown kind", kind: "glob", pattern: "woop*", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := Compile(tt.kind, tt.pattern)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("Expected an error for %q", tt.pattern)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, s := range tt.matches {
+				if !re.MatchString(s) {
+					t.Errorf("Expected %q to match %q", tt.pattern, s)
+				}
+			}
+			for _, s := range tt.misses {
+				if re.MatchString(s) {
+					t.Errorf("Expected %q not to match %q", tt.pattern, s)
+				}
+			}
+		})
+	}
+}
+
+// setupTestMatcher creates a matcher on a memory store with a fixed clock
+func setupTestMatcher(t *testing.T, triggers ...storage.Trigger) (*Matcher, *storage.MemoryStore, *time.Time) {
+	t.Helper()
+	if err := logger.Init(); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(logger.Close)
+
+	store := storage.NewMemoryStore()
+	for _, tr := range triggers {
+		if _, err := store.AddTrigger(context.Background(), tr); err != nil {
+			t.Fatalf("AddTrigger: %v", err)
+		}
+	}
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	m := NewMatcher(store, time.Minute)
+	m.now = func() time.Time { return now }
+	return m, store, &now
+}
+
+// TestMatcher_Match tests scoping, cooldowns and chances.
+func TestMatcher_Match(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("scoping", func(t *testing.T) {
+		m, _, _ := setupTestMatcher(t,
+			storage.Trigger{GuildID: "guild-1", ChannelID: "channel-2", Kind: storage.TriggerWord, Pattern: "wooper", Category: "quagsire", Chance: 100},
+			storage.Trigger{GuildID: "guild-1", Kind: storage.TriggerWord, Pattern: "wooper", Category: "wooper", Chance: 100},
+		)
+
+		tests := []struct {
+			guildID, channelID, content string
+			wantCategory                string
+		}{
+			{"guild-1", "channel-1", "a wild wooper", "wooper"},
+			{"guild-1", "channel-2", "a wild wooper", "quagsire"},
+			{"guild-1", "channel-1", "a wild quagsire", ""},
+			{"guild-2", "channel-1", "a wild wooper", ""},
+			{"", "dm-channel", "a wild wooper", ""},
+		}
+		for _, tt := range tests {
+			tr, ok, err := m.Match(ctx, tt.guildID, tt.channelID, tt.content)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ok != (tt.wantCategory != "") || tr.Category != tt.wantCategory {
+				t.Errorf("%s/%s %q: expected %q, got %q (matched %v)", tt.guildID, tt.channelID, tt.content, tt.wantCategory, tr.Category, ok)
+			}
+		}
+	})
+
+	t.Run("cooldown", func(t *testing.T) {
+		m, _, now := setupTestMatcher(t,
+			storage.Trigger{GuildID: "guild-1", Kind: storage.TriggerWord, Pattern: "wooper", Category: "wooper", Cooldown: time.Minute, Chance: 100},
+		)
+
+		steps := []struct {
+			after     time.Duration
+			channelID string
+			want      bool
+		}{
+			{0, "channel-1", true},
+			{30 * time.Second, "channel-1", false},
+			{0, "channel-2", true},
+			{30 * time.Second, "channel-1", true},
+		}
+		for i, step := range steps {
+			*now = now.Add(step.after)
+			if _, ok, _ := m.Match(ctx, "guild-1", step.channelID, "wooper"); ok != step.want {
+				t.Errorf("Step %d: expected match %v, got %v", i, step.want, ok)
+			}
+		}
+	})
+
+	t.Run("chance", func(t *testing.T) {
+		m, _, _ := setupTestMatcher(t,
+			storage.Trigger{GuildID: "guild-1", Kind: storage.TriggerWord, Pattern: "wooper", Category: "rare", Chance: 10},
+			storage.Trigger{GuildID: "guild-1", Kind: storage.TriggerWord, Pattern: "wooper", Category: "wooper", Chance: 100},
+		)
+
+		for roll, want := range map[int]string{9: "rare", 10: "wooper", 99: "wooper"} {
+			m.roll = func() int { return roll }
+			if tr, _, _ := m.Match(ctx, "guild-1", "channel-1", "wooper"); tr.Category != want {
+				t.Errorf("Roll %d: expected %q, got %q", roll, want, tr.Category)
+			}
+		}
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		m, store, now := setupTestMatcher(t)
+		if _, ok, _ := m.Match(ctx, "guild-1", "channel-1", "wooper"); ok {
+			t.Fatalf("Expected no match without triggers")
+		}
+
+		store.AddTrigger(ctx, storage.Trigger{GuildID: "guild-1", Kind: storage.TriggerWord, Pattern: "wooper", Category: "wooper", Chance: 100})
+		if _, ok, _ := m.Match(ctx, "guild-1", "channel-1", "wooper"); ok {
+			t.Errorf("Expected the cached triggers to be used")
+		}
+		*now = now.Add(time.Minute)
+		if _, ok, _ := m.Match(ctx, "guild-1", "channel-1", "wooper"); !ok {
+			t.Errorf("Expected the triggers to be reloaded after the TTL")
+		}
+
+		store.RemoveTrigger(ctx, "guild-1", 1)
+		m.Invalidate("guild-1")
+		if _, ok, _ := m.Match(ctx, "guild-1", "channel-1", "wooper"); ok {
+			t.Errorf("Expected the triggers to be reloaded after Invalidate")
+		}
+	})
+}
@@ -3,25 +3,40 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"mutsumi-bot/internal/audit"
 	"mutsumi-bot/internal/bot"
+	"mutsumi-bot/internal/cli"
 	"mutsumi-bot/internal/config"
 	"mutsumi-bot/internal/handlers"
+	"mutsumi-bot/internal/i18n"
 	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/reload"
+	"mutsumi-bot/internal/scheduler"
 	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+	"mutsumi-bot/internal/triggers"
 
 	"github.com/bwmarrin/discordgo"
 	"go.uber.org/zap"
 )
 
-func buildCategoryChoices(contentService services.ContentService) []*discordgo.ApplicationCommandOptionChoice {
-	categories := contentService.GetAvailableCategories()
+func buildCategoryChoices(contentService services.ContentService) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	categories, err := contentService.GetAvailableCategories()
+	if err != nil {
+		return nil, err
+	}
 
 	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(categories))
 	for i, category := range categories {
@@ -31,7 +46,7 @@ func buildCategoryChoices(contentService services.ContentService) []*discordgo.A
 		}
 	}
 
-	return choices
+	return choices, nil
 }
 
 func main() {
@@ -41,30 +56,112 @@ func main() {
 	}
 	defer logger.Close()
 
+	// Offline subcommands manage the database without starting the bot;
+	// flags configure the bot itself
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		code := cli.Run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+		stop()
+		logger.Close()
+		os.Exit(code)
+	}
+
 	logger.Logger.Info("Starting mutsumi-bot")
 
-	cfg, err := config.Load()
+	loadOptions := config.Options{Args: os.Args[1:], RequireToken: true}
+	cfg, err := config.LoadWith(loadOptions)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "Usage: mutsumi-bot [flags]")
+		config.Usage(os.Stderr)
+		os.Exit(2)
+	}
 	if err != nil {
 		logger.Logger.Fatal("config error", zap.Error(err))
 	}
+	if err := logger.SetLevel(cfg.LogLevel); err != nil {
+		logger.Logger.Fatal("config error", zap.Error(err))
+	}
 
-	databaseService, err := services.NewDatabaseService(cfg.DatabaseConnection)
+	databaseService, err := services.NewDatabaseServiceWithOptions(cfg.DatabaseConnection, storage.Options{
+		MaxOpenConns:     cfg.Database.MaxOpenConns,
+		MaxIdleConns:     cfg.Database.MaxIdleConns,
+		ConnMaxLifetime:  cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime:  cfg.Database.ConnMaxIdleTime,
+		StatementTimeout: cfg.Database.StatementTimeout,
+		ConnectTimeout:   cfg.Database.ConnectTimeout,
+	})
 	if err != nil {
 		logger.Logger.Fatal("database service error", zap.Error(err))
 	}
 	defer databaseService.Close()
+	databaseService.SetCategoryCacheTTL(cfg.Cache.CategoryTTL)
+	databaseService.SetSelection(services.SelectionMode(cfg.Selection.Mode), cfg.Selection.Exploration)
 
+	limiter := handlers.NewRateLimiter(cfg.RateLimit.Commands, cfg.RateLimit.Window)
+	triggerMatcher := triggers.NewMatcher(databaseService.Store(), triggers.DefaultCacheTTL)
+	localizer := handlers.NewLocalizer(databaseService.Store(), cfg.Bot.Locale)
+	mentionPolicy := handlers.NewMentionPolicy(databaseService.Store())
 	messageHandler := handlers.NewMessageHandler(databaseService)
+	messageHandler.SetPrefix(cfg.Bot.Prefix)
+	messageHandler.Limiter = limiter
+	messageHandler.Triggers = triggerMatcher
+	messageHandler.SaveButton = true
+	messageHandler.Locales = localizer
+	messageHandler.AttachmentThreshold = cfg.Delivery.AttachmentThreshold
+	messageHandler.Mentions = mentionPolicy
 	interactionHandler := handlers.NewInteractionHandler(databaseService)
+	interactionHandler.Limiter = limiter
+	interactionHandler.SaveButton = true
+	interactionHandler.Locales = localizer
+	interactionHandler.Store = databaseService.Store()
+	interactionHandler.Delivery = handlers.NewDeliveryPolicy(cfg.Delivery.Mode, cfg.Delivery.CategoryModes())
+	interactionHandler.AttachmentThreshold = cfg.Delivery.AttachmentThreshold
+	interactionHandler.Mentions = mentionPolicy
+	interactionHandler.Buttons = handlers.NewContentButtons(databaseService.Store(),
+		handlers.NewButtonSigner([]byte(cfg.Buttons.Secret)), cfg.Buttons.Timeout, cfg.Buttons.Delete)
+	contentAdminHandler := handlers.NewContentAdminHandler(databaseService.Store())
+	contentAdminHandler.Locales = localizer
+	contentAdminHandler.Categories = databaseService
+	scheduleHandler := handlers.NewScheduleHandler(databaseService.Store(), databaseService)
+	scheduleHandler.Locales = localizer
+	triggerHandler := handlers.NewTriggerHandler(databaseService.Store(), databaseService, triggerMatcher)
+	triggerHandler.Locales = localizer
+	favoritesHandler := handlers.NewFavoritesHandler(databaseService.Store())
+	favoritesHandler.AttachmentThreshold = cfg.Delivery.AttachmentThreshold
+	favoritesHandler.Mentions = mentionPolicy
+	favoritesHandler.Locales = localizer
+	topHandler := handlers.NewTopHandler(databaseService.Store())
+	topHandler.Locales = localizer
+	localeHandler := handlers.NewLocaleHandler(localizer)
+	mentionsHandler := handlers.NewMentionsHandler(mentionPolicy)
+	mentionsHandler.Locales = localizer
 
-	b, err := bot.New(cfg.DiscordBotToken)
+	botOptions := []bot.Option{
+		bot.WithIntents(cfg.Bot.GatewayIntents()),
+		bot.WithShards(cfg.Bot.ShardCount, cfg.Bot.ShardIDs...),
+	}
+	if cfg.Bot.DevGuildID != "" {
+		botOptions = append(botOptions, bot.WithGuildID(cfg.Bot.DevGuildID))
+	}
+	b, err := bot.New(cfg.DiscordBotToken, botOptions...)
 	if err != nil {
 		logger.Logger.Fatal("bot error", zap.Error(err))
 	}
 	b.AddHandler(messageHandler.OnMessageCreate)
 	b.AddHandler(interactionHandler.OnInteractionCreate)
+	b.AddHandler(contentAdminHandler.OnInteractionCreate)
+	b.AddHandler(scheduleHandler.OnInteractionCreate)
+	b.AddHandler(triggerHandler.OnInteractionCreate)
+	b.AddHandler(favoritesHandler.OnInteractionCreate)
+	b.AddHandler(topHandler.OnInteractionCreate)
+	b.AddHandler(localeHandler.OnInteractionCreate)
+	b.AddHandler(mentionsHandler.OnInteractionCreate)
 
 	// Register slash commands
+	categoryChoices, err := buildCategoryChoices(databaseService)
+	if err != nil {
+		logger.Logger.Fatal("failed to load categories", zap.Error(err))
+	}
 	commands := []*discordgo.ApplicationCommand{
 		{
 			Name:        "command",
@@ -75,29 +172,66 @@ func main() {
 					Name:        "command",
 					Description: "Command to get content from",
 					Required:    true,
-					Choices:     buildCategoryChoices(databaseService),
+					Choices:     categoryChoices,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "private",
+					Description: "Only show the reply to you",
 				},
 			},
 		},
+		handlers.ContentAdminCommand(),
+		handlers.ScheduleCommand(),
+		handlers.TriggerCommand(),
+		handlers.FavoritesCommand(),
+		handlers.TopCommand(),
+		handlers.LocaleCommand(),
+		handlers.MentionsCommand(),
+	}
+	commands = append(commands, handlers.ContextMenuCommands()...)
+
+	// /suggest needs somewhere to post suggestions for review
+	if cfg.Suggestions.ReviewChannelID != "" {
+		suggestionHandler := handlers.NewSuggestionHandler(databaseService.Store(), databaseService,
+			cfg.Suggestions.ReviewChannelID, cfg.Suggestions.MaxPending, cfg.Suggestions.DailyLimit)
+		suggestionHandler.Locales = localizer
+		b.AddHandler(suggestionHandler.OnInteractionCreate)
+		commands = append(commands, handlers.SuggestCommand())
+	}
+	for _, cmd := range commands {
+		i18n.LocalizeCommand(cmd)
 	}
 
 	logger.Logger.Info("Bot initialized successfully")
 
 	// Start health check HTTP server
-	healthPort := os.Getenv("HEALTH_PORT")
-	if healthPort == "" {
-		healthPort = "8089"
-	}
+	healthPort := strconv.Itoa(cfg.HTTP.HealthPort)
 
 	healthMux := http.NewServeMux()
-	healthMux.HandleFunc("/health", healthHandler(databaseService))
+	healthMux.HandleFunc("/health", healthHandler(databaseService, b))
+	healthMux.HandleFunc("/readyz", readyHandler(databaseService, b))
+
+	// Reload the log level, prefix, rate limits, cache TTL and selection in place
+	reloader := reload.New(cfg,
+		func() (config.Config, error) { return config.LoadWith(loadOptions) },
+		func(next config.Config) {
+			_ = logger.SetLevel(next.LogLevel)
+			messageHandler.SetPrefix(next.Bot.Prefix)
+			limiter.SetLimit(next.RateLimit.Commands, next.RateLimit.Window)
+			databaseService.SetCategoryCacheTTL(next.Cache.CategoryTTL)
+			databaseService.SetSelection(services.SelectionMode(next.Selection.Mode), next.Selection.Exploration)
+		})
+	if cfg.HTTP.AdminToken != "" {
+		healthMux.Handle("/admin/reload", reloader.Handler(cfg.HTTP.AdminToken))
+	}
 
 	healthServer := &http.Server{
 		Addr:         ":" + healthPort,
 		Handler:      healthMux,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  15 * time.Second,
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
 	}
 
 	// Start health server in a goroutine
@@ -111,6 +245,30 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	databaseService.StartHealthCheck(ctx, 10*time.Second)
+	reloader.WatchSignals(ctx, syscall.SIGHUP)
+	if cfg.File != "" {
+		reloader.WatchFile(ctx, cfg.File, reload.DefaultPollInterval)
+	}
+
+	// Every replica runs the scheduler; only the lock holder posts
+	missedRuns, err := scheduler.ParseMissedPolicy(cfg.Scheduler.MissedRuns)
+	if err != nil {
+		logger.Logger.Fatal("config error", zap.Error(err))
+	}
+	postScheduler := scheduler.New(databaseService.Store(), databaseService, b.Session(), scheduler.Options{
+		PollInterval: cfg.Scheduler.PollInterval,
+		MissedRuns:   missedRuns,
+		Locale:       localizer.ForGuild,
+		Mentions:     mentionPolicy.ForGuild,
+	})
+	go postScheduler.Run(ctx)
+
+	if cfg.Audit.ChannelID != "" {
+		auditMirror := audit.New(databaseService.Store(), b.Session(), audit.Options{ChannelID: cfg.Audit.ChannelID})
+		go auditMirror.Run(ctx)
+	}
+
 	// Start bot in a goroutine
 	go func() {
 		if err := b.StartWithCommands(ctx, commands); err != nil {
@@ -123,15 +281,16 @@ func main() {
 	logger.Logger.Info("Shutting down...")
 
 	// Gracefully shutdown health server
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Bot.ShutdownTimeout)
 	defer cancel()
 	if err := healthServer.Shutdown(shutdownCtx); err != nil {
 		logger.Logger.Error("Error shutting down health server", zap.Error(err))
 	}
 }
 
-// healthHandler returns a handler function for the /health endpoint
-func healthHandler(dbService *services.DatabaseService) http.HandlerFunc {
+// healthHandler returns a handler function for the /health endpoint, which
+// also reports the status of every gateway shard
+func healthHandler(dbService *services.DatabaseService, b *bot.Bot) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Check database connection
 		dbHealthy := true
@@ -153,6 +312,7 @@ func healthHandler(dbService *services.DatabaseService) http.HandlerFunc {
 			"database": map[string]interface{}{
 				"connected": dbHealthy,
 			},
+			"shards": b.Shards(),
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -160,3 +320,23 @@ func healthHandler(dbService *services.DatabaseService) http.HandlerFunc {
 		json.NewEncoder(w).Encode(response)
 	}
 }
+
+// readyHandler returns a handler for the /readyz endpoint, which fails while
+// database calls fail or a gateway shard is disconnected
+func readyHandler(dbService *services.DatabaseService, b *bot.Bot) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := "ready"
+		statusCode := http.StatusOK
+		if err := errors.Join(dbService.Ready(), b.Ready()); err != nil {
+			status = "unavailable"
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":    status,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package fakediscord
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// File is an attachment uploaded with a multipart request
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// readPayload returns the JSON payload of a request along with any uploaded
+// files. Discord accepts either a plain JSON body or a multipart form whose
+// "payload_json" part carries the JSON.
+func readPayload(r *http.Request) ([]byte, []File, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := io.ReadAll(r.Body)
+		return body, nil, err
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("read multipart body: %w", err)
+	}
+
+	var payload []byte
+	var files []File
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("read multipart part: %w", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read multipart part: %w", err)
+		}
+		if part.FormName() == "payload_json" {
+			payload = data
+			continue
+		}
+		files = append(files, File{Name: part.FileName(), Content: data})
+	}
+
+	return payload, files, nil
+}
+
+// decodeComponents decodes a JSON array of message components. discordgo only
+// knows how to unmarshal components as part of a message, so the array is
+// wrapped in one.
+func decodeComponents(raw json.RawMessage) ([]discordgo.MessageComponent, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var m discordgo.Message
+	if err := json.Unmarshal([]byte(`{"components":`+string(raw)+`}`), &m); err != nil {
+		return nil, fmt.Errorf("decode components: %w", err)
+	}
+	return m.Components, nil
+}
+
+// decodeMessageSend decodes the body of a create message request
+func decodeMessageSend(payload []byte) (*discordgo.MessageSend, error) {
+	var req struct {
+		discordgo.MessageSend
+		Components json.RawMessage `json:"components"`
+	}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return nil, fmt.Errorf("decode message: %w", err)
+	}
+
+	components, err := decodeComponents(req.Components)
+	if err != nil {
+		return nil, err
+	}
+	req.MessageSend.Components = components
+
+	return &req.MessageSend, nil
+}
+
+// decodeInteractionResponse decodes the body of an interaction callback
+func decodeInteractionResponse(payload []byte) (*discordgo.InteractionResponse, error) {
+	var req struct {
+		Type discordgo.InteractionResponseType `json:"type"`
+		Data *struct {
+			discordgo.InteractionResponseData
+			Components json.RawMessage `json:"components"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return nil, fmt.Errorf("decode interaction response: %w", err)
+	}
+
+	resp := &discordgo.InteractionResponse{Type: req.Type}
+	if req.Data != nil {
+		components, err := decodeComponents(req.Data.Components)
+		if err != nil {
+			return nil, err
+		}
+		resp.Data = &req.Data.InteractionResponseData
+		resp.Data.Components = components
+	}
+
+	return resp, nil
+}
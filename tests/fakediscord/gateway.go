@@ -0,0 +1,236 @@
+package fakediscord
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+// Gateway opcodes used by the fake server
+const (
+	opDispatch     = 0
+	opHeartbeat    = 1
+	opIdentify     = 2
+	opResume       = 6
+	opHello        = 10
+	opHeartbeatAck = 11
+)
+
+// heartbeatIntervalMs is long enough that tests never wait on a heartbeat
+const heartbeatIntervalMs = 41250
+
+// gatewayPayload is a gateway message in either direction
+type gatewayPayload struct {
+	Op       int             `json:"op"`
+	Data     json.RawMessage `json:"d"`
+	Sequence int64           `json:"s,omitempty"`
+	Type     string          `json:"t,omitempty"`
+}
+
+// gatewayConn is a single client connection to the fake gateway
+type gatewayConn struct {
+	ws *websocket.Conn
+
+	mu       sync.Mutex
+	sequence int64
+	ready    bool
+	shard    *[2]int
+}
+
+// send writes a payload to the client
+func (c *gatewayConn) send(op int, eventType string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal %s payload: %w", eventType, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := gatewayPayload{Op: op, Data: raw, Type: eventType}
+	if op == opDispatch {
+		c.sequence++
+		p.Sequence = c.sequence
+	}
+	return c.ws.WriteJSON(p)
+}
+
+func (s *Server) handleGatewayConn(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn := &gatewayConn{ws: ws}
+
+	s.mu.Lock()
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		ws.Close()
+	}()
+
+	if err := conn.send(opHello, "", map[string]int{"heartbeat_interval": heartbeatIntervalMs}); err != nil {
+		return
+	}
+
+	for {
+		var p gatewayPayload
+		if err := ws.ReadJSON(&p); err != nil {
+			return
+		}
+
+		switch p.Op {
+		case opHeartbeat:
+			if err := conn.send(opHeartbeatAck, "", nil); err != nil {
+				return
+			}
+		case opIdentify:
+			// The presence part of IDENTIFY does not round-trip through
+			// discordgo's types, so only the fields tests care about are kept
+			var identify struct {
+				Token   string           `json:"token"`
+				Shard   *[2]int          `json:"shard"`
+				Intents discordgo.Intent `json:"intents"`
+			}
+			if err := json.Unmarshal(p.Data, &identify); err != nil {
+				return
+			}
+			err := s.onIdentify(conn, discordgo.Identify{
+				Token:   identify.Token,
+				Shard:   identify.Shard,
+				Intents: identify.Intents,
+			})
+			if err != nil {
+				return
+			}
+		case opResume:
+			if err := conn.send(opDispatch, "RESUMED", map[string]any{}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// onIdentify records an IDENTIFY and answers with READY
+func (s *Server) onIdentify(conn *gatewayConn, identify discordgo.Identify) error {
+	s.mu.Lock()
+	s.identifies = append(s.identifies, identify)
+	s.mu.Unlock()
+
+	ready := discordgo.Ready{
+		Version:     9,
+		SessionID:   s.nextID(),
+		User:        s.BotUser,
+		Shard:       identify.Shard,
+		Application: &discordgo.Application{ID: s.AppID},
+		Guilds:      []*discordgo.Guild{},
+	}
+	if err := conn.send(opDispatch, "READY", ready); err != nil {
+		return err
+	}
+
+	conn.mu.Lock()
+	conn.ready = true
+	conn.shard = identify.Shard
+	conn.mu.Unlock()
+
+	return nil
+}
+
+// ConnectedClients returns the number of gateway clients that completed IDENTIFY
+func (s *Server) ConnectedClients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for c := range s.conns {
+		c.mu.Lock()
+		if c.ready {
+			n++
+		}
+		c.mu.Unlock()
+	}
+	return n
+}
+
+// Dispatch sends an event to every ready gateway client. When a client
+// identified with a shard, only the shard owning guildID receives it.
+func (s *Server) Dispatch(eventType, guildID string, data any) error {
+	s.mu.Lock()
+	conns := make([]*gatewayConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	sent := 0
+	for _, c := range conns {
+		c.mu.Lock()
+		ready, shard := c.ready, c.shard
+		c.mu.Unlock()
+
+		if !ready || !ownsGuild(shard, guildID) {
+			continue
+		}
+		if err := c.send(opDispatch, eventType, data); err != nil {
+			return fmt.Errorf("dispatch %s: %w", eventType, err)
+		}
+		sent++
+	}
+
+	if sent == 0 {
+		return fmt.Errorf("dispatch %s: no gateway client connected", eventType)
+	}
+	return nil
+}
+
+// DispatchMessageCreate sends a MESSAGE_CREATE event. Missing IDs and
+// timestamps are filled in.
+func (s *Server) DispatchMessageCreate(m *discordgo.Message) error {
+	if m.ID == "" {
+		m.ID = s.nextID()
+	}
+	if m.Timestamp.IsZero() {
+		m.Timestamp = time.Now().UTC()
+	}
+	return s.Dispatch("MESSAGE_CREATE", m.GuildID, m)
+}
+
+// DispatchInteractionCreate sends an INTERACTION_CREATE event. Missing IDs,
+// tokens and the application ID are filled in.
+func (s *Server) DispatchInteractionCreate(i *discordgo.Interaction) error {
+	if i.ID == "" {
+		i.ID = s.nextID()
+	}
+	if i.Token == "" {
+		i.Token = "token-" + i.ID
+	}
+	if i.AppID == "" {
+		i.AppID = s.AppID
+	}
+	return s.Dispatch("INTERACTION_CREATE", i.GuildID, i)
+}
+
+// ownsGuild reports whether a shard receives events for a guild, following
+// Discord's (guild_id >> 22) % num_shards rule
+func ownsGuild(shard *[2]int, guildID string) bool {
+	if shard == nil || shard[1] <= 1 {
+		return true
+	}
+	// Direct messages are always sent to shard 0
+	id, err := strconv.ParseUint(guildID, 10, 64)
+	if err != nil {
+		return shard[0] == 0
+	}
+	return int((id>>22)%uint64(shard[1])) == shard[0]
+}
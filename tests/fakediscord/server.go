@@ -0,0 +1,425 @@
+// Package fakediscord provides a local stand-in for the Discord REST API and
+// gateway, speaking just enough of both protocols to run the bot end to end
+// in tests without a real token.
+package fakediscord
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+// DefaultAppID is the application (and bot user) ID reported by the server
+const DefaultAppID = "100000000000000001"
+
+// InteractionResponse is an interaction callback received by the server
+type InteractionResponse struct {
+	InteractionID string
+	Token         string
+	Response      discordgo.InteractionResponse
+}
+
+// ResponseEdit is an edit of an interaction's original response
+type ResponseEdit struct {
+	Token   string
+	Content string
+	Files   []File
+}
+
+// Server is a fake Discord API. All REST calls made through Client and all
+// gateway connections are recorded so tests can assert on them.
+type Server struct {
+	AppID   string
+	BotUser *discordgo.User
+	// Token, when set, is the only bot token GET /users/@me accepts
+	Token string
+	// Shards and MaxConcurrency are reported by GET /gateway/bot; zero
+	// values report 1
+	Shards         int
+	MaxConcurrency int
+
+	httpServer *httptest.Server
+	upgrader   websocket.Upgrader
+
+	mu         sync.Mutex
+	conns      map[*gatewayConn]struct{}
+	identifies []discordgo.Identify
+	commands   map[string][]*discordgo.ApplicationCommand // guild ID ("" for global) -> commands
+	cmdWrites  int
+	messages   []*discordgo.Message
+	responses  []InteractionResponse
+	edits      []ResponseEdit
+	lastID     int64
+}
+
+// New starts a fake Discord server and stops it when the test finishes
+func New(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		AppID: DefaultAppID,
+		BotUser: &discordgo.User{
+			ID:       DefaultAppID,
+			Username: "mutsumi-test",
+			Bot:      true,
+		},
+		conns:    make(map[*gatewayConn]struct{}),
+		commands: make(map[string][]*discordgo.ApplicationCommand),
+		lastID:   200000000000000000,
+	}
+	s.httpServer = httptest.NewServer(s.routes())
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Close disconnects all gateway clients and stops the server
+func (s *Server) Close() {
+	s.mu.Lock()
+	for c := range s.conns {
+		c.ws.Close()
+	}
+	s.mu.Unlock()
+	s.httpServer.Close()
+}
+
+// URL returns the base URL of the fake server
+func (s *Server) URL() string {
+	return s.httpServer.URL
+}
+
+// Client returns an HTTP client that sends every request, whatever its host,
+// to the fake server. Pass it to the Discord session in place of the default.
+func (s *Server) Client() *http.Client {
+	target, _ := url.Parse(s.httpServer.URL)
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &rewriteTransport{target: target, base: http.DefaultTransport},
+	}
+}
+
+// rewriteTransport redirects requests to the fake server
+type rewriteTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+	return t.base.RoundTrip(req)
+}
+
+// nextID returns a new unique snowflake-like ID
+func (s *Server) nextID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	return strconv.FormatInt(s.lastID, 10)
+}
+
+// Identifies returns the IDENTIFY payloads received on the gateway
+func (s *Server) Identifies() []discordgo.Identify {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]discordgo.Identify(nil), s.identifies...)
+}
+
+// Commands returns the application commands registered for a guild, or the
+// global commands when guildID is empty
+func (s *Server) Commands(guildID string) []*discordgo.ApplicationCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*discordgo.ApplicationCommand(nil), s.commands[guildID]...)
+}
+
+// CommandWrites returns the number of requests that created or overwrote
+// application commands
+func (s *Server) CommandWrites() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cmdWrites
+}
+
+// Messages returns the channel messages created through the REST API
+func (s *Server) Messages() []*discordgo.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*discordgo.Message(nil), s.messages...)
+}
+
+// InteractionResponses returns the interaction callbacks received
+func (s *Server) InteractionResponses() []InteractionResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]InteractionResponse(nil), s.responses...)
+}
+
+// ResponseEdits returns the edits made to original interaction responses
+func (s *Server) ResponseEdits() []ResponseEdit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ResponseEdit(nil), s.edits...)
+}
+
+// WaitFor polls cond until it returns true or the timeout expires.
+// It reports whether the condition was met.
+func (s *Server) WaitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if cond() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// routes builds the HTTP handler for the REST API and the gateway
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	api := "/api/v" + discordgo.APIVersion
+
+	// discordgo appends a trailing slash to the gateway URL
+	mux.HandleFunc("GET /gateway/{$}", s.handleGatewayConn)
+	mux.HandleFunc("GET "+api+"/gateway", s.handleGateway)
+	mux.HandleFunc("GET "+api+"/gateway/bot", s.handleGatewayBot)
+
+	mux.HandleFunc("GET "+api+"/applications/{app}/commands", s.handleListCommands)
+	mux.HandleFunc("POST "+api+"/applications/{app}/commands", s.handleCreateCommand)
+	mux.HandleFunc("PUT "+api+"/applications/{app}/commands", s.handleOverwriteCommands)
+	mux.HandleFunc("DELETE "+api+"/applications/{app}/commands/{id}", s.handleDeleteCommand)
+	mux.HandleFunc("GET "+api+"/applications/{app}/guilds/{guild}/commands", s.handleListCommands)
+	mux.HandleFunc("POST "+api+"/applications/{app}/guilds/{guild}/commands", s.handleCreateCommand)
+	mux.HandleFunc("PUT "+api+"/applications/{app}/guilds/{guild}/commands", s.handleOverwriteCommands)
+	mux.HandleFunc("DELETE "+api+"/applications/{app}/guilds/{guild}/commands/{id}", s.handleDeleteCommand)
+
+	mux.HandleFunc("GET "+api+"/users/@me", s.handleCurrentUser)
+	mux.HandleFunc("POST "+api+"/channels/{channel}/messages", s.handleCreateMessage)
+	mux.HandleFunc("POST "+api+"/interactions/{id}/{token}/callback", s.handleInteractionCallback)
+	mux.HandleFunc("PATCH "+api+"/webhooks/{app}/{token}/messages/@original", s.handleEditOriginalResponse)
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "404: Not Found")
+	})
+
+	return mux
+}
+
+// gatewayURL returns the websocket URL clients should connect to
+func (s *Server) gatewayURL() string {
+	return "ws" + strings.TrimPrefix(s.httpServer.URL, "http") + "/gateway"
+}
+
+func (s *Server) handleGateway(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"url": s.gatewayURL()})
+}
+
+func (s *Server) handleGatewayBot(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, discordgo.GatewayBotResponse{
+		URL:    s.gatewayURL(),
+		Shards: max(s.Shards, 1),
+		SessionStartLimit: discordgo.SessionInformation{
+			Total:          1000,
+			Remaining:      1000,
+			ResetAfter:     0,
+			MaxConcurrency: max(s.MaxConcurrency, 1),
+		},
+	})
+}
+
+func (s *Server) handleCurrentUser(w http.ResponseWriter, r *http.Request) {
+	if s.Token != "" && r.Header.Get("Authorization") != "Bot "+s.Token {
+		writeError(w, http.StatusUnauthorized, "401: Unauthorized")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.BotUser)
+}
+
+func (s *Server) handleListCommands(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Commands(r.PathValue("guild")))
+}
+
+func (s *Server) handleCreateCommand(w http.ResponseWriter, r *http.Request) {
+	var cmd discordgo.ApplicationCommand
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	guildID := r.PathValue("guild")
+	cmd.ID = s.nextID()
+	cmd.ApplicationID = r.PathValue("app")
+	cmd.GuildID = guildID
+
+	s.mu.Lock()
+	s.cmdWrites++
+	existing := s.commands[guildID]
+	replaced := false
+	for i, c := range existing {
+		if c.Name == cmd.Name && c.Type == cmd.Type {
+			existing[i] = &cmd
+			replaced = true
+		}
+	}
+	if !replaced {
+		s.commands[guildID] = append(existing, &cmd)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, cmd)
+}
+
+func (s *Server) handleOverwriteCommands(w http.ResponseWriter, r *http.Request) {
+	var cmds []*discordgo.ApplicationCommand
+	if err := json.NewDecoder(r.Body).Decode(&cmds); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	guildID := r.PathValue("guild")
+	for _, cmd := range cmds {
+		cmd.ID = s.nextID()
+		cmd.ApplicationID = r.PathValue("app")
+		cmd.GuildID = guildID
+	}
+
+	s.mu.Lock()
+	s.cmdWrites++
+	s.commands[guildID] = cmds
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, cmds)
+}
+
+func (s *Server) handleDeleteCommand(w http.ResponseWriter, r *http.Request) {
+	guildID := r.PathValue("guild")
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cmds := s.commands[guildID]
+	for i, c := range cmds {
+		if c.ID == id {
+			s.commands[guildID] = append(cmds[:i:i], cmds[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "Unknown application command")
+}
+
+func (s *Server) handleCreateMessage(w http.ResponseWriter, r *http.Request) {
+	payload, files, err := readPayload(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data, err := decodeMessageSend(payload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	msg := &discordgo.Message{
+		ID:         s.nextID(),
+		ChannelID:  r.PathValue("channel"),
+		Content:    data.Content,
+		Embeds:     data.Embeds,
+		Components: data.Components,
+		Author:     s.BotUser,
+		Timestamp:  time.Now().UTC(),
+	}
+	for _, f := range files {
+		msg.Attachments = append(msg.Attachments, &discordgo.MessageAttachment{
+			ID:       s.nextID(),
+			Filename: f.Name,
+			Size:     len(f.Content),
+		})
+	}
+
+	s.mu.Lock()
+	s.messages = append(s.messages, msg)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, msg)
+}
+
+func (s *Server) handleInteractionCallback(w http.ResponseWriter, r *http.Request) {
+	payload, _, err := readPayload(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp, err := decodeInteractionResponse(payload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.responses = append(s.responses, InteractionResponse{
+		InteractionID: r.PathValue("id"),
+		Token:         r.PathValue("token"),
+		Response:      *resp,
+	})
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleEditOriginalResponse(w http.ResponseWriter, r *http.Request) {
+	payload, files, err := readPayload(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var body struct {
+		Content *string `json:"content"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	edit := ResponseEdit{Token: r.PathValue("token"), Files: files}
+	if body.Content != nil {
+		edit.Content = *body.Content
+	}
+
+	s.mu.Lock()
+	s.edits = append(s.edits, edit)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, &discordgo.Message{
+		ID:      s.nextID(),
+		Content: edit.Content,
+		Author:  s.BotUser,
+	})
+}
+
+// writeJSON writes v as a JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error body shaped like Discord's
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"code": 0, "message": message})
+}
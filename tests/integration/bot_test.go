@@ -0,0 +1,196 @@
+package integration
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"mutsumi-bot/internal/bot"
+	"mutsumi-bot/internal/handlers"
+	"mutsumi-bot/internal/logger"
+	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
+	"mutsumi-bot/tests/fakediscord"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// staticContentService serves fixed content without a database
+type staticContentService map[string][]string
+
+func (s staticContentService) GetRandomContent(command, locale string) (string, error) {
+	if contents := s[command]; len(contents) > 0 {
+		return contents[0], nil
+	}
+	return "", services.ErrCategoryNotFound
+}
+
+func (s staticContentService) GetRandomEntry(command, locale string) (storage.Entry, error) {
+	content, err := s.GetRandomContent(command, locale)
+	return storage.Entry{Category: command, Content: content}, err
+}
+
+func (s staticContentService) GetContentCount(command string) (int, error) {
+	return len(s[command]), nil
+}
+
+func (s staticContentService) GetAvailableCategories() ([]string, error) {
+	categories := make([]string, 0, len(s))
+	for category := range s {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories, nil
+}
+
+func (s staticContentService) AddEntry(entry storage.Entry) (int64, error) {
+	s[entry.Category] = append(s[entry.Category], entry.Content)
+	return int64(len(s[entry.Category])), nil
+}
+
+func (s staticContentService) UpdateEntry(entry storage.Entry, actor string) error {
+	return nil
+}
+
+var _ services.ContentService = staticContentService(nil)
+
+// startTestBot runs the bot against a fake Discord server until the test ends
+func startTestBot(t *testing.T, contentService services.ContentService) *fakediscord.Server {
+	t.Helper()
+
+	if err := logger.Init(); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	t.Cleanup(logger.Close)
+
+	srv := fakediscord.New(t)
+
+	b, err := bot.New("test-token", bot.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create bot: %v", err)
+	}
+	b.AddHandler(handlers.NewMessageHandler(contentService).OnMessageCreate)
+	b.AddHandler(handlers.NewInteractionHandler(contentService).OnInteractionCreate)
+
+	commands := []*discordgo.ApplicationCommand{
+		{
+			Name:        "command",
+			Description: "Get content from a registered command",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "command",
+					Description: "Command to get content from",
+					Required:    true,
+				},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- b.StartWithCommands(ctx, commands)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Bot stopped with error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("Bot did not stop in time")
+		}
+	})
+
+	if !srv.WaitFor(5*time.Second, func() bool { return len(srv.Commands("")) == len(commands) }) {
+		t.Fatalf("Slash commands were not registered")
+	}
+
+	return srv
+}
+
+// TestBot_EndToEnd runs the bot against a fake Discord server.
+func TestBot_EndToEnd(t *testing.T) {
+	contentService := staticContentService{
+		"wooper": {"Wooper wooper wooper!"},
+		"cats":   {"Meow meow!"},
+	}
+	srv := startTestBot(t, contentService)
+
+	t.Run("identify", func(t *testing.T) {
+		identifies := srv.Identifies()
+		if len(identifies) != 1 {
+			t.Fatalf("Expected 1 identify but got %d", len(identifies))
+		}
+		if identifies[0].Token != "Bot test-token" {
+			t.Errorf("Expected token %q, got %q", "Bot test-token", identifies[0].Token)
+		}
+		want := discordgo.IntentsGuildMessages | discordgo.IntentMessageContent
+		if identifies[0].Intents != want {
+			t.Errorf("Expected intents %d, got %d", want, identifies[0].Intents)
+		}
+	})
+
+	t.Run("slash command registration", func(t *testing.T) {
+		commands := srv.Commands("")
+		if len(commands) != 1 || commands[0].Name != "command" {
+			t.Fatalf("Expected /command to be registered, got %+v", commands)
+		}
+	})
+
+	t.Run("message command", func(t *testing.T) {
+		before := len(srv.Messages())
+		err := srv.DispatchMessageCreate(&discordgo.Message{
+			ChannelID: "300000000000000001",
+			GuildID:   "400000000000000001",
+			Content:   "!wooper",
+			Author:    &discordgo.User{ID: "500000000000000001", Username: "tester"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to dispatch message: %v", err)
+		}
+
+		if !srv.WaitFor(5*time.Second, func() bool { return len(srv.Messages()) > before }) {
+			t.Fatalf("Bot did not reply to the message")
+		}
+		reply := srv.Messages()[before]
+		if reply.Content != "Wooper wooper wooper!" {
+			t.Errorf("Expected wooper content, got %q", reply.Content)
+		}
+		if reply.ChannelID != "300000000000000001" {
+			t.Errorf("Expected reply in the same channel, got %s", reply.ChannelID)
+		}
+	})
+
+	t.Run("slash command", func(t *testing.T) {
+		before := len(srv.InteractionResponses())
+		err := srv.DispatchInteractionCreate(&discordgo.Interaction{
+			Type:      discordgo.InteractionApplicationCommand,
+			ChannelID: "300000000000000001",
+			GuildID:   "400000000000000001",
+			Member: &discordgo.Member{
+				User: &discordgo.User{ID: "500000000000000001", Username: "tester"},
+			},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: "command",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: "command", Type: discordgo.ApplicationCommandOptionString, Value: "cats"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to dispatch interaction: %v", err)
+		}
+
+		if !srv.WaitFor(5*time.Second, func() bool { return len(srv.InteractionResponses()) > before }) {
+			t.Fatalf("Bot did not respond to the interaction")
+		}
+		resp := srv.InteractionResponses()[before].Response
+		if resp.Data == nil || resp.Data.Content != "Meow meow!" {
+			t.Errorf("Expected cats content, got %+v", resp.Data)
+		}
+	})
+}
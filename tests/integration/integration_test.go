@@ -1,24 +1,24 @@
 package integration
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"mutsumi-bot/internal/logger"
 	"mutsumi-bot/internal/services"
+	"mutsumi-bot/internal/storage"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 // TestIntegration tests the overall flow of the mutsumi-bot application.
+// It uses DATABASE_CONNECTION when set, and a seeded SQLite database otherwise.
 func TestIntegration(t *testing.T) {
-	// Skip if running in CI or if database connection is not available
-	dbConn := os.Getenv("DATABASE_CONNECTION")
-	if os.Getenv("CI") != "" || dbConn == "" {
-		t.Skip("Skipping integration test - no database connection or running in CI")
-	}
-
 	// Initialize logger for integration tests
 	err := logger.Init()
 	if err != nil {
@@ -26,6 +26,12 @@ func TestIntegration(t *testing.T) {
 	}
 	defer logger.Close()
 
+	dbConn := os.Getenv("DATABASE_CONNECTION")
+	if dbConn == "" {
+		dbConn = "sqlite://" + filepath.Join(t.TempDir(), "integration.db")
+		seedDatabase(t, dbConn)
+	}
+
 	// Test database service initialization
 	dbService, err := services.NewDatabaseService(dbConn)
 	if err != nil {
@@ -34,28 +40,54 @@ func TestIntegration(t *testing.T) {
 	defer dbService.Close()
 
 	// Test that commands can be retrieved
-	commands := dbService.GetAvailableCategories()
+	commands, err := dbService.GetAvailableCategories()
+	if err != nil {
+		t.Fatalf("Failed to get categories: %v", err)
+	}
 	if len(commands) == 0 {
 		t.Log("No commands found in database - this is okay if database is empty")
 	}
 
 	// Test getting random content for existing commands
 	for _, command := range commands {
-		content := dbService.GetRandomContent(command)
-		if content == "" {
-			t.Errorf("Expected content for command %s but got empty", command)
+		content, err := dbService.GetRandomContent(command, "")
+		if err != nil || content == "" {
+			t.Errorf("Expected content for command %s but got %q, %v", command, content, err)
 		}
 	}
 
 	// Test content count
 	for _, command := range commands {
-		count := dbService.GetContentCount(command)
-		if count <= 0 {
+		count, err := dbService.GetContentCount(command)
+		if err != nil || count <= 0 {
 			t.Errorf("Expected positive count for command %s but got %d", command, count)
 		}
 	}
 }
 
+// seedDatabase adds a few entries to the database at dsn
+func seedDatabase(t *testing.T, dsn string) {
+	t.Helper()
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, dsn)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer store.Close()
+
+	seed := []struct{ category, content string }{
+		{"wooper", "Wooper is the best!"},
+		{"wooper", "Wooper wooper wooper!"},
+		{"cats", "Meow meow!"},
+	}
+	for _, e := range seed {
+		if _, err := store.AddContent(ctx, e.category, e.content); err != nil {
+			t.Fatalf("Failed to seed content: %v", err)
+		}
+	}
+}
+
 // TestMain tests the main function components without running the full application.
 func TestMain(t *testing.T) {
 	// This test verifies that the main function components can be initialized
@@ -74,3 +106,85 @@ func TestMain(t *testing.T) {
 	_ = services.DatabaseService{}
 	_ = sql.DB{}
 }
+
+// TestDatabaseService_Unavailable tests that failing database calls are
+// reported as an outage rather than as missing content.
+func TestDatabaseService_Unavailable(t *testing.T) {
+	if err := logger.Init(); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer logger.Close()
+
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "outage.db")
+	seedDatabase(t, dsn)
+
+	store, err := storage.Open(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	dbService := services.NewDatabaseServiceWithStore(store)
+
+	if _, err := dbService.GetRandomContent("wooper", ""); err != nil || !dbService.Healthy() || dbService.Ready() != nil {
+		t.Fatalf("Expected a healthy service before the outage")
+	}
+
+	// Closing the store makes every call fail like a lost connection
+	store.Close()
+
+	if content, err := dbService.GetRandomContent("wooper", ""); !errors.Is(err, services.ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable during the outage, got %q, %v", content, err)
+	}
+	if _, err := dbService.GetAvailableCategories(); !errors.Is(err, services.ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable from GetAvailableCategories, got %v", err)
+	}
+	if dbService.Healthy() {
+		t.Errorf("Expected the service to be unhealthy")
+	}
+	if err := dbService.Ready(); !errors.Is(err, storage.ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable from Ready, got %v", err)
+	}
+}
+
+// TestDatabaseService_Errors tests the sentinel errors returned for missing
+// and blank categories, with and without the category cache.
+func TestDatabaseService_Errors(t *testing.T) {
+	if err := logger.Init(); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer logger.Close()
+
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "errors.db")
+	seedDatabase(t, dsn)
+
+	store, err := storage.Open(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer store.Close()
+	if _, err := store.AddContent(context.Background(), "blank", ""); err != nil {
+		t.Fatalf("Failed to seed content: %v", err)
+	}
+
+	for _, ttl := range []time.Duration{0, time.Minute} {
+		dbService := services.NewDatabaseServiceWithStore(store)
+		dbService.SetCategoryCacheTTL(ttl)
+
+		tests := []struct {
+			category string
+			wantErr  error
+		}{
+			{"wooper", nil},
+			{"dogs", services.ErrCategoryNotFound},
+			{"blank", services.ErrEmptyCategory},
+		}
+		for _, tt := range tests {
+			content, err := dbService.GetRandomContent(tt.category, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("cache %v, %s: expected error %v, got %v", ttl, tt.category, tt.wantErr, err)
+			}
+			if tt.wantErr == nil && content == "" {
+				t.Errorf("cache %v, %s: expected content", ttl, tt.category)
+			}
+		}
+	}
+}